@@ -0,0 +1,26 @@
+// Package blob stores the contents of uploaded files, addressed by key, outside of the database.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned when no blob is stored under a key.
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that could escape the store, such as ones containing path separators.
+var ErrInvalidKey = errors.New("invalid blob key")
+
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey accepts the opaque identifiers blobs are stored under and nothing that could be read as a path.
+func validKey(key string) bool {
+	return key != "" && key != "." && key != ".." && !strings.ContainsAny(key, `/\`)
+}
@@ -0,0 +1,38 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating local store: %v", err)
+	}
+
+	err = store.Put(ctx, "some-key", strings.NewReader("Hello, world!"), "text/plain")
+	assert.NoError(t, err, "Unexpected error while storing a blob")
+
+	body, err := store.Get(ctx, "some-key")
+	assert.NoError(t, err, "Unexpected error while retrieving a blob")
+	content, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "Hello, world!", string(content), "Mismatch in blob content")
+
+	assert.NoError(t, store.Delete(ctx, "some-key"), "Unexpected error while deleting a blob")
+	assert.NoError(t, store.Delete(ctx, "some-key"), "Expected deleting a missing blob to succeed")
+
+	_, err = store.Get(ctx, "some-key")
+	assert.Equal(t, ErrNotFound, err, "Expected a deleted blob not to be found")
+
+	for _, key := range []string{"", "..", "../escape", `dir\file`} {
+		assert.Equal(t, ErrInvalidKey, store.Put(ctx, key, strings.NewReader(""), "text/plain"), "Expected key %q to be refused", key)
+	}
+}
@@ -0,0 +1,129 @@
+// Package emoji recognises single emoji as defined by Unicode, including
+// skin tone variants, keycaps, flags and sequences joined with ZWJ.
+package emoji
+
+import "unicode"
+
+const (
+	maxLength = 64
+
+	zeroWidthJoiner   = '\u200D'
+	variationSelector = '\uFE0F'
+	combiningKeycap   = '\u20E3'
+	blackFlag         = '\U0001F3F4'
+	cancelTag         = '\U000E007F'
+)
+
+// pictographic approximates the Extended_Pictographic property, the set of characters emoji are built from.
+var pictographic = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00A9, Hi: 0x00A9, Stride: 1},
+		{Lo: 0x00AE, Hi: 0x00AE, Stride: 1},
+		{Lo: 0x203C, Hi: 0x203C, Stride: 1},
+		{Lo: 0x2049, Hi: 0x2049, Stride: 1},
+		{Lo: 0x2122, Hi: 0x2122, Stride: 1},
+		{Lo: 0x2139, Hi: 0x2139, Stride: 1},
+		{Lo: 0x2194, Hi: 0x2199, Stride: 1},
+		{Lo: 0x21A9, Hi: 0x21AA, Stride: 1},
+		{Lo: 0x231A, Hi: 0x231B, Stride: 1},
+		{Lo: 0x2328, Hi: 0x2328, Stride: 1},
+		{Lo: 0x23CF, Hi: 0x23CF, Stride: 1},
+		{Lo: 0x23E9, Hi: 0x23F3, Stride: 1},
+		{Lo: 0x23F8, Hi: 0x23FA, Stride: 1},
+		{Lo: 0x24C2, Hi: 0x24C2, Stride: 1},
+		{Lo: 0x25AA, Hi: 0x25AB, Stride: 1},
+		{Lo: 0x25B6, Hi: 0x25B6, Stride: 1},
+		{Lo: 0x25C0, Hi: 0x25C0, Stride: 1},
+		{Lo: 0x25FB, Hi: 0x25FE, Stride: 1},
+		{Lo: 0x2600, Hi: 0x27BF, Stride: 1},
+		{Lo: 0x2934, Hi: 0x2935, Stride: 1},
+		{Lo: 0x2B05, Hi: 0x2B07, Stride: 1},
+		{Lo: 0x2B1B, Hi: 0x2B1C, Stride: 1},
+		{Lo: 0x2B50, Hi: 0x2B50, Stride: 1},
+		{Lo: 0x2B55, Hi: 0x2B55, Stride: 1},
+		{Lo: 0x3030, Hi: 0x3030, Stride: 1},
+		{Lo: 0x303D, Hi: 0x303D, Stride: 1},
+		{Lo: 0x3297, Hi: 0x3297, Stride: 1},
+		{Lo: 0x3299, Hi: 0x3299, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1F000, Hi: 0x1F1E5, Stride: 1},
+		{Lo: 0x1F200, Hi: 0x1F3FA, Stride: 1},
+		{Lo: 0x1F400, Hi: 0x1FAFF, Stride: 1},
+	},
+}
+
+var (
+	skinTone          = &unicode.RangeTable{R32: []unicode.Range32{{Lo: 0x1F3FB, Hi: 0x1F3FF, Stride: 1}}}
+	regionalIndicator = &unicode.RangeTable{R32: []unicode.Range32{{Lo: 0x1F1E6, Hi: 0x1F1FF, Stride: 1}}}
+	tag               = &unicode.RangeTable{R32: []unicode.Range32{{Lo: 0xE0020, Hi: 0xE007E, Stride: 1}}}
+)
+
+// Valid reports whether s is exactly one emoji.
+func Valid(s string) bool {
+	if len(s) == 0 || len(s) > maxLength {
+		return false
+	}
+
+	runes := []rune(s)
+	if isFlag(runes) || isKeycap(runes) {
+		return true
+	}
+
+	// Anything else is one or more pictographs, each with optional modifiers, joined by ZWJ.
+	start := 0
+	for i, r := range runes {
+		if r == zeroWidthJoiner {
+			if !isElement(runes[start:i]) {
+				return false
+			}
+			start = i + 1
+		}
+	}
+	return isElement(runes[start:])
+}
+
+// isFlag reports whether runes is a pair of regional indicators.
+func isFlag(runes []rune) bool {
+	return len(runes) == 2 && unicode.Is(regionalIndicator, runes[0]) && unicode.Is(regionalIndicator, runes[1])
+}
+
+// isKeycap reports whether runes is a digit, # or * followed by the combining keycap.
+func isKeycap(runes []rune) bool {
+	if len(runes) == 3 && runes[1] == variationSelector {
+		runes = []rune{runes[0], runes[2]}
+	}
+	if len(runes) != 2 || runes[1] != combiningKeycap {
+		return false
+	}
+	return (runes[0] >= '0' && runes[0] <= '9') || runes[0] == '#' || runes[0] == '*'
+}
+
+// isElement reports whether runes is a single pictograph followed by an optional
+// variation selector, skin tone and, for subdivision flags, a tag sequence.
+func isElement(runes []rune) bool {
+	if len(runes) == 0 || !unicode.Is(pictographic, runes[0]) {
+		return false
+	}
+
+	rest := runes[1:]
+	if len(rest) > 0 && rest[0] == variationSelector {
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && unicode.Is(skinTone, rest[0]) {
+		rest = rest[1:]
+	}
+	if len(rest) == 0 {
+		return true
+	}
+
+	if runes[0] != blackFlag || rest[len(rest)-1] != cancelTag || len(rest) < 2 {
+		return false
+	}
+	for _, r := range rest[:len(rest)-1] {
+		if !unicode.Is(tag, r) {
+			return false
+		}
+	}
+	return true
+}
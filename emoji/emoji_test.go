@@ -0,0 +1,44 @@
+package emoji
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		input string
+		valid bool
+	}{
+		{"single emoji", "👍", true},
+		{"emoji with variation selector", "❤️", true},
+		{"text style emoji", "❤", true},
+		{"skin tone", "👍🏽", true},
+		{"zwj sequence", "👩‍💻", true},
+		{"family", "👨‍👩‍👧‍👦", true},
+		{"zwj sequence with skin tone", "🧑🏿‍🚀", true},
+		{"flag", "🇮🇳", true},
+		{"subdivision flag", "🏴󠁧󠁢󠁳󠁣󠁴󠁿", true},
+		{"keycap", "#️⃣", true},
+		{"keycap without variation selector", "7⃣", true},
+		{"empty", "", false},
+		{"letter", "a", false},
+		{"word", "like", false},
+		{"two emoji", "👍👍", false},
+		{"emoji and text", "👍ok", false},
+		{"lone regional indicator", "🇮", false},
+		{"three regional indicators", "🇮🇳🇮", false},
+		{"lone skin tone", "🏽", false},
+		{"dangling joiner", "👩\u200D", false},
+		{"leading joiner", "\u200D👩", false},
+		{"digit without keycap", "7", false},
+		{"tags on another emoji", "👍󠁧󠁢󠁿", false},
+		{"unterminated tags", "🏴󠁧󠁢", false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.valid, Valid(tc.input), "TEST: %s", tc.desc)
+	}
+}
@@ -31,6 +31,16 @@ func HttpStatusError(statusCode int, message string) errors.Raw {
 		statusMessage = "Forbidden"
 	case 404:
 		statusMessage = "Not Found"
+	case 409:
+		statusMessage = "Conflict"
+	case 413:
+		statusMessage = "Payload Too Large"
+	case 415:
+		statusMessage = "Unsupported Media Type"
+	case 422:
+		statusMessage = "Unprocessable Entity"
+	case 429:
+		statusMessage = "Too Many Requests"
 	case 500:
 		statusMessage = "Internal Server Error"
 	default:
@@ -24,6 +24,11 @@ func TestHttpStatusError(t *testing.T) {
 		{401, "Unauthorized - Test message"},
 		{403, "Forbidden - Test message"},
 		{404, "Not Found - Test message"},
+		{409, "Conflict - Test message"},
+		{413, "Payload Too Large - Test message"},
+		{415, "Unsupported Media Type - Test message"},
+		{422, "Unprocessable Entity - Test message"},
+		{429, "Too Many Requests - Test message"},
 		{500, "Internal Server Error - Test message"},
 		{999, "Unknown Status Code - Test message"},
 	}
@@ -0,0 +1,306 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/blob"
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/media"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/google/uuid"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+const (
+	// multipartMemory is how much of an upload is buffered in memory before spilling to a temporary file.
+	multipartMemory = 1 << 20
+	// multipartOverhead allows for the boundaries and headers around the uploaded file.
+	multipartOverhead = 1 << 16
+	sniffLength       = 512
+	maxFileNameLength = 255
+)
+
+// HandleUploadAttachment stores a file sent as the "file" field of a multipart form.
+// The type of the file is sniffed from its content and has to agree with the type it was sent as.
+func (h Handler) HandleUploadAttachment(ctx *gofr.Context) (interface{}, error) {
+	maxSize := int64(IntConfig(ctx.Config, "ATTACHMENT_MAX_SIZE", 25<<20))
+
+	req := ctx.Request()
+	req.Body = http.MaxBytesReader(nil, req.Body, maxSize+multipartOverhead)
+
+	err := req.ParseMultipartForm(multipartMemory)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, e.HttpStatusError(413, "File is too large")
+		}
+		return nil, e.HttpStatusError(400, "Invalid multipart form")
+	}
+	defer req.MultipartForm.RemoveAll()
+
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		return nil, e.HttpStatusError(400, "Missing file")
+	}
+	defer file.Close()
+
+	if header.Size > maxSize {
+		return nil, e.HttpStatusError(413, "File is too large")
+	}
+
+	sniff := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, e.HttpStatusError(400, "Empty file")
+	}
+
+	contentType := mediaType(http.DetectContentType(sniff[:n]))
+	if !model.AttachmentTypes[contentType] {
+		return nil, e.HttpStatusError(415, "Files of type "+contentType+" are not supported")
+	}
+
+	declared := mediaType(header.Header.Get("Content-Type"))
+	if !(declared == "" || declared == "application/octet-stream" || declared == contentType) {
+		return nil, e.HttpStatusError(400, "File content does not match its type "+declared)
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	attachment := NewAttachment(ctx.Value("userId").(string), fileName(header.Filename), contentType, header.Size)
+	if media.Supported(contentType) {
+		attachment.Status = model.AttachmentPending
+	}
+
+	err = h.Blobs.Put(ctx, attachment.ID, file, contentType)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	err = h.Attachment.CreateAttachment(ctx, attachment)
+	if err != nil {
+		ctx.Logger.Error(err)
+		h.Blobs.Delete(ctx, attachment.ID)
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	// Images are processed in the background; a full queue leaves them to the sweep of pending attachments.
+	if attachment.Status == model.AttachmentPending {
+		h.Media.Enqueue(attachment)
+	}
+
+	return types.Raw{Data: attachment}, nil
+}
+
+// HandleGetAttachment hands out a short-lived signed URL the attachment can be downloaded from,
+// along with URLs for its thumbnails. Clients poll it for the status of images still being processed.
+func (h Handler) HandleGetAttachment(ctx *gofr.Context) (interface{}, error) {
+	attachmentId := ctx.PathParam("id")
+	if strings.TrimSpace(attachmentId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter attachmentId")
+	}
+
+	_, err := uuid.Parse(attachmentId)
+	if err != nil {
+		return nil, e.HttpStatusError(404, "Attachment does not exists")
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	attachment, err := h.Attachment.GetAttachment(ctx, userId, attachmentId)
+	if err != nil {
+		return nil, attachmentError(ctx, err)
+	}
+
+	secret := ctx.Config.Get("ATTACHMENT_URL_SECRET")
+	if secret == "" {
+		ctx.Logger.Error("ATTACHMENT_URL_SECRET is not configured")
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	expiresAt := time.Now().Add(SecondsConfig(ctx.Config, "ATTACHMENT_URL_TTL", 5*60)).Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("user", userId)
+	query.Set("expires", expires)
+	query.Set("signature", signDownload(secret, attachmentId, userId, expires))
+
+	content := "/attachments/" + url.PathEscape(attachmentId) + "/content?"
+	response := model.AttachmentURLResponse{
+		Attachment: *attachment,
+		URL:        content + query.Encode(),
+		ExpiresAt:  expiresAt,
+	}
+
+	// Thumbnails share the signature of the attachment, the size only picks which copy is served.
+	for _, thumbnail := range attachment.Thumbnails {
+		if response.ThumbnailURLs == nil {
+			response.ThumbnailURLs = make(map[string]string, len(attachment.Thumbnails))
+		}
+		query.Set("size", thumbnail.Size)
+		response.ThumbnailURLs[thumbnail.Size] = content + query.Encode()
+	}
+
+	return types.Raw{Data: response}, nil
+}
+
+// HandleDownloadAttachment serves the content of an attachment, or of one of its thumbnails if a size is given,
+// to the holder of a valid signed URL. The user the URL was signed for still has to be allowed to see the attachment.
+// Images are only served once their metadata has been stripped.
+func (h Handler) HandleDownloadAttachment(ctx *gofr.Context) (interface{}, error) {
+	attachmentId := ctx.PathParam("id")
+	userId := ctx.Param("user")
+	expires := ctx.Param("expires")
+	signature := ctx.Param("signature")
+
+	_, err := uuid.Parse(attachmentId)
+	if err != nil {
+		return nil, e.HttpStatusError(404, "Attachment does not exists")
+	}
+
+	secret := ctx.Config.Get("ATTACHMENT_URL_SECRET")
+	if secret == "" {
+		ctx.Logger.Error("ATTACHMENT_URL_SECRET is not configured")
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	expected := signDownload(secret, attachmentId, userId, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, e.HttpStatusError(403, "Invalid signature")
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, e.HttpStatusError(403, "Download link has expired")
+	}
+
+	attachment, err := h.Attachment.GetAttachment(ctx, userId, attachmentId)
+	if err != nil {
+		return nil, attachmentError(ctx, err)
+	}
+
+	switch attachment.Status {
+	case model.AttachmentReady:
+	case model.AttachmentPending:
+		return nil, e.HttpStatusError(409, "Attachment is still being processed")
+	default:
+		return nil, e.HttpStatusError(404, "Attachment does not exists")
+	}
+
+	key, contentType := attachment.BlobID, attachment.ContentType
+	if size := ctx.Param("size"); size != "" {
+		thumbnail := findThumbnail(attachment.Thumbnails, size)
+		if thumbnail == nil {
+			return nil, e.HttpStatusError(404, "Thumbnail does not exists")
+		}
+		key, contentType = thumbnailKey(attachment.BlobID, size), thumbnail.ContentType
+	}
+
+	body, err := h.Blobs.Get(ctx, key)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == blob.ErrNotFound {
+			return nil, e.HttpStatusError(404, "Attachment does not exists")
+		}
+		return nil, e.HttpStatusError(500, "")
+	}
+	defer body.Close()
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	return types.File{Content: content, ContentType: contentType}, nil
+}
+
+func findThumbnail(thumbnails []model.Thumbnail, size string) *model.Thumbnail {
+	for i := range thumbnails {
+		if thumbnails[i].Size == size {
+			return &thumbnails[i]
+		}
+	}
+	return nil
+}
+
+// thumbnailKey is where the thumbnail of the given size is kept in the blob store, next to the attachment.
+func thumbnailKey(attachmentId, size string) string {
+	return attachmentId + "." + size
+}
+
+// attachFiles adds the attachments with attachmentIds to the message. They have to be uploaded by the sender,
+// processed and not be sent with another message yet.
+func (h Handler) attachFiles(ctx *gofr.Context, message *model.Message, attachmentIds []string) error {
+	if len(attachmentIds) == 0 {
+		return nil
+	}
+
+	attachments, err := h.Attachment.GetUnsentAttachments(ctx, message.From, attachmentIds)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return e.HttpStatusError(500, "")
+	}
+	if len(*attachments) != len(attachmentIds) {
+		return e.HttpStatusError(400, "Invalid Parameter attachmentIds")
+	}
+
+	message.Attachments = *attachments
+	return nil
+}
+
+func attachmentError(ctx *gofr.Context, err error) error {
+	ctx.Logger.Info("err: ", err.Error())
+	if err == sql.ErrNoRows {
+		return e.HttpStatusError(404, "Attachment does not exists")
+	} else if err == e.NewError("You are not authorized to see that attachment") {
+		return e.HttpStatusError(403, err.Error())
+	}
+	return e.HttpStatusError(500, "")
+}
+
+// signDownload authenticates a download of the attachment by the user until expires.
+func signDownload(secret, attachmentId, userId, expires string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(attachmentId + "\n" + userId + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func mediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// fileName keeps the base name of an uploaded file, as clients may send full paths.
+func fileName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if runes := []rune(name); len(runes) > maxFileNameLength {
+		name = string(runes[:maxFileNameLength])
+	}
+	return name
+}
@@ -0,0 +1,347 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/blob"
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/request"
+	"gofr.dev/pkg/gofr/responder"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// The attachments mockAttachmentStore knows of. Any other attachment is a text file of the user.
+const (
+	testAttachmentID        = "7d3f1e2a-9b4c-4d5e-8f6a-1b2c3d4e5f60"
+	testImageAttachmentID   = "0e1d2c3b-4a59-4687-9a5b-6c7d8e9f0a1b"
+	testPendingAttachmentID = "5a6b7c8d-9e0f-4a1b-8c2d-3e4f5a6b7c8d"
+	testFailedAttachmentID  = "8c9d0e1f-2a3b-4c4d-9e5f-6a7b8c9d0e1f"
+	testForeignAttachmentID = "3f4e5d6c-7b8a-4968-8576-a4b3c2d1e0f9"
+	testMissingAttachmentID = "b1c2d3e4-f5a6-4b7c-8d9e-0f1a2b3c4d5e"
+)
+
+type mockAttachmentStore struct {
+	unsent  int
+	updated *model.Attachment
+}
+
+func (mockAttachmentStore) CreateAttachment(ctx *gofr.Context, attachment model.Attachment) error {
+	return nil
+}
+
+func (mockAttachmentStore) GetAttachment(ctx *gofr.Context, userId, attachmentId string) (*model.Attachment, error) {
+	switch attachmentId {
+	case testForeignAttachmentID:
+		return nil, e.NewError("You are not authorized to see that attachment")
+	case testMissingAttachmentID:
+		return nil, sql.ErrNoRows
+	case testPendingAttachmentID:
+		return &model.Attachment{ID: attachmentId, OwnerID: userId, FileName: "cat.png", ContentType: "image/png", Status: model.AttachmentPending, BlobID: attachmentId}, nil
+	case testFailedAttachmentID:
+		return &model.Attachment{ID: attachmentId, OwnerID: userId, FileName: "cat.png", ContentType: "image/png", Status: model.AttachmentFailed, BlobID: attachmentId}, nil
+	case testImageAttachmentID:
+		return &model.Attachment{ID: attachmentId, OwnerID: userId, FileName: "cat.png", ContentType: "image/png", Status: model.AttachmentReady, BlobID: attachmentId,
+			Thumbnails: []model.Thumbnail{{Size: "small", Width: 96, Height: 48, ContentType: "image/jpeg"}}}, nil
+	}
+	return &model.Attachment{ID: attachmentId, OwnerID: userId, FileName: "note.txt", ContentType: "text/plain", Status: model.AttachmentReady, BlobID: attachmentId}, nil
+}
+
+func (m mockAttachmentStore) GetUnsentAttachments(ctx *gofr.Context, userId string, attachmentIds []string) (*[]model.Attachment, error) {
+	attachments := make([]model.Attachment, 0)
+	for _, attachmentId := range attachmentIds[:m.unsent] {
+		attachments = append(attachments, model.Attachment{ID: attachmentId, OwnerID: userId})
+	}
+	return &attachments, nil
+}
+
+func (mockAttachmentStore) GetPendingAttachments(ctx *gofr.Context, createdBefore time.Time, limit uint) (*[]model.Attachment, error) {
+	return &[]model.Attachment{}, nil
+}
+
+func (m mockAttachmentStore) UpdateAttachment(ctx *gofr.Context, attachment model.Attachment) error {
+	if m.updated != nil {
+		*m.updated = attachment
+	}
+	return nil
+}
+
+func newUploadContext(app *gofr.Gofr, fileName, contentType string, content []byte) *gofr.Context {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if content != nil {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, fileName))
+		header.Set("Content-Type", contentType)
+		part, _ := form.CreatePart(header)
+		part.Write(content)
+	}
+	form.Close()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://dummy/attachments", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+
+	ctx := gofr.NewContext(responder.NewContextualResponder(w, r), request.NewHTTPRequest(r), app)
+	ctx.Context = context.WithValue(context.Background(), "userId", "someUserId")
+	return ctx
+}
+
+func TestHandleUploadAttachment(t *testing.T) {
+	app := gofr.New()
+	t.Setenv("ATTACHMENT_MAX_SIZE", "1024")
+
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating blob store: %v", err)
+	}
+	h := Handler{Attachment: mockAttachmentStore{}, Blobs: blobs}
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	testCases := []struct {
+		desc        string
+		fileName    string
+		contentType string
+		content     []byte
+		err         error
+	}{
+		{"upload success", "C:\\Users\\me\\note.txt", "text/plain", []byte("Hello, world!"), nil},
+		{"upload without declared type", "cat.png", "application/octet-stream", png, nil},
+		{"missing file", "", "", nil, e.HttpStatusError(400, "Missing file")},
+		{"empty file", "note.txt", "text/plain", []byte{}, e.HttpStatusError(400, "Empty file")},
+		{"content does not match type", "cat.jpg", "image/jpeg", png, e.HttpStatusError(400, "File content does not match its type image/jpeg")},
+		{"unsupported type", "setup.exe", "application/octet-stream", []byte("MZ\x90\x00\x03\x00\x00\x00"), e.HttpStatusError(415, "Files of type application/octet-stream are not supported")},
+		{"file too large", "big.txt", "text/plain", bytes.Repeat([]byte("a"), 2048), e.HttpStatusError(413, "File is too large")},
+	}
+
+	for _, tc := range testCases {
+		ctx := newUploadContext(app, tc.fileName, tc.contentType, tc.content)
+
+		result, err := h.HandleUploadAttachment(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err != nil {
+			continue
+		}
+
+		attachment := result.(types.Raw).Data.(model.Attachment)
+		assert.Equal(t, "someUserId", attachment.OwnerID, "TEST: %s: mismatch in owner", tc.desc)
+		assert.Equal(t, int64(len(tc.content)), attachment.Size, "TEST: %s: mismatch in size", tc.desc)
+		if attachment.ContentType == "image/png" {
+			assert.Equal(t, model.AttachmentPending, attachment.Status, "TEST: %s: expected images to await processing", tc.desc)
+		} else {
+			assert.Equal(t, model.AttachmentReady, attachment.Status, "TEST: %s: expected other files to be ready", tc.desc)
+		}
+
+		stored, err := blobs.Get(ctx, attachment.ID)
+		assert.NoError(t, err, "TEST: %s: expected the file to be stored", tc.desc)
+		stored.Close()
+	}
+
+	result, _ := h.HandleUploadAttachment(newUploadContext(app, "C:\\Users\\me\\note.txt", "text/plain; charset=utf-8", []byte("Hello")))
+	assert.Equal(t, "note.txt", result.(types.Raw).Data.(model.Attachment).FileName, "Expected only the base name of the file to be kept")
+	assert.Equal(t, "text/plain", result.(types.Raw).Data.(model.Attachment).ContentType, "Expected the sniffed content type")
+}
+
+func TestHandleDownloadAttachment(t *testing.T) {
+	app := gofr.New()
+	t.Setenv("ATTACHMENT_URL_SECRET", "someSecret")
+
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating blob store: %v", err)
+	}
+	blobs.Put(context.Background(), testAttachmentID, bytes.NewReader([]byte("Hello, world!")), "text/plain")
+
+	h := Handler{Attachment: mockAttachmentStore{}, Blobs: blobs}
+
+	ctx := newTestContext(app, http.MethodGet, "http://dummy", nil)
+	ctx.SetPathParams(map[string]string{"id": testAttachmentID})
+
+	result, err := h.HandleGetAttachment(ctx)
+	assert.NoError(t, err, "Unexpected error while signing a download URL")
+
+	signed, _ := url.Parse(result.(types.Raw).Data.(model.AttachmentURLResponse).URL)
+	assert.Equal(t, "/attachments/"+testAttachmentID+"/content", signed.Path, "Mismatch in download path")
+
+	download := func(query url.Values) (interface{}, error) {
+		ctx := newTestContext(app, http.MethodGet, "http://dummy"+signed.Path+"?"+query.Encode(), nil)
+		ctx.SetPathParams(map[string]string{"id": testAttachmentID})
+		return h.HandleDownloadAttachment(ctx)
+	}
+
+	result, err = download(signed.Query())
+	assert.NoError(t, err, "Unexpected error while downloading")
+	assert.Equal(t, types.File{Content: []byte("Hello, world!"), ContentType: "text/plain"}, result, "Mismatch in downloaded file")
+
+	tampered := signed.Query()
+	tampered.Set("user", "someoneElse")
+	_, err = download(tampered)
+	assert.Equal(t, e.HttpStatusError(403, "Invalid signature"), err, "Expected a URL signed for someone else to be refused")
+
+	expired := signed.Query()
+	expires := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	expired.Set("expires", expires)
+	expired.Set("signature", signDownload("someSecret", testAttachmentID, "someUserId", expires))
+	_, err = download(expired)
+	assert.Equal(t, e.HttpStatusError(403, "Download link has expired"), err, "Expected an expired URL to be refused")
+
+	blobs.Put(context.Background(), testImageAttachmentID+".small", bytes.NewReader([]byte("thumbnail")), "image/jpeg")
+
+	ctx = newTestContext(app, http.MethodGet, "http://dummy", nil)
+	ctx.SetPathParams(map[string]string{"id": testImageAttachmentID})
+
+	result, err = h.HandleGetAttachment(ctx)
+	assert.NoError(t, err, "Unexpected error while signing a download URL")
+
+	thumbnailURLs := result.(types.Raw).Data.(model.AttachmentURLResponse).ThumbnailURLs
+	assert.Len(t, thumbnailURLs, 1, "Expected a URL for every thumbnail")
+
+	thumbnail, _ := url.Parse(thumbnailURLs["small"])
+	ctx = newTestContext(app, http.MethodGet, "http://dummy"+thumbnail.String(), nil)
+	ctx.SetPathParams(map[string]string{"id": testImageAttachmentID})
+
+	result, err = h.HandleDownloadAttachment(ctx)
+	assert.NoError(t, err, "Unexpected error while downloading a thumbnail")
+	assert.Equal(t, types.File{Content: []byte("thumbnail"), ContentType: "image/jpeg"}, result, "Mismatch in downloaded thumbnail")
+
+	missing := thumbnail.Query()
+	missing.Set("size", "large")
+	ctx = newTestContext(app, http.MethodGet, "http://dummy"+thumbnail.Path+"?"+missing.Encode(), nil)
+	ctx.SetPathParams(map[string]string{"id": testImageAttachmentID})
+
+	_, err = h.HandleDownloadAttachment(ctx)
+	assert.Equal(t, e.HttpStatusError(404, "Thumbnail does not exists"), err, "Expected a missing thumbnail to be refused")
+
+	for _, tc := range []struct {
+		attachmentID string
+		err          error
+	}{
+		{testPendingAttachmentID, e.HttpStatusError(409, "Attachment is still being processed")},
+		{testFailedAttachmentID, e.HttpStatusError(404, "Attachment does not exists")},
+		{"someAttachment", e.HttpStatusError(404, "Attachment does not exists")},
+	} {
+		blobs.Put(context.Background(), tc.attachmentID, bytes.NewReader([]byte("original")), "image/png")
+		expires := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+		query := url.Values{"user": {"someUserId"}, "expires": {expires}, "signature": {signDownload("someSecret", tc.attachmentID, "someUserId", expires)}}
+		ctx := newTestContext(app, http.MethodGet, "http://dummy/attachments/"+tc.attachmentID+"/content?"+query.Encode(), nil)
+		ctx.SetPathParams(map[string]string{"id": tc.attachmentID})
+
+		_, err := h.HandleDownloadAttachment(ctx)
+		assert.Equal(t, tc.err, err, "TEST: %s: expected unprocessed images not to be served", tc.attachmentID)
+	}
+
+	for _, tc := range []struct {
+		attachmentID string
+		err          error
+	}{
+		{testForeignAttachmentID, e.HttpStatusError(403, "You are not authorized to see that attachment")},
+		{testMissingAttachmentID, e.HttpStatusError(404, "Attachment does not exists")},
+		{"someAttachment", e.HttpStatusError(404, "Attachment does not exists")},
+	} {
+		ctx := newTestContext(app, http.MethodGet, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"id": tc.attachmentID})
+
+		_, err := h.HandleGetAttachment(ctx)
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.attachmentID)
+	}
+}
+
+func TestHandleSendMessageWithAttachments(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc   string
+		body   string
+		unsent int
+		err    error
+	}{
+		{"attachments without content", `{"recipientId":"` + testRecipientID + `","attachmentIds":["a1","a2"]}`, 2, nil},
+		{"attachment not unsent by sender", `{"recipientId":"` + testRecipientID + `","content":"Hi","attachmentIds":["a1","a2"]}`, 1, e.HttpStatusError(400, "Invalid Parameter attachmentIds")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Auth: existingTCAuthStore{}, Message: successfulTCMessageStore{}, Friend: mockFriendStore{}, Block: mockBlockStore{}, Conversation: mockConversationStore{}, Attachment: mockAttachmentStore{unsent: tc.unsent}}
+
+		result, err := h.HandleSendMessageByID(newTestContext(app, http.MethodPost, "http://dummy", []byte(tc.body)))
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			assert.Len(t, result.(types.Raw).Data.(model.Message).Attachments, 2, "TEST: %s: mismatch in attachments", tc.desc)
+		}
+	}
+
+	h := Handler{Auth: existingTCAuthStore{}, Message: successfulTCMessageStore{}, Friend: mockFriendStore{}, Block: mockBlockStore{}, Conversation: mockConversationStore{}}
+	_, err := h.HandleSendMessageByID(newTestContext(app, http.MethodPost, "http://dummy", []byte(`{"recipientId":"`+testRecipientID+`"}`)))
+	assert.Error(t, err, "Expected a message without content or attachments to be refused")
+}
+
+func TestProcessAttachment(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+	ctx.Context = context.Background()
+
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating blob store: %v", err)
+	}
+
+	var updated model.Attachment
+	h := Handler{Attachment: mockAttachmentStore{updated: &updated}, Blobs: blobs}
+
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.RGBA{R: 200, G: 100, B: 50, A: 255}}, image.Point{}, draw.Src)
+	var encoded bytes.Buffer
+	png.Encode(&encoded, img)
+
+	blobs.Put(ctx, testImageAttachmentID, bytes.NewReader(encoded.Bytes()), "image/png")
+	attachment := model.Attachment{ID: testImageAttachmentID, ContentType: "image/png", Size: int64(encoded.Len()), Status: model.AttachmentPending}
+
+	err = h.ProcessAttachment(ctx, attachment)
+
+	assert.NoError(t, err, "Unexpected error while processing an image")
+	assert.Equal(t, model.AttachmentReady, updated.Status, "Expected the image to be ready")
+	assert.Equal(t, 400, updated.Width, "Mismatch in width")
+	assert.Equal(t, 200, updated.Height, "Mismatch in height")
+	assert.NotEmpty(t, updated.BlurHash, "Expected a BlurHash")
+	assert.Equal(t, []model.Thumbnail{{Size: "small", Width: 96, Height: 48, ContentType: "image/jpeg"}, {Size: "medium", Width: 320, Height: 160, ContentType: "image/jpeg"}},
+		updated.Thumbnails, "Mismatch in thumbnails")
+
+	for _, thumbnail := range updated.Thumbnails {
+		stored, err := blobs.Get(ctx, thumbnailKey(testImageAttachmentID, thumbnail.Size))
+		assert.NoError(t, err, "Expected the %s thumbnail to be stored", thumbnail.Size)
+		stored.Close()
+	}
+
+	blobs.Put(ctx, "brokenId", bytes.NewReader([]byte("\x89PNG\r\n\x1a\nnot really")), "image/png")
+
+	err = h.ProcessAttachment(ctx, model.Attachment{ID: "brokenId", ContentType: "image/png", Status: model.AttachmentPending})
+
+	assert.NoError(t, err, "Unexpected error while processing a broken image")
+	assert.Equal(t, model.AttachmentFailed, updated.Status, "Expected a broken image to fail processing")
+	assert.Empty(t, updated.Thumbnails, "Expected no thumbnails for a broken image")
+	_, err = blobs.Get(ctx, "brokenId")
+	assert.Equal(t, blob.ErrNotFound, err, "Expected the original of a broken image to be deleted")
+
+	err = h.ProcessAttachment(ctx, model.Attachment{ID: testMissingAttachmentID, ContentType: "image/png"})
+
+	assert.Equal(t, blob.ErrNotFound, err, "Expected an error for a missing file")
+}
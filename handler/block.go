@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/store"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+func (h Handler) HandleBlockUser(ctx *gofr.Context) (interface{}, error) {
+	blockedId, err := userIdParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userId := ctx.Value("userId").(string)
+	if blockedId == userId {
+		return nil, e.HttpStatusError(400, "You cannot block yourself")
+	}
+
+	ok, err := h.Auth.AccountExists(ctx, blockedId)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, "")
+	}
+	if !ok {
+		return nil, e.HttpStatusError(404, "User does not exists")
+	}
+
+	err = h.Block.Block(ctx, userId, blockedId)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, "")
+	}
+	return nil, nil
+}
+
+func (h Handler) HandleUnblockUser(ctx *gofr.Context) (interface{}, error) {
+	blockedId, err := userIdParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.Block.Unblock(ctx, ctx.Value("userId").(string), blockedId)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, "")
+	}
+	return nil, nil
+}
+
+func (h Handler) HandleGetBlockedUsers(ctx *gofr.Context) (interface{}, error) {
+	blocked, err := h.Block.GetBlockedUsers(ctx, ctx.Value("userId").(string))
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+	return types.Raw{Data: blocked}, nil
+}
+
+// blockedBy reports whether the user was blocked by the other user.
+func (h Handler) blockedBy(ctx *gofr.Context, otherUserId, userId string) (bool, error) {
+	blocked, err := h.Block.IsBlocked(ctx, otherUserId, userId)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return false, e.HttpStatusError(500, "")
+	}
+	return blocked, nil
+}
+
+// dropBlockedMessage answers the sender of a direct message its recipient blocked. Unless BLOCKED_SEND_SILENT
+// is false, the message looks sent to its sender, so that they cannot tell they were blocked, but goes nowhere.
+func dropBlockedMessage(ctx *gofr.Context, message model.Message) (interface{}, error) {
+	if !BoolConfig(ctx.Config, "BLOCKED_SEND_SILENT", true) {
+		return nil, e.HttpStatusError(403, "You cannot send messages to that user")
+	}
+
+	message.ConversationID = store.DirectConversationID(message.From, message.To)
+	return types.Raw{Data: message}, nil
+}
+
+// dropBlockedFriendRequest answers the sender of a friend request its recipient blocked, like dropBlockedMessage.
+func dropBlockedFriendRequest(ctx *gofr.Context, recipientId string) (interface{}, error) {
+	if !BoolConfig(ctx.Config, "BLOCKED_SEND_SILENT", true) {
+		return nil, e.HttpStatusError(403, "You cannot send that user a friend request")
+	}
+
+	return types.Raw{Data: model.FriendRequest{User: model.User{ID: recipientId}, Status: model.FriendRequestPending, CreatedAt: time.Now()}}, nil
+}
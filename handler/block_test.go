@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// mockBlockStore has blockerId block everyone, and nobody else block anyone.
+type mockBlockStore struct {
+	blockerId string
+}
+
+func (mockBlockStore) Block(ctx *gofr.Context, userId, blockedId string) error {
+	return nil
+}
+
+func (mockBlockStore) Unblock(ctx *gofr.Context, userId, blockedId string) error {
+	return nil
+}
+
+func (mockBlockStore) GetBlockedUsers(ctx *gofr.Context, userId string) (*[]model.BlockedUser, error) {
+	blocked := make([]model.BlockedUser, 0)
+	return &blocked, nil
+}
+
+func (b mockBlockStore) IsBlocked(ctx *gofr.Context, blockerId, userId string) (bool, error) {
+	return b.blockerId != "" && blockerId == b.blockerId, nil
+}
+
+func TestHandleBlocks(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc   string
+		handle func(Handler, *gofr.Context) (interface{}, error)
+		userID string
+		err    error
+	}{
+		{"block success", Handler.HandleBlockUser, testRecipientID, nil},
+		{"block oneself", Handler.HandleBlockUser, testUserID, e.HttpStatusError(400, "You cannot block yourself")},
+		{"block an unknown user", Handler.HandleBlockUser, testMissingRecipientID, e.HttpStatusError(404, "User does not exists")},
+		{"missing user", Handler.HandleBlockUser, "", e.HttpStatusError(400, "Missing Parameter userId")},
+		{"block a user who is not a uuid", Handler.HandleBlockUser, "abc", e.HttpStatusError(422, "Invalid Parameter userId")},
+		{"unblock success", Handler.HandleUnblockUser, testRecipientID, nil},
+		{"unblock a user who is not a uuid", Handler.HandleUnblockUser, "abc", e.HttpStatusError(422, "Invalid Parameter userId")},
+		{"list blocked users", Handler.HandleGetBlockedUsers, "", nil},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Auth: existingTCAuthStore{}, Block: mockBlockStore{}}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", nil)
+		ctx.Context = context.WithValue(ctx.Context, "userId", testUserID)
+		ctx.SetPathParams(map[string]string{"userId": tc.userID})
+
+		_, err := tc.handle(h, ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+	}
+}
+
+// noMessagesTCMessageStore fails the test that stores a message.
+type noMessagesTCMessageStore struct {
+	successfulTCMessageStore
+	t *testing.T
+}
+
+func (m noMessagesTCMessageStore) AddMessage(ctx *gofr.Context, message model.Message) error {
+	m.t.Errorf("Expected messages to blockers not to be stored")
+	return nil
+}
+
+func TestHandleSendMessageToBlocker(t *testing.T) {
+	app := gofr.New()
+
+	h := Handler{Auth: existingTCAuthStore{}, Message: noMessagesTCMessageStore{t: t}, Friend: mockFriendStore{}, Block: mockBlockStore{blockerId: testRecipientID}, Conversation: errorTCConversationStore{}}
+
+	ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(`{"recipientId":"`+testRecipientID+`","content":"Hello"}`))
+
+	result, err := h.HandleSendMessageByID(ctx)
+
+	assert.NoError(t, err, "Expected blocked messages to look sent")
+	message := result.(types.Raw).Data.(model.Message)
+	assert.Equal(t, store.DirectConversationID("someUserId", testRecipientID), message.ConversationID, "Mismatch in conversation")
+
+	t.Setenv("BLOCKED_SEND_SILENT", "false")
+
+	ctx = newTestContext(app, http.MethodPost, "http://dummy", []byte(`{"recipientId":"`+testRecipientID+`","content":"Hello"}`))
+
+	_, err = h.HandleSendMessageByID(ctx)
+
+	assert.Equal(t, e.HttpStatusError(403, "You cannot send messages to that user"), err, "Expected blocked messages to fail when configured")
+}
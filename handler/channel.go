@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/go-playground/validator"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+func (h Handler) HandleCreateChannel(ctx *gofr.Context) (interface{}, error) {
+	var createChannelRequest model.CreateChannelRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&createChannelRequest)
+	err = validator.New().Struct(createChannelRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	channel := NewChannel(userId, createChannelRequest.Handle, createChannelRequest.Name, createChannelRequest.Description)
+
+	err = h.Channel.CreateChannel(ctx, channel)
+	if err != nil {
+		return nil, channelError(ctx, err)
+	}
+
+	channel.SubscriberCount, channel.Subscribed, channel.Role = 1, true, model.RoleAdmin
+	return types.Raw{Data: channel}, nil
+}
+
+func (h Handler) HandleGetChannel(ctx *gofr.Context) (interface{}, error) {
+	channel, err := h.channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return types.Raw{Data: channel}, nil
+}
+
+func (h Handler) HandleSubscribeChannel(ctx *gofr.Context) (interface{}, error) {
+	channel, err := h.channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.Channel.Subscribe(ctx, ctx.Value("userId").(string), channel.ID)
+	if err != nil {
+		return nil, channelError(ctx, err)
+	}
+	return nil, nil
+}
+
+func (h Handler) HandleUnsubscribeChannel(ctx *gofr.Context) (interface{}, error) {
+	channel, err := h.channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.Channel.Unsubscribe(ctx, ctx.Value("userId").(string), channel.ID)
+	if err != nil {
+		return nil, channelError(ctx, err)
+	}
+	return nil, nil
+}
+
+// HandleUpdateChannelSubscriber lets an admin of a channel make one of its subscribers an admin, or step an admin down.
+func (h Handler) HandleUpdateChannelSubscriber(ctx *gofr.Context) (interface{}, error) {
+	var updateSubscriberRequest model.UpdateSubscriberRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&updateSubscriberRequest)
+	err = validator.New().Struct(updateSubscriberRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	subscriberId := ctx.PathParam("subscriberId")
+	if strings.TrimSpace(subscriberId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter subscriberId")
+	}
+
+	channel, err := h.channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if channel.Role != model.RoleAdmin {
+		return nil, e.HttpStatusError(403, "Only admins can manage that channel")
+	}
+
+	err = h.Channel.UpdateSubscriberRole(ctx, channel.ID, subscriberId, updateSubscriberRequest.Role)
+	if err != nil {
+		return nil, channelError(ctx, err)
+	}
+	return nil, nil
+}
+
+// HandleSendChannelMessage posts a message to a channel the user is an admin of, for all of its subscribers to read.
+func (h Handler) HandleSendChannelMessage(ctx *gofr.Context) (interface{}, error) {
+	var messageRequest model.SendChannelMessageRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&messageRequest)
+	err = validator.New().Struct(messageRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	channel, err := h.channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if channel.Role != model.RoleAdmin {
+		return nil, e.HttpStatusError(403, "Only admins can post to that channel")
+	}
+
+	message := NewMessage(ctx.Value("userId").(string), "", messageRequest.Content)
+	message.ConversationID = channel.ID
+
+	err = h.formatMessage(ctx, &message)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.quoteReply(ctx, &message, messageRequest.ReplyToID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.attachFiles(ctx, &message, messageRequest.AttachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.Message.AddMessage(ctx, message)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, err.Error())
+	}
+
+	err = h.Conversation.RecordMessage(ctx, message)
+	if err != nil {
+		ctx.Logger.Error(err)
+	}
+
+	h.notifyMentions(message, nil)
+	h.queueLinkPreview(message)
+
+	return types.Raw{Data: message}, nil
+}
+
+func (h Handler) HandleGetChannelMessages(ctx *gofr.Context) (interface{}, error) {
+	page, err := pageParam(ctx)
+	if err != nil {
+		return nil, e.HttpStatusError(400, "Invalid Parameter page")
+	}
+
+	channel, err := h.channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !channel.Subscribed {
+		return nil, e.HttpStatusError(403, "You are not subscribed to that channel")
+	}
+
+	messages, err := h.Message.GetConversationMessages(ctx, ctx.Value("userId").(string), channel.ID, page, model.RequestMessageLimit)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	if len(*messages) == 0 {
+		return nil, e.HttpStatusError(404, "Page does not exists")
+	}
+
+	lastPage := false
+	if len(*messages) < model.RequestMessageLimit {
+		lastPage = true
+	}
+	return types.Raw{Data: model.GetMessagesResponse{Page: page, LastPage: lastPage, Messages: *messages}}, nil
+}
+
+// channel returns the channel with the handle in the path, as seen by the user.
+func (h Handler) channel(ctx *gofr.Context) (*model.Channel, error) {
+	handle := strings.ToLower(ctx.PathParam("handle"))
+	if strings.TrimSpace(handle) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter handle")
+	}
+
+	channel, err := h.Channel.GetChannel(ctx, ctx.Value("userId").(string), handle)
+	if err != nil {
+		return nil, channelError(ctx, err)
+	}
+	return channel, nil
+}
+
+func channelError(ctx *gofr.Context, err error) error {
+	ctx.Logger.Info("err: ", err.Error())
+	switch err {
+	case sql.ErrNoRows:
+		return e.HttpStatusError(404, "Channel does not exists")
+	case e.NewError("That user is not subscribed to the channel"):
+		return e.HttpStatusError(404, err.Error())
+	case e.NewError("That handle is taken"), e.NewError("A channel needs at least one admin"):
+		return e.HttpStatusError(409, err.Error())
+	}
+	return e.HttpStatusError(500, "")
+}
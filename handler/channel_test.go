@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// mockChannelStore shows the user the channel with role, and fails every other call with err, if any.
+type mockChannelStore struct {
+	role string
+	err  error
+}
+
+func (c mockChannelStore) CreateChannel(ctx *gofr.Context, channel model.Channel) error {
+	return c.err
+}
+
+func (c mockChannelStore) GetChannel(ctx *gofr.Context, userId, handle string) (*model.Channel, error) {
+	if handle == "missing" {
+		return nil, sql.ErrNoRows
+	}
+	return &model.Channel{ID: "channelId", Handle: handle, Subscribed: c.role != "", Role: c.role}, nil
+}
+
+func (c mockChannelStore) Subscribe(ctx *gofr.Context, userId, channelId string) error {
+	return c.err
+}
+
+func (c mockChannelStore) Unsubscribe(ctx *gofr.Context, userId, channelId string) error {
+	return c.err
+}
+
+func (c mockChannelStore) UpdateSubscriberRole(ctx *gofr.Context, channelId, subscriberId, role string) error {
+	return c.err
+}
+
+func TestHandleCreateChannel(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc  string
+		body  string
+		store store.ChannelStore
+		err   error
+	}{
+		{"create channel success", `{"handle":"CatNews","name":"Cat News"}`, mockChannelStore{}, nil},
+		{"invalid handle", `{"handle":"cat news","name":"Cat News"}`, mockChannelStore{}, e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'CreateChannelRequest.Handle' Error:Field validation for 'Handle' failed on the 'alphanum' tag")},
+		{"handle taken", `{"handle":"catnews","name":"Cat News"}`, mockChannelStore{err: e.NewError("That handle is taken")}, e.HttpStatusError(409, "That handle is taken")},
+		{"channel store error", `{"handle":"catnews","name":"Cat News"}`, mockChannelStore{err: e.NewError("")}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Channel: tc.store}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(tc.body))
+
+		result, err := h.HandleCreateChannel(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			channel := result.(types.Raw).Data.(model.Channel)
+			assert.Equal(t, "catnews", channel.Handle, "TEST: %s: expected handles to be lowercase", tc.desc)
+			assert.Equal(t, model.RoleAdmin, channel.Role, "TEST: %s: expected the creator to be an admin", tc.desc)
+		}
+	}
+}
+
+func TestHandleChannelSubscriptions(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc         string
+		handle       func(Handler, *gofr.Context) (interface{}, error)
+		channel      string
+		body         string
+		subscriberID string
+		store        store.ChannelStore
+		err          error
+	}{
+		{"get channel success", Handler.HandleGetChannel, "catnews", "", "", mockChannelStore{}, nil},
+		{"get missing channel", Handler.HandleGetChannel, "missing", "", "", mockChannelStore{}, e.HttpStatusError(404, "Channel does not exists")},
+		{"missing handle", Handler.HandleGetChannel, "", "", "", mockChannelStore{}, e.HttpStatusError(400, "Missing Parameter handle")},
+		{"subscribe success", Handler.HandleSubscribeChannel, "catnews", "", "", mockChannelStore{}, nil},
+		{"subscribe to a missing channel", Handler.HandleSubscribeChannel, "missing", "", "", mockChannelStore{}, e.HttpStatusError(404, "Channel does not exists")},
+		{"unsubscribe success", Handler.HandleUnsubscribeChannel, "catnews", "", "", mockChannelStore{role: model.RoleSubscriber}, nil},
+		{"last admin unsubscribes", Handler.HandleUnsubscribeChannel, "catnews", "", "", mockChannelStore{role: model.RoleAdmin, err: e.NewError("A channel needs at least one admin")}, e.HttpStatusError(409, "A channel needs at least one admin")},
+		{"promote success", Handler.HandleUpdateChannelSubscriber, "catnews", `{"role":"admin"}`, "subscriberId", mockChannelStore{role: model.RoleAdmin}, nil},
+		{"promote by a subscriber", Handler.HandleUpdateChannelSubscriber, "catnews", `{"role":"admin"}`, "subscriberId", mockChannelStore{role: model.RoleSubscriber}, e.HttpStatusError(403, "Only admins can manage that channel")},
+		{"promote a stranger", Handler.HandleUpdateChannelSubscriber, "catnews", `{"role":"admin"}`, "subscriberId", mockChannelStore{role: model.RoleAdmin, err: e.NewError("That user is not subscribed to the channel")}, e.HttpStatusError(404, "That user is not subscribed to the channel")},
+		{"missing subscriber", Handler.HandleUpdateChannelSubscriber, "catnews", `{"role":"admin"}`, "", mockChannelStore{role: model.RoleAdmin}, e.HttpStatusError(400, "Missing Parameter subscriberId")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Channel: tc.store}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(tc.body))
+		ctx.SetPathParams(map[string]string{"handle": tc.channel, "subscriberId": tc.subscriberID})
+
+		_, err := tc.handle(h, ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+	}
+}
+
+func TestHandleChannelMessages(t *testing.T) {
+	app := gofr.New()
+
+	sendCases := []struct {
+		desc string
+		role string
+		err  error
+	}{
+		{"admin posts", model.RoleAdmin, nil},
+		{"subscriber posts", model.RoleSubscriber, e.HttpStatusError(403, "Only admins can post to that channel")},
+		{"stranger posts", "", e.HttpStatusError(403, "Only admins can post to that channel")},
+	}
+
+	for _, tc := range sendCases {
+		h := Handler{Message: successfulTCMessageStore{}, Channel: mockChannelStore{role: tc.role}, Conversation: mockConversationStore{}}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(`{"content":"Cats are great"}`))
+		ctx.SetPathParams(map[string]string{"handle": "catnews"})
+
+		result, err := h.HandleSendChannelMessage(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			assert.Equal(t, "channelId", result.(types.Raw).Data.(model.Message).ConversationID, "TEST: %s: mismatch in conversation", tc.desc)
+		}
+	}
+
+	readCases := []struct {
+		desc string
+		role string
+		err  error
+	}{
+		{"subscriber reads", model.RoleSubscriber, nil},
+		{"admin reads", model.RoleAdmin, nil},
+		{"stranger reads", "", e.HttpStatusError(403, "You are not subscribed to that channel")},
+	}
+
+	for _, tc := range readCases {
+		h := Handler{Message: groupMessagesTCMessageStore{count: 2}, Channel: mockChannelStore{role: tc.role}}
+
+		ctx := newTestContext(app, http.MethodGet, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"handle": "catnews"})
+
+		_, err := h.HandleGetChannelMessages(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"gofr.dev/pkg/gofr"
+)
+
+// IntConfig reads a positive integer config, falling back to def when it is missing or malformed.
+func IntConfig(config gofr.Config, key string, def int) int {
+	value, err := strconv.Atoi(config.Get(key))
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
+
+// SecondsConfig reads a duration configured in seconds, falling back to def when it is missing or malformed.
+func SecondsConfig(config gofr.Config, key string, def int) time.Duration {
+	return time.Duration(IntConfig(config, key, def)) * time.Second
+}
+
+// BoolConfig reads a boolean config, falling back to def when it is missing or malformed.
+func BoolConfig(config gofr.Config, key string, def bool) bool {
+	value, err := strconv.ParseBool(config.Get(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"strings"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/go-playground/validator"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// HandleDiscoverContacts finds which phone numbers in the user's phone book belong to discoverable users.
+// Every hash counts against the user's daily CONTACT_DISCOVERY_DAILY_LIMIT, so that nobody can enumerate accounts.
+func (h Handler) HandleDiscoverContacts(ctx *gofr.Context) (interface{}, error) {
+	var discoverRequest model.DiscoverContactsRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&discoverRequest)
+	err = validator.New().Struct(discoverRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	hashes := make([]string, 0, len(discoverRequest.Hashes))
+	seen := make(map[string]bool)
+	for _, hash := range discoverRequest.Hashes {
+		hash = strings.ToLower(hash)
+		if !seen[hash] {
+			seen[hash] = true
+			hashes = append(hashes, hash)
+		}
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	ok, err := h.Contact.UseDiscoveryQuota(ctx, userId, len(hashes), IntConfig(ctx.Config, "CONTACT_DISCOVERY_DAILY_LIMIT", 1000))
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, "")
+	}
+	if !ok {
+		return nil, e.HttpStatusError(429, "Daily contact discovery limit reached")
+	}
+
+	users, err := h.Contact.DiscoverUsers(ctx, userId, hashes)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+	return types.Raw{Data: users}, nil
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// mockContactStore allows quota hashes a day and finds a user for every hash, recording the hashes looked up.
+type mockContactStore struct {
+	quota  int
+	hashes *[]string
+}
+
+func (c mockContactStore) DiscoverUsers(ctx *gofr.Context, userId string, hashes []string) (*[]model.User, error) {
+	*c.hashes = hashes
+	users := make([]model.User, len(hashes))
+	return &users, nil
+}
+
+func (c mockContactStore) UseDiscoveryQuota(ctx *gofr.Context, userId string, hashes, limit int) (bool, error) {
+	return hashes <= c.quota, nil
+}
+
+func TestHandleDiscoverContacts(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc   string
+		body   string
+		quota  int
+		hashes []string
+		err    error
+	}{
+		{"discover success", `{"hashes":["0123456789abcdef","ABCDEFABCDEF","abcdefabcdef"]}`, 10, []string{"0123456789abcdef", "abcdefabcdef"}, nil},
+		{"quota exhausted", `{"hashes":["0123456789abcdef"]}`, 0, nil, e.HttpStatusError(429, "Daily contact discovery limit reached")},
+		{"hash too short", `{"hashes":["0123"]}`, 10, nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'DiscoverContactsRequest.Hashes[0]' Error:Field validation for 'Hashes[0]' failed on the 'min' tag")},
+		{"not a hash", `{"hashes":["0123456789xyz"]}`, 10, nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'DiscoverContactsRequest.Hashes[0]' Error:Field validation for 'Hashes[0]' failed on the 'hexadecimal' tag")},
+	}
+
+	for _, tc := range testCases {
+		var hashes []string
+		h := Handler{Contact: mockContactStore{quota: tc.quota, hashes: &hashes}}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(tc.body))
+
+		result, err := h.HandleDiscoverContacts(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			assert.Equal(t, tc.hashes, hashes, "TEST: %s: expected hashes to be lowercased and deduplicated", tc.desc)
+			assert.Len(t, *result.(types.Raw).Data.(*[]model.User), len(tc.hashes), "TEST: %s: unexpected number of users", tc.desc)
+		}
+	}
+}
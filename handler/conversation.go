@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+func (h Handler) HandleGetConversations(ctx *gofr.Context) (interface{}, error) {
+	return h.conversations(ctx, true)
+}
+
+// HandleGetMessageRequests lists the direct conversations strangers started with the user, which the user has yet to accept.
+func (h Handler) HandleGetMessageRequests(ctx *gofr.Context) (interface{}, error) {
+	return h.conversations(ctx, false)
+}
+
+func (h Handler) conversations(ctx *gofr.Context, accepted bool) (interface{}, error) {
+	page, err := pageParam(ctx)
+	if err != nil {
+		return nil, e.HttpStatusError(400, "Invalid Parameter page")
+	}
+
+	conversations, err := h.Conversation.GetConversations(ctx, ctx.Value("userId").(string), accepted, page, model.RequestConversationLimit)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	lastPage := false
+	if len(*conversations) < model.RequestConversationLimit {
+		lastPage = true
+	}
+	return types.Raw{Data: model.GetConversationsResponse{Page: page, LastPage: lastPage, Conversations: *conversations}}, nil
+}
+
+func (h Handler) HandleMarkConversationRead(ctx *gofr.Context) (interface{}, error) {
+	conversationId := ctx.PathParam("id")
+	if strings.TrimSpace(conversationId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter conversationId")
+	}
+
+	err := h.Conversation.MarkRead(ctx, ctx.Value("userId").(string), conversationId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+	return nil, nil
+}
+
+func (h Handler) HandleAcceptMessageRequest(ctx *gofr.Context) (interface{}, error) {
+	conversationId := ctx.PathParam("id")
+	if strings.TrimSpace(conversationId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter conversationId")
+	}
+
+	err := h.Conversation.AcceptMessageRequest(ctx, ctx.Value("userId").(string), conversationId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == sql.ErrNoRows {
+			return nil, e.HttpStatusError(404, "Message request does not exists")
+		}
+		return nil, e.HttpStatusError(500, "")
+	}
+	return nil, nil
+}
+
+// pageParam reads the optional 1-based page query parameter, defaulting to the first page.
+func pageParam(ctx *gofr.Context) (uint, error) {
+	pageParam := ctx.Param("page")
+	if strings.TrimSpace(pageParam) == "" {
+		return 1, nil
+	}
+
+	page, err := strconv.ParseUint(pageParam, 10, 32)
+	if err != nil || page == 0 {
+		return 0, e.NewError("page must be a positive integer")
+	}
+	return uint(page), nil
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+type errorTCConversationStore struct{}
+
+func (errorTCConversationStore) EnsureDirectConversation(ctx *gofr.Context, userId, peerId string, accepted bool) (string, error) {
+	return "", e.NewError("")
+}
+
+func (errorTCConversationStore) RecordMessage(ctx *gofr.Context, message model.Message) error {
+	return e.NewError("")
+}
+
+func (errorTCConversationStore) GetConversations(ctx *gofr.Context, userId string, accepted bool, page, limit uint) (*[]model.Conversation, error) {
+	return nil, e.NewError("")
+}
+
+func (errorTCConversationStore) GetMemberIds(ctx *gofr.Context, conversationId string) ([]string, error) {
+	return nil, e.NewError("")
+}
+
+func (errorTCConversationStore) MarkRead(ctx *gofr.Context, userId, conversationId string) error {
+	return e.NewError("")
+}
+
+func (errorTCConversationStore) AcceptMessageRequest(ctx *gofr.Context, userId, conversationId string) error {
+	return e.NewError("")
+}
+
+func (errorTCConversationStore) GetDisappearingTimer(ctx *gofr.Context, conversationId string) (*model.DisappearingTimer, error) {
+	return nil, e.NewError("")
+}
+
+func (errorTCConversationStore) SetDisappearingTimer(ctx *gofr.Context, userId, conversationId string, timer model.DisappearingTimer) (*model.Conversation, error) {
+	return nil, e.NewError("")
+}
+
+func (errorTCConversationStore) SaveDraft(ctx *gofr.Context, userId, conversationId string, content string, updatedAt time.Time) (*model.Draft, bool, error) {
+	return nil, false, e.NewError("")
+}
+
+func (errorTCConversationStore) GetDraft(ctx *gofr.Context, userId, conversationId string) (*model.Draft, error) {
+	return nil, e.NewError("")
+}
+
+func (errorTCConversationStore) DeleteDraft(ctx *gofr.Context, userId, conversationId string) (*model.Draft, error) {
+	return nil, e.NewError("")
+}
+
+func (errorTCConversationStore) GetMemberNames(ctx *gofr.Context, conversationId string, userIds []string) (map[string]string, error) {
+	return nil, e.NewError("")
+}
+
+func (errorTCConversationStore) Mute(ctx *gofr.Context, userId, conversationId string, until *time.Time) error {
+	return e.NewError("")
+}
+
+func (errorTCConversationStore) Unmute(ctx *gofr.Context, userId, conversationId string) error {
+	return e.NewError("")
+}
+
+type testCaseConversation struct {
+	desc           string
+	url            string
+	conversationID string
+	expected       interface{}
+	err            error
+}
+
+func TestHandleGetConversations(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		tc testCaseConversation
+		h  Handler
+	}{
+		{testCaseConversation{desc: "get conversations success", url: "http://dummy/conversations", expected: types.Raw{}}, Handler{Conversation: mockConversationStore{}}},
+		{testCaseConversation{desc: "get conversations page success", url: "http://dummy/conversations?page=2", expected: types.Raw{}}, Handler{Conversation: mockConversationStore{}}},
+		{testCaseConversation{desc: "invalid page", url: "http://dummy/conversations?page=zero", err: e.HttpStatusError(400, "Invalid Parameter page")}, Handler{Conversation: mockConversationStore{}}},
+		{testCaseConversation{desc: "non positive page", url: "http://dummy/conversations?page=0", err: e.HttpStatusError(400, "Invalid Parameter page")}, Handler{Conversation: mockConversationStore{}}},
+		{testCaseConversation{desc: "conversation store error", url: "http://dummy/conversations", err: e.HttpStatusError(500, "")}, Handler{Conversation: errorTCConversationStore{}}},
+	}
+
+	for _, testCase := range testCases {
+		ctx := newTestContext(app, http.MethodGet, testCase.tc.url, nil)
+
+		result, err := testCase.h.HandleGetConversations(ctx)
+
+		if testCase.tc.err != nil {
+			assert.Equal(t, testCase.tc.err, err, "TEST: %s: unexpected error", testCase.tc.desc)
+		} else {
+			assert.NoError(t, err, "TEST: Unexpected Error: %s", testCase.tc.desc)
+			assert.IsType(t, testCase.tc.expected, result, "TEST: %s: unexpected result type", testCase.tc.desc)
+		}
+	}
+}
+
+func TestHandleMarkConversationRead(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		tc testCaseConversation
+		h  Handler
+	}{
+		{testCaseConversation{desc: "mark read success", conversationID: "someConversationId"}, Handler{Conversation: mockConversationStore{}}},
+		{testCaseConversation{desc: "missing parameter", err: e.HttpStatusError(400, "Missing Parameter conversationId")}, Handler{Conversation: mockConversationStore{}}},
+		{testCaseConversation{desc: "conversation store error", conversationID: "someConversationId", err: e.HttpStatusError(500, "")}, Handler{Conversation: errorTCConversationStore{}}},
+	}
+
+	for _, testCase := range testCases {
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"id": testCase.tc.conversationID})
+
+		_, err := testCase.h.HandleMarkConversationRead(ctx)
+
+		if testCase.tc.err != nil {
+			assert.Equal(t, testCase.tc.err, err, "TEST: %s: unexpected error", testCase.tc.desc)
+		} else {
+			assert.NoError(t, err, "TEST: Unexpected Error: %s", testCase.tc.desc)
+		}
+	}
+}
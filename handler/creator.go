@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"strings"
+
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 	"time"
@@ -53,7 +55,51 @@ func NewMessage(senderId, recipientId, content string) model.Message {
 		ID:      uuid.New().String(),
 		From:    senderId,
 		To:      recipientId,
+		Type:    model.MessageText,
 		Content: content,
 		Timestamp: time.Now(),
 	}
 }
+
+// NewGroup is a group created by creatorId, who becomes its admin, with memberIds as its other members.
+func NewGroup(creatorId, name string, memberIds []string) model.Group {
+	now := time.Now()
+	members := []model.GroupMember{{User: model.User{ID: creatorId}, Role: model.RoleAdmin, JoinedAt: now}}
+	for _, memberId := range memberIds {
+		if memberId != creatorId {
+			members = append(members, model.GroupMember{User: model.User{ID: memberId}, Role: model.RoleMember, JoinedAt: now})
+		}
+	}
+
+	return model.Group{
+		ID:        uuid.New().String(),
+		Name:      name,
+		CreatedBy: creatorId,
+		CreatedAt: now,
+		Members:   members,
+	}
+}
+
+// NewChannel is a channel created by creatorId, who becomes its admin. Handles are case insensitive.
+func NewChannel(creatorId, handle, name, description string) model.Channel {
+	return model.Channel{
+		ID:          uuid.New().String(),
+		Handle:      strings.ToLower(handle),
+		Name:        name,
+		Description: description,
+		CreatedBy:   creatorId,
+		CreatedAt:   time.Now(),
+	}
+}
+
+func NewAttachment(ownerId, fileName, contentType string, size int64) model.Attachment {
+	return model.Attachment{
+		ID:          uuid.New().String(),
+		OwnerID:     ownerId,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+		CreatedAt:   time.Now(),
+		Status:      model.AttachmentReady,
+	}
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/media"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/go-playground/validator"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// ExpiredMessageBatch is the number of expired messages deleted at once.
+const ExpiredMessageBatch = 100
+
+// HandleSetDisappearingTimer sets how long after being sent or read the new messages of a direct conversation or group
+// disappear, or turns disappearing messages off. Either participant may change it; the change is recorded in the
+// conversation by a system message, which is returned.
+func (h Handler) HandleSetDisappearingTimer(ctx *gofr.Context) (interface{}, error) {
+	var timerRequest model.SetDisappearingTimerRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&timerRequest)
+	err = validator.New().Struct(timerRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	conversationId := ctx.PathParam("id")
+	if strings.TrimSpace(conversationId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter conversationId")
+	}
+
+	userId := ctx.Value("userId").(string)
+	timer := model.DisappearingTimer{After: timerRequest.After, From: timerRequest.From}
+
+	conversation, err := h.Conversation.SetDisappearingTimer(ctx, userId, conversationId, timer)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == sql.ErrNoRows {
+			return nil, e.HttpStatusError(404, "Conversation does not exists")
+		}
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	var peerId string
+	if conversation.Type == model.ConversationDirect {
+		peerId, err = h.peerID(ctx, userId, conversation.ID)
+		if err != nil {
+			ctx.Logger.Error(err)
+			return nil, e.HttpStatusError(500, "")
+		}
+	}
+
+	message := NewMessage(userId, peerId, disappearingTimerText(conversation.DisappearingTimer))
+	message.ConversationID = conversation.ID
+	message.System = &model.SystemEvent{Event: model.SystemDisappearingTimerChanged, DisappearingTimer: conversation.DisappearingTimer}
+
+	err = h.Message.AddMessage(ctx, message)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, err.Error())
+	}
+
+	err = h.Conversation.RecordMessage(ctx, message)
+	if err != nil {
+		ctx.Logger.Error(err)
+	}
+
+	return types.Raw{Data: message}, nil
+}
+
+// peerID returns the other member of a direct conversation, or the user for notes to self.
+func (h Handler) peerID(ctx *gofr.Context, userId, conversationId string) (string, error) {
+	memberIds, err := h.Conversation.GetMemberIds(ctx, conversationId)
+	if err != nil {
+		return "", err
+	}
+
+	for _, memberId := range memberIds {
+		if memberId != userId {
+			return memberId, nil
+		}
+	}
+	return userId, nil
+}
+
+// disappearing applies the disappearing timer of the message's conversation to it. Messages that disappear counting
+// from when they are sent get their expiry right away; the others get it once their recipient reads them.
+func (h Handler) disappearing(ctx *gofr.Context, message *model.Message) error {
+	timer, err := h.Conversation.GetDisappearingTimer(ctx, message.ConversationID)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.Logger.Error(err)
+		return e.HttpStatusError(500, "")
+	}
+	if timer == nil {
+		return nil
+	}
+
+	message.DisappearAfter = timer.After
+	if timer.From == model.DisappearFromSent {
+		sentAt := message.Timestamp
+		if message.ScheduledAt != nil {
+			sentAt = *message.ScheduledAt
+		}
+		expiresAt := sentAt.Add(time.Duration(timer.After) * time.Second)
+		message.ExpiresAt = &expiresAt
+	}
+	return nil
+}
+
+// DeleteExpiredMessages deletes the disappearing messages whose time is up, along with the files attached to them.
+// It is safe to run on several replicas at once.
+func (h Handler) DeleteExpiredMessages(ctx *gofr.Context) error {
+	for {
+		deleted, blobIds, err := h.Message.DeleteExpiredMessages(ctx, time.Now(), ExpiredMessageBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, blobId := range blobIds {
+			h.deleteBlobs(ctx, blobId)
+		}
+
+		if deleted < ExpiredMessageBatch {
+			return nil
+		}
+	}
+}
+
+// deleteBlobs removes the file of an attachment and its thumbnails from the blob store. A blob left behind is only logged,
+// as no attachment refers to it anymore.
+func (h Handler) deleteBlobs(ctx *gofr.Context, blobId string) {
+	keys := []string{blobId}
+	for _, size := range media.ThumbnailSizes {
+		keys = append(keys, thumbnailKey(blobId, size.Name))
+	}
+
+	for _, key := range keys {
+		err := h.Blobs.Delete(ctx, key)
+		if err != nil {
+			ctx.Logger.Error(err)
+		}
+	}
+}
+
+// disappearingTimerText describes a change of the disappearing timer in the system message recording it.
+func disappearingTimerText(timer *model.DisappearingTimer) string {
+	if timer == nil {
+		return "Disappearing messages were turned off"
+	}
+
+	from := "sent"
+	if timer.From == model.DisappearFromRead {
+		from = "read"
+	}
+	return fmt.Sprintf("New messages will disappear %s after they are %s", formatDuration(timer.After), from)
+}
+
+// formatDuration spells out a number of seconds in the largest unit that divides it, such as "1 hour" or "7 days".
+func formatDuration(seconds uint) string {
+	units := []struct {
+		name    string
+		seconds uint
+	}{{"week", 7 * 24 * 60 * 60}, {"day", 24 * 60 * 60}, {"hour", 60 * 60}, {"minute", 60}, {"second", 1}}
+
+	for _, unit := range units {
+		if seconds%unit.seconds == 0 {
+			count := seconds / unit.seconds
+			if count == 1 {
+				return fmt.Sprintf("1 %s", unit.name)
+			}
+			return fmt.Sprintf("%d %ss", count, unit.name)
+		}
+	}
+	return fmt.Sprintf("%d seconds", seconds)
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/blob"
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// timerTCConversationStore keeps the disappearing timer of a direct conversation between the test user and peer-id.
+type timerTCConversationStore struct {
+	mockConversationStore
+	timer *model.DisappearingTimer
+	err   error
+}
+
+func (c timerTCConversationStore) GetMemberIds(ctx *gofr.Context, conversationId string) ([]string, error) {
+	return []string{"someUserId", "peer-id"}, nil
+}
+
+func (c timerTCConversationStore) GetDisappearingTimer(ctx *gofr.Context, conversationId string) (*model.DisappearingTimer, error) {
+	return c.timer, nil
+}
+
+func (c timerTCConversationStore) SetDisappearingTimer(ctx *gofr.Context, userId, conversationId string, timer model.DisappearingTimer) (*model.Conversation, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	conversation := model.Conversation{ID: "conversationId", Type: model.ConversationDirect}
+	if timer.After > 0 {
+		conversation.DisappearingTimer = &timer
+	}
+	return &conversation, nil
+}
+
+// expiredTCMessageStore deletes one expired message with the given attachments.
+type expiredTCMessageStore struct {
+	successfulTCMessageStore
+	attachmentIds []string
+}
+
+func (m expiredTCMessageStore) DeleteExpiredMessages(ctx *gofr.Context, now time.Time, limit uint) (int, []string, error) {
+	return 1, m.attachmentIds, nil
+}
+
+func TestHandleSetDisappearingTimer(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc     string
+		body     string
+		storeErr error
+		content  string
+		err      error
+	}{
+		{desc: "one day from sent", body: `{"after":86400,"from":"sent"}`, content: "New messages will disappear 1 day after they are sent"},
+		{desc: "one hour from read", body: `{"after":3600,"from":"read"}`, content: "New messages will disappear 1 hour after they are read"},
+		{desc: "turned off", body: `{"after":0}`, content: "Disappearing messages were turned off"},
+		{desc: "too short", body: `{"after":10,"from":"sent"}`,
+			err: e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'SetDisappearingTimerRequest.After' Error:Field validation for 'After' failed on the 'min' tag")},
+		{desc: "missing from", body: `{"after":3600}`,
+			err: e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'SetDisappearingTimerRequest.From' Error:Field validation for 'From' failed on the 'required_with' tag")},
+		{desc: "unknown conversation", body: `{"after":3600,"from":"sent"}`, storeErr: sql.ErrNoRows, err: e.HttpStatusError(404, "Conversation does not exists")},
+		{desc: "store error", body: `{"after":3600,"from":"sent"}`, storeErr: e.NewError(""), err: e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		var added []model.Message
+		h := Handler{Message: scheduleTCMessageStore{added: &added}, Conversation: timerTCConversationStore{err: tc.storeErr}}
+
+		ctx := newTestContext(app, http.MethodPut, "http://dummy", []byte(tc.body))
+		ctx.SetPathParams(map[string]string{"id": "peer-id"})
+
+		result, err := h.HandleSetDisappearingTimer(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			message := result.(types.Raw).Data.(model.Message)
+			assert.Equal(t, tc.content, message.Content, "TEST: %s: mismatch in system message", tc.desc)
+			assert.Equal(t, "peer-id", message.To, "TEST: %s: expected the system message to go to the peer", tc.desc)
+			assert.Equal(t, model.SystemDisappearingTimerChanged, message.System.Event, "TEST: %s: mismatch in system event", tc.desc)
+			assert.Len(t, added, 1, "TEST: %s: expected the system message to be stored", tc.desc)
+		}
+	}
+}
+
+func TestHandleSendDisappearingMessage(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc    string
+		timer   *model.DisappearingTimer
+		expires bool
+	}{
+		{desc: "no timer"},
+		{desc: "from sent", timer: &model.DisappearingTimer{After: 3600, From: model.DisappearFromSent}, expires: true},
+		{desc: "from read", timer: &model.DisappearingTimer{After: 3600, From: model.DisappearFromRead}},
+	}
+
+	for _, tc := range testCases {
+		var added []model.Message
+		h := Handler{Auth: existingTCAuthStore{}, Message: scheduleTCMessageStore{added: &added}, Friend: mockFriendStore{}, Block: mockBlockStore{},
+			Conversation: timerTCConversationStore{timer: tc.timer}}
+
+		body := `{"recipientId":"` + testRecipientID + `","content":"Burn after reading"}`
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(body))
+
+		result, err := h.HandleSendMessageByID(ctx)
+
+		assert.NoError(t, err, "TEST: %s: unexpected error", tc.desc)
+		message := result.(types.Raw).Data.(model.Message)
+		if tc.timer != nil {
+			assert.Equal(t, tc.timer.After, message.DisappearAfter, "TEST: %s: mismatch in disappearing timer", tc.desc)
+		}
+		if tc.expires {
+			assert.Equal(t, message.Timestamp.Add(time.Hour), *message.ExpiresAt, "TEST: %s: expected the message to expire an hour after it is sent", tc.desc)
+		} else {
+			assert.Nil(t, message.ExpiresAt, "TEST: %s: expected no expiry until the message is read", tc.desc)
+		}
+	}
+}
+
+func TestDeleteExpiredMessages(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+	ctx.Context = context.Background()
+
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating blob store: %v", err)
+	}
+	for _, key := range []string{"attachment-id", thumbnailKey("attachment-id", "small"), "other-attachment-id"} {
+		err = blobs.Put(ctx, key, strings.NewReader("content"), "image/png")
+		if err != nil {
+			t.Fatalf("Error storing blob: %v", err)
+		}
+	}
+
+	h := Handler{Message: expiredTCMessageStore{attachmentIds: []string{"attachment-id"}}, Blobs: blobs}
+
+	err = h.DeleteExpiredMessages(ctx)
+
+	assert.NoError(t, err, "Unexpected error deleting expired messages")
+	for _, key := range []string{"attachment-id", thumbnailKey("attachment-id", "small")} {
+		_, err = blobs.Get(ctx, key)
+		assert.Equal(t, blob.ErrNotFound, err, "Expected blob %s of an expired message to be deleted", key)
+	}
+	_, err = blobs.Get(ctx, "other-attachment-id")
+	assert.NoError(t, err, "Expected other blobs to be kept")
+}
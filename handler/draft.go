@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/go-playground/validator"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// HandleSaveDraft saves the draft of the user in a conversation, or to a peer, and pushes it to the user's devices.
+// A draft last edited before the one saved already is not saved; the saved draft is returned either way.
+func (h Handler) HandleSaveDraft(ctx *gofr.Context) (interface{}, error) {
+	var draftRequest model.SaveDraftRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&draftRequest)
+	err = validator.New().Struct(draftRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	conversationId := ctx.PathParam("id")
+	if strings.TrimSpace(conversationId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter conversationId")
+	}
+
+	// Clocks of devices that run ahead cannot push their drafts past the ones written after them.
+	updatedAt := time.Now()
+	if draftRequest.UpdatedAt != nil && draftRequest.UpdatedAt.Before(updatedAt) {
+		updatedAt = *draftRequest.UpdatedAt
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	draft, updated, err := h.Conversation.SaveDraft(ctx, userId, conversationId, draftRequest.Content, updatedAt)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == sql.ErrNoRows {
+			return nil, e.HttpStatusError(404, "Conversation does not exists")
+		}
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	if updated {
+		h.Hub.Publish(userId, model.Event{Type: model.EventDraftUpdated, Data: draft})
+	}
+	return types.Raw{Data: draft}, nil
+}
+
+// HandleGetDraft returns the draft of the user in a conversation, or to a peer.
+func (h Handler) HandleGetDraft(ctx *gofr.Context) (interface{}, error) {
+	conversationId := ctx.PathParam("id")
+	if strings.TrimSpace(conversationId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter conversationId")
+	}
+
+	draft, err := h.Conversation.GetDraft(ctx, ctx.Value("userId").(string), conversationId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == sql.ErrNoRows {
+			return nil, e.HttpStatusError(404, "Draft does not exists")
+		}
+		return nil, e.HttpStatusError(500, "")
+	}
+	return types.Raw{Data: draft}, nil
+}
+
+// HandleDeleteDraft discards the draft of the user in a conversation, or to a peer.
+func (h Handler) HandleDeleteDraft(ctx *gofr.Context) (interface{}, error) {
+	conversationId := ctx.PathParam("id")
+	if strings.TrimSpace(conversationId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter conversationId")
+	}
+
+	err := h.deleteDraft(ctx, ctx.Value("userId").(string), conversationId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+	return nil, nil
+}
+
+// clearDraft discards the draft the sender of a message had in its conversation, now that it is sent.
+// Failing to is only logged, as the message went out.
+func (h Handler) clearDraft(ctx *gofr.Context, message model.Message) {
+	err := h.deleteDraft(ctx, message.From, message.ConversationID)
+	if err != nil {
+		ctx.Logger.Error(err)
+	}
+}
+
+// deleteDraft deletes a draft of the user and tells the user's devices about it, if there was one.
+func (h Handler) deleteDraft(ctx *gofr.Context, userId, conversationId string) error {
+	draft, err := h.Conversation.DeleteDraft(ctx, userId, conversationId)
+	if err != nil {
+		return err
+	}
+
+	if draft != nil {
+		h.Hub.Publish(userId, model.Event{Type: model.EventDraftDeleted, Data: model.Draft{ConversationID: draft.ConversationID, UpdatedAt: time.Now()}})
+	}
+	return nil
+}
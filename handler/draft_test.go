@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/realtime"
+	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// draftTCConversationStore keeps a draft saved at savedAt, which only newer drafts replace, and deletes it when asked.
+type draftTCConversationStore struct {
+	mockConversationStore
+	savedAt time.Time
+	err     error
+}
+
+func (c draftTCConversationStore) SaveDraft(ctx *gofr.Context, userId, conversationId string, content string, updatedAt time.Time) (*model.Draft, bool, error) {
+	if c.err != nil {
+		return nil, false, c.err
+	}
+	if !updatedAt.After(c.savedAt) {
+		return &model.Draft{ConversationID: "conversationId", Content: "Saved", UpdatedAt: c.savedAt}, false, nil
+	}
+	return &model.Draft{ConversationID: "conversationId", Content: content, UpdatedAt: updatedAt}, true, nil
+}
+
+func (c draftTCConversationStore) DeleteDraft(ctx *gofr.Context, userId, conversationId string) (*model.Draft, error) {
+	return &model.Draft{ConversationID: "conversationId", Content: "Saved", UpdatedAt: c.savedAt}, nil
+}
+
+// missingDraftTCConversationStore has no drafts.
+type missingDraftTCConversationStore struct {
+	mockConversationStore
+}
+
+func (missingDraftTCConversationStore) GetDraft(ctx *gofr.Context, userId, conversationId string) (*model.Draft, error) {
+	return nil, sql.ErrNoRows
+}
+
+func TestHandleSaveDraft(t *testing.T) {
+	app := gofr.New()
+	savedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		desc    string
+		body    string
+		store   store.ConversationStore
+		content string
+		events  []string
+		err     error
+	}{
+		{desc: "newer draft", body: `{"content":"See you","updatedAt":"2024-05-01T12:01:00Z"}`, store: draftTCConversationStore{savedAt: savedAt},
+			content: "See you", events: []string{model.EventDraftUpdated}},
+		{desc: "draft without time", body: `{"content":"See you"}`, store: draftTCConversationStore{savedAt: savedAt},
+			content: "See you", events: []string{model.EventDraftUpdated}},
+		{desc: "older draft", body: `{"content":"See","updatedAt":"2024-05-01T11:59:00Z"}`, store: draftTCConversationStore{savedAt: savedAt},
+			content: "Saved", events: []string{}},
+		{desc: "empty draft", body: `{"content":""}`, store: draftTCConversationStore{savedAt: savedAt}, events: []string{},
+			err: e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'SaveDraftRequest.Content' Error:Field validation for 'Content' failed on the 'required' tag")},
+		{desc: "unknown conversation", body: `{"content":"See you"}`, store: draftTCConversationStore{err: sql.ErrNoRows}, events: []string{},
+			err: e.HttpStatusError(404, "Conversation does not exists")},
+		{desc: "conversation store error", body: `{"content":"See you"}`, store: errorTCConversationStore{}, events: []string{},
+			err: e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		hub := realtime.NewHub()
+		deviceConn := connectTestClient(t, hub, "someUserId")
+		h := Handler{Conversation: tc.store, Hub: hub}
+
+		ctx := newTestContext(app, http.MethodPut, "http://dummy", []byte(tc.body))
+		ctx.SetPathParams(map[string]string{"id": "peer-id"})
+
+		result, err := h.HandleSaveDraft(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			assert.Equal(t, tc.content, result.(types.Raw).Data.(*model.Draft).Content, "TEST: %s: mismatch in saved draft", tc.desc)
+		}
+		assert.Equal(t, tc.events, readTestEvents(deviceConn), "TEST: %s: mismatch in events pushed to the user's devices", tc.desc)
+	}
+}
+
+func TestHandleSaveDraftFromTheFuture(t *testing.T) {
+	app := gofr.New()
+
+	h := Handler{Conversation: mockConversationStore{}, Hub: realtime.NewHub()}
+
+	body := `{"content":"See you","updatedAt":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`
+	ctx := newTestContext(app, http.MethodPut, "http://dummy", []byte(body))
+	ctx.SetPathParams(map[string]string{"id": "peer-id"})
+
+	result, err := h.HandleSaveDraft(ctx)
+
+	assert.NoError(t, err, "Unexpected error while saving a draft")
+	assert.False(t, result.(types.Raw).Data.(*model.Draft).UpdatedAt.After(time.Now()), "Expected a draft from a clock running ahead to be saved as of now")
+}
+
+func TestHandleGetDraft(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc  string
+		store store.ConversationStore
+		err   error
+	}{
+		{"get draft success", mockConversationStore{}, nil},
+		{"no draft", missingDraftTCConversationStore{}, e.HttpStatusError(404, "Draft does not exists")},
+		{"conversation store error", errorTCConversationStore{}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Conversation: tc.store}
+
+		ctx := newTestContext(app, http.MethodGet, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"id": "peer-id"})
+
+		_, err := h.HandleGetDraft(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+	}
+}
+
+func TestHandleDeleteDraft(t *testing.T) {
+	app := gofr.New()
+	hub := realtime.NewHub()
+	deviceConn := connectTestClient(t, hub, "someUserId")
+
+	testCases := []struct {
+		desc   string
+		store  store.ConversationStore
+		events []string
+		err    error
+	}{
+		{desc: "delete draft", store: draftTCConversationStore{}, events: []string{model.EventDraftDeleted}},
+		{desc: "no draft", store: mockConversationStore{}, events: []string{}},
+		{desc: "conversation store error", store: errorTCConversationStore{}, events: []string{}, err: e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Conversation: tc.store, Hub: hub}
+
+		ctx := newTestContext(app, http.MethodDelete, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"id": "peer-id"})
+
+		_, err := h.HandleDeleteDraft(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		assert.Equal(t, tc.events, readTestEvents(deviceConn), "TEST: %s: mismatch in events pushed to the user's devices", tc.desc)
+	}
+}
+
+func TestSendMessageClearsDraft(t *testing.T) {
+	app := gofr.New()
+	hub := realtime.NewHub()
+	deviceConn := connectTestClient(t, hub, "someUserId")
+
+	var added []model.Message
+	h := Handler{Auth: existingTCAuthStore{}, Message: scheduleTCMessageStore{added: &added}, Friend: mockFriendStore{}, Block: mockBlockStore{},
+		Conversation: draftTCConversationStore{}, Hub: hub}
+
+	body := `{"recipientId":"` + testRecipientID + `","content":"See you"}`
+	ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(body))
+
+	_, err := h.HandleSendMessageByID(ctx)
+
+	assert.NoError(t, err, "Unexpected error while sending a message")
+	assert.Equal(t, []string{model.EventDraftDeleted}, readTestEvents(deviceConn), "Expected the draft of the message to be cleared")
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// HandleForwardMessage sends a copy of a message the user can see to each of the recipients, marked as forwarded.
+// The copies share the attachments of the message instead of uploading them again. Once a message was forwarded
+// MESSAGE_FORWARD_LIMIT times along a chain, it cannot be forwarded any further.
+func (h Handler) HandleForwardMessage(ctx *gofr.Context) (interface{}, error) {
+	var forwardRequest model.ForwardMessageRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&forwardRequest)
+	err = validator.New().Struct(forwardRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	messageId := ctx.PathParam("id")
+	if strings.TrimSpace(messageId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter messageId")
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	original, err := h.Message.GetMessage(ctx, userId, messageId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == sql.ErrNoRows {
+			return nil, e.HttpStatusError(404, "Message does not exists")
+		} else if err == e.NewError("You are not authorized to see that message") {
+			return nil, e.HttpStatusError(403, err.Error())
+		}
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	if original.Deleted || original.System != nil {
+		return nil, e.HttpStatusError(422, "That message cannot be forwarded")
+	}
+	if original.ForwardCount >= uint(IntConfig(ctx.Config, "MESSAGE_FORWARD_LIMIT", 5)) {
+		return nil, e.HttpStatusError(422, "That message has been forwarded too many times")
+	}
+
+	// Every recipient is checked before anything is sent, so a bad one does not leave the forward half done.
+	forwards := make([]model.Message, 0, len(forwardRequest.RecipientIDs))
+	for _, recipientId := range forwardRequest.RecipientIDs {
+		id, err := h.recipientID(ctx, recipientId)
+		if err != nil {
+			return nil, err
+		}
+
+		message := NewMessage(userId, id, original.Content)
+		message.Type, message.Payload = original.Type, original.Payload
+		message.Entities, message.HTML = original.Entities, original.HTML
+		message.Forwarded, message.ForwardCount = true, original.ForwardCount+1
+		message.Attachments = forwardedAttachments(userId, original.Attachments)
+
+		err = h.checkForward(ctx, message)
+		if err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, message)
+	}
+
+	messages := make([]model.Message, 0, len(forwards))
+	for _, message := range forwards {
+		result, err := h.sendDirectMessage(ctx, message, "", nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, result.(types.Raw).Data.(model.Message))
+	}
+
+	return types.Raw{Data: messages}, nil
+}
+
+// checkForward refuses a forwarded message the way sending it would: to oneself when that is not allowed,
+// or to a recipient who blocked the user when that is not silent. It also sets up the conversation it goes to.
+func (h Handler) checkForward(ctx *gofr.Context, message model.Message) error {
+	err := checkSelfMessage(ctx, message)
+	if err != nil {
+		return err
+	}
+
+	blocked, err := h.blockedBy(ctx, message.To, message.From)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		_, err = dropBlockedMessage(ctx, message)
+		return err
+	}
+
+	return h.directConversation(ctx, &message)
+}
+
+// forwardedAttachments copies attachments for a message the user forwards. The copies belong to the user
+// and share the files of the attachments they copy.
+func forwardedAttachments(userId string, attachments []model.Attachment) []model.Attachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	copies := make([]model.Attachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		attachment.ID, attachment.OwnerID = uuid.New().String(), userId
+		copies = append(copies, attachment)
+	}
+	return copies
+}
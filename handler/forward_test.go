@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// forwardTCMessageStore records the messages added, and finds a message to forward for each of the IDs it knows.
+type forwardTCMessageStore struct {
+	scheduleTCMessageStore
+}
+
+func (m forwardTCMessageStore) GetMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, error) {
+	switch messageId {
+	case "missingId":
+		return nil, sql.ErrNoRows
+	case "foreignId":
+		return nil, e.NewError("You are not authorized to see that message")
+	case "deletedId":
+		return &model.Message{ID: messageId, Deleted: true}, nil
+	case "chainId":
+		return &model.Message{ID: messageId, Content: "Send this to 10 friends", Forwarded: true, ForwardCount: 5}, nil
+	}
+	return &model.Message{ID: messageId, Content: "Look at this cat", From: "otherUserId", To: userId, ForwardCount: 1, Forwarded: true,
+		Attachments: []model.Attachment{{ID: "attachmentId", OwnerID: "otherUserId", FileName: "cat.png", BlobID: "blobId"}}}, nil
+}
+
+func TestHandleForwardMessage(t *testing.T) {
+	app := gofr.New()
+	t.Setenv("BLOCKED_SEND_SILENT", "false")
+
+	testCases := []struct {
+		desc            string
+		messageId       string
+		body            string
+		forwarded       int
+		blockerId       string
+		conversationErr bool
+		err             error
+	}{
+		{desc: "forward to two recipients", messageId: "messageId", body: `{"recipientIds":["` + testRecipientID + `","7c9e6679-7425-40de-944b-e07fc1f90ae7"]}`, forwarded: 2},
+		{desc: "no recipients", messageId: "messageId", body: `{"recipientIds":[]}`,
+			err: e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'ForwardMessageRequest.RecipientIDs' Error:Field validation for 'RecipientIDs' failed on the 'min' tag")},
+		{desc: "missing recipient", messageId: "messageId", body: `{"recipientIds":["` + testRecipientID + `","` + testMissingRecipientID + `"]}`,
+			err: e.HttpStatusError(404, "Recipient does not exists")},
+		{desc: "recipient who blocked the user", messageId: "messageId", body: `{"recipientIds":["` + testRecipientID + `","7c9e6679-7425-40de-944b-e07fc1f90ae7"]}`,
+			blockerId: "7c9e6679-7425-40de-944b-e07fc1f90ae7", err: e.HttpStatusError(403, "You cannot send messages to that user")},
+		{desc: "conversation not set up", messageId: "messageId", body: `{"recipientIds":["` + testRecipientID + `"]}`, conversationErr: true,
+			err: e.HttpStatusError(500, "")},
+		{desc: "missing message", messageId: "missingId", body: `{"recipientIds":["` + testRecipientID + `"]}`, err: e.HttpStatusError(404, "Message does not exists")},
+		{desc: "message of another conversation", messageId: "foreignId", body: `{"recipientIds":["` + testRecipientID + `"]}`,
+			err: e.HttpStatusError(403, "You are not authorized to see that message")},
+		{desc: "deleted message", messageId: "deletedId", body: `{"recipientIds":["` + testRecipientID + `"]}`, err: e.HttpStatusError(422, "That message cannot be forwarded")},
+		{desc: "forward limit reached", messageId: "chainId", body: `{"recipientIds":["` + testRecipientID + `"]}`,
+			err: e.HttpStatusError(422, "That message has been forwarded too many times")},
+	}
+
+	for _, tc := range testCases {
+		var added []model.Message
+		h := Handler{Auth: existingTCAuthStore{}, Message: forwardTCMessageStore{scheduleTCMessageStore{added: &added}}, Friend: mockFriendStore{},
+			Block: mockBlockStore{}, Conversation: mockConversationStore{}}
+		if tc.blockerId != "" {
+			h.Block = mockBlockStore{blockerId: tc.blockerId}
+		}
+		if tc.conversationErr {
+			h.Conversation = errorTCConversationStore{}
+		}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(tc.body))
+		ctx.SetPathParams(map[string]string{"id": tc.messageId})
+
+		result, err := h.HandleForwardMessage(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		assert.Len(t, added, tc.forwarded, "TEST: %s: mismatch in number of messages sent", tc.desc)
+		if tc.err != nil {
+			continue
+		}
+
+		messages := result.(types.Raw).Data.([]model.Message)
+		assert.Len(t, messages, tc.forwarded, "TEST: %s: mismatch in number of forwarded messages", tc.desc)
+		for _, message := range added {
+			assert.Equal(t, "someUserId", message.From, "TEST: %s: expected the forwarder to send the message", tc.desc)
+			assert.Equal(t, "Look at this cat", message.Content, "TEST: %s: mismatch in forwarded content", tc.desc)
+			assert.True(t, message.Forwarded, "TEST: %s: expected the message to be marked forwarded", tc.desc)
+			assert.Equal(t, uint(2), message.ForwardCount, "TEST: %s: mismatch in forward count", tc.desc)
+			assert.Len(t, message.Attachments, 1, "TEST: %s: expected the attachment to be forwarded", tc.desc)
+			assert.NotEqual(t, "attachmentId", message.Attachments[0].ID, "TEST: %s: expected a copy of the attachment", tc.desc)
+			assert.Equal(t, "someUserId", message.Attachments[0].OwnerID, "TEST: %s: expected the forwarder to own the copy", tc.desc)
+			assert.Equal(t, "blobId", message.Attachments[0].BlobID, "TEST: %s: expected the copy to share the file", tc.desc)
+		}
+	}
+}
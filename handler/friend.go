@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/google/uuid"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// HandleSendFriendRequest asks another user to become friends. If that user already asked
+// the sender, the two simply become friends.
+func (h Handler) HandleSendFriendRequest(ctx *gofr.Context) (interface{}, error) {
+	recipientId, err := userIdParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userId := ctx.Value("userId").(string)
+	if recipientId == userId {
+		return nil, e.HttpStatusError(400, "You cannot befriend yourself")
+	}
+
+	ok, err := h.Auth.AccountExists(ctx, recipientId)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, "")
+	}
+	if !ok {
+		return nil, e.HttpStatusError(404, "User does not exists")
+	}
+
+	blocked, err := h.blockedBy(ctx, recipientId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return dropBlockedFriendRequest(ctx, recipientId)
+	}
+
+	friends, err := h.Friend.AreFriends(ctx, userId, recipientId)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, "")
+	}
+	if friends {
+		return nil, e.HttpStatusError(409, "You are already friends with that user")
+	}
+
+	request := model.FriendRequest{User: model.User{ID: recipientId}, Status: model.FriendRequestAccepted, CreatedAt: time.Now()}
+
+	err = h.Friend.AcceptFriendRequest(ctx, userId, recipientId)
+	if err == sql.ErrNoRows {
+		request.Status = model.FriendRequestPending
+		err = h.Friend.SendFriendRequest(ctx, userId, recipientId)
+	}
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	return types.Raw{Data: request}, nil
+}
+
+func (h Handler) HandleCancelFriendRequest(ctx *gofr.Context) (interface{}, error) {
+	return h.answerFriendRequest(ctx, h.Friend.CancelFriendRequest)
+}
+
+func (h Handler) HandleAcceptFriendRequest(ctx *gofr.Context) (interface{}, error) {
+	return h.answerFriendRequest(ctx, h.Friend.AcceptFriendRequest)
+}
+
+func (h Handler) HandleDeclineFriendRequest(ctx *gofr.Context) (interface{}, error) {
+	return h.answerFriendRequest(ctx, h.Friend.DeclineFriendRequest)
+}
+
+// answerFriendRequest applies answer to the pending friend request between the user and the user in the path.
+func (h Handler) answerFriendRequest(ctx *gofr.Context, answer func(ctx *gofr.Context, userId, otherUserId string) error) (interface{}, error) {
+	otherUserId, err := userIdParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = answer(ctx, ctx.Value("userId").(string), otherUserId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == sql.ErrNoRows {
+			return nil, e.HttpStatusError(404, "Friend request does not exists")
+		}
+		return nil, e.HttpStatusError(500, "")
+	}
+	return nil, nil
+}
+
+func (h Handler) HandleGetIncomingFriendRequests(ctx *gofr.Context) (interface{}, error) {
+	return h.friendRequests(ctx, true)
+}
+
+func (h Handler) HandleGetOutgoingFriendRequests(ctx *gofr.Context) (interface{}, error) {
+	return h.friendRequests(ctx, false)
+}
+
+func (h Handler) friendRequests(ctx *gofr.Context, incoming bool) (interface{}, error) {
+	requests, err := h.Friend.GetFriendRequests(ctx, ctx.Value("userId").(string), incoming)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+	return types.Raw{Data: requests}, nil
+}
+
+func (h Handler) HandleRemoveFriend(ctx *gofr.Context) (interface{}, error) {
+	friendId, err := userIdParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.Friend.RemoveFriend(ctx, ctx.Value("userId").(string), friendId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == e.NewError("That user is not your friend") {
+			return nil, e.HttpStatusError(404, err.Error())
+		}
+		return nil, e.HttpStatusError(500, "")
+	}
+	return nil, nil
+}
+
+func userIdParam(ctx *gofr.Context) (string, error) {
+	userId := ctx.PathParam("userId")
+	if strings.TrimSpace(userId) == "" {
+		return "", e.HttpStatusError(400, "Missing Parameter userId")
+	}
+
+	id, err := uuid.Parse(userId)
+	if err != nil {
+		return "", e.HttpStatusError(422, "Invalid Parameter userId")
+	}
+	return id.String(), nil
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// existingTCAuthStore knows every account but testMissingRecipientID.
+type existingTCAuthStore struct {
+	mockAuthStore
+}
+
+func (existingTCAuthStore) AccountExists(ctx *gofr.Context, userId string) (bool, error) {
+	return userId != testMissingRecipientID, nil
+}
+
+// testUserID is the user acting in the friend and block tests, and the others are users they know of.
+const (
+	testUserID      = "9f1c2d34-5b6a-4c7d-8e9f-0a1b2c3d4e5f"
+	testFriendID    = "1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d"
+	testRequesterID = "6e7f8a9b-0c1d-4e2f-9a3b-4c5d6e7f8a9b"
+	testBlockerID   = "2c3d4e5f-6a7b-4c8d-9e0f-1a2b3c4d5e6f"
+)
+
+// requestsTCFriendStore has the user befriended with friendId and asked by requesterId, and nothing else.
+type requestsTCFriendStore struct {
+	mockFriendStore
+	friendId    string
+	requesterId string
+}
+
+func (f requestsTCFriendStore) AreFriends(ctx *gofr.Context, userId1, userId2 string) (bool, error) {
+	return userId2 == f.friendId, nil
+}
+
+func (f requestsTCFriendStore) RemoveFriend(ctx *gofr.Context, userId, friendId string) error {
+	if friendId != f.friendId {
+		return e.NewError("That user is not your friend")
+	}
+	return nil
+}
+
+func (f requestsTCFriendStore) AcceptFriendRequest(ctx *gofr.Context, recipientId, senderId string) error {
+	if senderId != f.requesterId {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (f requestsTCFriendStore) DeclineFriendRequest(ctx *gofr.Context, recipientId, senderId string) error {
+	return f.AcceptFriendRequest(ctx, recipientId, senderId)
+}
+
+func TestHandleSendFriendRequest(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc   string
+		userID string
+		status string
+		err    error
+	}{
+		{"send friend request success", testRecipientID, model.FriendRequestPending, nil},
+		{"send friend request back", testRequesterID, model.FriendRequestAccepted, nil},
+		{"already friends", testFriendID, "", e.HttpStatusError(409, "You are already friends with that user")},
+		{"befriend oneself", testUserID, "", e.HttpStatusError(400, "You cannot befriend yourself")},
+		{"unknown user", testMissingRecipientID, "", e.HttpStatusError(404, "User does not exists")},
+		{"missing user", "", "", e.HttpStatusError(400, "Missing Parameter userId")},
+		{"user is not a uuid", "someone", "", e.HttpStatusError(422, "Invalid Parameter userId")},
+		{"blocked by the recipient", testBlockerID, model.FriendRequestPending, nil},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Auth: existingTCAuthStore{}, Friend: requestsTCFriendStore{friendId: testFriendID, requesterId: testRequesterID}, Block: mockBlockStore{blockerId: testBlockerID}}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", nil)
+		ctx.Context = context.WithValue(ctx.Context, "userId", testUserID)
+		ctx.SetPathParams(map[string]string{"userId": tc.userID})
+
+		result, err := h.HandleSendFriendRequest(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			request := result.(types.Raw).Data.(model.FriendRequest)
+			assert.Equal(t, tc.status, request.Status, "TEST: %s: mismatch in status", tc.desc)
+			assert.Equal(t, tc.userID, request.User.ID, "TEST: %s: mismatch in recipient", tc.desc)
+		}
+	}
+}
+
+func TestHandleAnswerFriendRequest(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc   string
+		handle func(Handler, *gofr.Context) (interface{}, error)
+		userID string
+		err    error
+	}{
+		{"accept success", Handler.HandleAcceptFriendRequest, testRequesterID, nil},
+		{"accept a missing request", Handler.HandleAcceptFriendRequest, testRecipientID, e.HttpStatusError(404, "Friend request does not exists")},
+		{"accept a user who is not a uuid", Handler.HandleAcceptFriendRequest, "someone", e.HttpStatusError(422, "Invalid Parameter userId")},
+		{"decline success", Handler.HandleDeclineFriendRequest, testRequesterID, nil},
+		{"decline a missing request", Handler.HandleDeclineFriendRequest, testRecipientID, e.HttpStatusError(404, "Friend request does not exists")},
+		{"cancel success", Handler.HandleCancelFriendRequest, testRecipientID, nil},
+		{"unfriend success", Handler.HandleRemoveFriend, testFriendID, nil},
+		{"unfriend a stranger", Handler.HandleRemoveFriend, testRecipientID, e.HttpStatusError(404, "That user is not your friend")},
+		{"unfriend a user who is not a uuid", Handler.HandleRemoveFriend, "someone", e.HttpStatusError(422, "Invalid Parameter userId")},
+		{"missing user", Handler.HandleRemoveFriend, "", e.HttpStatusError(400, "Missing Parameter userId")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Friend: requestsTCFriendStore{friendId: testFriendID, requesterId: testRequesterID}}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"userId": tc.userID})
+
+		_, err := tc.handle(h, ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+	}
+}
+
+func TestHandleGetFriendRequests(t *testing.T) {
+	app := gofr.New()
+
+	for _, handle := range []func(Handler, *gofr.Context) (interface{}, error){Handler.HandleGetIncomingFriendRequests, Handler.HandleGetOutgoingFriendRequests} {
+		h := Handler{Friend: mockFriendStore{}}
+
+		ctx := newTestContext(app, http.MethodGet, "http://dummy", nil)
+
+		result, err := handle(h, ctx)
+
+		assert.NoError(t, err, "Unexpected error while retrieving friend requests")
+		assert.IsType(t, &[]model.FriendRequest{}, result.(types.Raw).Data, "Unexpected result type")
+	}
+}
+
+// acceptanceTCConversationStore records whether direct conversations were accepted for the recipient.
+type acceptanceTCConversationStore struct {
+	mockConversationStore
+	accepted *bool
+}
+
+func (c acceptanceTCConversationStore) EnsureDirectConversation(ctx *gofr.Context, userId, peerId string, accepted bool) (string, error) {
+	*c.accepted = accepted
+	return "conversationId", nil
+}
+
+func TestHandleSendMessageToStranger(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc     string
+		friend   store.FriendStore
+		accepted bool
+	}{
+		{"message a friend", requestsTCFriendStore{friendId: testRecipientID}, true},
+		{"message a stranger", requestsTCFriendStore{}, false},
+	}
+
+	for _, tc := range testCases {
+		var accepted bool
+		h := Handler{Auth: existingTCAuthStore{}, Message: successfulTCMessageStore{}, Friend: tc.friend, Block: mockBlockStore{}, Conversation: acceptanceTCConversationStore{accepted: &accepted}}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(`{"recipientId":"`+testRecipientID+`","content":"Hello"}`))
+
+		_, err := h.HandleSendMessageByID(ctx)
+
+		assert.NoError(t, err, "TEST: %s: unexpected error", tc.desc)
+		assert.Equal(t, tc.accepted, accepted, "TEST: %s: expected messages from strangers to be message requests", tc.desc)
+	}
+}
@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/go-playground/validator"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+func (h Handler) HandleCreateGroup(ctx *gofr.Context) (interface{}, error) {
+	var createGroupRequest model.CreateGroupRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&createGroupRequest)
+	err = validator.New().Struct(createGroupRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	group := NewGroup(userId, createGroupRequest.Name, createGroupRequest.MemberIDs)
+	if len(group.Members) > IntConfig(ctx.Config, "GROUP_MAX_MEMBERS", 256) {
+		return nil, e.HttpStatusError(409, "That group is full")
+	}
+
+	err = h.Group.CreateGroup(ctx, group, BoolConfig(ctx.Config, "BLOCKED_SEND_SILENT", true))
+	if err != nil {
+		return nil, groupError(ctx, err)
+	}
+
+	return h.getGroup(ctx, userId, group.ID)
+}
+
+func (h Handler) HandleGetGroup(ctx *gofr.Context) (interface{}, error) {
+	groupId := ctx.PathParam("id")
+	if strings.TrimSpace(groupId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter groupId")
+	}
+
+	return h.getGroup(ctx, ctx.Value("userId").(string), groupId)
+}
+
+func (h Handler) HandleRenameGroup(ctx *gofr.Context) (interface{}, error) {
+	var renameGroupRequest model.RenameGroupRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&renameGroupRequest)
+	err = validator.New().Struct(renameGroupRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	groupId := ctx.PathParam("id")
+	if strings.TrimSpace(groupId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter groupId")
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	err = h.Group.RenameGroup(ctx, userId, groupId, renameGroupRequest.Name)
+	if err != nil {
+		return nil, groupError(ctx, err)
+	}
+
+	return h.getGroup(ctx, userId, groupId)
+}
+
+func (h Handler) HandleAddGroupMembers(ctx *gofr.Context) (interface{}, error) {
+	var addMembersRequest model.AddGroupMembersRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&addMembersRequest)
+	err = validator.New().Struct(addMembersRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	groupId := ctx.PathParam("id")
+	if strings.TrimSpace(groupId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter groupId")
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	err = h.Group.AddMembers(ctx, userId, groupId, addMembersRequest.MemberIDs, IntConfig(ctx.Config, "GROUP_MAX_MEMBERS", 256),
+		BoolConfig(ctx.Config, "BLOCKED_SEND_SILENT", true))
+	if err != nil {
+		return nil, groupError(ctx, err)
+	}
+
+	return h.getGroup(ctx, userId, groupId)
+}
+
+func (h Handler) HandleUpdateGroupMember(ctx *gofr.Context) (interface{}, error) {
+	var updateMemberRequest model.UpdateGroupMemberRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&updateMemberRequest)
+	err = validator.New().Struct(updateMemberRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	groupId, memberId := ctx.PathParam("id"), ctx.PathParam("memberId")
+	if strings.TrimSpace(groupId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter groupId")
+	}
+	if strings.TrimSpace(memberId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter memberId")
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	err = h.Group.UpdateMemberRole(ctx, userId, groupId, memberId, updateMemberRequest.Role)
+	if err != nil {
+		return nil, groupError(ctx, err)
+	}
+
+	return h.getGroup(ctx, userId, groupId)
+}
+
+func (h Handler) HandleRemoveGroupMember(ctx *gofr.Context) (interface{}, error) {
+	groupId, memberId := ctx.PathParam("id"), ctx.PathParam("memberId")
+	if strings.TrimSpace(groupId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter groupId")
+	}
+	if strings.TrimSpace(memberId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter memberId")
+	}
+
+	err := h.Group.RemoveMember(ctx, ctx.Value("userId").(string), groupId, memberId)
+	if err != nil {
+		return nil, groupError(ctx, err)
+	}
+	return nil, nil
+}
+
+func (h Handler) HandleLeaveGroup(ctx *gofr.Context) (interface{}, error) {
+	groupId := ctx.PathParam("id")
+	if strings.TrimSpace(groupId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter groupId")
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	err := h.Group.RemoveMember(ctx, userId, groupId, userId)
+	if err != nil {
+		return nil, groupError(ctx, err)
+	}
+	return nil, nil
+}
+
+// HandleSendGroupMessage sends a message to every member of a group the user is a member of.
+func (h Handler) HandleSendGroupMessage(ctx *gofr.Context) (interface{}, error) {
+	var messageRequest model.SendGroupMessageRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&messageRequest)
+	err = validator.New().Struct(messageRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	groupId := ctx.PathParam("id")
+	if strings.TrimSpace(groupId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter groupId")
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	_, err = h.Group.GetRole(ctx, userId, groupId)
+	if err != nil {
+		return nil, groupError(ctx, err)
+	}
+
+	message := NewMessage(userId, "", messageRequest.Content)
+	message.ConversationID = groupId
+
+	err = h.typedMessage(ctx, &message, messageRequest.Type, messageRequest.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.formatMessage(ctx, &message)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.disappearing(ctx, &message)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.quoteReply(ctx, &message, messageRequest.ReplyToID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.attachFiles(ctx, &message, messageRequest.AttachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.Message.AddMessage(ctx, message)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(500, err.Error())
+	}
+
+	err = h.Conversation.RecordMessage(ctx, message)
+	if err != nil {
+		ctx.Logger.Error(err)
+	}
+
+	h.clearDraft(ctx, message)
+	h.notifyMentions(message, nil)
+	h.queueLinkPreview(message)
+
+	return types.Raw{Data: message}, nil
+}
+
+func (h Handler) HandleGetGroupMessages(ctx *gofr.Context) (interface{}, error) {
+	groupId := ctx.PathParam("id")
+	if strings.TrimSpace(groupId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter groupId")
+	}
+
+	page, err := pageParam(ctx)
+	if err != nil {
+		return nil, e.HttpStatusError(400, "Invalid Parameter page")
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	_, err = h.Group.GetRole(ctx, userId, groupId)
+	if err != nil {
+		return nil, groupError(ctx, err)
+	}
+
+	messages, err := h.Message.GetConversationMessages(ctx, userId, groupId, page, model.RequestMessageLimit)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	if len(*messages) == 0 {
+		return nil, e.HttpStatusError(404, "Page does not exists")
+	}
+
+	lastPage := false
+	if len(*messages) < model.RequestMessageLimit {
+		lastPage = true
+	}
+	return types.Raw{Data: model.GetMessagesResponse{Page: page, LastPage: lastPage, Messages: *messages}}, nil
+}
+
+func (h Handler) getGroup(ctx *gofr.Context, userId, groupId string) (interface{}, error) {
+	group, err := h.Group.GetGroup(ctx, userId, groupId)
+	if err != nil {
+		return nil, groupError(ctx, err)
+	}
+	return types.Raw{Data: group}, nil
+}
+
+func groupError(ctx *gofr.Context, err error) error {
+	ctx.Logger.Info("err: ", err.Error())
+	switch err {
+	case sql.ErrNoRows:
+		return e.HttpStatusError(404, "Group does not exists")
+	case e.NewError("You are not a member of that group"), e.NewError("Only admins can manage that group"),
+		e.NewError("You cannot add those users to a group"):
+		return e.HttpStatusError(403, err.Error())
+	case e.NewError("That user is not a member of the group"):
+		return e.HttpStatusError(404, err.Error())
+	case e.NewError("Some of those users do not exist"):
+		return e.HttpStatusError(400, err.Error())
+	case e.NewError("That group is full"), e.NewError("A group needs at least one admin"):
+		return e.HttpStatusError(409, err.Error())
+	}
+	return e.HttpStatusError(500, "")
+}
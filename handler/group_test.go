@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// mockGroupStore fails every call with err, if any.
+type mockGroupStore struct {
+	err     error
+	blocked bool
+}
+
+// blockedError is what the store answers when adding members who are blocked, unless it may drop them.
+func (g mockGroupStore) blockedError(dropBlocked bool) error {
+	if g.blocked && !dropBlocked {
+		return e.NewError("You cannot add those users to a group")
+	}
+	return g.err
+}
+
+func (g mockGroupStore) CreateGroup(ctx *gofr.Context, group model.Group, dropBlocked bool) error {
+	return g.blockedError(dropBlocked)
+}
+
+func (g mockGroupStore) GetGroup(ctx *gofr.Context, userId, groupId string) (*model.Group, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &model.Group{ID: groupId}, nil
+}
+
+func (g mockGroupStore) GetRole(ctx *gofr.Context, userId, groupId string) (string, error) {
+	return model.RoleMember, g.err
+}
+
+func (g mockGroupStore) RenameGroup(ctx *gofr.Context, userId, groupId, name string) error {
+	return g.err
+}
+
+func (g mockGroupStore) AddMembers(ctx *gofr.Context, userId, groupId string, memberIds []string, maxMembers int, dropBlocked bool) error {
+	return g.blockedError(dropBlocked)
+}
+
+func (g mockGroupStore) UpdateMemberRole(ctx *gofr.Context, userId, groupId, memberId, role string) error {
+	return g.err
+}
+
+func (g mockGroupStore) RemoveMember(ctx *gofr.Context, userId, groupId, memberId string) error {
+	return g.err
+}
+
+type groupMessagesTCMessageStore struct {
+	successfulTCMessageStore
+	count int
+}
+
+func (m groupMessagesTCMessageStore) GetConversationMessages(ctx *gofr.Context, userId, conversationId string, page, limit uint) (*[]model.Message, error) {
+	messages := make([]model.Message, m.count)
+	return &messages, nil
+}
+
+func TestHandleCreateGroup(t *testing.T) {
+	app := gofr.New()
+
+	tooMany := make([]string, 256)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("%q", uuid.New().String())
+	}
+
+	testCases := []struct {
+		desc  string
+		body  string
+		store store.GroupStore
+		err   error
+	}{
+		{"create group success", `{"name":"Cats","memberIds":["` + uuid.New().String() + `"]}`, mockGroupStore{}, nil},
+		{"group of one", `{"name":"Cats"}`, mockGroupStore{}, nil},
+		{"missing name", `{"memberIds":[]}`, mockGroupStore{}, e.HttpStatusError(400, "Invalid inputs or missing required fields")},
+		{"invalid member", `{"name":"Cats","memberIds":["someone"]}`, mockGroupStore{}, e.HttpStatusError(400, "Invalid inputs or missing required fields")},
+		{"too many members", `{"name":"Cats","memberIds":[` + strings.Join(tooMany, ",") + `]}`, mockGroupStore{}, e.HttpStatusError(409, "That group is full")},
+		{"unknown members", `{"name":"Cats"}`, mockGroupStore{err: e.NewError("Some of those users do not exist")}, e.HttpStatusError(400, "Some of those users do not exist")},
+		{"group store error", `{"name":"Cats"}`, mockGroupStore{err: e.NewError("")}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Group: tc.store}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(tc.body))
+
+		result, err := h.HandleCreateGroup(ctx)
+
+		if tc.err != nil {
+			assert.Error(t, err, "TEST: %s: expected an error", tc.desc)
+			assert.Contains(t, err.Error(), tc.err.Error(), "TEST: %s: unexpected error", tc.desc)
+		} else {
+			assert.NoError(t, err, "TEST: Unexpected Error: %s", tc.desc)
+			assert.IsType(t, types.Raw{}, result, "TEST: %s: unexpected result type", tc.desc)
+		}
+	}
+}
+
+func TestHandleAddBlockedGroupMembers(t *testing.T) {
+	app := gofr.New()
+	h := Handler{Group: mockGroupStore{blocked: true}}
+	body := []byte(`{"name":"Cats","memberIds":["` + testRecipientID + `"]}`)
+
+	ctx := newTestContext(app, http.MethodPost, "http://dummy", body)
+	_, err := h.HandleCreateGroup(ctx)
+	assert.NoError(t, err, "Expected blocked members to be left out silently")
+
+	ctx = newTestContext(app, http.MethodPost, "http://dummy", body)
+	ctx.SetPathParams(map[string]string{"id": "groupId"})
+	_, err = h.HandleAddGroupMembers(ctx)
+	assert.NoError(t, err, "Expected blocked members to be left out silently")
+
+	t.Setenv("BLOCKED_SEND_SILENT", "false")
+
+	ctx = newTestContext(app, http.MethodPost, "http://dummy", body)
+	_, err = h.HandleCreateGroup(ctx)
+	assert.Equal(t, e.HttpStatusError(403, "You cannot add those users to a group"), err, "Expected blocked members to be refused when configured")
+
+	ctx = newTestContext(app, http.MethodPost, "http://dummy", body)
+	ctx.SetPathParams(map[string]string{"id": "groupId"})
+	_, err = h.HandleAddGroupMembers(ctx)
+	assert.Equal(t, e.HttpStatusError(403, "You cannot add those users to a group"), err, "Expected blocked members to be refused when configured")
+}
+
+func TestHandleManageGroup(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc     string
+		handle   func(Handler, *gofr.Context) (interface{}, error)
+		body     string
+		memberID string
+		store    store.GroupStore
+		err      error
+	}{
+		{"get group success", Handler.HandleGetGroup, "", "", mockGroupStore{}, nil},
+		{"get group of a non member", Handler.HandleGetGroup, "", "", mockGroupStore{err: e.NewError("You are not a member of that group")}, e.HttpStatusError(403, "You are not a member of that group")},
+		{"get missing group", Handler.HandleGetGroup, "", "", mockGroupStore{err: sql.ErrNoRows}, e.HttpStatusError(404, "Group does not exists")},
+		{"rename success", Handler.HandleRenameGroup, `{"name":"Dogs"}`, "", mockGroupStore{}, nil},
+		{"rename by a member", Handler.HandleRenameGroup, `{"name":"Dogs"}`, "", mockGroupStore{err: e.NewError("Only admins can manage that group")}, e.HttpStatusError(403, "Only admins can manage that group")},
+		{"add members success", Handler.HandleAddGroupMembers, `{"memberIds":["` + uuid.New().String() + `"]}`, "", mockGroupStore{}, nil},
+		{"add no members", Handler.HandleAddGroupMembers, `{"memberIds":[]}`, "", mockGroupStore{}, e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'AddGroupMembersRequest.MemberIDs' Error:Field validation for 'MemberIDs' failed on the 'min' tag")},
+		{"add to a full group", Handler.HandleAddGroupMembers, `{"memberIds":["` + uuid.New().String() + `"]}`, "", mockGroupStore{err: e.NewError("That group is full")}, e.HttpStatusError(409, "That group is full")},
+		{"promote success", Handler.HandleUpdateGroupMember, `{"role":"admin"}`, "memberId", mockGroupStore{}, nil},
+		{"invalid role", Handler.HandleUpdateGroupMember, `{"role":"owner"}`, "memberId", mockGroupStore{}, e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'UpdateGroupMemberRequest.Role' Error:Field validation for 'Role' failed on the 'oneof' tag")},
+		{"last admin steps down", Handler.HandleUpdateGroupMember, `{"role":"member"}`, "memberId", mockGroupStore{err: e.NewError("A group needs at least one admin")}, e.HttpStatusError(409, "A group needs at least one admin")},
+		{"missing member", Handler.HandleUpdateGroupMember, `{"role":"admin"}`, "", mockGroupStore{}, e.HttpStatusError(400, "Missing Parameter memberId")},
+		{"remove success", Handler.HandleRemoveGroupMember, "", "memberId", mockGroupStore{}, nil},
+		{"remove a non member", Handler.HandleRemoveGroupMember, "", "memberId", mockGroupStore{err: e.NewError("That user is not a member of the group")}, e.HttpStatusError(404, "That user is not a member of the group")},
+		{"leave success", Handler.HandleLeaveGroup, "", "", mockGroupStore{}, nil},
+		{"leave error", Handler.HandleLeaveGroup, "", "", mockGroupStore{err: e.NewError("")}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Group: tc.store}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(tc.body))
+		ctx.SetPathParams(map[string]string{"id": "groupId", "memberId": tc.memberID})
+
+		_, err := tc.handle(h, ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+	}
+}
+
+func TestHandleSendGroupMessage(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc    string
+		body    string
+		groupID string
+		message store.MessageStore
+		group   store.GroupStore
+		err     error
+	}{
+		{"send success", `{"content":"Hello, cats!"}`, "groupId", successfulTCMessageStore{}, mockGroupStore{}, nil},
+		{"missing content", `{}`, "groupId", successfulTCMessageStore{}, mockGroupStore{}, e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'SendGroupMessageRequest.Content' Error:Field validation for 'Content' failed on the 'required_without_all' tag")},
+		{"missing group", `{"content":"Hello"}`, "", successfulTCMessageStore{}, mockGroupStore{}, e.HttpStatusError(400, "Missing Parameter groupId")},
+		{"not a member", `{"content":"Hello"}`, "groupId", successfulTCMessageStore{}, mockGroupStore{err: e.NewError("You are not a member of that group")}, e.HttpStatusError(403, "You are not a member of that group")},
+		{"message store error", `{"content":"Hello"}`, "groupId", errorTCMessageStore{}, mockGroupStore{}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Message: tc.message, Group: tc.group, Conversation: mockConversationStore{}}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(tc.body))
+		ctx.SetPathParams(map[string]string{"id": tc.groupID})
+
+		result, err := h.HandleSendGroupMessage(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			message := result.(types.Raw).Data.(model.Message)
+			assert.Equal(t, "groupId", message.ConversationID, "TEST: %s: mismatch in conversation", tc.desc)
+			assert.Empty(t, message.To, "TEST: %s: expected no recipient for a group message", tc.desc)
+		}
+	}
+}
+
+func TestHandleGetGroupMessages(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc    string
+		url     string
+		message store.MessageStore
+		group   store.GroupStore
+		err     error
+	}{
+		{"get messages success", "http://dummy", groupMessagesTCMessageStore{count: 3}, mockGroupStore{}, nil},
+		{"empty page", "http://dummy?page=2", groupMessagesTCMessageStore{}, mockGroupStore{}, e.HttpStatusError(404, "Page does not exists")},
+		{"invalid page", "http://dummy?page=zero", groupMessagesTCMessageStore{}, mockGroupStore{}, e.HttpStatusError(400, "Invalid Parameter page")},
+		{"not a member", "http://dummy", groupMessagesTCMessageStore{count: 3}, mockGroupStore{err: e.NewError("You are not a member of that group")}, e.HttpStatusError(403, "You are not a member of that group")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Message: tc.message, Group: tc.group}
+
+		ctx := newTestContext(app, http.MethodGet, tc.url, nil)
+		ctx.SetPathParams(map[string]string{"id": "groupId"})
+
+		result, err := h.HandleGetGroupMessages(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			assert.True(t, result.(types.Raw).Data.(model.GetMessagesResponse).LastPage, "TEST: %s: expected the last page", tc.desc)
+		}
+	}
+}
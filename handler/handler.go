@@ -6,26 +6,55 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/aryanA101a/legoshichat-backend/blob"
 	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/jobs"
+	"github.com/aryanA101a/legoshichat-backend/markup"
 	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/realtime"
 	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/aryanA101a/legoshichat-backend/unfurl"
 	"github.com/go-playground/validator"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"gofr.dev/pkg/gofr"
 	"gofr.dev/pkg/gofr/types"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Handler struct {
-	Auth        store.AuthStore
-	Message     store.MessageStore
-	Friend      store.FriendStore
-	AuthCreator Creator
+	Auth            store.AuthStore
+	Message         store.MessageStore
+	Friend          store.FriendStore
+	Block           store.BlockStore
+	Contact         store.ContactStore
+	Conversation    store.ConversationStore
+	Group           store.GroupStore
+	Channel         store.ChannelStore
+	Presence        store.PresenceStore
+	Settings        store.SettingsStore
+	Attachment      store.AttachmentStore
+	Search          store.SearchStore
+	Blobs           blob.BlobStore
+	Media           *jobs.Queue[model.Attachment]
+	Unfurler        *unfurl.Unfurler
+	Previews        *jobs.Queue[model.Message]
+	AuthCreator     Creator
+	Hub             *realtime.Hub
+	PresenceTracker *realtime.Presence
+	Typing          *realtime.Typing
+	TypingLimiter   *realtime.RateLimiter
 }
 
-func New(a store.AuthStore, m store.MessageStore, f store.FriendStore, c Creator) Handler {
-	return Handler{Auth: a, Message: m, Friend: f, AuthCreator: c}
+// ActivityTracker is told about every request that passes JWT authentication.
+type ActivityTracker interface {
+	TrackActivity(ctx *gofr.Context, userId string)
+}
+
+func New(a store.AuthStore, m store.MessageStore, f store.FriendStore, bl store.BlockStore, ct store.ContactStore, cs store.ConversationStore, g store.GroupStore, ch store.ChannelStore, p store.PresenceStore, s store.SettingsStore, at store.AttachmentStore, sr store.SearchStore, b blob.BlobStore, mq *jobs.Queue[model.Attachment], u *unfurl.Unfurler, pq *jobs.Queue[model.Message], c Creator, hub *realtime.Hub, pt *realtime.Presence, t *realtime.Typing, tl *realtime.RateLimiter) Handler {
+	return Handler{Auth: a, Message: m, Friend: f, Block: bl, Contact: ct, Conversation: cs, Group: g, Channel: ch, Presence: p, Settings: s, Attachment: at, Search: sr, Blobs: b, Media: mq, Unfurler: u, Previews: pq, AuthCreator: c, Hub: hub, PresenceTracker: pt, Typing: t, TypingLimiter: tl}
 }
 
 func (h Handler) HandleCreateAccount(ctx *gofr.Context) (interface{}, error) {
@@ -98,17 +127,23 @@ func (h Handler) HandleSendMessageByID(ctx *gofr.Context) (interface{}, error) {
 		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
 	}
 
-	message := NewMessage(ctx.Value("userId").(string), messageRequest.RecipientID, messageRequest.Content)
+	recipientId, err := h.recipientID(ctx, messageRequest.RecipientID)
+	if err != nil {
+		return nil, err
+	}
 
-	h.Friend.AddFriend(ctx, ctx.Value("userId").(string), messageRequest.RecipientID)
+	message := NewMessage(ctx.Value("userId").(string), recipientId, messageRequest.Content)
+	err = h.typedMessage(ctx, &message, messageRequest.Type, messageRequest.Payload)
+	if err != nil {
+		return nil, err
+	}
 
-	err = h.Message.AddMessage(ctx, message)
+	err = h.formatMessage(ctx, &message)
 	if err != nil {
-		ctx.Logger.Error(err)
-		return nil, e.HttpStatusError(500, err.Error())
+		return nil, err
 	}
 
-	return types.Raw{Data: message}, nil
+	return h.sendComposedMessage(ctx, message, messageRequest.ReplyToID, messageRequest.AttachmentIDs, messageRequest.SendAt)
 }
 
 func (h Handler) HandleSendMessageByPhoneNumber(ctx *gofr.Context) (interface{}, error) {
@@ -131,8 +166,72 @@ func (h Handler) HandleSendMessageByPhoneNumber(ctx *gofr.Context) (interface{},
 	}
 
 	message := NewMessage(ctx.Value("userId").(string), *recipientId, messageRequest.Content)
+	err = h.typedMessage(ctx, &message, messageRequest.Type, messageRequest.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.formatMessage(ctx, &message)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.sendComposedMessage(ctx, message, messageRequest.ReplyToID, messageRequest.AttachmentIDs, messageRequest.SendAt)
+}
+
+// sendComposedMessage sends a message the user wrote with sendDirectMessage, and discards the draft it was written from.
+func (h Handler) sendComposedMessage(ctx *gofr.Context, message model.Message, replyToId string, attachmentIds []string, sendAt *time.Time) (interface{}, error) {
+	result, err := h.sendDirectMessage(ctx, message, replyToId, attachmentIds, sendAt)
+	if err != nil {
+		return nil, err
+	}
+
+	h.clearDraft(ctx, result.(types.Raw).Data.(model.Message))
+	return result, nil
+}
+
+// sendDirectMessage sends a message to its recipient, or schedules it to be sent at sendAt.
+// A scheduled message joins its direct conversation only once it is sent.
+func (h Handler) sendDirectMessage(ctx *gofr.Context, message model.Message, replyToId string, attachmentIds []string, sendAt *time.Time) (interface{}, error) {
+	err := checkSendAt(ctx, sendAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkSelfMessage(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := h.blockedBy(ctx, message.To, message.From)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return dropBlockedMessage(ctx, message)
+	}
+
+	// A scheduled message gets its conversation right away, so that releasing it later cannot fail halfway.
+	err = h.directConversation(ctx, &message)
+	if err != nil {
+		return nil, err
+	}
+	message.ScheduledAt = sendAt
+
+	err = h.disappearing(ctx, &message)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.quoteReply(ctx, &message, replyToId)
+	if err != nil {
+		return nil, err
+	}
 
-	h.Friend.AddFriend(ctx, ctx.Value("userId").(string), *recipientId)
+	err = h.attachFiles(ctx, &message, attachmentIds)
+	if err != nil {
+		return nil, err
+	}
 
 	err = h.Message.AddMessage(ctx, message)
 	if err != nil {
@@ -140,9 +239,93 @@ func (h Handler) HandleSendMessageByPhoneNumber(ctx *gofr.Context) (interface{},
 		return nil, e.HttpStatusError(500, err.Error())
 	}
 
+	if message.ScheduledAt != nil {
+		return types.Raw{Data: message}, nil
+	}
+
+	err = h.Conversation.RecordMessage(ctx, message)
+	if err != nil {
+		ctx.Logger.Error(err)
+	}
+
+	h.queueLinkPreview(message)
 	return types.Raw{Data: message}, nil
 }
 
+// recipientID checks that the recipient of a message sent by ID is an existing account, and returns its ID in canonical form.
+func (h Handler) recipientID(ctx *gofr.Context, recipientId string) (string, error) {
+	id, err := uuid.Parse(recipientId)
+	if err != nil {
+		return "", e.HttpStatusError(422, "Invalid Parameter recipientId")
+	}
+
+	exists, err := h.Auth.AccountExists(ctx, id.String())
+	if err != nil {
+		ctx.Logger.Error(err)
+		return "", e.HttpStatusError(500, "")
+	}
+	if !exists {
+		return "", e.HttpStatusError(404, "Recipient does not exists")
+	}
+	return id.String(), nil
+}
+
+// checkSelfMessage refuses messages to oneself unless MESSAGE_SELF_ALLOWED, which is on by default for notes to self.
+func checkSelfMessage(ctx *gofr.Context, message model.Message) error {
+	if message.From == message.To && !BoolConfig(ctx.Config, "MESSAGE_SELF_ALLOWED", true) {
+		return e.HttpStatusError(422, "You cannot send messages to yourself")
+	}
+	return nil
+}
+
+// directConversation puts the message in the direct conversation between its sender and recipient.
+// Messages from strangers land in the recipient's message requests rather than the inbox.
+func (h Handler) directConversation(ctx *gofr.Context, message *model.Message) error {
+	// notes to self never end up among the message requests
+	accepted := message.From == message.To
+	if !accepted {
+		friends, err := h.Friend.AreFriends(ctx, message.From, message.To)
+		if err != nil {
+			ctx.Logger.Error(err)
+			return e.HttpStatusError(500, "")
+		}
+		accepted = friends
+	}
+
+	conversationId, err := h.Conversation.EnsureDirectConversation(ctx, message.From, message.To, accepted)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return e.HttpStatusError(500, "")
+	}
+
+	message.ConversationID = conversationId
+	return nil
+}
+
+// quoteReply makes the message a reply to the message with replyToId, which has to belong to the same conversation.
+func (h Handler) quoteReply(ctx *gofr.Context, message *model.Message, replyToId string) error {
+	if strings.TrimSpace(replyToId) == "" {
+		return nil
+	}
+
+	quoted, err := h.Message.GetMessage(ctx, message.From, replyToId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == sql.ErrNoRows || err == e.NewError("You are not authorized to see that message") {
+			return e.HttpStatusError(400, "Invalid Parameter replyToId")
+		}
+		return e.HttpStatusError(500, "")
+	}
+
+	if quoted.ConversationID != message.ConversationID {
+		return e.HttpStatusError(400, "Invalid Parameter replyToId")
+	}
+
+	preview := quoted.Preview()
+	message.ReplyTo = &preview
+	return nil
+}
+
 func (h Handler) HandleGetMessage(ctx *gofr.Context) (interface{}, error) {
 	messageId := ctx.PathParam("id")
 	if strings.TrimSpace(messageId) == "" {
@@ -162,6 +345,25 @@ func (h Handler) HandleGetMessage(ctx *gofr.Context) (interface{}, error) {
 	return types.Raw{Data: message}, nil
 }
 
+func (h Handler) HandleGetMessageHistory(ctx *gofr.Context) (interface{}, error) {
+	messageId := ctx.PathParam("id")
+	if strings.TrimSpace(messageId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter messageId")
+	}
+
+	history, err := h.Message.GetMessageHistory(ctx, ctx.Value("userId").(string), messageId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == sql.ErrNoRows {
+			return nil, e.HttpStatusError(404, "Message does not exists")
+		} else if err == e.NewError("You are not authorized to see that message") {
+			return nil, e.HttpStatusError(403, err.Error())
+		}
+		return nil, e.HttpStatusError(500, "")
+	}
+	return types.Raw{Data: history}, nil
+}
+
 func (h Handler) HandlePutMessage(ctx *gofr.Context) (interface{}, error) {
 	var updateMessageRequest model.UpdateMessageRequest
 	err := json.NewDecoder(ctx.Request().Body).Decode(&updateMessageRequest)
@@ -176,16 +378,52 @@ func (h Handler) HandlePutMessage(ctx *gofr.Context) (interface{}, error) {
 		return nil, e.HttpStatusError(400, "Missing Parameter messageId")
 	}
 
-	message, err := h.Message.UpdateMessage(ctx, ctx.Value("userId").(string), messageId, updateMessageRequest.Content)
+	window := SecondsConfig(ctx.Config, "MESSAGE_EDIT_WINDOW", 15*60)
+	userId := ctx.Value("userId").(string)
+
+	// Mentions are checked against the conversation of the message, which is only looked up when there are any.
+	var edited *model.Message
+	if len(markup.Mentions(updateMessageRequest.Content)) > 0 {
+		edited, err = h.Message.GetMessage(ctx, userId, messageId)
+		if err != nil {
+			ctx.Logger.Info("err: ", err.Error())
+			if err == sql.ErrNoRows {
+				return nil, e.HttpStatusError(404, "Message does not exists")
+			} else if err == e.NewError("You are not authorized to see that message") {
+				return nil, e.HttpStatusError(403, err.Error())
+			}
+			return nil, e.HttpStatusError(500, "")
+		}
+	}
+
+	conversationId, mentions := "", false
+	if edited != nil {
+		conversationId, mentions = edited.ConversationID, edited.To == ""
+	}
+	content, entities, err := h.parseMarkup(ctx, updateMessageRequest.Content, conversationId, mentions)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := h.Message.UpdateMessage(ctx, userId, messageId, content, entities, window)
 	if err != nil {
 		ctx.Logger.Info("err: ", err.Error())
 		if err == sql.ErrNoRows {
 			return nil, e.HttpStatusError(404, "Message does not exists")
 		} else if err == e.NewError("You are not authorized to update that message") {
 			return nil, e.HttpStatusError(403, err.Error())
+		} else if err == e.NewError("That message can no longer be edited") {
+			return nil, e.HttpStatusError(403, err.Error())
+		} else if err == e.NewError("Only text messages can be edited") {
+			return nil, e.HttpStatusError(422, err.Error())
 		}
 		return nil, e.HttpStatusError(500, "")
 	}
+
+	if edited != nil {
+		h.notifyMentions(*message, edited.Entities)
+	}
+	h.queueLinkPreview(*message)
 	return types.Raw{Data: message}, nil
 }
 
@@ -195,19 +433,47 @@ func (h Handler) HandleDeleteMessage(ctx *gofr.Context) (interface{}, error) {
 		return nil, e.HttpStatusError(400, "Missing Parameter messageId")
 	}
 
-	err := h.Message.DeleteMessage(ctx, ctx.Value("userId").(string), messageId)
+	mode := ctx.Param("for")
+	if strings.TrimSpace(mode) == "" {
+		mode = model.DeleteForEveryone
+	}
+	if !(mode == model.DeleteForEveryone || mode == model.DeleteForMe) {
+		return nil, e.HttpStatusError(400, "Invalid Parameter for")
+	}
+
+	window := SecondsConfig(ctx.Config, "MESSAGE_DELETE_WINDOW", 48*60*60)
+
+	err := h.Message.DeleteMessage(ctx, ctx.Value("userId").(string), messageId, mode, window)
 	if err != nil {
 		ctx.Logger.Info("err: ", err.Error())
 		if err == sql.ErrNoRows {
 			return nil, e.HttpStatusError(404, "Message does not exists")
 		} else if err == e.NewError("You are not authorized to delete that message") {
 			return nil, e.HttpStatusError(403, err.Error())
+		} else if err == e.NewError("That message can no longer be deleted for everyone") {
+			return nil, e.HttpStatusError(403, err.Error())
 		}
 		return nil, e.HttpStatusError(500, "")
 	}
 	return nil, nil
 }
 
+// PurgeDeletedMessages removes the tombstones of messages deleted for everyone more than MESSAGE_TOMBSTONE_RETENTION
+// seconds ago, along with the files attached to them.
+func (h Handler) PurgeDeletedMessages(ctx *gofr.Context) error {
+	retention := SecondsConfig(ctx.Config, "MESSAGE_TOMBSTONE_RETENTION", 30*24*60*60)
+
+	_, blobIds, err := h.Message.PurgeDeletedMessages(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+
+	for _, blobId := range blobIds {
+		h.deleteBlobs(ctx, blobId)
+	}
+	return nil
+}
+
 func (h Handler) HandleGetMessages(ctx *gofr.Context) (interface{}, error) {
 	var getMessageRequest model.GetMessagesRequest
 	err := json.NewDecoder(ctx.Request().Body).Decode(&getMessageRequest)
@@ -221,7 +487,7 @@ func (h Handler) HandleGetMessages(ctx *gofr.Context) (interface{}, error) {
 		return nil, e.HttpStatusError(403, "You are not authorized to retrieve these messages")
 	}
 
-	messages, err := h.Message.GetMessages(ctx, getMessageRequest.SenderID, getMessageRequest.RecipientID, getMessageRequest.Page, model.RequestMessageLimit)
+	messages, err := h.Message.GetMessages(ctx, ctx.Value("userId").(string), getMessageRequest.SenderID, getMessageRequest.RecipientID, getMessageRequest.Page, model.RequestMessageLimit)
 	if err != nil {
 		ctx.Logger.Info("err: ", err.Error())
 		if err == sql.ErrNoRows {
@@ -254,7 +520,7 @@ func (h Handler) GetFriends(ctx *gofr.Context) (interface{}, error) {
 	return types.Raw{Data: friends}, nil
 }
 
-func WithJWTAuth(handlerFunc gofr.Handler, authStore store.AuthStore) gofr.Handler {
+func WithJWTAuth(handlerFunc gofr.Handler, authStore store.AuthStore, tracker ActivityTracker) gofr.Handler {
 	return func(ctx *gofr.Context) (interface{}, error) {
 
 		tokenString, err := extractToken(ctx)
@@ -282,6 +548,9 @@ func WithJWTAuth(handlerFunc gofr.Handler, authStore store.AuthStore) gofr.Handl
 		}
 
 		*&ctx.Context = context.WithValue(ctx.Context, "userId", userID)
+		if tracker != nil {
+			tracker.TrackActivity(ctx, userID)
+		}
 		return handlerFunc(ctx)
 	}
 }
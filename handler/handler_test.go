@@ -7,13 +7,16 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"gofr.dev/pkg/gofr"
 	"gofr.dev/pkg/gofr/request"
 	"gofr.dev/pkg/gofr/responder"
 	"gofr.dev/pkg/gofr/types"
 
+	"github.com/aryanA101a/legoshichat-backend/blob"
 	e "github.com/aryanA101a/legoshichat-backend/error"
 	"github.com/aryanA101a/legoshichat-backend/model"
 	"github.com/aryanA101a/legoshichat-backend/store"
@@ -71,6 +74,19 @@ func (jwtErrorTCmockAuthCreator) NewJWT(ctx *gofr.Context, userId string) (strin
 	return "", e.NewError("")
 }
 
+// newTestContext builds a request context for the authorized user "someUserId".
+func newTestContext(app *gofr.Gofr, method, url string, body []byte) *gofr.Context {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, url, bytes.NewReader(body))
+
+	req := request.NewHTTPRequest(r)
+	res := responder.NewContextualResponder(w, r)
+	ctx := gofr.NewContext(res, req, app)
+	*&ctx.Context = context.WithValue(context.Background(), "userId", "someUserId")
+
+	return ctx
+}
+
 type testCase struct {
 	desc     string
 	body     []byte
@@ -264,18 +280,95 @@ func (successfulTCMessageStore) GetMessage(ctx *gofr.Context, userId, messageId
 	return nil, nil
 }
 
-func (successfulTCMessageStore) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string) (*model.Message, error) {
+func (successfulTCMessageStore) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string, entities []model.Entity, window time.Duration) (*model.Message, error) {
 	return &model.Message{}, nil
 }
 
-func (successfulTCMessageStore) DeleteMessage(ctx *gofr.Context, userId, messageId string) error {
+func (successfulTCMessageStore) GetMessageHistory(ctx *gofr.Context, userId, messageId string) (*model.MessageHistory, error) {
+	return &model.MessageHistory{}, nil
+}
+
+func (successfulTCMessageStore) AddReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error) {
+	return &model.Message{}, true, nil
+}
+
+func (successfulTCMessageStore) RemoveReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error) {
+	return &model.Message{}, true, nil
+}
+
+func (successfulTCMessageStore) DeleteMessage(ctx *gofr.Context, userId, messageId, mode string, window time.Duration) error {
 	return nil
 }
 
-func (successfulTCMessageStore) GetMessages(ctx *gofr.Context, senderId, recieverId string, page, limit uint) (*[]model.Message, error) {
+func (successfulTCMessageStore) GetMessages(ctx *gofr.Context, userId, senderId, recieverId string, page, limit uint) (*[]model.Message, error) {
+	return nil, nil
+}
+
+func (successfulTCMessageStore) GetConversationMessages(ctx *gofr.Context, userId, conversationId string, page, limit uint) (*[]model.Message, error) {
+	return nil, nil
+}
+
+func (successfulTCMessageStore) PurgeDeletedMessages(ctx *gofr.Context, deletedBefore time.Time) (int, []string, error) {
+	return 0, nil, nil
+}
+
+func (successfulTCMessageStore) GetScheduledMessages(ctx *gofr.Context, userId string, page, limit uint) (*[]model.Message, error) {
+	return &[]model.Message{}, nil
+}
+
+func (successfulTCMessageStore) UpdateScheduledMessage(ctx *gofr.Context, userId, messageId string, content *string, entities []model.Entity, sendAt *time.Time) (*model.Message, error) {
+	return &model.Message{}, nil
+}
+
+func (successfulTCMessageStore) CancelScheduledMessage(ctx *gofr.Context, userId, messageId string) ([]string, error) {
 	return nil, nil
 }
 
+func (successfulTCMessageStore) ReleaseScheduledMessages(ctx *gofr.Context, now time.Time, limit uint) (*[]model.Message, []string, error) {
+	return &[]model.Message{}, nil, nil
+}
+
+func (successfulTCMessageStore) DeleteExpiredMessages(ctx *gofr.Context, now time.Time, limit uint) (int, []string, error) {
+	return 0, nil, nil
+}
+
+func (successfulTCMessageStore) StarMessage(ctx *gofr.Context, userId, messageId string) error {
+	return nil
+}
+
+func (successfulTCMessageStore) UnstarMessage(ctx *gofr.Context, userId, messageId string) error {
+	return nil
+}
+
+func (successfulTCMessageStore) GetStarredMessages(ctx *gofr.Context, userId string, page, limit uint) (*[]model.Message, error) {
+	return &[]model.Message{}, nil
+}
+
+func (successfulTCMessageStore) PinMessage(ctx *gofr.Context, userId, messageId string, limit int) (*model.Message, bool, error) {
+	return &model.Message{ConversationID: "conversationId"}, true, nil
+}
+
+func (successfulTCMessageStore) UnpinMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, bool, error) {
+	return &model.Message{ConversationID: "conversationId"}, true, nil
+}
+
+func (successfulTCMessageStore) GetPinnedMessages(ctx *gofr.Context, userId, conversationId string) (*[]model.PinnedMessage, error) {
+	return &[]model.PinnedMessage{}, nil
+}
+
+func (successfulTCMessageStore) Vote(ctx *gofr.Context, userId, messageId string, options []int) (*model.PollResults, error) {
+	return &model.PollResults{MessageID: messageId, ConversationID: "conversationId", Options: []model.PollTally{{Option: 0, Text: "Salad", Count: 1}, {Option: 1, Text: "Eggs"}},
+		Voters: 1, MyVotes: options}, nil
+}
+
+func (successfulTCMessageStore) GetPollResults(ctx *gofr.Context, userId, messageId string) (*model.PollResults, error) {
+	return &model.PollResults{MessageID: messageId, ConversationID: "conversationId"}, nil
+}
+
+func (successfulTCMessageStore) SetLinkPreview(ctx *gofr.Context, messageId, content string, preview model.LinkPreview) (bool, error) {
+	return true, nil
+}
+
 type errorTCMessageStore struct{}
 
 func (errorTCMessageStore) AddMessage(ctx *gofr.Context, message model.Message) error {
@@ -286,18 +379,94 @@ func (errorTCMessageStore) GetMessage(ctx *gofr.Context, userId, messageId strin
 	return nil, sql.ErrNoRows
 }
 
-func (errorTCMessageStore) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string) (*model.Message, error) {
+func (errorTCMessageStore) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string, entities []model.Entity, window time.Duration) (*model.Message, error) {
 	return nil, sql.ErrNoRows
 }
 
-func (errorTCMessageStore) DeleteMessage(ctx *gofr.Context, userId, messageId string) error {
+func (errorTCMessageStore) GetMessageHistory(ctx *gofr.Context, userId, messageId string) (*model.MessageHistory, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (errorTCMessageStore) AddReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error) {
+	return nil, false, sql.ErrNoRows
+}
+
+func (errorTCMessageStore) RemoveReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error) {
+	return nil, false, sql.ErrNoRows
+}
+
+func (errorTCMessageStore) DeleteMessage(ctx *gofr.Context, userId, messageId, mode string, window time.Duration) error {
 	return sql.ErrNoRows
 }
 
-func (errorTCMessageStore) GetMessages(ctx *gofr.Context, senderId, recieverId string, page, limit uint) (*[]model.Message, error) {
+func (errorTCMessageStore) GetMessages(ctx *gofr.Context, userId, senderId, recieverId string, page, limit uint) (*[]model.Message, error) {
 	return nil, nil
 }
 
+func (errorTCMessageStore) GetConversationMessages(ctx *gofr.Context, userId, conversationId string, page, limit uint) (*[]model.Message, error) {
+	return nil, nil
+}
+
+func (errorTCMessageStore) PurgeDeletedMessages(ctx *gofr.Context, deletedBefore time.Time) (int, []string, error) {
+	return 0, nil, nil
+}
+
+func (errorTCMessageStore) GetScheduledMessages(ctx *gofr.Context, userId string, page, limit uint) (*[]model.Message, error) {
+	return &[]model.Message{}, nil
+}
+
+func (errorTCMessageStore) UpdateScheduledMessage(ctx *gofr.Context, userId, messageId string, content *string, entities []model.Entity, sendAt *time.Time) (*model.Message, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (errorTCMessageStore) CancelScheduledMessage(ctx *gofr.Context, userId, messageId string) ([]string, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (errorTCMessageStore) ReleaseScheduledMessages(ctx *gofr.Context, now time.Time, limit uint) (*[]model.Message, []string, error) {
+	return &[]model.Message{}, nil, nil
+}
+
+func (errorTCMessageStore) DeleteExpiredMessages(ctx *gofr.Context, now time.Time, limit uint) (int, []string, error) {
+	return 0, nil, nil
+}
+
+func (errorTCMessageStore) StarMessage(ctx *gofr.Context, userId, messageId string) error {
+	return sql.ErrNoRows
+}
+
+func (errorTCMessageStore) UnstarMessage(ctx *gofr.Context, userId, messageId string) error {
+	return sql.ErrNoRows
+}
+
+func (errorTCMessageStore) GetStarredMessages(ctx *gofr.Context, userId string, page, limit uint) (*[]model.Message, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (errorTCMessageStore) PinMessage(ctx *gofr.Context, userId, messageId string, limit int) (*model.Message, bool, error) {
+	return nil, false, sql.ErrNoRows
+}
+
+func (errorTCMessageStore) UnpinMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, bool, error) {
+	return nil, false, sql.ErrNoRows
+}
+
+func (errorTCMessageStore) GetPinnedMessages(ctx *gofr.Context, userId, conversationId string) (*[]model.PinnedMessage, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (errorTCMessageStore) Vote(ctx *gofr.Context, userId, messageId string, options []int) (*model.PollResults, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (errorTCMessageStore) GetPollResults(ctx *gofr.Context, userId, messageId string) (*model.PollResults, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (errorTCMessageStore) SetLinkPreview(ctx *gofr.Context, messageId, content string, preview model.LinkPreview) (bool, error) {
+	return false, nil
+}
+
 type messageStoreErrorTCMessageStore struct{}
 
 func (messageStoreErrorTCMessageStore) AddMessage(ctx *gofr.Context, message model.Message) error {
@@ -308,18 +477,94 @@ func (messageStoreErrorTCMessageStore) GetMessage(ctx *gofr.Context, userId, mes
 	return nil, e.NewError("")
 }
 
-func (messageStoreErrorTCMessageStore) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string) (*model.Message, error) {
+func (messageStoreErrorTCMessageStore) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string, entities []model.Entity, window time.Duration) (*model.Message, error) {
 	return nil, e.NewError("")
 }
 
-func (messageStoreErrorTCMessageStore) DeleteMessage(ctx *gofr.Context, userId, messageId string) error {
+func (messageStoreErrorTCMessageStore) GetMessageHistory(ctx *gofr.Context, userId, messageId string) (*model.MessageHistory, error) {
+	return nil, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) AddReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error) {
+	return nil, false, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) RemoveReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error) {
+	return nil, false, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) DeleteMessage(ctx *gofr.Context, userId, messageId, mode string, window time.Duration) error {
 	return e.NewError("")
 }
 
-func (messageStoreErrorTCMessageStore) GetMessages(ctx *gofr.Context, senderId, recieverId string, page, limit uint) (*[]model.Message, error) {
+func (messageStoreErrorTCMessageStore) GetMessages(ctx *gofr.Context, userId, senderId, recieverId string, page, limit uint) (*[]model.Message, error) {
+	return nil, nil
+}
+
+func (messageStoreErrorTCMessageStore) GetConversationMessages(ctx *gofr.Context, userId, conversationId string, page, limit uint) (*[]model.Message, error) {
 	return nil, nil
 }
 
+func (messageStoreErrorTCMessageStore) PurgeDeletedMessages(ctx *gofr.Context, deletedBefore time.Time) (int, []string, error) {
+	return 0, nil, nil
+}
+
+func (messageStoreErrorTCMessageStore) GetScheduledMessages(ctx *gofr.Context, userId string, page, limit uint) (*[]model.Message, error) {
+	return nil, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) UpdateScheduledMessage(ctx *gofr.Context, userId, messageId string, content *string, entities []model.Entity, sendAt *time.Time) (*model.Message, error) {
+	return nil, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) CancelScheduledMessage(ctx *gofr.Context, userId, messageId string) ([]string, error) {
+	return nil, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) ReleaseScheduledMessages(ctx *gofr.Context, now time.Time, limit uint) (*[]model.Message, []string, error) {
+	return nil, nil, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) DeleteExpiredMessages(ctx *gofr.Context, now time.Time, limit uint) (int, []string, error) {
+	return 0, nil, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) StarMessage(ctx *gofr.Context, userId, messageId string) error {
+	return e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) UnstarMessage(ctx *gofr.Context, userId, messageId string) error {
+	return e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) GetStarredMessages(ctx *gofr.Context, userId string, page, limit uint) (*[]model.Message, error) {
+	return nil, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) PinMessage(ctx *gofr.Context, userId, messageId string, limit int) (*model.Message, bool, error) {
+	return nil, false, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) UnpinMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, bool, error) {
+	return nil, false, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) GetPinnedMessages(ctx *gofr.Context, userId, conversationId string) (*[]model.PinnedMessage, error) {
+	return nil, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) Vote(ctx *gofr.Context, userId, messageId string, options []int) (*model.PollResults, error) {
+	return nil, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) GetPollResults(ctx *gofr.Context, userId, messageId string) (*model.PollResults, error) {
+	return nil, e.NewError("")
+}
+
+func (messageStoreErrorTCMessageStore) SetLinkPreview(ctx *gofr.Context, messageId, content string, preview model.LinkPreview) (bool, error) {
+	return false, e.NewError("")
+}
+
 type authorizationErrorTCMessageStore struct{}
 
 func (authorizationErrorTCMessageStore) AddMessage(ctx *gofr.Context, message model.Message) error {
@@ -330,34 +575,204 @@ func (authorizationErrorTCMessageStore) GetMessage(ctx *gofr.Context, userId, me
 	return nil, e.NewError("You are not authorized to see that message")
 }
 
-func (authorizationErrorTCMessageStore) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string) (*model.Message, error) {
+func (authorizationErrorTCMessageStore) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string, entities []model.Entity, window time.Duration) (*model.Message, error) {
+	return nil, e.NewError("You are not authorized to see that message")
+}
+
+func (authorizationErrorTCMessageStore) GetMessageHistory(ctx *gofr.Context, userId, messageId string) (*model.MessageHistory, error) {
 	return nil, e.NewError("You are not authorized to see that message")
 }
 
-func (authorizationErrorTCMessageStore) DeleteMessage(ctx *gofr.Context, userId, messageId string) error {
+func (authorizationErrorTCMessageStore) AddReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error) {
+	return nil, false, e.NewError("You are not authorized to see that message")
+}
+
+func (authorizationErrorTCMessageStore) RemoveReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error) {
+	return nil, false, e.NewError("You are not authorized to see that message")
+}
+
+func (authorizationErrorTCMessageStore) DeleteMessage(ctx *gofr.Context, userId, messageId, mode string, window time.Duration) error {
 	return e.NewError("You are not authorized to see that message")
 }
 
-func (authorizationErrorTCMessageStore) GetMessages(ctx *gofr.Context, senderId, recieverId string, page, limit uint) (*[]model.Message, error) {
+func (authorizationErrorTCMessageStore) GetMessages(ctx *gofr.Context, userId, senderId, recieverId string, page, limit uint) (*[]model.Message, error) {
 	return nil, nil
 }
 
+func (authorizationErrorTCMessageStore) GetConversationMessages(ctx *gofr.Context, userId, conversationId string, page, limit uint) (*[]model.Message, error) {
+	return nil, nil
+}
+
+func (authorizationErrorTCMessageStore) PurgeDeletedMessages(ctx *gofr.Context, deletedBefore time.Time) (int, []string, error) {
+	return 0, nil, nil
+}
+
+func (authorizationErrorTCMessageStore) GetScheduledMessages(ctx *gofr.Context, userId string, page, limit uint) (*[]model.Message, error) {
+	return &[]model.Message{}, nil
+}
+
+func (authorizationErrorTCMessageStore) UpdateScheduledMessage(ctx *gofr.Context, userId, messageId string, content *string, entities []model.Entity, sendAt *time.Time) (*model.Message, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (authorizationErrorTCMessageStore) CancelScheduledMessage(ctx *gofr.Context, userId, messageId string) ([]string, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (authorizationErrorTCMessageStore) ReleaseScheduledMessages(ctx *gofr.Context, now time.Time, limit uint) (*[]model.Message, []string, error) {
+	return &[]model.Message{}, nil, nil
+}
+
+func (authorizationErrorTCMessageStore) DeleteExpiredMessages(ctx *gofr.Context, now time.Time, limit uint) (int, []string, error) {
+	return 0, nil, nil
+}
+
+func (authorizationErrorTCMessageStore) StarMessage(ctx *gofr.Context, userId, messageId string) error {
+	return e.NewError("You are not authorized to see that message")
+}
+
+func (authorizationErrorTCMessageStore) UnstarMessage(ctx *gofr.Context, userId, messageId string) error {
+	return e.NewError("You are not authorized to see that message")
+}
+
+func (authorizationErrorTCMessageStore) GetStarredMessages(ctx *gofr.Context, userId string, page, limit uint) (*[]model.Message, error) {
+	return nil, e.NewError("You are not authorized to see that message")
+}
+
+func (authorizationErrorTCMessageStore) PinMessage(ctx *gofr.Context, userId, messageId string, limit int) (*model.Message, bool, error) {
+	return nil, false, e.NewError("You are not authorized to see that message")
+}
+
+func (authorizationErrorTCMessageStore) UnpinMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, bool, error) {
+	return nil, false, e.NewError("You are not authorized to see that message")
+}
+
+func (authorizationErrorTCMessageStore) GetPinnedMessages(ctx *gofr.Context, userId, conversationId string) (*[]model.PinnedMessage, error) {
+	return nil, e.NewError("You are not authorized to see that message")
+}
+
+func (authorizationErrorTCMessageStore) Vote(ctx *gofr.Context, userId, messageId string, options []int) (*model.PollResults, error) {
+	return nil, e.NewError("You are not authorized to see that message")
+}
+
+func (authorizationErrorTCMessageStore) GetPollResults(ctx *gofr.Context, userId, messageId string) (*model.PollResults, error) {
+	return nil, e.NewError("You are not authorized to see that message")
+}
+
+func (authorizationErrorTCMessageStore) SetLinkPreview(ctx *gofr.Context, messageId, content string, preview model.LinkPreview) (bool, error) {
+	return false, nil
+}
+
 type mockFriendStore struct{}
 
-func (f mockFriendStore) AddFriend(ctx *gofr.Context, senderId, recieverId string) error {
+func (f mockFriendStore) GetFriends(ctx *gofr.Context, userId string) (*[]model.User, error) {
+	return nil, nil
+}
+
+func (f mockFriendStore) AreFriends(ctx *gofr.Context, userId1, userId2 string) (bool, error) {
+	return true, nil
+}
+
+func (f mockFriendStore) RemoveFriend(ctx *gofr.Context, userId, friendId string) error {
 	return nil
 }
 
-func (f mockFriendStore) GetFriends(ctx *gofr.Context, userId string) (*[]model.User, error) {
+func (f mockFriendStore) SendFriendRequest(ctx *gofr.Context, senderId, recipientId string) error {
+	return nil
+}
+
+func (f mockFriendStore) AcceptFriendRequest(ctx *gofr.Context, recipientId, senderId string) error {
+	return nil
+}
+
+func (f mockFriendStore) DeclineFriendRequest(ctx *gofr.Context, recipientId, senderId string) error {
+	return nil
+}
+
+func (f mockFriendStore) CancelFriendRequest(ctx *gofr.Context, senderId, recipientId string) error {
+	return nil
+}
+
+func (f mockFriendStore) GetFriendRequests(ctx *gofr.Context, userId string, incoming bool) (*[]model.FriendRequest, error) {
+	requests := make([]model.FriendRequest, 0)
+	return &requests, nil
+}
+
+// testRecipientID is an account that existingTCAuthStore knows, and testMissingRecipientID one it does not.
+const (
+	testRecipientID        = "3b241101-e2bb-4255-8caf-4136c566a962"
+	testMissingRecipientID = "00000000-0000-4000-8000-000000000000"
+)
+
+type mockConversationStore struct{}
+
+func (mockConversationStore) EnsureDirectConversation(ctx *gofr.Context, userId, peerId string, accepted bool) (string, error) {
+	return "conversationId", nil
+}
+
+func (mockConversationStore) RecordMessage(ctx *gofr.Context, message model.Message) error {
+	return nil
+}
+
+func (mockConversationStore) GetConversations(ctx *gofr.Context, userId string, accepted bool, page, limit uint) (*[]model.Conversation, error) {
+	conversations := make([]model.Conversation, 0)
+	return &conversations, nil
+}
+
+func (mockConversationStore) GetMemberIds(ctx *gofr.Context, conversationId string) ([]string, error) {
+	return []string{"testUserID"}, nil
+}
+
+func (mockConversationStore) MarkRead(ctx *gofr.Context, userId, conversationId string) error {
+	return nil
+}
+
+func (mockConversationStore) AcceptMessageRequest(ctx *gofr.Context, userId, conversationId string) error {
+	return nil
+}
+
+func (mockConversationStore) GetDisappearingTimer(ctx *gofr.Context, conversationId string) (*model.DisappearingTimer, error) {
 	return nil, nil
 }
 
+func (mockConversationStore) SetDisappearingTimer(ctx *gofr.Context, userId, conversationId string, timer model.DisappearingTimer) (*model.Conversation, error) {
+	return &model.Conversation{ID: "conversationId", Type: model.ConversationDirect}, nil
+}
+
+func (mockConversationStore) SaveDraft(ctx *gofr.Context, userId, conversationId string, content string, updatedAt time.Time) (*model.Draft, bool, error) {
+	return &model.Draft{ConversationID: "conversationId", Content: content, UpdatedAt: updatedAt}, true, nil
+}
+
+func (mockConversationStore) GetDraft(ctx *gofr.Context, userId, conversationId string) (*model.Draft, error) {
+	return &model.Draft{ConversationID: "conversationId", Content: "Hello"}, nil
+}
+
+func (mockConversationStore) DeleteDraft(ctx *gofr.Context, userId, conversationId string) (*model.Draft, error) {
+	return nil, nil
+}
+
+func (mockConversationStore) GetMemberNames(ctx *gofr.Context, conversationId string, userIds []string) (map[string]string, error) {
+	names := make(map[string]string)
+	for _, userId := range userIds {
+		names[userId] = "Member"
+	}
+	return names, nil
+}
+
+func (mockConversationStore) Mute(ctx *gofr.Context, userId, conversationId string, until *time.Time) error {
+	return nil
+}
+
+func (mockConversationStore) Unmute(ctx *gofr.Context, userId, conversationId string) error {
+	return nil
+}
+
 func TestHandleSendMessageByID(t *testing.T) {
 	app := gofr.New()
 
 	validInputTC := testCaseSendMessage{
 		desc:     "send message by ID success",
-		body:     []byte(`{"recipientID":"123","content":"Hello, World!"}`),
+		body:     []byte(`{"recipientId":"`+testRecipientID+`","content":"Hello, World!"}`),
 		userID:   "testUserID",
 		expected: types.Raw{},
 		err:      nil,
@@ -371,14 +786,14 @@ func TestHandleSendMessageByID(t *testing.T) {
 
 	messageStoreErrorTC := testCaseSendMessage{
 		desc:   "message store error",
-		body:   []byte(`{"recipientID":"123","content":"Hello, World!"}`),
+		body:   []byte(`{"recipientId":"`+testRecipientID+`","content":"Hello, World!"}`),
 		userID: "testUserID",
 		err:    e.HttpStatusError(500, "message store error"),
 	}
 
-	runSendMessageTest(t, validInputTC, app, Handler{Message: successfulTCMessageStore{}, Friend: mockFriendStore{}})
-	runSendMessageTest(t, invalidInputTC, app, Handler{Message: successfulTCMessageStore{}, Friend: mockFriendStore{}})
-	runSendMessageTest(t, messageStoreErrorTC, app, Handler{Message: errorTCMessageStore{}, Friend: mockFriendStore{}})
+	runSendMessageTest(t, validInputTC, app, Handler{Auth: existingTCAuthStore{}, Message: successfulTCMessageStore{}, Friend: mockFriendStore{}, Block: mockBlockStore{}, Conversation: mockConversationStore{}})
+	runSendMessageTest(t, invalidInputTC, app, Handler{Auth: existingTCAuthStore{}, Message: successfulTCMessageStore{}, Friend: mockFriendStore{}, Block: mockBlockStore{}, Conversation: mockConversationStore{}})
+	runSendMessageTest(t, messageStoreErrorTC, app, Handler{Auth: existingTCAuthStore{}, Message: errorTCMessageStore{}, Friend: mockFriendStore{}, Block: mockBlockStore{}, Conversation: mockConversationStore{}})
 }
 
 type testCaseSendMessage struct {
@@ -439,10 +854,10 @@ func TestHandleSendMessageByPhoneNumber(t *testing.T) {
 		err:    e.HttpStatusError(404, "Recipient does not exists"),
 	}
 
-	runSendMessageByPhoneNumberTest(t, validInputTC, app, Handler{Message: successfulTCMessageStore{}, Friend: mockFriendStore{}, Auth: newMockAuthStore()})
-	runSendMessageByPhoneNumberTest(t, invalidInputTC, app, Handler{Message: successfulTCMessageStore{}, Friend: mockFriendStore{}, Auth:newMockAuthStore()})
-	runSendMessageByPhoneNumberTest(t, messageStoreErrorTC, app, Handler{Message: errorTCMessageStore{}, Friend: mockFriendStore{}, Auth:newMockAuthStore()})
-	runSendMessageByPhoneNumberTest(t, recipientNotFoundTC, app, Handler{Message: successfulTCMessageStore{}, Friend: mockFriendStore{}, Auth: userNotExistsTCmockAuthStore{}})
+	runSendMessageByPhoneNumberTest(t, validInputTC, app, Handler{Message: successfulTCMessageStore{}, Friend: mockFriendStore{}, Block: mockBlockStore{}, Conversation: mockConversationStore{}, Auth: newMockAuthStore()})
+	runSendMessageByPhoneNumberTest(t, invalidInputTC, app, Handler{Message: successfulTCMessageStore{}, Friend: mockFriendStore{}, Block: mockBlockStore{}, Conversation: mockConversationStore{}, Auth:newMockAuthStore()})
+	runSendMessageByPhoneNumberTest(t, messageStoreErrorTC, app, Handler{Message: errorTCMessageStore{}, Friend: mockFriendStore{}, Block: mockBlockStore{}, Conversation: mockConversationStore{}, Auth:newMockAuthStore()})
+	runSendMessageByPhoneNumberTest(t, recipientNotFoundTC, app, Handler{Message: successfulTCMessageStore{}, Friend: mockFriendStore{}, Block: mockBlockStore{}, Conversation: mockConversationStore{}, Auth: userNotExistsTCmockAuthStore{}})
 }
 
 type testCaseSendMessageByPhoneNumber struct {
@@ -605,6 +1020,13 @@ func TestHandlePutMessage(t *testing.T) {
 		userID:    "testUserID",
 		err:       e.HttpStatusError(403, "You are not authorized to update that message"),
 	}
+	editWindowExpiredTC := testCasePutMessage{
+		desc:      "edit window expired",
+		body:      []byte(`{"content":"Updated content"}`),
+		messageID: "testMessageID",
+		userID:    "testUserID",
+		err:       e.HttpStatusError(403, "That message can no longer be edited"),
+	}
 
 	runPutMessageTest(t, validInputTC, app, Handler{Message: successfulTCMessageStore{}})
 	runPutMessageTest(t, invalidBodyTC, app, Handler{Message: successfulTCMessageStore{}})
@@ -612,6 +1034,7 @@ func TestHandlePutMessage(t *testing.T) {
 	runPutMessageTest(t, messageNotFoundErrorTC, app, Handler{Message: errorTCMessageStore{}})
 	runPutMessageTest(t, messageStoreErrorTC, app, Handler{Message: messageStoreErrorTCMessageStore{}})
 	runPutMessageTest(t, authorizationErrorTC, app, Handler{Message: authorizationErrorTCMessageStore{}})
+	runPutMessageTest(t, editWindowExpiredTC, app, Handler{Message: editWindowExpiredTCMessageStore{}})
 
 }
 
@@ -709,3 +1132,259 @@ func runDeleteMessageTest(t *testing.T, tc testCaseGetDeleteMessage, app *gofr.G
 
 
 
+
+type deleteModeTCMessageStore struct {
+	successfulTCMessageStore
+	mode *string
+	err  error
+}
+
+func (m deleteModeTCMessageStore) DeleteMessage(ctx *gofr.Context, userId, messageId, mode string, window time.Duration) error {
+	*m.mode = mode
+	return m.err
+}
+
+func TestHandleDeleteMessageModes(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc     string
+		url      string
+		storeErr error
+		mode     string
+		err      error
+	}{
+		{"delete for everyone by default", "http://dummy", nil, model.DeleteForEveryone, nil},
+		{"delete for everyone", "http://dummy?for=everyone", nil, model.DeleteForEveryone, nil},
+		{"delete for me", "http://dummy?for=me", nil, model.DeleteForMe, nil},
+		{"invalid mode", "http://dummy?for=them", nil, "", e.HttpStatusError(400, "Invalid Parameter for")},
+		{"delete window passed", "http://dummy?for=everyone", e.NewError("That message can no longer be deleted for everyone"), model.DeleteForEveryone,
+			e.HttpStatusError(403, "That message can no longer be deleted for everyone")},
+	}
+
+	for _, tc := range testCases {
+		var mode string
+		h := Handler{Message: deleteModeTCMessageStore{mode: &mode, err: tc.storeErr}}
+
+		ctx := newTestContext(app, http.MethodDelete, tc.url, nil)
+		ctx.SetPathParams(map[string]string{"id": "someMessageId"})
+
+		_, err := h.HandleDeleteMessage(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		assert.Equal(t, tc.mode, mode, "TEST: %s: unexpected delete mode", tc.desc)
+	}
+}
+
+// purgeTCMessageStore purges one tombstone with the given attachments.
+type purgeTCMessageStore struct {
+	successfulTCMessageStore
+	blobIds []string
+}
+
+func (m purgeTCMessageStore) PurgeDeletedMessages(ctx *gofr.Context, deletedBefore time.Time) (int, []string, error) {
+	return 1, m.blobIds, nil
+}
+
+func TestPurgeDeletedMessages(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+	ctx.Context = context.Background()
+
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating blob store: %v", err)
+	}
+	for _, key := range []string{"attachment-id", thumbnailKey("attachment-id", "small"), "other-attachment-id"} {
+		err = blobs.Put(ctx, key, strings.NewReader("content"), "image/png")
+		if err != nil {
+			t.Fatalf("Error storing blob: %v", err)
+		}
+	}
+
+	h := Handler{Message: purgeTCMessageStore{blobIds: []string{"attachment-id"}}, Blobs: blobs}
+
+	err = h.PurgeDeletedMessages(ctx)
+
+	assert.NoError(t, err, "Unexpected error purging deleted messages")
+	for _, key := range []string{"attachment-id", thumbnailKey("attachment-id", "small")} {
+		_, err = blobs.Get(ctx, key)
+		assert.Equal(t, blob.ErrNotFound, err, "Expected blob %s of a purged message to be deleted", key)
+	}
+	_, err = blobs.Get(ctx, "other-attachment-id")
+	assert.NoError(t, err, "Expected other blobs to be kept")
+
+	h = Handler{Message: errorPurgeTCMessageStore{}}
+
+	err = h.PurgeDeletedMessages(ctx)
+
+	assert.Error(t, err, "Expected an error when purging fails")
+}
+
+type errorPurgeTCMessageStore struct {
+	successfulTCMessageStore
+}
+
+func (errorPurgeTCMessageStore) PurgeDeletedMessages(ctx *gofr.Context, deletedBefore time.Time) (int, []string, error) {
+	return 0, nil, e.NewError("")
+}
+
+type editWindowExpiredTCMessageStore struct {
+	successfulTCMessageStore
+}
+
+func (editWindowExpiredTCMessageStore) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string, entities []model.Entity, window time.Duration) (*model.Message, error) {
+	return nil, e.NewError("That message can no longer be edited")
+}
+
+func TestHandleGetMessageHistory(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc      string
+		messageID string
+		store     store.MessageStore
+		err       error
+	}{
+		{"history success", "someMessageId", successfulTCMessageStore{}, nil},
+		{"missing parameter", "", successfulTCMessageStore{}, e.HttpStatusError(400, "Missing Parameter messageId")},
+		{"message not found", "someMessageId", errorTCMessageStore{}, e.HttpStatusError(404, "Message does not exists")},
+		{"authorization error", "someMessageId", authorizationErrorTCMessageStore{}, e.HttpStatusError(403, "You are not authorized to see that message")},
+		{"message store error", "someMessageId", messageStoreErrorTCMessageStore{}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Message: tc.store}
+
+		ctx := newTestContext(app, http.MethodGet, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"id": tc.messageID})
+
+		result, err := h.HandleGetMessageHistory(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			assert.IsType(t, types.Raw{}, result, "TEST: %s: unexpected result type", tc.desc)
+		}
+	}
+}
+
+type replyTCMessageStore struct {
+	successfulTCMessageStore
+}
+
+func (replyTCMessageStore) GetMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, error) {
+	switch messageId {
+	case "sameConversationId":
+		return &model.Message{ID: messageId, ConversationID: "conversationId", Content: "How are you?", From: "recipientId", To: userId}, nil
+	case "otherConversationId":
+		return &model.Message{ID: messageId, ConversationID: "otherConversationId", Content: "Hi", From: userId, To: "someoneElseId"}, nil
+	case "deletedId":
+		return &model.Message{ID: messageId, ConversationID: "conversationId", From: userId, To: "recipientId", Deleted: true}, nil
+	case "foreignId":
+		return nil, e.NewError("You are not authorized to see that message")
+	case "brokenId":
+		return nil, e.NewError("")
+	}
+	return nil, sql.ErrNoRows
+}
+
+func TestHandleSendMessageReply(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc      string
+		replyToId string
+		preview   *model.MessagePreview
+		err       error
+	}{
+		{"reply in the same conversation", "sameConversationId", &model.MessagePreview{ID: "sameConversationId", From: "recipientId", Snippet: "How are you?"}, nil},
+		{"reply to a deleted message", "deletedId", &model.MessagePreview{ID: "deletedId", Deleted: true}, nil},
+		{"no reply", "", nil, nil},
+		{"reply to another conversation", "otherConversationId", nil, e.HttpStatusError(400, "Invalid Parameter replyToId")},
+		{"reply to a message the user is not part of", "foreignId", nil, e.HttpStatusError(400, "Invalid Parameter replyToId")},
+		{"reply to a missing message", "missingId", nil, e.HttpStatusError(400, "Invalid Parameter replyToId")},
+		{"message store error", "brokenId", nil, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Auth: existingTCAuthStore{}, Message: replyTCMessageStore{}, Friend: mockFriendStore{}, Block: mockBlockStore{}, Conversation: mockConversationStore{}}
+
+		body := fmt.Sprintf(`{"recipientId":%q,"content":"Sure","replyToId":%q}`, testRecipientID, tc.replyToId)
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(body))
+
+		result, err := h.HandleSendMessageByID(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			assert.Equal(t, tc.preview, result.(types.Raw).Data.(model.Message).ReplyTo, "TEST: %s: unexpected reply preview", tc.desc)
+		}
+	}
+}
+
+// errorTCAuthStore fails to look accounts up.
+type errorTCAuthStore struct {
+	mockAuthStore
+}
+
+func (errorTCAuthStore) AccountExists(ctx *gofr.Context, userId string) (bool, error) {
+	return false, e.NewError("")
+}
+
+func TestHandleSendMessageByIDRecipients(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc        string
+		sender      string
+		recipient   string
+		selfAllowed string
+		blockedSend string
+		auth        store.AuthStore
+		friend      store.FriendStore
+		block       store.BlockStore
+		to          string
+		accepted    bool
+		err         error
+	}{
+		{desc: "friend", recipient: testRecipientID, friend: requestsTCFriendStore{friendId: testRecipientID}, to: testRecipientID, accepted: true},
+		{desc: "stranger", recipient: testRecipientID, friend: requestsTCFriendStore{}, to: testRecipientID, accepted: false},
+		{desc: "uppercase recipient", recipient: "3B241101-E2BB-4255-8CAF-4136C566A962", friend: requestsTCFriendStore{friendId: testRecipientID}, to: testRecipientID, accepted: true},
+		{desc: "recipient is not a uuid", recipient: "123", err: e.HttpStatusError(422, "Invalid Parameter recipientId")},
+		{desc: "recipient does not exist", recipient: testMissingRecipientID, err: e.HttpStatusError(404, "Recipient does not exists")},
+		{desc: "recipient lookup fails", recipient: testRecipientID, auth: errorTCAuthStore{}, err: e.HttpStatusError(500, "")},
+		{desc: "note to self", sender: testRecipientID, recipient: testRecipientID, friend: requestsTCFriendStore{}, to: testRecipientID, accepted: true},
+		{desc: "note to self when not allowed", sender: testRecipientID, recipient: testRecipientID, selfAllowed: "false", err: e.HttpStatusError(422, "You cannot send messages to yourself")},
+		{desc: "blocked by the recipient", recipient: testRecipientID, block: mockBlockStore{blockerId: testRecipientID}, to: testRecipientID},
+		{desc: "blocked by the recipient when not silent", recipient: testRecipientID, blockedSend: "false", block: mockBlockStore{blockerId: testRecipientID}, err: e.HttpStatusError(403, "You cannot send messages to that user")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Setenv("MESSAGE_SELF_ALLOWED", tc.selfAllowed)
+			t.Setenv("BLOCKED_SEND_SILENT", tc.blockedSend)
+
+			var accepted bool
+			h := Handler{Auth: existingTCAuthStore{}, Message: successfulTCMessageStore{}, Friend: tc.friend, Block: mockBlockStore{},
+				Conversation: acceptanceTCConversationStore{accepted: &accepted}}
+			if tc.auth != nil {
+				h.Auth = tc.auth
+			}
+			if tc.block != nil {
+				h.Block = tc.block
+			}
+
+			ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(fmt.Sprintf(`{"recipientId":%q,"content":"Hello"}`, tc.recipient)))
+			if tc.sender != "" {
+				ctx.Context = context.WithValue(ctx.Context, "userId", tc.sender)
+			}
+
+			result, err := h.HandleSendMessageByID(ctx)
+
+			assert.Equal(t, tc.err, err, "unexpected error")
+			if tc.err == nil {
+				assert.Equal(t, tc.to, result.(types.Raw).Data.(model.Message).To, "mismatch in recipient")
+				assert.Equal(t, tc.accepted, accepted, "mismatch in acceptance of the conversation")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/aryanA101a/legoshichat-backend/media"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/gofr"
+)
+
+// ProcessAttachment strips the metadata from an uploaded image, stores its thumbnails next to it
+// and records its dimensions and BlurHash. Images that cannot be processed are marked as failed
+// and their original is deleted, so it is never served with its metadata.
+func (h Handler) ProcessAttachment(ctx *gofr.Context, attachment model.Attachment) error {
+	body, err := h.Blobs.Get(ctx, attachment.ID)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	image, err := media.Process(data, attachment.ContentType)
+	if err != nil {
+		ctx.Logger.Infof("attachment %s could not be processed: %v", attachment.ID, err)
+		attachment.Status = model.AttachmentFailed
+		if err := h.Attachment.UpdateAttachment(ctx, attachment); err != nil {
+			return err
+		}
+		return h.Blobs.Delete(ctx, attachment.ID)
+	}
+
+	// Thumbnails are stored first, so a ready attachment never points at missing ones.
+	attachment.Thumbnails = make([]model.Thumbnail, 0, len(image.Thumbnails))
+	for _, thumbnail := range image.Thumbnails {
+		err = h.Blobs.Put(ctx, thumbnailKey(attachment.ID, thumbnail.Name), bytes.NewReader(thumbnail.Data), thumbnail.ContentType)
+		if err != nil {
+			return err
+		}
+		attachment.Thumbnails = append(attachment.Thumbnails, model.Thumbnail{Size: thumbnail.Name, Width: thumbnail.Width,
+			Height: thumbnail.Height, ContentType: thumbnail.ContentType})
+	}
+
+	if image.Original != nil {
+		err = h.Blobs.Put(ctx, attachment.ID, bytes.NewReader(image.Original), attachment.ContentType)
+		if err != nil {
+			return err
+		}
+		attachment.Size = int64(len(image.Original))
+	}
+
+	attachment.Status = model.AttachmentReady
+	attachment.Width, attachment.Height, attachment.BlurHash = image.Width, image.Height, image.BlurHash
+	return h.Attachment.UpdateAttachment(ctx, attachment)
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/markup"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/gofr"
+)
+
+// formatMessage parses the markup a text message was written in into its plain content and the entities formatting it.
+// Members of groups and channels may be mentioned in their messages; mentions in direct messages are kept as written.
+func (h Handler) formatMessage(ctx *gofr.Context, message *model.Message) error {
+	if message.Type != model.MessageText || message.Content == "" {
+		return nil
+	}
+
+	content, entities, err := h.parseMarkup(ctx, message.Content, message.ConversationID, message.To == "")
+	if err != nil {
+		return err
+	}
+
+	message.Content, message.Entities = content, entities
+	if len(entities) > 0 {
+		message.HTML = markup.HTML(content, entities)
+	}
+	return nil
+}
+
+// parseMarkup parses text written in markup, resolving mentions against the members of a conversation if mentions is set.
+// Mentioning anyone else is an error.
+func (h Handler) parseMarkup(ctx *gofr.Context, text, conversationId string, mentions bool) (string, []model.Entity, error) {
+	var resolver markup.Resolver
+	if mentions {
+		userIds := markup.Mentions(text)
+		if len(userIds) > 0 {
+			names, err := h.Conversation.GetMemberNames(ctx, conversationId, userIds)
+			if err != nil {
+				ctx.Logger.Error(err)
+				return "", nil, e.HttpStatusError(500, "")
+			}
+
+			for _, userId := range userIds {
+				if _, ok := names[userId]; !ok {
+					return "", nil, e.HttpStatusError(400, "Invalid Parameter content - "+userId+" is not a member of the conversation")
+				}
+			}
+			resolver = func(userId string) (string, bool) {
+				name, ok := names[userId]
+				return name, ok
+			}
+		}
+	}
+
+	content, entities, err := markup.Parse(text, resolver)
+	if err != nil {
+		return "", nil, e.HttpStatusError(400, "Invalid Parameter content - "+err.Error())
+	}
+	return content, entities, nil
+}
+
+// notifyMentions tells the users a message mentions about it, except its sender and the users mentioned in before,
+// who were told already. Mentions reach users even in conversations they muted.
+func (h Handler) notifyMentions(message model.Message, before []model.Entity) {
+	notified := map[string]bool{message.From: true}
+	for _, entity := range before {
+		if entity.Type == model.EntityMention {
+			notified[entity.UserID] = true
+		}
+	}
+
+	event := model.Event{Type: model.EventMentioned, Data: model.MentionEvent{ConversationID: message.ConversationID, Message: message.Preview()}}
+	for _, entity := range message.Entities {
+		if entity.Type != model.EntityMention || notified[entity.UserID] {
+			continue
+		}
+
+		notified[entity.UserID] = true
+		h.Hub.Publish(entity.UserID, event)
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/realtime"
+	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+const testMemberID = "6f9619ff-8b86-4011-b42d-00c04fc964ff"
+
+// namesTCConversationStore knows the names of the members in names only.
+type namesTCConversationStore struct {
+	mockConversationStore
+	names map[string]string
+}
+
+func (c namesTCConversationStore) GetMemberNames(ctx *gofr.Context, conversationId string, userIds []string) (map[string]string, error) {
+	names := make(map[string]string)
+	for _, userId := range userIds {
+		if name, ok := c.names[userId]; ok {
+			names[userId] = name
+		}
+	}
+	return names, nil
+}
+
+// mentionTCMessageStore keeps a message of the user to a group, or to a peer if to is set, mentioning before, and edits it.
+type mentionTCMessageStore struct {
+	successfulTCMessageStore
+	to     string
+	before []model.Entity
+}
+
+func (m mentionTCMessageStore) GetMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, error) {
+	return &model.Message{ID: messageId, From: userId, To: m.to, ConversationID: "groupId", Type: model.MessageText, Entities: m.before}, nil
+}
+
+func (m mentionTCMessageStore) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string, entities []model.Entity, window time.Duration) (*model.Message, error) {
+	return &model.Message{ID: messageId, From: userId, To: m.to, ConversationID: "groupId", Type: model.MessageText, Content: updatedContent, Entities: entities}, nil
+}
+
+func TestHandleSendFormattedGroupMessage(t *testing.T) {
+	app := gofr.New()
+	members := namesTCConversationStore{names: map[string]string{testRecipientID: "Legoshi"}}
+
+	testCases := []struct {
+		desc         string
+		content      string
+		conversation store.ConversationStore
+		text         string
+		html         string
+		events       []string
+		err          error
+	}{
+		{desc: "formatting and mention", content: "*Hi* <@" + testRecipientID + ">", conversation: members, text: "Hi @Legoshi",
+			html:   `<strong>Hi</strong> <span class="mention" data-user-id="` + testRecipientID + `">@Legoshi</span>`,
+			events: []string{model.EventMentioned}},
+		{desc: "mentioned twice", content: "<@" + testRecipientID + "> <@" + testRecipientID + ">", conversation: members, text: "@Legoshi @Legoshi",
+			html:   `<span class="mention" data-user-id="` + testRecipientID + `">@Legoshi</span> <span class="mention" data-user-id="` + testRecipientID + `">@Legoshi</span>`,
+			events: []string{model.EventMentioned}},
+		{desc: "plain text", content: "1 < 2", conversation: members, text: "1 < 2", events: []string{}},
+		{desc: "mention of a non member", content: "hi <@" + testMemberID + ">", conversation: members, events: []string{},
+			err: e.HttpStatusError(400, "Invalid Parameter content - "+testMemberID+" is not a member of the conversation")},
+		{desc: "unsafe link", content: "[hi](javascript:alert(1))", conversation: members, events: []string{},
+			err: e.HttpStatusError(400, "Invalid Parameter content - links must point to http or https URLs")},
+		{desc: "conversation store error", content: "hi <@" + testRecipientID + ">", conversation: errorTCConversationStore{}, events: []string{},
+			err: e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		hub := realtime.NewHub()
+		memberConn := connectTestClient(t, hub, testRecipientID)
+		senderConn := connectTestClient(t, hub, "someUserId")
+
+		h := Handler{Message: successfulTCMessageStore{}, Group: mockGroupStore{}, Conversation: tc.conversation, Hub: hub}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(`{"content":"`+tc.content+`"}`))
+		ctx.SetPathParams(map[string]string{"id": "groupId"})
+
+		result, err := h.HandleSendGroupMessage(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			message := result.(types.Raw).Data.(model.Message)
+			assert.Equal(t, tc.text, message.Content, "TEST: %s: mismatch in plain-text content", tc.desc)
+			assert.Equal(t, tc.html, message.HTML, "TEST: %s: mismatch in HTML", tc.desc)
+		}
+		assert.Equal(t, tc.events, readTestEvents(memberConn), "TEST: %s: mismatch in events pushed to the mentioned member", tc.desc)
+		assert.Empty(t, readTestEvents(senderConn), "TEST: %s: expected the sender not to be told about their own message", tc.desc)
+	}
+}
+
+func TestHandleSendFormattedDirectMessage(t *testing.T) {
+	app := gofr.New()
+
+	var added []model.Message
+	h := Handler{Auth: existingTCAuthStore{}, Message: scheduleTCMessageStore{added: &added}, Friend: mockFriendStore{}, Block: mockBlockStore{},
+		Conversation: mockConversationStore{}}
+
+	body := `{"recipientId":"` + testRecipientID + `","content":"_see_ <@` + testRecipientID + `>"}`
+	ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(body))
+
+	result, err := h.HandleSendMessageByID(ctx)
+
+	assert.NoError(t, err, "Unexpected error while sending a formatted message")
+	message := result.(types.Raw).Data.(model.Message)
+	assert.Equal(t, "see <@"+testRecipientID+">", message.Content, "Expected mentions in direct messages to be kept as written")
+	assert.Equal(t, []model.Entity{{Type: model.EntityItalic, Offset: 0, Length: 3}}, message.Entities, "Mismatch in entities")
+}
+
+func TestHandlePutMessageMentions(t *testing.T) {
+	app := gofr.New()
+
+	members := namesTCConversationStore{names: map[string]string{testRecipientID: "Legoshi", testMemberID: "Louis"}}
+	before := []model.Entity{{Type: model.EntityMention, Offset: 0, Length: 8, UserID: testRecipientID}}
+
+	testCases := []struct {
+		desc    string
+		message store.MessageStore
+		text    string
+		events  []string
+		err     error
+	}{
+		{desc: "group message", message: mentionTCMessageStore{before: before}, text: "@Legoshi and @Louis", events: []string{model.EventMentioned}},
+		{desc: "direct message", message: mentionTCMessageStore{to: testRecipientID}, events: []string{},
+			text: "<@" + testRecipientID + "> and <@" + testMemberID + ">"},
+		{desc: "missing message", message: errorTCMessageStore{}, events: []string{}, err: e.HttpStatusError(404, "Message does not exists")},
+	}
+
+	for _, tc := range testCases {
+		hub := realtime.NewHub()
+		mentionedConn := connectTestClient(t, hub, testRecipientID)
+		newlyMentionedConn := connectTestClient(t, hub, testMemberID)
+
+		h := Handler{Message: tc.message, Conversation: members, Hub: hub}
+
+		body := `{"content":"<@` + testRecipientID + `> and <@` + testMemberID + `>"}`
+		ctx := newTestContext(app, http.MethodPut, "http://dummy", []byte(body))
+		ctx.SetPathParams(map[string]string{"id": "someMessageId"})
+
+		result, err := h.HandlePutMessage(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			message := result.(types.Raw).Data.(*model.Message)
+			assert.Equal(t, tc.text, message.Content, "TEST: %s: mismatch in edited content", tc.desc)
+		}
+		assert.Empty(t, readTestEvents(mentionedConn), "TEST: %s: expected users mentioned before the edit not to be told again", tc.desc)
+		assert.Equal(t, tc.events, readTestEvents(newlyMentionedConn), "TEST: %s: mismatch in events pushed to users mentioned by the edit", tc.desc)
+	}
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/gofr"
+)
+
+// HandleMuteConversation mutes a conversation of the user, or a direct conversation by the ID of the peer, until the time
+// given or until it is unmuted. Mentions of the user reach the user either way.
+func (h Handler) HandleMuteConversation(ctx *gofr.Context) (interface{}, error) {
+	var muteRequest model.MuteRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&muteRequest)
+	if err != nil && err != io.EOF {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+	if muteRequest.Until != nil {
+		if !muteRequest.Until.After(time.Now()) {
+			return nil, e.HttpStatusError(400, "Invalid Parameter until - must be in the future")
+		}
+		// muted_until is kept in local time like every other timestamp.
+		*muteRequest.Until = muteRequest.Until.Local()
+	}
+
+	conversationId := ctx.PathParam("id")
+	if strings.TrimSpace(conversationId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter conversationId")
+	}
+
+	err = h.Conversation.Mute(ctx, ctx.Value("userId").(string), conversationId, muteRequest.Until)
+	return nil, muteError(ctx, err)
+}
+
+// HandleUnmuteConversation unmutes a conversation of the user, or a direct conversation by the ID of the peer.
+func (h Handler) HandleUnmuteConversation(ctx *gofr.Context) (interface{}, error) {
+	conversationId := ctx.PathParam("id")
+	if strings.TrimSpace(conversationId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter conversationId")
+	}
+
+	err := h.Conversation.Unmute(ctx, ctx.Value("userId").(string), conversationId)
+	return nil, muteError(ctx, err)
+}
+
+func muteError(ctx *gofr.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	ctx.Logger.Info("err: ", err.Error())
+	if err == sql.ErrNoRows {
+		return e.HttpStatusError(404, "Conversation does not exists")
+	}
+	return e.HttpStatusError(500, "")
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+)
+
+// muteTCConversationStore records until what time a conversation is muted, and fails muting and unmuting with err.
+type muteTCConversationStore struct {
+	mockConversationStore
+	until *time.Time
+	err   error
+}
+
+func (c muteTCConversationStore) Mute(ctx *gofr.Context, userId, conversationId string, until *time.Time) error {
+	if c.until != nil && until != nil {
+		*c.until = *until
+	}
+	return c.err
+}
+
+func (c muteTCConversationStore) Unmute(ctx *gofr.Context, userId, conversationId string) error {
+	return c.err
+}
+
+func TestHandleMuteConversation(t *testing.T) {
+	app := gofr.New()
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	testCases := []struct {
+		desc           string
+		body           string
+		conversationId string
+		err            error
+		storeErr       error
+	}{
+		{desc: "until unmuted", conversationId: "someConversationId"},
+		{desc: "until a time", body: `{"until":"` + future + `"}`, conversationId: "someConversationId"},
+		{desc: "until a past time", body: `{"until":"` + past + `"}`, conversationId: "someConversationId",
+			err: e.HttpStatusError(400, "Invalid Parameter until - must be in the future")},
+		{desc: "missing conversation id", err: e.HttpStatusError(400, "Missing Parameter conversationId")},
+		{desc: "missing conversation", conversationId: "someConversationId", storeErr: sql.ErrNoRows,
+			err: e.HttpStatusError(404, "Conversation does not exists")},
+		{desc: "store error", conversationId: "someConversationId", storeErr: e.NewError(""), err: e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		var until time.Time
+		h := Handler{Conversation: muteTCConversationStore{until: &until, err: tc.storeErr}}
+
+		ctx := newTestContext(app, http.MethodPut, "http://dummy", []byte(tc.body))
+		ctx.SetPathParams(map[string]string{"id": tc.conversationId})
+
+		_, err := h.HandleMuteConversation(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if !until.IsZero() {
+			assert.Equal(t, time.Local, until.Location(), "TEST: %s: expected the time to be muted until in local time", tc.desc)
+		}
+	}
+}
+
+func TestHandleUnmuteConversation(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc           string
+		conversationId string
+		err            error
+		storeErr       error
+	}{
+		{desc: "success", conversationId: "someConversationId"},
+		{desc: "missing conversation id", err: e.HttpStatusError(400, "Missing Parameter conversationId")},
+		{desc: "missing conversation", conversationId: "someConversationId", storeErr: sql.ErrNoRows,
+			err: e.HttpStatusError(404, "Conversation does not exists")},
+		{desc: "store error", conversationId: "someConversationId", storeErr: e.NewError(""), err: e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Conversation: muteTCConversationStore{err: tc.storeErr}}
+
+		ctx := newTestContext(app, http.MethodDelete, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"id": tc.conversationId})
+
+		_, err := h.HandleUnmuteConversation(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+	}
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/google/uuid"
+	"gofr.dev/pkg/gofr"
+)
+
+// typedMessage gives a message the type it is sent as along with its payload, whose fields were validated with the
+// request, and renders the payload as the content of the message for clients that do not know the type.
+func (h Handler) typedMessage(ctx *gofr.Context, message *model.Message, kind string, payload *model.MessagePayload) error {
+	if kind == "" {
+		kind = model.MessageText
+	}
+	message.Type = kind
+
+	if kind == model.MessageText {
+		if payload != nil {
+			return e.HttpStatusError(400, "Invalid Parameter payload - text messages have none")
+		}
+		return nil
+	}
+
+	if payload == nil || payloadType(*payload) != kind {
+		return e.HttpStatusError(400, "Invalid Parameter payload - expected the "+kind+" of the message only")
+	}
+
+	switch kind {
+	case model.MessageContact:
+		err := h.contactCard(ctx, payload.Contact)
+		if err != nil {
+			return err
+		}
+	case model.MessagePoll:
+		if payload.Poll.Closed(time.Now()) {
+			return e.HttpStatusError(400, "Invalid Parameter payload - the poll must close in the future")
+		}
+	}
+
+	message.Payload = payload
+	message.Content = payloadText(kind, *payload)
+	return nil
+}
+
+// payloadType returns the type of the one payload set, or nothing unless exactly one is.
+func payloadType(payload model.MessagePayload) string {
+	types := make([]string, 0, 1)
+	if payload.Location != nil {
+		types = append(types, model.MessageLocation)
+	}
+	if payload.Contact != nil {
+		types = append(types, model.MessageContact)
+	}
+	if payload.Poll != nil {
+		types = append(types, model.MessagePoll)
+	}
+
+	if len(types) != 1 {
+		return ""
+	}
+	return types[0]
+}
+
+// contactCard checks that a contact card refers to an existing user or carries a vCard, and names the contact of
+// a vCard after it unless the sender named it.
+func (h Handler) contactCard(ctx *gofr.Context, contact *model.ContactCard) error {
+	if contact.VCard != "" {
+		if contact.UserID != "" {
+			return e.HttpStatusError(400, "Invalid Parameter payload - a contact is either a user or a vCard")
+		}
+		if contact.Name == "" {
+			contact.Name = vCardName(contact.VCard)
+		}
+		return nil
+	}
+
+	id, err := uuid.Parse(contact.UserID)
+	if err != nil {
+		return e.HttpStatusError(400, "Invalid Parameter payload - invalid contact userId")
+	}
+
+	exists, err := h.Auth.AccountExists(ctx, id.String())
+	if err != nil {
+		ctx.Logger.Error(err)
+		return e.HttpStatusError(500, "")
+	}
+	if !exists {
+		return e.HttpStatusError(404, "Contact does not exists")
+	}
+
+	contact.UserID = id.String()
+	return nil
+}
+
+// vCardName returns the formatted name of the contact of a vCard, or nothing when it has none.
+func vCardName(vCard string) string {
+	for _, line := range strings.Split(vCard, "\n") {
+		property, value, found := strings.Cut(strings.TrimRight(line, "\r"), ":")
+		if !found {
+			continue
+		}
+
+		// Properties may carry parameters, such as FN;CHARSET=UTF-8:Jane Doe.
+		name, _, _ := strings.Cut(property, ";")
+		if strings.EqualFold(name, "FN") {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// payloadText renders a payload of the given type as plain text.
+func payloadText(kind string, payload model.MessagePayload) string {
+	switch kind {
+	case model.MessageLocation:
+		location := payload.Location
+		coordinates := fmt.Sprintf("%.6f, %.6f", *location.Latitude, *location.Longitude)
+		if location.Name != "" {
+			return fmt.Sprintf("Location: %s (%s)", location.Name, coordinates)
+		}
+		return "Location: " + coordinates
+	case model.MessageContact:
+		if payload.Contact.Name != "" {
+			return "Contact: " + payload.Contact.Name
+		}
+		return "Contact card"
+	case model.MessagePoll:
+		var text strings.Builder
+		text.WriteString("Poll: " + payload.Poll.Question)
+		for _, option := range payload.Poll.Options {
+			text.WriteString("\n- " + option)
+		}
+		return text.String()
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+func TestHandleSendTypedMessage(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc    string
+		body    string
+		kind    string
+		content string
+		err     error
+	}{
+		{desc: "plain text", body: `"content":"Hello"`, kind: model.MessageText, content: "Hello"},
+		{desc: "explicit text", body: `"type":"text","content":"Hello"`, kind: model.MessageText, content: "Hello"},
+		{desc: "text with payload", body: `"content":"Hello","payload":{"location":{"latitude":1,"longitude":2}}`,
+			err: e.HttpStatusError(400, "Invalid Parameter payload - text messages have none")},
+		{desc: "location", body: `"type":"location","payload":{"location":{"latitude":48.8584,"longitude":2.2945,"accuracy":12}}`,
+			kind: model.MessageLocation, content: "Location: 48.858400, 2.294500"},
+		{desc: "named location on the equator", body: `"type":"location","payload":{"location":{"latitude":0,"longitude":-78.4554,"name":"Mitad del Mundo"}}`,
+			kind: model.MessageLocation, content: "Location: Mitad del Mundo (0.000000, -78.455400)"},
+		{desc: "location off the map", body: `"type":"location","payload":{"location":{"latitude":91,"longitude":2}}`,
+			err: e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'SendMessageByIDRequest.Payload.Location.Latitude' Error:Field validation for 'Latitude' failed on the 'max' tag")},
+		{desc: "location without longitude", body: `"type":"location","payload":{"location":{"latitude":1}}`,
+			err: e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'SendMessageByIDRequest.Payload.Location.Longitude' Error:Field validation for 'Longitude' failed on the 'required' tag")},
+		{desc: "payload of another type", body: `"type":"poll","payload":{"location":{"latitude":1,"longitude":2}}`,
+			err: e.HttpStatusError(400, "Invalid Parameter payload - expected the poll of the message only")},
+		{desc: "missing payload", body: `"type":"location","content":"Somewhere"`,
+			err: e.HttpStatusError(400, "Invalid Parameter payload - expected the location of the message only")},
+		{desc: "unknown type", body: `"type":"sticker","content":"Hello"`,
+			err: e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'SendMessageByIDRequest.Type' Error:Field validation for 'Type' failed on the 'oneof' tag")},
+		{desc: "contact of a user", body: `"type":"contact","payload":{"contact":{"userId":"` + testRecipientID + `","name":"Legoshi"}}`,
+			kind: model.MessageContact, content: "Contact: Legoshi"},
+		{desc: "contact of a missing user", body: `"type":"contact","payload":{"contact":{"userId":"` + testMissingRecipientID + `"}}`,
+			err: e.HttpStatusError(404, "Contact does not exists")},
+		{desc: "vCard", body: `"type":"contact","payload":{"contact":{"vcard":"BEGIN:VCARD\r\nVERSION:3.0\r\nFN;CHARSET=UTF-8:Louis Deer\r\nEND:VCARD"}}`,
+			kind: model.MessageContact, content: "Contact: Louis Deer"},
+		{desc: "contact of a user and a vCard", body: `"type":"contact","payload":{"contact":{"userId":"` + testRecipientID + `","vcard":"BEGIN:VCARD\r\nEND:VCARD"}}`,
+			err: e.HttpStatusError(400, "Invalid Parameter payload - a contact is either a user or a vCard")},
+		{desc: "poll", body: `"type":"poll","payload":{"poll":{"question":"Lunch?","options":["Salad","Eggs"],"multiSelect":true}}`,
+			kind: model.MessagePoll, content: "Poll: Lunch?\n- Salad\n- Eggs"},
+		{desc: "poll with one option", body: `"type":"poll","payload":{"poll":{"question":"Lunch?","options":["Salad"]}}`,
+			err: e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'SendMessageByIDRequest.Payload.Poll.Options' Error:Field validation for 'Options' failed on the 'min' tag")},
+		{desc: "poll closed already", body: `"type":"poll","payload":{"poll":{"question":"Lunch?","options":["Salad","Eggs"],"closesAt":"` + time.Now().Add(-time.Hour).Format(time.RFC3339) + `"}}`,
+			err: e.HttpStatusError(400, "Invalid Parameter payload - the poll must close in the future")},
+	}
+
+	for _, tc := range testCases {
+		var added []model.Message
+		h := Handler{Auth: existingTCAuthStore{}, Message: scheduleTCMessageStore{added: &added}, Friend: mockFriendStore{}, Block: mockBlockStore{},
+			Conversation: mockConversationStore{}}
+
+		body := `{"recipientId":"` + testRecipientID + `",` + tc.body + `}`
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(body))
+
+		result, err := h.HandleSendMessageByID(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err != nil {
+			assert.Empty(t, added, "TEST: %s: expected no message to be sent", tc.desc)
+			continue
+		}
+
+		message := result.(types.Raw).Data.(model.Message)
+		assert.Equal(t, tc.kind, message.Type, "TEST: %s: mismatch in message type", tc.desc)
+		assert.Equal(t, tc.content, message.Content, "TEST: %s: mismatch in plain-text content", tc.desc)
+		assert.Equal(t, tc.kind == model.MessageText, message.Payload == nil, "TEST: %s: expected a payload for typed messages only", tc.desc)
+	}
+}
+
+// typedTCMessageStore refuses to edit messages that are not text.
+type typedTCMessageStore struct {
+	successfulTCMessageStore
+}
+
+func (typedTCMessageStore) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string, entities []model.Entity, window time.Duration) (*model.Message, error) {
+	return nil, e.NewError("Only text messages can be edited")
+}
+
+func TestHandlePutTypedMessage(t *testing.T) {
+	app := gofr.New()
+
+	h := Handler{Message: typedTCMessageStore{}}
+
+	ctx := newTestContext(app, http.MethodPut, "http://dummy", []byte(`{"content":"Somewhere else"}`))
+	ctx.SetPathParams(map[string]string{"id": "someMessageId"})
+
+	_, err := h.HandlePutMessage(ctx)
+
+	assert.Equal(t, e.HttpStatusError(422, "Only text messages can be edited"), err, "Expected edits of typed messages to be refused")
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"database/sql"
+	"strings"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// HandlePinMessage pins a message to its conversation. A conversation holds at most MESSAGE_PIN_LIMIT pins.
+func (h Handler) HandlePinMessage(ctx *gofr.Context) (interface{}, error) {
+	limit := IntConfig(ctx.Config, "MESSAGE_PIN_LIMIT", 5)
+	return h.handlePin(ctx, func(ctx *gofr.Context, userId, messageId string) (*model.Message, bool, error) {
+		return h.Message.PinMessage(ctx, userId, messageId, limit)
+	}, model.EventMessagePinned)
+}
+
+func (h Handler) HandleUnpinMessage(ctx *gofr.Context) (interface{}, error) {
+	return h.handlePin(ctx, h.Message.UnpinMessage, model.EventMessageUnpinned)
+}
+
+type pinFunc func(ctx *gofr.Context, userId, messageId string) (*model.Message, bool, error)
+
+// handlePin applies pin to the message in the path and tells every member of its conversation about it if anything changed.
+func (h Handler) handlePin(ctx *gofr.Context, pin pinFunc, eventType string) (interface{}, error) {
+	messageId := ctx.PathParam("id")
+	if strings.TrimSpace(messageId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter messageId")
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	message, changed, err := pin(ctx, userId, messageId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		switch err {
+		case sql.ErrNoRows:
+			return nil, e.HttpStatusError(404, "Message does not exists")
+		case e.NewError("You are not authorized to see that message"):
+			return nil, e.HttpStatusError(403, err.Error())
+		case e.NewError("Messages of channels cannot be pinned"):
+			return nil, e.HttpStatusError(422, err.Error())
+		case e.NewError("That conversation has too many pinned messages"):
+			return nil, e.HttpStatusError(409, err.Error())
+		}
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	if changed {
+		memberIds, err := h.Conversation.GetMemberIds(ctx, message.ConversationID)
+		if err != nil {
+			ctx.Logger.Error(err)
+			return nil, nil
+		}
+
+		event := model.Event{Type: eventType, Data: model.PinEvent{MessageID: messageId, ConversationID: message.ConversationID, By: userId}}
+		for _, memberId := range memberIds {
+			h.Hub.Publish(memberId, event)
+		}
+	}
+	return nil, nil
+}
+
+// HandleGetPinnedMessages lists the messages pinned to a conversation of the user, the most recently pinned first.
+// A direct conversation may also be referred to by the ID of the peer.
+func (h Handler) HandleGetPinnedMessages(ctx *gofr.Context) (interface{}, error) {
+	conversationId := ctx.PathParam("id")
+	if strings.TrimSpace(conversationId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter conversationId")
+	}
+
+	pins, err := h.Message.GetPinnedMessages(ctx, ctx.Value("userId").(string), conversationId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+	return types.Raw{Data: pins}, nil
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/realtime"
+	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+)
+
+// pinTCMessageStore pins and unpins messages, changing something or not, or fails pinning with err.
+type pinTCMessageStore struct {
+	successfulTCMessageStore
+	changed bool
+	err     error
+}
+
+func (m pinTCMessageStore) PinMessage(ctx *gofr.Context, userId, messageId string, limit int) (*model.Message, bool, error) {
+	if m.err != nil {
+		return nil, false, m.err
+	}
+	return &model.Message{ID: messageId, From: "friend-1", To: userId, ConversationID: "conversationId"}, m.changed, nil
+}
+
+func (m pinTCMessageStore) UnpinMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, bool, error) {
+	return &model.Message{ID: messageId, From: "friend-1", To: userId, ConversationID: "conversationId"}, m.changed, nil
+}
+
+func TestHandlePinMessage(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc      string
+		messageID string
+		store     store.MessageStore
+		err       error
+	}{
+		{"pin success", "someMessageId", successfulTCMessageStore{}, nil},
+		{"missing parameter", "", successfulTCMessageStore{}, e.HttpStatusError(400, "Missing Parameter messageId")},
+		{"message not found", "someMessageId", errorTCMessageStore{}, e.HttpStatusError(404, "Message does not exists")},
+		{"authorization error", "someMessageId", authorizationErrorTCMessageStore{}, e.HttpStatusError(403, "You are not authorized to see that message")},
+		{"channel message", "someMessageId", pinTCMessageStore{err: e.NewError("Messages of channels cannot be pinned")},
+			e.HttpStatusError(422, "Messages of channels cannot be pinned")},
+		{"too many pins", "someMessageId", pinTCMessageStore{err: e.NewError("That conversation has too many pinned messages")},
+			e.HttpStatusError(409, "That conversation has too many pinned messages")},
+		{"message store error", "someMessageId", messageStoreErrorTCMessageStore{}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Message: tc.store, Conversation: mockConversationStore{}, Hub: realtime.NewHub()}
+
+		ctx := newTestContext(app, http.MethodPut, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"id": tc.messageID})
+
+		_, err := h.HandlePinMessage(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+	}
+}
+
+func TestHandlePinEvents(t *testing.T) {
+	app := gofr.New()
+	hub := realtime.NewHub()
+
+	senderConn := connectTestClient(t, hub, "friend-1")
+	pinnerConn := connectTestClient(t, hub, "someUserId")
+	strangerConn := connectTestClient(t, hub, "stranger")
+
+	pin := func(h Handler, handle func(Handler, *gofr.Context) (interface{}, error)) {
+		ctx := newTestContext(app, http.MethodPut, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"id": "someMessageId"})
+		_, err := handle(h, ctx)
+		assert.NoError(t, err, "Unexpected error while pinning")
+	}
+
+	conversation := membersTCConversationStore{memberIds: []string{"friend-1", "someUserId"}}
+	changed := Handler{Message: pinTCMessageStore{changed: true}, Conversation: conversation, Hub: hub}
+	unchanged := Handler{Message: pinTCMessageStore{changed: false}, Conversation: conversation, Hub: hub}
+
+	pin(changed, Handler.HandlePinMessage)
+	pin(unchanged, Handler.HandlePinMessage)
+	pin(changed, Handler.HandleUnpinMessage)
+	pin(unchanged, Handler.HandleUnpinMessage)
+
+	expected := []string{model.EventMessagePinned, model.EventMessageUnpinned}
+	assert.Equal(t, expected, readTestEvents(senderConn), "Expected the other participant to be told about pins that changed something")
+	assert.Equal(t, expected, readTestEvents(pinnerConn), "Expected the pinner's devices to be told about their pins")
+	assert.Empty(t, readTestEvents(strangerConn), "Expected pins not to be published outside the conversation")
+}
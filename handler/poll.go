@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/go-playground/validator"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// HandleVote casts or changes the vote of the user in the poll in the path and tells every member of its
+// conversation about the new results.
+func (h Handler) HandleVote(ctx *gofr.Context) (interface{}, error) {
+	messageId := ctx.PathParam("id")
+	if strings.TrimSpace(messageId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter messageId")
+	}
+
+	var voteRequest model.VoteRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&voteRequest)
+	err = validator.New().Struct(voteRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	results, err := h.Message.Vote(ctx, userId, messageId, voteRequest.Options)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		switch err {
+		case e.NewError("That poll takes a single option"):
+			return nil, e.HttpStatusError(400, "Invalid Parameter options - the poll takes a single option")
+		case e.NewError("That poll has no such option"):
+			return nil, e.HttpStatusError(400, "Invalid Parameter options - the poll has no such option")
+		case e.NewError("That message is not a poll"), e.NewError("That poll is closed"):
+			return nil, e.HttpStatusError(422, err.Error())
+		}
+		return nil, pollError(err)
+	}
+
+	h.publishPollResults(ctx, *results)
+	return types.Raw{Data: results}, nil
+}
+
+// HandleGetPollResults returns the results of the poll in the path.
+func (h Handler) HandleGetPollResults(ctx *gofr.Context) (interface{}, error) {
+	messageId := ctx.PathParam("id")
+	if strings.TrimSpace(messageId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter messageId")
+	}
+
+	results, err := h.Message.GetPollResults(ctx, ctx.Value("userId").(string), messageId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == e.NewError("That message is not a poll") {
+			return nil, e.HttpStatusError(422, err.Error())
+		}
+		return nil, pollError(err)
+	}
+	return types.Raw{Data: results}, nil
+}
+
+// pollError maps the errors common to every poll request.
+func pollError(err error) error {
+	switch err {
+	case sql.ErrNoRows:
+		return e.HttpStatusError(404, "Message does not exists")
+	case e.NewError("You are not authorized to see that message"):
+		return e.HttpStatusError(403, err.Error())
+	}
+	return e.HttpStatusError(500, "")
+}
+
+// publishPollResults pushes the results of a poll to every member of its conversation, without the votes of the voter.
+func (h Handler) publishPollResults(ctx *gofr.Context, results model.PollResults) {
+	memberIds, err := h.Conversation.GetMemberIds(ctx, results.ConversationID)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return
+	}
+
+	results.MyVotes = nil
+	event := model.Event{Type: model.EventPollUpdated, Data: results}
+	for _, memberId := range memberIds {
+		h.Hub.Publish(memberId, event)
+	}
+}
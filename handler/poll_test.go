@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/realtime"
+	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// pollTCMessageStore fails votes with err.
+type pollTCMessageStore struct {
+	successfulTCMessageStore
+	err error
+}
+
+func (m pollTCMessageStore) Vote(ctx *gofr.Context, userId, messageId string, options []int) (*model.PollResults, error) {
+	return nil, m.err
+}
+
+func (m pollTCMessageStore) GetPollResults(ctx *gofr.Context, userId, messageId string) (*model.PollResults, error) {
+	return nil, m.err
+}
+
+func TestHandleVote(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc      string
+		messageID string
+		body      string
+		store     store.MessageStore
+		err       error
+	}{
+		{desc: "vote success", messageID: "someMessageId", body: `{"options":[0]}`, store: successfulTCMessageStore{}},
+		{desc: "missing parameter", body: `{"options":[0]}`, store: successfulTCMessageStore{}, err: e.HttpStatusError(400, "Missing Parameter messageId")},
+		{desc: "no options", messageID: "someMessageId", body: `{"options":[]}`, store: successfulTCMessageStore{},
+			err: e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'VoteRequest.Options' Error:Field validation for 'Options' failed on the 'min' tag")},
+		{desc: "repeated option", messageID: "someMessageId", body: `{"options":[1,1]}`, store: successfulTCMessageStore{},
+			err: e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'VoteRequest.Options' Error:Field validation for 'Options' failed on the 'unique' tag")},
+		{desc: "several options in a single-select poll", messageID: "someMessageId", body: `{"options":[0,1]}`,
+			store: pollTCMessageStore{err: e.NewError("That poll takes a single option")},
+			err:   e.HttpStatusError(400, "Invalid Parameter options - the poll takes a single option")},
+		{desc: "option out of range", messageID: "someMessageId", body: `{"options":[7]}`,
+			store: pollTCMessageStore{err: e.NewError("That poll has no such option")},
+			err:   e.HttpStatusError(400, "Invalid Parameter options - the poll has no such option")},
+		{desc: "not a poll", messageID: "someMessageId", body: `{"options":[0]}`, store: pollTCMessageStore{err: e.NewError("That message is not a poll")},
+			err: e.HttpStatusError(422, "That message is not a poll")},
+		{desc: "closed poll", messageID: "someMessageId", body: `{"options":[0]}`, store: pollTCMessageStore{err: e.NewError("That poll is closed")},
+			err: e.HttpStatusError(422, "That poll is closed")},
+		{desc: "message not found", messageID: "someMessageId", body: `{"options":[0]}`, store: errorTCMessageStore{},
+			err: e.HttpStatusError(404, "Message does not exists")},
+		{desc: "not a member", messageID: "someMessageId", body: `{"options":[0]}`, store: authorizationErrorTCMessageStore{},
+			err: e.HttpStatusError(403, "You are not authorized to see that message")},
+		{desc: "message store error", messageID: "someMessageId", body: `{"options":[0]}`, store: messageStoreErrorTCMessageStore{},
+			err: e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Message: tc.store, Conversation: mockConversationStore{}, Hub: realtime.NewHub()}
+
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(tc.body))
+		ctx.SetPathParams(map[string]string{"id": tc.messageID})
+
+		result, err := h.HandleVote(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			assert.Equal(t, []int{0}, result.(types.Raw).Data.(*model.PollResults).MyVotes, "TEST: %s: expected the votes of the user", tc.desc)
+		}
+	}
+}
+
+func TestHandleVoteEvents(t *testing.T) {
+	app := gofr.New()
+	hub := realtime.NewHub()
+
+	askerConn := connectTestClient(t, hub, "friend-1")
+	voterConn := connectTestClient(t, hub, "someUserId")
+	strangerConn := connectTestClient(t, hub, "stranger")
+
+	conversation := membersTCConversationStore{memberIds: []string{"friend-1", "someUserId"}}
+	vote := func(store store.MessageStore) {
+		h := Handler{Message: store, Conversation: conversation, Hub: hub}
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(`{"options":[0]}`))
+		ctx.SetPathParams(map[string]string{"id": "someMessageId"})
+		_, _ = h.HandleVote(ctx)
+	}
+
+	vote(successfulTCMessageStore{})
+	vote(pollTCMessageStore{err: e.NewError("That poll is closed")})
+
+	expected := []string{model.EventPollUpdated}
+	assert.Equal(t, expected, readTestEvents(askerConn), "Expected the members to be told about the new results")
+	assert.Equal(t, expected, readTestEvents(voterConn), "Expected the voter's devices to be told about the new results")
+	assert.Empty(t, readTestEvents(strangerConn), "Expected results not to be published outside the conversation")
+}
+
+func TestHandleGetPollResults(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc  string
+		store store.MessageStore
+		err   error
+	}{
+		{"get results success", successfulTCMessageStore{}, nil},
+		{"not a poll", pollTCMessageStore{err: e.NewError("That message is not a poll")}, e.HttpStatusError(422, "That message is not a poll")},
+		{"message not found", errorTCMessageStore{}, e.HttpStatusError(404, "Message does not exists")},
+		{"not a member", authorizationErrorTCMessageStore{}, e.HttpStatusError(403, "You are not authorized to see that message")},
+		{"message store error", messageStoreErrorTCMessageStore{}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Message: tc.store}
+
+		ctx := newTestContext(app, http.MethodGet, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"id": "someMessageId"})
+
+		_, err := h.HandleGetPollResults(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"strings"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// TrackActivity records an authenticated request of the user, persisting their last-seen
+// now and then and letting their friends know when it brings them back from offline.
+func (h Handler) TrackActivity(ctx *gofr.Context, userId string) {
+	changed, persist := h.PresenceTracker.Touch(userId)
+
+	if persist {
+		err := h.Presence.UpdateLastSeen(ctx, userId, time.Now())
+		if err != nil {
+			ctx.Logger.Error(err)
+		}
+	}
+
+	if changed {
+		h.publishPresence(ctx, userId, nil)
+	}
+}
+
+// ExpirePresence lets the friends of users who went from away to offline know, along with when they were last seen.
+// That is persisted as well, as persisting it is throttled while the users are active.
+func (h Handler) ExpirePresence(ctx *gofr.Context) error {
+	for userId, lastActive := range h.PresenceTracker.Expire() {
+		lastSeen := lastActive
+		err := h.Presence.UpdateLastSeen(ctx, userId, lastSeen)
+		if err != nil {
+			ctx.Logger.Error(err)
+		}
+		h.publishPresence(ctx, userId, &lastSeen)
+	}
+	return nil
+}
+
+func (h Handler) HandleGetPresence(ctx *gofr.Context) (interface{}, error) {
+	userIds := splitIds(ctx.Param("ids"))
+	if len(userIds) == 0 {
+		return nil, e.HttpStatusError(400, "Missing Parameter ids")
+	}
+	if len(userIds) > model.RequestPresenceLimit {
+		return nil, e.HttpStatusError(400, "Too many ids requested")
+	}
+
+	friends, err := h.Friend.GetFriends(ctx, ctx.Value("userId").(string))
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	isFriend := make(map[string]bool, len(*friends))
+	for _, friend := range *friends {
+		isFriend[friend.ID] = true
+	}
+
+	friendIds := make([]string, 0, len(userIds))
+	for _, userId := range userIds {
+		if isFriend[userId] {
+			friendIds = append(friendIds, userId)
+		}
+	}
+
+	records, err := h.Presence.GetPresenceRecords(ctx, friendIds)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	presences := make([]model.Presence, 0, len(*records))
+	for _, record := range *records {
+		presence := model.Presence{UserID: record.UserID, Status: h.PresenceTracker.Status(record.UserID)}
+		if record.LastSeenVisibility != model.LastSeenNobody {
+			presence.LastSeen = record.LastSeen
+		}
+		presences = append(presences, presence)
+	}
+
+	return types.Raw{Data: presences}, nil
+}
+
+// publishPresence pushes the user's current status to their friends. lastSeen is only shared
+// if the user's privacy setting allows it.
+func (h Handler) publishPresence(ctx *gofr.Context, userId string, lastSeen *time.Time) {
+	friends, err := h.Friend.GetFriends(ctx, userId)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return
+	}
+
+	presence := model.Presence{UserID: userId, Status: h.PresenceTracker.Status(userId)}
+	if lastSeen != nil {
+		settings, err := h.Settings.GetSettings(ctx, userId)
+		if err != nil {
+			ctx.Logger.Error(err)
+		} else if *settings.LastSeenVisibility != model.LastSeenNobody {
+			presence.LastSeen = lastSeen
+		}
+	}
+
+	for _, friend := range *friends {
+		h.Hub.Publish(friend.ID, model.Event{Type: model.EventPresenceChanged, Data: presence})
+	}
+}
+
+// splitIds parses a comma separated list of ids, dropping blanks and duplicates.
+func splitIds(param string) []string {
+	ids := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, id := range strings.Split(param, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
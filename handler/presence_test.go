@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/realtime"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+type friendsTCFriendStore struct {
+	mockFriendStore
+}
+
+func (friendsTCFriendStore) GetFriends(ctx *gofr.Context, userId string) (*[]model.User, error) {
+	return &[]model.User{{ID: "friend-1"}, {ID: "friend-2"}}, nil
+}
+
+func (friendsTCFriendStore) AreFriends(ctx *gofr.Context, userId1, userId2 string) (bool, error) {
+	return userId2 == "friend-1" || userId2 == "friend-2", nil
+}
+
+type mockPresenceStore struct {
+	lastSeen time.Time
+}
+
+func (mockPresenceStore) UpdateLastSeen(ctx *gofr.Context, userId string, lastSeen time.Time) error {
+	return nil
+}
+
+func (p mockPresenceStore) GetPresenceRecords(ctx *gofr.Context, userIds []string) (*[]model.PresenceRecord, error) {
+	records := make([]model.PresenceRecord, 0)
+	for _, userId := range userIds {
+		visibility := model.LastSeenFriends
+		if userId == "friend-2" {
+			visibility = model.LastSeenNobody
+		}
+		records = append(records, model.PresenceRecord{UserID: userId, LastSeen: &p.lastSeen, LastSeenVisibility: visibility})
+	}
+	return &records, nil
+}
+
+type errorTCPresenceStore struct{}
+
+func (errorTCPresenceStore) UpdateLastSeen(ctx *gofr.Context, userId string, lastSeen time.Time) error {
+	return e.NewError("")
+}
+
+func (errorTCPresenceStore) GetPresenceRecords(ctx *gofr.Context, userIds []string) (*[]model.PresenceRecord, error) {
+	return nil, e.NewError("")
+}
+
+type mockSettingsStore struct{}
+
+func (mockSettingsStore) GetSettings(ctx *gofr.Context, userId string) (*model.Settings, error) {
+	lastSeenVisibility := model.LastSeenFriends
+	return &model.Settings{LastSeenVisibility: &lastSeenVisibility}, nil
+}
+
+func (mockSettingsStore) UpdateSettings(ctx *gofr.Context, userId string, settings model.Settings) (*model.Settings, error) {
+	return &settings, nil
+}
+
+type errorTCSettingsStore struct{}
+
+func (errorTCSettingsStore) GetSettings(ctx *gofr.Context, userId string) (*model.Settings, error) {
+	return nil, e.NewError("")
+}
+
+func (errorTCSettingsStore) UpdateSettings(ctx *gofr.Context, userId string, settings model.Settings) (*model.Settings, error) {
+	return nil, e.NewError("")
+}
+
+func TestHandleGetPresence(t *testing.T) {
+	app := gofr.New()
+	lastSeen := time.Now()
+	h := Handler{Friend: friendsTCFriendStore{}, Presence: mockPresenceStore{lastSeen: lastSeen}, PresenceTracker: realtime.NewPresence(realtime.NewHub(), time.Minute, time.Minute)}
+
+	h.PresenceTracker.Touch("friend-1")
+
+	result, err := h.HandleGetPresence(newTestContext(app, http.MethodGet, "http://dummy/presence?ids=friend-1,stranger,friend-2,friend-1", nil))
+
+	assert.NoError(t, err, "TEST: Unexpected Error: get presence success")
+	presences := result.(types.Raw).Data.([]model.Presence)
+	assert.Len(t, presences, 2, "Expected presence of friends only")
+	assert.Equal(t, model.PresenceAway, presences[0].Status, "Mismatch in status of an active friend")
+	assert.Equal(t, lastSeen, *presences[0].LastSeen, "Expected the last seen of a friend sharing it")
+	assert.Equal(t, model.PresenceOffline, presences[1].Status, "Mismatch in status of an inactive friend")
+	assert.Nil(t, presences[1].LastSeen, "Expected the last seen of a friend hiding it to be left out")
+
+	_, err = h.HandleGetPresence(newTestContext(app, http.MethodGet, "http://dummy/presence?ids=,", nil))
+	assert.Equal(t, e.HttpStatusError(400, "Missing Parameter ids"), err, "TEST: missing parameter: unexpected error")
+
+	h.Presence = errorTCPresenceStore{}
+	_, err = h.HandleGetPresence(newTestContext(app, http.MethodGet, "http://dummy/presence?ids=friend-1", nil))
+	assert.Equal(t, e.HttpStatusError(500, ""), err, "TEST: presence store error: unexpected error")
+}
+
+func TestExpirePresence(t *testing.T) {
+	app := gofr.New()
+	hub := realtime.NewHub()
+	// Users are away for no time at all, so everyone touched is due to expire.
+	tracker := realtime.NewPresence(hub, 0, time.Minute)
+	h := Handler{Friend: friendsTCFriendStore{}, Presence: mockPresenceStore{}, Settings: mockSettingsStore{}, Hub: hub, PresenceTracker: tracker}
+
+	friendConn := connectTestClient(t, hub, "friend-1")
+	tracker.Touch("someUserId")
+	ctx := newTestContext(app, http.MethodGet, "http://dummy", nil)
+
+	err := h.ExpirePresence(ctx)
+
+	assert.NoError(t, err, "Unexpected error expiring presence")
+	assert.Equal(t, []string{model.EventPresenceChanged}, readTestEvents(friendConn), "Expected friends to be told the user went offline")
+
+	err = h.ExpirePresence(ctx)
+
+	assert.NoError(t, err, "Unexpected error expiring presence")
+	assert.Empty(t, readTestEvents(friendConn), "Expected a user to go offline only once")
+}
+
+func TestHandleUpdateSettings(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc string
+		body []byte
+		h    Handler
+		err  error
+	}{
+		{"update settings success", []byte(`{"lastSeenVisibility":"nobody"}`), Handler{Settings: mockSettingsStore{}}, nil},
+		{"update discoverability only", []byte(`{"discoverable":false}`), Handler{Settings: mockSettingsStore{}}, nil},
+		{"update nothing", []byte(`{}`), Handler{Settings: mockSettingsStore{}}, e.NewError("")},
+		{"invalid visibility", []byte(`{"lastSeenVisibility":"strangers"}`), Handler{Settings: mockSettingsStore{}}, e.NewError("")},
+		{"visibility beyond friends", []byte(`{"lastSeenVisibility":"everyone"}`), Handler{Settings: mockSettingsStore{}}, e.NewError("")},
+		{"invalid request body", []byte(`invalidjson`), Handler{Settings: mockSettingsStore{}}, e.NewError("")},
+		{"settings store error", []byte(`{"lastSeenVisibility":"friends"}`), Handler{Settings: errorTCSettingsStore{}}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		result, err := tc.h.HandleUpdateSettings(newTestContext(app, http.MethodPut, "http://dummy/settings", tc.body))
+
+		if tc.err != nil {
+			assert.Error(t, err, "TEST: %s: unexpected error", tc.desc)
+		} else {
+			assert.NoError(t, err, "TEST: Unexpected Error: %s", tc.desc)
+			assert.IsType(t, types.Raw{}, result, "TEST: %s: unexpected result type", tc.desc)
+		}
+	}
+}
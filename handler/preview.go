@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/unfurl"
+	"gofr.dev/pkg/gofr"
+)
+
+// queueLinkPreview hands a sent text message with links to the workers that fetch link previews.
+// Scheduled messages are handed over once they are sent.
+func (h Handler) queueLinkPreview(message model.Message) {
+	if message.Type != model.MessageText || message.ScheduledAt != nil || len(unfurl.Links(message.Content, message.Entities)) == 0 {
+		return
+	}
+	h.Previews.Enqueue(message)
+}
+
+// PreviewLinks attaches the preview of the first link of a message that has one, and pushes it to the participants
+// of the conversation. A message that was edited or deleted meanwhile is left alone, as its preview may no longer fit.
+func (h Handler) PreviewLinks(ctx *gofr.Context, message model.Message) error {
+	for _, link := range unfurl.Links(message.Content, message.Entities) {
+		preview, err := h.Unfurler.Unfurl(ctx, link)
+		if err != nil {
+			ctx.Logger.Infof("a link of message %s has no preview: %v", message.ID, err)
+			continue
+		}
+
+		attached, err := h.Message.SetLinkPreview(ctx, message.ID, message.Content, *preview)
+		if err != nil || !attached {
+			return err
+		}
+
+		memberIds, err := h.Conversation.GetMemberIds(ctx, message.ConversationID)
+		if err != nil {
+			return err
+		}
+
+		event := model.Event{Type: model.EventLinkPreview, Data: model.LinkPreviewEvent{MessageID: message.ID, ConversationID: message.ConversationID, Preview: *preview}}
+		for _, memberId := range memberIds {
+			h.Hub.Publish(memberId, event)
+		}
+		return nil
+	}
+	return nil
+}
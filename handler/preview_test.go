@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/jobs"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/realtime"
+	"github.com/aryanA101a/legoshichat-backend/unfurl"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+)
+
+// previewTCMessageStore records the previews attached to messages, unless the messages are stale.
+type previewTCMessageStore struct {
+	successfulTCMessageStore
+	attached *[]model.LinkPreview
+	stale    bool
+}
+
+func (m previewTCMessageStore) SetLinkPreview(ctx *gofr.Context, messageId, content string, preview model.LinkPreview) (bool, error) {
+	if m.stale {
+		return false, nil
+	}
+	*m.attached = append(*m.attached, preview)
+	return true, nil
+}
+
+func TestPreviewLinks(t *testing.T) {
+	app := gofr.New()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><meta property="og:title" content="Wolves in the city"><meta property="og:site_name" content="Beast News"></head></html>`)
+	})
+	mux.HandleFunc("/bare", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>Nothing to see</body></html>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	article := model.LinkPreview{URL: server.URL + "/article", SiteName: "Beast News", Title: "Wolves in the city"}
+
+	testCases := []struct {
+		desc     string
+		content  string
+		stale    bool
+		attached []model.LinkPreview
+		events   []string
+	}{
+		{desc: "link with a preview", content: "read " + server.URL + "/article", attached: []model.LinkPreview{article},
+			events: []string{model.EventLinkPreview}},
+		{desc: "first link without a preview", content: server.URL + "/bare or " + server.URL + "/article", attached: []model.LinkPreview{article},
+			events: []string{model.EventLinkPreview}},
+		{desc: "no preview", content: "read " + server.URL + "/bare", attached: []model.LinkPreview{}, events: []string{}},
+		{desc: "edited meanwhile", content: "read " + server.URL + "/article", stale: true, attached: []model.LinkPreview{}, events: []string{}},
+	}
+
+	for _, tc := range testCases {
+		hub := realtime.NewHub()
+		memberConn := connectTestClient(t, hub, testRecipientID)
+
+		attached := make([]model.LinkPreview, 0)
+		h := Handler{Message: previewTCMessageStore{attached: &attached, stale: tc.stale}, Conversation: membersTCConversationStore{memberIds: []string{testRecipientID}},
+			Hub: hub, Unfurler: unfurl.NewUnfurler(server.Client(), time.Minute, 10)}
+
+		ctx := newTestContext(app, http.MethodGet, "http://dummy", nil)
+		err := h.PreviewLinks(ctx, model.Message{ID: "someMessageId", ConversationID: "conversationId", Type: model.MessageText, Content: tc.content})
+
+		assert.NoError(t, err, "TEST: %s: unexpected error", tc.desc)
+		assert.Equal(t, tc.attached, attached, "TEST: %s: mismatch in attached previews", tc.desc)
+		assert.Equal(t, tc.events, readTestEvents(memberConn), "TEST: %s: mismatch in events pushed to members", tc.desc)
+	}
+}
+
+func TestHandleSendGroupMessageQueuesLinkPreview(t *testing.T) {
+	app := gofr.New()
+
+	queued := make(chan model.Message, 2)
+	previews := jobs.NewQueue(1, 2, func(message model.Message) {
+		queued <- message
+	})
+	h := Handler{Message: successfulTCMessageStore{}, Group: mockGroupStore{}, Conversation: mockConversationStore{}, Hub: realtime.NewHub(), Previews: previews}
+
+	for _, content := range []string{"no links", "see https://gofr.dev"} {
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(`{"content":"`+content+`"}`))
+		ctx.SetPathParams(map[string]string{"id": "groupId"})
+
+		_, err := h.HandleSendGroupMessage(ctx)
+
+		assert.NoError(t, err, "Unexpected error while sending %q", content)
+	}
+	previews.Close()
+	close(queued)
+
+	contents := make([]string, 0)
+	for message := range queued {
+		contents = append(contents, message.Content)
+	}
+	assert.Equal(t, []string{"see https://gofr.dev"}, contents, "Expected only messages with links to be queued for previews")
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/aryanA101a/legoshichat-backend/emoji"
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/gofr"
+)
+
+func (h Handler) HandleAddReaction(ctx *gofr.Context) (interface{}, error) {
+	return h.handleReaction(ctx, h.Message.AddReaction, model.EventReactionAdded)
+}
+
+func (h Handler) HandleRemoveReaction(ctx *gofr.Context) (interface{}, error) {
+	return h.handleReaction(ctx, h.Message.RemoveReaction, model.EventReactionRemoved)
+}
+
+type reactionFunc func(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error)
+
+// handleReaction applies react to the message in the path and tells every member of its conversation about it if anything changed.
+func (h Handler) handleReaction(ctx *gofr.Context, react reactionFunc, eventType string) (interface{}, error) {
+	messageId := ctx.PathParam("id")
+	if strings.TrimSpace(messageId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter messageId")
+	}
+
+	reaction := ctx.PathParam("emoji")
+	if !emoji.Valid(reaction) {
+		return nil, e.HttpStatusError(400, "Invalid Parameter emoji")
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	message, changed, err := react(ctx, userId, messageId, reaction)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == sql.ErrNoRows {
+			return nil, e.HttpStatusError(404, "Message does not exists")
+		} else if err == e.NewError("You are not authorized to see that message") {
+			return nil, e.HttpStatusError(403, err.Error())
+		}
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	if changed {
+		memberIds, err := h.Conversation.GetMemberIds(ctx, message.ConversationID)
+		if err != nil {
+			ctx.Logger.Error(err)
+			return nil, nil
+		}
+
+		event := model.Event{Type: eventType, Data: model.ReactionEvent{MessageID: messageId, From: userId, Emoji: reaction}}
+		for _, memberId := range memberIds {
+			h.Hub.Publish(memberId, event)
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/realtime"
+	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+)
+
+type reactionTCMessageStore struct {
+	successfulTCMessageStore
+	changed bool
+}
+
+func (m reactionTCMessageStore) AddReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error) {
+	return &model.Message{ID: messageId, From: "friend-1", To: userId}, m.changed, nil
+}
+
+func (m reactionTCMessageStore) RemoveReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error) {
+	return &model.Message{ID: messageId, From: "friend-1", To: userId}, m.changed, nil
+}
+
+type membersTCConversationStore struct {
+	mockConversationStore
+	memberIds []string
+}
+
+func (c membersTCConversationStore) GetMemberIds(ctx *gofr.Context, conversationId string) ([]string, error) {
+	return c.memberIds, nil
+}
+
+func TestHandleReaction(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc      string
+		messageID string
+		emoji     string
+		store     store.MessageStore
+		err       error
+	}{
+		{"reaction success", "someMessageId", "👍", successfulTCMessageStore{}, nil},
+		{"missing parameter", "", "👍", successfulTCMessageStore{}, e.HttpStatusError(400, "Missing Parameter messageId")},
+		{"not an emoji", "someMessageId", "like", successfulTCMessageStore{}, e.HttpStatusError(400, "Invalid Parameter emoji")},
+		{"several emoji", "someMessageId", "👍👍", successfulTCMessageStore{}, e.HttpStatusError(400, "Invalid Parameter emoji")},
+		{"message not found", "someMessageId", "👍", errorTCMessageStore{}, e.HttpStatusError(404, "Message does not exists")},
+		{"authorization error", "someMessageId", "👍", authorizationErrorTCMessageStore{}, e.HttpStatusError(403, "You are not authorized to see that message")},
+		{"message store error", "someMessageId", "👍", messageStoreErrorTCMessageStore{}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Message: tc.store, Conversation: mockConversationStore{}}
+
+		for _, handle := range []func(*gofr.Context) (interface{}, error){h.HandleAddReaction, h.HandleRemoveReaction} {
+			ctx := newTestContext(app, http.MethodPut, "http://dummy", nil)
+			ctx.SetPathParams(map[string]string{"id": tc.messageID, "emoji": tc.emoji})
+
+			_, err := handle(ctx)
+
+			assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		}
+	}
+}
+
+func TestHandleReactionEvents(t *testing.T) {
+	app := gofr.New()
+	hub := realtime.NewHub()
+
+	senderConn := connectTestClient(t, hub, "friend-1")
+	reactorConn := connectTestClient(t, hub, "someUserId")
+	strangerConn := connectTestClient(t, hub, "stranger")
+
+	react := func(h Handler, handle func(Handler, *gofr.Context) (interface{}, error)) {
+		ctx := newTestContext(app, http.MethodPut, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"id": "someMessageId", "emoji": "👍"})
+		_, err := handle(h, ctx)
+		assert.NoError(t, err, "Unexpected error while reacting")
+	}
+
+	conversation := membersTCConversationStore{memberIds: []string{"friend-1", "someUserId"}}
+	changed := Handler{Message: reactionTCMessageStore{changed: true}, Conversation: conversation, Hub: hub}
+	unchanged := Handler{Message: reactionTCMessageStore{changed: false}, Conversation: conversation, Hub: hub}
+
+	react(changed, Handler.HandleAddReaction)
+	react(unchanged, Handler.HandleAddReaction)
+	react(changed, Handler.HandleRemoveReaction)
+	react(unchanged, Handler.HandleRemoveReaction)
+
+	expected := []string{model.EventReactionAdded, model.EventReactionRemoved}
+	assert.Equal(t, expected, readTestEvents(senderConn), "Expected the sender to be told about reactions that changed something")
+	assert.Equal(t, expected, readTestEvents(reactorConn), "Expected the reactor's devices to be told about their reactions")
+	assert.Empty(t, readTestEvents(strangerConn), "Expected reactions not to be published outside the conversation")
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/go-playground/validator"
+	"gofr.dev/pkg/gofr"
+)
+
+// HandleWebSocket keeps a real-time connection open for the authorized user, over which
+// events are pushed to them and client events are read for as long as it stays up.
+func (h Handler) HandleWebSocket(ctx *gofr.Context) (interface{}, error) {
+	if ctx.WebSocketConnection == nil {
+		return nil, e.HttpStatusError(400, "Expected a websocket upgrade request")
+	}
+
+	userId := ctx.Value("userId").(string)
+
+	client, first := h.Hub.Register(userId, ctx.WebSocketConnection)
+	if first {
+		h.publishPresence(ctx, userId, nil)
+	}
+
+	for {
+		event, err := client.ReadEvent()
+		if err != nil {
+			break
+		}
+		h.handleClientEvent(ctx, userId, *event)
+	}
+
+	if h.Hub.Unregister(client) {
+		h.PresenceTracker.Forget(userId)
+		h.Typing.StopAll(userId)
+
+		lastSeen := time.Now()
+		err := h.Presence.UpdateLastSeen(ctx, userId, lastSeen)
+		if err != nil {
+			ctx.Logger.Error(err)
+		}
+		h.publishPresence(ctx, userId, &lastSeen)
+	}
+
+	return nil, nil
+}
+
+// handleClientEvent acts on an event sent by a client. Unknown and malformed events are ignored.
+func (h Handler) handleClientEvent(ctx *gofr.Context, userId string, event model.ClientEvent) {
+	switch event.Type {
+	case model.EventTypingStart, model.EventTypingStop:
+		h.handleTyping(ctx, userId, event)
+	}
+}
+
+// handleTyping relays a typing signal to the peer, as long as the sender is within their rate limit
+// and the peer is one of their friends.
+func (h Handler) handleTyping(ctx *gofr.Context, userId string, event model.ClientEvent) {
+	var typingRequest model.TypingRequest
+	err := json.Unmarshal(event.Data, &typingRequest)
+	if err == nil {
+		err = validator.New().Struct(typingRequest)
+	}
+	if err != nil {
+		ctx.Logger.Debug("ignoring typing event: ", err.Error())
+		return
+	}
+
+	if event.Type == model.EventTypingStop {
+		h.Typing.Stop(userId, typingRequest.To)
+		return
+	}
+
+	if !h.TypingLimiter.Allow(userId) {
+		return
+	}
+
+	ok, err := h.Friend.AreFriends(ctx, userId, typingRequest.To)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	h.Typing.Start(userId, typingRequest.To)
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/realtime"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+)
+
+// connectTestClient registers a websocket connection for userId on the hub and returns the client end of it.
+func connectTestClient(t *testing.T, hub *realtime.Hub, userId string) *websocket.Conn {
+	serverConns := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Error upgrading connection: %v", err)
+			return
+		}
+		serverConns <- conn
+	}))
+	t.Cleanup(ts.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Error dialing test server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	hub.Register(userId, <-serverConns)
+	return conn
+}
+
+// readTestEvents reads the types of the events received on conn until it stays quiet for a moment.
+func readTestEvents(conn *websocket.Conn) []string {
+	types := make([]string, 0)
+	for {
+		var event model.ClientEvent
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		if err := conn.ReadJSON(&event); err != nil {
+			return types
+		}
+		types = append(types, event.Type)
+	}
+}
+
+func TestHandleTyping(t *testing.T) {
+	app := gofr.New()
+	hub := realtime.NewHub()
+	h := Handler{
+		Friend:        friendsTCFriendStore{},
+		Hub:           hub,
+		Typing:        realtime.NewTyping(hub, time.Minute),
+		TypingLimiter: realtime.NewRateLimiter(0.001, 2),
+	}
+
+	friendConn := connectTestClient(t, hub, "friend-1")
+	strangerConn := connectTestClient(t, hub, "stranger")
+	ctx := newTestContext(app, http.MethodGet, "http://dummy/ws", nil)
+
+	h.handleClientEvent(ctx, "someUserId", model.ClientEvent{Type: model.EventTypingStart, Data: []byte(`{"to":"stranger"}`)})
+	h.handleClientEvent(ctx, "someUserId", model.ClientEvent{Type: model.EventTypingStart, Data: []byte(`{"to":"friend-1"}`)})
+	h.handleClientEvent(ctx, "someUserId", model.ClientEvent{Type: model.EventTypingStop, Data: []byte(`{"to":"friend-1"}`)})
+	h.handleClientEvent(ctx, "someUserId", model.ClientEvent{Type: model.EventTypingStart, Data: []byte(`{"to":"friend-1"}`)})
+	h.handleClientEvent(ctx, "someUserId", model.ClientEvent{Type: model.EventTypingStart, Data: []byte(`{}`)})
+	h.handleClientEvent(ctx, "someUserId", model.ClientEvent{Type: model.EventTypingStart, Data: []byte(`invalidjson`)})
+
+	assert.Equal(t, []string{model.EventTypingStart, model.EventTypingStop}, readTestEvents(friendConn), "Expected typing beyond the rate limit to be dropped")
+	assert.Empty(t, readTestEvents(strangerConn), "Expected typing not to be relayed to non friends")
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/go-playground/validator"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// HandleGetScheduledMessages lists the messages the user scheduled that are yet to be sent, the earliest first.
+func (h Handler) HandleGetScheduledMessages(ctx *gofr.Context) (interface{}, error) {
+	page, err := pageParam(ctx)
+	if err != nil {
+		return nil, e.HttpStatusError(400, "Invalid Parameter page")
+	}
+
+	messages, err := h.Message.GetScheduledMessages(ctx, ctx.Value("userId").(string), page, model.RequestMessageLimit)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	lastPage := false
+	if len(*messages) < model.RequestMessageLimit {
+		lastPage = true
+	}
+	return types.Raw{Data: model.GetMessagesResponse{Page: page, LastPage: lastPage, Messages: *messages}}, nil
+}
+
+func (h Handler) HandleUpdateScheduledMessage(ctx *gofr.Context) (interface{}, error) {
+	var updateRequest model.UpdateScheduledMessageRequest
+	err := json.NewDecoder(ctx.Request().Body).Decode(&updateRequest)
+	err = validator.New().Struct(updateRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	messageId := ctx.PathParam("id")
+	if strings.TrimSpace(messageId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter messageId")
+	}
+
+	err = checkSendAt(ctx, updateRequest.SendAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only direct messages are scheduled, so nobody can be mentioned in them.
+	var entities []model.Entity
+	if updateRequest.Content != nil {
+		content, parsed, err := h.parseMarkup(ctx, *updateRequest.Content, "", false)
+		if err != nil {
+			return nil, err
+		}
+		updateRequest.Content, entities = &content, parsed
+	}
+
+	message, err := h.Message.UpdateScheduledMessage(ctx, ctx.Value("userId").(string), messageId, updateRequest.Content, entities, updateRequest.SendAt)
+	if err != nil {
+		return nil, scheduledMessageError(ctx, err)
+	}
+	return types.Raw{Data: message}, nil
+}
+
+func (h Handler) HandleCancelScheduledMessage(ctx *gofr.Context) (interface{}, error) {
+	messageId := ctx.PathParam("id")
+	if strings.TrimSpace(messageId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter messageId")
+	}
+
+	blobIds, err := h.Message.CancelScheduledMessage(ctx, ctx.Value("userId").(string), messageId)
+	if err != nil {
+		return nil, scheduledMessageError(ctx, err)
+	}
+
+	for _, blobId := range blobIds {
+		h.deleteBlobs(ctx, blobId)
+	}
+	return nil, nil
+}
+
+// ReleaseScheduledMessages sends the scheduled messages that are due, and records them in the inboxes of their conversations.
+// It is safe to run on several replicas at once.
+func (h Handler) ReleaseScheduledMessages(ctx *gofr.Context) error {
+	for {
+		messages, blobIds, err := h.Message.ReleaseScheduledMessages(ctx, time.Now(), model.ScheduledMessageBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, blobId := range blobIds {
+			h.deleteBlobs(ctx, blobId)
+		}
+
+		// Their conversations were set up when they were scheduled.
+		for _, message := range *messages {
+			err = h.Conversation.RecordMessage(ctx, message)
+			if err != nil {
+				ctx.Logger.Error(err)
+			}
+
+			h.queueLinkPreview(message)
+		}
+
+		if len(*messages) < model.ScheduledMessageBatch {
+			return nil
+		}
+	}
+}
+
+// checkSendAt makes sure a message is scheduled in the future, at most MESSAGE_SCHEDULE_MAX_AHEAD seconds ahead,
+// and moves sendAt to local time like every other timestamp of a message.
+func checkSendAt(ctx *gofr.Context, sendAt *time.Time) error {
+	if sendAt == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if !sendAt.After(now) {
+		return e.HttpStatusError(422, "sendAt has to be in the future")
+	}
+	if sendAt.After(now.Add(SecondsConfig(ctx.Config, "MESSAGE_SCHEDULE_MAX_AHEAD", 365*24*60*60))) {
+		return e.HttpStatusError(422, "sendAt is too far in the future")
+	}
+
+	*sendAt = sendAt.Local()
+	return nil
+}
+
+func scheduledMessageError(ctx *gofr.Context, err error) error {
+	ctx.Logger.Info("err: ", err.Error())
+	if err == sql.ErrNoRows {
+		return e.HttpStatusError(404, "Scheduled message does not exists")
+	}
+	return e.HttpStatusError(500, "")
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/blob"
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/jobs"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// scheduleTCMessageStore records the messages added, and releases the messages given to it along with the blobs of dropped ones.
+type scheduleTCMessageStore struct {
+	successfulTCMessageStore
+	added        *[]model.Message
+	released     []model.Message
+	droppedBlobs []string
+}
+
+func (m scheduleTCMessageStore) AddMessage(ctx *gofr.Context, message model.Message) error {
+	*m.added = append(*m.added, message)
+	return nil
+}
+
+func (m scheduleTCMessageStore) ReleaseScheduledMessages(ctx *gofr.Context, now time.Time, limit uint) (*[]model.Message, []string, error) {
+	return &m.released, m.droppedBlobs, nil
+}
+
+// recordingTCConversationStore records the conversations ensured and the messages recorded in them.
+type recordingTCConversationStore struct {
+	mockConversationStore
+	ensured  *[]string
+	recorded *[]string
+}
+
+func (c recordingTCConversationStore) EnsureDirectConversation(ctx *gofr.Context, userId, peerId string, accepted bool) (string, error) {
+	*c.ensured = append(*c.ensured, store.DirectConversationID(userId, peerId))
+	return store.DirectConversationID(userId, peerId), nil
+}
+
+func (c recordingTCConversationStore) RecordMessage(ctx *gofr.Context, message model.Message) error {
+	*c.recorded = append(*c.recorded, message.ID)
+	return nil
+}
+
+func TestHandleSendScheduledMessage(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc     string
+		sendAt   time.Time
+		maxAhead string
+		err      error
+	}{
+		{desc: "scheduled", sendAt: time.Now().Add(time.Hour)},
+		{desc: "in the past", sendAt: time.Now().Add(-time.Minute), err: e.HttpStatusError(422, "sendAt has to be in the future")},
+		{desc: "too far ahead", sendAt: time.Now().AddDate(2, 0, 0), err: e.HttpStatusError(422, "sendAt is too far in the future")},
+		{desc: "further ahead than configured", sendAt: time.Now().Add(2 * time.Hour), maxAhead: "3600", err: e.HttpStatusError(422, "sendAt is too far in the future")},
+	}
+
+	for _, tc := range testCases {
+		t.Setenv("MESSAGE_SCHEDULE_MAX_AHEAD", tc.maxAhead)
+
+		var added []model.Message
+		var ensured, recorded []string
+		h := Handler{Auth: existingTCAuthStore{}, Message: scheduleTCMessageStore{added: &added}, Friend: mockFriendStore{}, Block: mockBlockStore{},
+			Conversation: recordingTCConversationStore{ensured: &ensured, recorded: &recorded}}
+
+		body := `{"recipientId":"` + testRecipientID + `","content":"Happy birthday!","sendAt":"` + tc.sendAt.Format(time.RFC3339Nano) + `"}`
+		ctx := newTestContext(app, http.MethodPost, "http://dummy", []byte(body))
+
+		result, err := h.HandleSendMessageByID(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			message := result.(types.Raw).Data.(model.Message)
+			assert.True(t, tc.sendAt.Equal(*message.ScheduledAt), "TEST: %s: mismatch in scheduled time", tc.desc)
+			assert.Equal(t, store.DirectConversationID("someUserId", testRecipientID), message.ConversationID, "TEST: %s: mismatch in conversation", tc.desc)
+			assert.Len(t, added, 1, "TEST: %s: expected the message to be stored", tc.desc)
+			assert.Equal(t, []string{message.ConversationID}, ensured, "TEST: %s: expected the conversation to be set up when scheduling", tc.desc)
+			assert.Empty(t, recorded, "TEST: %s: expected the message to stay out of the inbox until it is sent", tc.desc)
+		} else {
+			assert.Empty(t, added, "TEST: %s: expected no message to be stored", tc.desc)
+		}
+	}
+}
+
+func TestHandleGetScheduledMessages(t *testing.T) {
+	app := gofr.New()
+
+	h := Handler{Message: successfulTCMessageStore{}}
+
+	ctx := newTestContext(app, http.MethodGet, "http://dummy?page=2", nil)
+
+	result, err := h.HandleGetScheduledMessages(ctx)
+
+	assert.NoError(t, err, "Unexpected error listing scheduled messages")
+	assert.Equal(t, model.GetMessagesResponse{Page: 2, LastPage: true, Messages: []model.Message{}}, result.(types.Raw).Data, "Mismatch in scheduled messages")
+
+	h = Handler{Message: messageStoreErrorTCMessageStore{}}
+
+	_, err = h.HandleGetScheduledMessages(newTestContext(app, http.MethodGet, "http://dummy", nil))
+
+	assert.Equal(t, e.HttpStatusError(500, ""), err, "Expected an internal error when listing fails")
+}
+
+func TestHandleUpdateScheduledMessage(t *testing.T) {
+	app := gofr.New()
+
+	sendAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	testCases := []struct {
+		desc    string
+		body    string
+		message store.MessageStore
+		err     error
+	}{
+		{"update content", `{"content":"Happy birthday!!"}`, successfulTCMessageStore{}, nil},
+		{"reschedule", `{"sendAt":"` + sendAt + `"}`, successfulTCMessageStore{}, nil},
+		{"nothing to update", `{}`, successfulTCMessageStore{}, e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'UpdateScheduledMessageRequest.SendAt' Error:Field validation for 'SendAt' failed on the 'required_without' tag")},
+		{"empty content", `{"content":""}`, successfulTCMessageStore{}, e.HttpStatusError(400, "Invalid inputs or missing required fields -Key: 'UpdateScheduledMessageRequest.Content' Error:Field validation for 'Content' failed on the 'min' tag")},
+		{"rescheduled into the past", `{"sendAt":"2020-01-01T00:00:00Z"}`, successfulTCMessageStore{}, e.HttpStatusError(422, "sendAt has to be in the future")},
+		{"already sent", `{"content":"Happy birthday!!"}`, errorTCMessageStore{}, e.HttpStatusError(404, "Scheduled message does not exists")},
+		{"update fails", `{"content":"Happy birthday!!"}`, messageStoreErrorTCMessageStore{}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Message: tc.message}
+
+		ctx := newTestContext(app, http.MethodPut, "http://dummy", []byte(tc.body))
+		ctx.SetPathParams(map[string]string{"id": "messageId"})
+
+		_, err := h.HandleUpdateScheduledMessage(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+	}
+}
+
+// cancelTCMessageStore cancels a scheduled message with the given attachments.
+type cancelTCMessageStore struct {
+	successfulTCMessageStore
+	blobIds []string
+}
+
+func (m cancelTCMessageStore) CancelScheduledMessage(ctx *gofr.Context, userId, messageId string) ([]string, error) {
+	return m.blobIds, nil
+}
+
+func TestHandleCancelScheduledMessage(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc    string
+		message store.MessageStore
+		err     error
+	}{
+		{"cancel", successfulTCMessageStore{}, nil},
+		{"already sent", errorTCMessageStore{}, e.HttpStatusError(404, "Scheduled message does not exists")},
+		{"cancel fails", messageStoreErrorTCMessageStore{}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Message: tc.message}
+
+		ctx := newTestContext(app, http.MethodDelete, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"id": "messageId"})
+
+		_, err := h.HandleCancelScheduledMessage(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+	}
+
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating blob store: %v", err)
+	}
+	for _, key := range []string{"attachment-id", thumbnailKey("attachment-id", "small")} {
+		blobs.Put(context.Background(), key, strings.NewReader("content"), "image/png")
+	}
+
+	h := Handler{Message: cancelTCMessageStore{blobIds: []string{"attachment-id"}}, Blobs: blobs}
+
+	ctx := newTestContext(app, http.MethodDelete, "http://dummy", nil)
+	ctx.SetPathParams(map[string]string{"id": "messageId"})
+
+	_, err = h.HandleCancelScheduledMessage(ctx)
+
+	assert.NoError(t, err, "Unexpected error cancelling a message with attachments")
+	for _, key := range []string{"attachment-id", thumbnailKey("attachment-id", "small")} {
+		_, err = blobs.Get(context.Background(), key)
+		assert.Equal(t, blob.ErrNotFound, err, "Expected blob %s of the cancelled message to be deleted", key)
+	}
+}
+
+func TestReleaseScheduledMessages(t *testing.T) {
+	app := gofr.New()
+
+	released := []model.Message{
+		{ID: "message-id-1", From: "someUserId", To: testRecipientID, Type: model.MessageText, Content: "see https://gofr.dev"},
+		{ID: "message-id-2", From: testRecipientID, To: "someUserId", Type: model.MessageText, Content: "no links"},
+	}
+
+	queued := make(chan model.Message, 2)
+	previews := jobs.NewQueue(1, 2, func(message model.Message) {
+		queued <- message
+	})
+
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating blob store: %v", err)
+	}
+	blobs.Put(context.Background(), "attachment-id", strings.NewReader("content"), "image/png")
+
+	var ensured, recorded []string
+	h := Handler{Message: scheduleTCMessageStore{released: released, droppedBlobs: []string{"attachment-id"}}, Friend: mockFriendStore{},
+		Conversation: recordingTCConversationStore{ensured: &ensured, recorded: &recorded}, Previews: previews, Blobs: blobs}
+
+	err = h.ReleaseScheduledMessages(newTestContext(app, http.MethodGet, "http://dummy", nil))
+	previews.Close()
+	close(queued)
+
+	assert.NoError(t, err, "Unexpected error releasing scheduled messages")
+	assert.Empty(t, ensured, "Expected the conversations of the released messages to be set up already")
+	assert.Equal(t, []string{"message-id-1", "message-id-2"}, recorded, "Expected the released messages to be recorded in their conversations")
+	_, err = blobs.Get(context.Background(), "attachment-id")
+	assert.Equal(t, blob.ErrNotFound, err, "Expected the blobs of dropped messages to be deleted")
+
+	previewed := make([]string, 0)
+	for message := range queued {
+		previewed = append(previewed, message.ID)
+	}
+	assert.Equal(t, []string{"message-id-1"}, previewed, "Expected released messages with links to be queued for previews")
+
+	h = Handler{Message: messageStoreErrorTCMessageStore{}}
+
+	err = h.ReleaseScheduledMessages(newTestContext(app, http.MethodGet, "http://dummy", nil))
+
+	assert.Error(t, err, "Expected an error when the release fails")
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/google/uuid"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// HandleSearchMessages searches the messages of the conversations the user is a member of, most relevant first.
+// Results are paginated with the opaque nextCursor of the previous page.
+func (h Handler) HandleSearchMessages(ctx *gofr.Context) (interface{}, error) {
+	query := strings.TrimSpace(ctx.Param("q"))
+	if query == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter q")
+	}
+	if len([]rune(query)) > model.MaxSearchQueryLength {
+		return nil, e.HttpStatusError(400, "Invalid Parameter q")
+	}
+
+	search := model.MessageSearch{UserID: ctx.Value("userId").(string), Query: query, Limit: model.RequestSearchLimit + 1}
+
+	if peerId := strings.TrimSpace(ctx.Param("peerId")); peerId != "" {
+		id, err := uuid.Parse(peerId)
+		if err != nil {
+			return nil, e.HttpStatusError(400, "Invalid Parameter peerId")
+		}
+		search.PeerID = id.String()
+	}
+
+	var err error
+	search.Since, err = dateParam(ctx, "since", false)
+	if err != nil {
+		return nil, e.HttpStatusError(400, "Invalid Parameter since")
+	}
+	search.Until, err = dateParam(ctx, "until", true)
+	if err != nil {
+		return nil, e.HttpStatusError(400, "Invalid Parameter until")
+	}
+	if search.Since != nil && search.Until != nil && !search.Since.Before(*search.Until) {
+		return nil, e.HttpStatusError(400, "Parameter since has to be before until")
+	}
+
+	if cursor := strings.TrimSpace(ctx.Param("cursor")); cursor != "" {
+		search.After, err = decodeSearchCursor(cursor)
+		if err != nil {
+			return nil, e.HttpStatusError(400, "Invalid Parameter cursor")
+		}
+	}
+
+	results, err := h.Search.SearchMessages(ctx, search)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	response := model.SearchMessagesResponse{Results: *results}
+	if len(response.Results) > model.RequestSearchLimit {
+		response.Results = response.Results[:model.RequestSearchLimit]
+		response.NextCursor = encodeSearchCursor(response.Results[model.RequestSearchLimit-1].Cursor())
+	}
+	return types.Raw{Data: response}, nil
+}
+
+// dateParam reads an optional query parameter holding either an RFC 3339 time or a date, in local time
+// like every timestamp of a message. A date stands for the start of that day, or for the end of it when end is set.
+func dateParam(ctx *gofr.Context, name string, end bool) (*time.Time, error) {
+	param := strings.TrimSpace(ctx.Param(name))
+	if param == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		t, err = time.ParseInLocation("2006-01-02", param, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+
+	t = t.Local()
+	return &t, nil
+}
+
+func encodeSearchCursor(cursor model.SearchCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchCursor(cursor string) (*model.SearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded model.SearchCursor
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = uuid.Parse(decoded.ID); err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
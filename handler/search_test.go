@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// mockSearchStore finds results messages, recording the search it was asked for.
+type mockSearchStore struct {
+	results int
+	search  *model.MessageSearch
+}
+
+func (s mockSearchStore) SearchMessages(ctx *gofr.Context, search model.MessageSearch) (*[]model.SearchResult, error) {
+	if search.Query == "fail" {
+		return nil, e.NewError("")
+	}
+	*s.search = search
+
+	results := make([]model.SearchResult, s.results)
+	for i := range results {
+		results[i] = model.SearchResult{Message: model.Message{ID: fmt.Sprintf("3b241101-e2bb-4255-8caf-%012d", i), Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}, Rank: 0.25}
+	}
+	return &results, nil
+}
+
+func TestHandleSearchMessages(t *testing.T) {
+	app := gofr.New()
+
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	until := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	sinceUTC := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).Local()
+	cursorAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cursor := encodeSearchCursor(model.SearchCursor{Rank: 0.25, Timestamp: cursorAt, ID: testRecipientID})
+
+	testCases := []struct {
+		desc     string
+		query    string
+		results  int
+		search   model.MessageSearch
+		returned int
+		more     bool
+		err      error
+	}{
+		{desc: "search", query: "q=tacos", results: 3,
+			search: model.MessageSearch{UserID: "someUserId", Query: "tacos", Limit: model.RequestSearchLimit + 1}, returned: 3},
+		{desc: "more results", query: "q=tacos", results: model.RequestSearchLimit + 1,
+			search: model.MessageSearch{UserID: "someUserId", Query: "tacos", Limit: model.RequestSearchLimit + 1}, returned: model.RequestSearchLimit, more: true},
+		{desc: "filters", query: "q=%22fish+tacos%22&peerId=3B241101-E2BB-4255-8CAF-4136C566A962&since=2024-05-01&until=2024-05-31&cursor=" + cursor,
+			search: model.MessageSearch{UserID: "someUserId", Query: `"fish tacos"`, PeerID: testRecipientID, Since: &since, Until: &until,
+				After: &model.SearchCursor{Rank: 0.25, Timestamp: cursorAt, ID: testRecipientID}, Limit: model.RequestSearchLimit + 1}},
+		{desc: "time with a zone", query: "q=tacos&since=2024-05-01T00:00:00Z",
+			search: model.MessageSearch{UserID: "someUserId", Query: "tacos", Since: &sinceUTC, Limit: model.RequestSearchLimit + 1}},
+		{desc: "missing query", query: "q=+", err: e.HttpStatusError(400, "Missing Parameter q")},
+		{desc: "invalid peer", query: "q=tacos&peerId=123", err: e.HttpStatusError(400, "Invalid Parameter peerId")},
+		{desc: "invalid since", query: "q=tacos&since=yesterday", err: e.HttpStatusError(400, "Invalid Parameter since")},
+		{desc: "empty date range", query: "q=tacos&since=2024-06-01T00:00:00Z&until=2024-05-01", err: e.HttpStatusError(400, "Parameter since has to be before until")},
+		{desc: "invalid cursor", query: "q=tacos&cursor=e30", err: e.HttpStatusError(400, "Invalid Parameter cursor")},
+		{desc: "search fails", query: "q=fail", err: e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		var search model.MessageSearch
+		h := Handler{Search: mockSearchStore{results: tc.results, search: &search}}
+
+		ctx := newTestContext(app, http.MethodGet, "http://dummy?"+tc.query, nil)
+
+		result, err := h.HandleSearchMessages(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			response := result.(types.Raw).Data.(model.SearchMessagesResponse)
+			assert.Equal(t, tc.search, search, "TEST: %s: mismatch in search", tc.desc)
+			assert.Len(t, response.Results, tc.returned, "TEST: %s: unexpected number of results", tc.desc)
+			assert.Equal(t, tc.more, response.NextCursor != "", "TEST: %s: mismatch in next cursor", tc.desc)
+			if tc.more {
+				next, err := decodeSearchCursor(response.NextCursor)
+				assert.NoError(t, err, "TEST: %s: unexpected error decoding the next cursor", tc.desc)
+				assert.Equal(t, response.Results[tc.returned-1].Cursor(), *next, "TEST: %s: next cursor does not point at the last result", tc.desc)
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"encoding/json"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/go-playground/validator"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+func (h Handler) HandleGetSettings(ctx *gofr.Context) (interface{}, error) {
+	settings, err := h.Settings.GetSettings(ctx, ctx.Value("userId").(string))
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+	return types.Raw{Data: settings}, nil
+}
+
+func (h Handler) HandleUpdateSettings(ctx *gofr.Context) (interface{}, error) {
+	var settingsRequest model.Settings
+	err := json.NewDecoder(ctx.Request().Body).Decode(&settingsRequest)
+	err = validator.New().Struct(settingsRequest)
+	if err != nil {
+		ctx.Logger.Error(err)
+		return nil, e.HttpStatusError(400, "Invalid inputs or missing required fields -"+err.Error())
+	}
+
+	settings, err := h.Settings.UpdateSettings(ctx, ctx.Value("userId").(string), settingsRequest)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+	return types.Raw{Data: settings}, nil
+}
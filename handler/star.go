@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"database/sql"
+	"strings"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+// HandleStarMessage bookmarks a message for the user. Stars are private, other members never learn about them.
+func (h Handler) HandleStarMessage(ctx *gofr.Context) (interface{}, error) {
+	messageId := ctx.PathParam("id")
+	if strings.TrimSpace(messageId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter messageId")
+	}
+
+	err := h.Message.StarMessage(ctx, ctx.Value("userId").(string), messageId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		if err == sql.ErrNoRows {
+			return nil, e.HttpStatusError(404, "Message does not exists")
+		} else if err == e.NewError("You are not authorized to see that message") {
+			return nil, e.HttpStatusError(403, err.Error())
+		}
+		return nil, e.HttpStatusError(500, "")
+	}
+	return nil, nil
+}
+
+func (h Handler) HandleUnstarMessage(ctx *gofr.Context) (interface{}, error) {
+	messageId := ctx.PathParam("id")
+	if strings.TrimSpace(messageId) == "" {
+		return nil, e.HttpStatusError(400, "Missing Parameter messageId")
+	}
+
+	err := h.Message.UnstarMessage(ctx, ctx.Value("userId").(string), messageId)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+	return nil, nil
+}
+
+// HandleGetStarredMessages lists the messages the user starred across their conversations, the most recently starred first.
+func (h Handler) HandleGetStarredMessages(ctx *gofr.Context) (interface{}, error) {
+	page, err := pageParam(ctx)
+	if err != nil {
+		return nil, e.HttpStatusError(400, "Invalid Parameter page")
+	}
+
+	messages, err := h.Message.GetStarredMessages(ctx, ctx.Value("userId").(string), page, model.RequestMessageLimit)
+	if err != nil {
+		ctx.Logger.Info("err: ", err.Error())
+		return nil, e.HttpStatusError(500, "")
+	}
+
+	lastPage := false
+	if len(*messages) < model.RequestMessageLimit {
+		lastPage = true
+	}
+	return types.Raw{Data: model.GetMessagesResponse{Page: page, LastPage: lastPage, Messages: *messages}}, nil
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/types"
+)
+
+func TestHandleStarMessage(t *testing.T) {
+	app := gofr.New()
+
+	testCases := []struct {
+		desc      string
+		messageID string
+		store     store.MessageStore
+		err       error
+	}{
+		{"star success", "someMessageId", successfulTCMessageStore{}, nil},
+		{"missing parameter", "", successfulTCMessageStore{}, e.HttpStatusError(400, "Missing Parameter messageId")},
+		{"message not found", "someMessageId", errorTCMessageStore{}, e.HttpStatusError(404, "Message does not exists")},
+		{"authorization error", "someMessageId", authorizationErrorTCMessageStore{}, e.HttpStatusError(403, "You are not authorized to see that message")},
+		{"message store error", "someMessageId", messageStoreErrorTCMessageStore{}, e.HttpStatusError(500, "")},
+	}
+
+	for _, tc := range testCases {
+		h := Handler{Message: tc.store}
+
+		ctx := newTestContext(app, http.MethodPut, "http://dummy", nil)
+		ctx.SetPathParams(map[string]string{"id": tc.messageID})
+
+		_, err := h.HandleStarMessage(ctx)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+	}
+}
+
+func TestHandleGetStarredMessages(t *testing.T) {
+	app := gofr.New()
+
+	h := Handler{Message: successfulTCMessageStore{}}
+	ctx := newTestContext(app, http.MethodGet, "http://dummy/starred?page=2", nil)
+
+	result, err := h.HandleGetStarredMessages(ctx)
+
+	assert.NoError(t, err, "Unexpected error while listing starred messages")
+	assert.Equal(t, model.GetMessagesResponse{Page: 2, LastPage: true, Messages: []model.Message{}}, result.(types.Raw).Data, "Mismatch in starred messages")
+
+	h = Handler{Message: messageStoreErrorTCMessageStore{}}
+	ctx = newTestContext(app, http.MethodGet, "http://dummy/starred", nil)
+
+	_, err = h.HandleGetStarredMessages(ctx)
+
+	assert.Equal(t, e.HttpStatusError(500, ""), err, "Expected an error when the message store fails")
+}
@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"gofr.dev/pkg/gofr"
+)
+
+// Every runs job in the background once per interval for the lifetime of the app.
+// Each run gets a fresh context carrying the app's datastores; failures are logged and retried on the next tick.
+func Every(app *gofr.Gofr, name string, interval time.Duration, job func(ctx *gofr.Context) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			Run(app, name, job)
+		}
+	}()
+}
+
+// Run runs job once, in the calling goroutine, with a fresh context carrying the app's datastores.
+// A failure is logged rather than returned.
+func Run(app *gofr.Gofr, name string, job func(ctx *gofr.Context) error) {
+	ctx := gofr.NewContext(nil, nil, app)
+	ctx.Context = context.Background()
+
+	err := job(ctx)
+	if err != nil {
+		app.Logger.Errorf("job %s failed: %v", name, err)
+	}
+}
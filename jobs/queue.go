@@ -0,0 +1,45 @@
+package jobs
+
+import "sync"
+
+// Queue hands items, such as uploaded attachments or sent messages, to a fixed number of background workers.
+type Queue[T any] struct {
+	items chan T
+	wg    sync.WaitGroup
+}
+
+// NewQueue starts workers that call process for every queued item.
+// At most size items wait in the queue; Enqueue drops the rest.
+func NewQueue[T any](workers, size int, process func(T)) *Queue[T] {
+	q := &Queue[T]{items: make(chan T, size)}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for item := range q.items {
+				process(item)
+			}
+		}()
+	}
+	return q
+}
+
+// Enqueue schedules item for processing without blocking.
+// It reports false when the queue is full or nil, leaving it to the caller what becomes of the item.
+func (q *Queue[T]) Enqueue(item T) bool {
+	if q == nil {
+		return false
+	}
+	select {
+	case q.items <- item:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops accepting items and waits for the queued ones to be processed.
+func (q *Queue[T]) Close() {
+	close(q.items)
+	q.wg.Wait()
+}
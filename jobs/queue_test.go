@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue(t *testing.T) {
+	var mu sync.Mutex
+	var processed []string
+	release := make(chan struct{})
+
+	q := NewQueue(1, 1, func(item string) {
+		<-release
+		mu.Lock()
+		processed = append(processed, item)
+		mu.Unlock()
+	})
+
+	assert.True(t, q.Enqueue("a1"), "Expected the first item to be queued")
+	// Wait for the worker to pick up a1, leaving room for one more.
+	assert.Eventually(t, func() bool { return len(q.items) == 0 }, time.Second, time.Millisecond)
+	assert.True(t, q.Enqueue("a2"), "Expected the second item to be queued")
+	assert.False(t, q.Enqueue("a3"), "Expected a full queue to drop items")
+
+	close(release)
+	q.Close()
+
+	assert.Equal(t, []string{"a1", "a2"}, processed, "Mismatch in processed items")
+
+	var nilQueue *Queue[string]
+	assert.False(t, nilQueue.Enqueue("a4"), "Expected a nil queue to drop items")
+}
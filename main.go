@@ -2,9 +2,15 @@ package main
 
 import (
 	"strconv"
+	"time"
 
+	"github.com/aryanA101a/legoshichat-backend/blob"
 	"github.com/aryanA101a/legoshichat-backend/handler"
+	"github.com/aryanA101a/legoshichat-backend/jobs"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/aryanA101a/legoshichat-backend/realtime"
 	"github.com/aryanA101a/legoshichat-backend/store"
+	"github.com/aryanA101a/legoshichat-backend/unfurl"
 	"gofr.dev/pkg/gofr"
 )
 
@@ -14,20 +20,151 @@ func main() {
 	authStore := store.NewAuthStore(app.DB())
 	messageStore := store.NewMessageStore(app.DB())
 	friendStore := store.NewFriendStore(app.DB())
+	blockStore := store.NewBlockStore(app.DB())
+	contactStore := store.NewContactStore(app.DB(), app.Config.Get("CONTACT_HASH_SALT"))
+	conversationStore := store.NewConversationStore(app.DB())
+	groupStore := store.NewGroupStore(app.DB())
+	channelStore := store.NewChannelStore(app.DB())
+	settingsStore := store.NewSettingsStore(app.DB())
+	presenceStore := store.NewPresenceStore(app.DB())
+	attachmentStore := store.NewAttachmentStore(app.DB())
+	searchStore := store.NewSearchStore(app.DB())
 	authCreator := handler.NewCreator()
-	h := handler.Handler{Auth: authStore, Message: messageStore, Friend: friendStore, AuthCreator: authCreator}
+
+	blobs, err := newBlobStore(app.Config)
+	if err != nil {
+		app.Logger.Fatalf("blob store: %v", err)
+	}
+
+	hub := realtime.NewHub()
+	presenceTracker := realtime.NewPresence(hub, handler.SecondsConfig(app.Config, "PRESENCE_AWAY_AFTER", 300), handler.SecondsConfig(app.Config, "PRESENCE_LAST_SEEN_INTERVAL", 60))
+	typing := realtime.NewTyping(hub, handler.SecondsConfig(app.Config, "TYPING_TIMEOUT", 5))
+	typingLimiter := realtime.NewRateLimiter(float64(handler.IntConfig(app.Config, "TYPING_RATE", 1)), handler.IntConfig(app.Config, "TYPING_BURST", 5))
+
+	h := handler.Handler{Auth: authStore, Message: messageStore, Friend: friendStore, Block: blockStore, Contact: contactStore, Conversation: conversationStore, Group: groupStore, Channel: channelStore,
+		Presence: presenceStore, Settings: settingsStore, Attachment: attachmentStore, Search: searchStore, Blobs: blobs, AuthCreator: authCreator, Hub: hub, PresenceTracker: presenceTracker,
+		Typing: typing, TypingLimiter: typingLimiter}
+	h.Media = jobs.NewQueue(handler.IntConfig(app.Config, "MEDIA_WORKERS", 2), handler.IntConfig(app.Config, "MEDIA_QUEUE_SIZE", 100), func(attachment model.Attachment) {
+		jobs.Run(app, "process attachment", func(ctx *gofr.Context) error {
+			return h.ProcessAttachment(ctx, attachment)
+		})
+	})
+	h.Unfurler = unfurl.NewUnfurler(unfurl.NewClient(handler.SecondsConfig(app.Config, "LINK_PREVIEW_TIMEOUT", 5)),
+		handler.SecondsConfig(app.Config, "LINK_PREVIEW_CACHE_TTL", 60*60), handler.IntConfig(app.Config, "LINK_PREVIEW_CACHE_SIZE", 1000))
+	h.Previews = jobs.NewQueue(handler.IntConfig(app.Config, "LINK_PREVIEW_WORKERS", 2), handler.IntConfig(app.Config, "LINK_PREVIEW_QUEUE_SIZE", 100), func(message model.Message) {
+		jobs.Run(app, "preview links", func(ctx *gofr.Context) error {
+			return h.PreviewLinks(ctx, message)
+		})
+	})
 
 	app.POST("/create-account", h.HandleCreateAccount)
 	app.POST("/login", h.HandleLogin)
 
-	app.GET("/message/{id}", handler.WithJWTAuth(h.HandleGetMessage, authStore))
-	app.PUT("/message/{id}", handler.WithJWTAuth(h.HandlePutMessage, authStore))
-	app.DELETE("/message/{id}", handler.WithJWTAuth(h.HandleDeleteMessage, authStore))
-	app.POST("/message/sendById", handler.WithJWTAuth(h.HandleSendMessageByID, authStore))
-	app.POST("/message/sendByPhoneNumber", handler.WithJWTAuth(h.HandleSendMessageByPhoneNumber, authStore))
-	app.POST("/messages", handler.WithJWTAuth(h.HandleGetMessages, authStore))
+	app.GET("/ws", handler.WithJWTAuth(h.HandleWebSocket, authStore, h))
+
+	app.GET("/message/{id}", handler.WithJWTAuth(h.HandleGetMessage, authStore, h))
+	app.GET("/message/{id}/history", handler.WithJWTAuth(h.HandleGetMessageHistory, authStore, h))
+	app.PUT("/message/{id}", handler.WithJWTAuth(h.HandlePutMessage, authStore, h))
+	app.PUT("/message/{id}/reactions/{emoji}", handler.WithJWTAuth(h.HandleAddReaction, authStore, h))
+	app.DELETE("/message/{id}/reactions/{emoji}", handler.WithJWTAuth(h.HandleRemoveReaction, authStore, h))
+	app.PUT("/message/{id}/star", handler.WithJWTAuth(h.HandleStarMessage, authStore, h))
+	app.DELETE("/message/{id}/star", handler.WithJWTAuth(h.HandleUnstarMessage, authStore, h))
+	app.PUT("/message/{id}/pin", handler.WithJWTAuth(h.HandlePinMessage, authStore, h))
+	app.DELETE("/message/{id}/pin", handler.WithJWTAuth(h.HandleUnpinMessage, authStore, h))
+	app.POST("/message/{id}/votes", handler.WithJWTAuth(h.HandleVote, authStore, h))
+	app.GET("/message/{id}/votes", handler.WithJWTAuth(h.HandleGetPollResults, authStore, h))
+	app.DELETE("/message/{id}", handler.WithJWTAuth(h.HandleDeleteMessage, authStore, h))
+	app.POST("/message/{id}/forward", handler.WithJWTAuth(h.HandleForwardMessage, authStore, h))
+	app.POST("/message/sendById", handler.WithJWTAuth(h.HandleSendMessageByID, authStore, h))
+	app.POST("/message/sendByPhoneNumber", handler.WithJWTAuth(h.HandleSendMessageByPhoneNumber, authStore, h))
+	app.POST("/messages", handler.WithJWTAuth(h.HandleGetMessages, authStore, h))
+	app.GET("/messages/scheduled", handler.WithJWTAuth(h.HandleGetScheduledMessages, authStore, h))
+	app.PUT("/messages/scheduled/{id}", handler.WithJWTAuth(h.HandleUpdateScheduledMessage, authStore, h))
+	app.DELETE("/messages/scheduled/{id}", handler.WithJWTAuth(h.HandleCancelScheduledMessage, authStore, h))
+
+	app.GET("/starred", handler.WithJWTAuth(h.HandleGetStarredMessages, authStore, h))
+
+	app.GET("/search/messages", handler.WithJWTAuth(h.HandleSearchMessages, authStore, h))
+
+	app.POST("/attachments", handler.WithJWTAuth(h.HandleUploadAttachment, authStore, h))
+	app.GET("/attachments/{id}", handler.WithJWTAuth(h.HandleGetAttachment, authStore, h))
+	app.GET("/attachments/{id}/content", h.HandleDownloadAttachment)
+
+	app.GET("/friends", handler.WithJWTAuth(h.GetFriends, authStore, h))
+	app.DELETE("/friends/{userId}", handler.WithJWTAuth(h.HandleRemoveFriend, authStore, h))
+	app.GET("/friends/requests/incoming", handler.WithJWTAuth(h.HandleGetIncomingFriendRequests, authStore, h))
+	app.GET("/friends/requests/outgoing", handler.WithJWTAuth(h.HandleGetOutgoingFriendRequests, authStore, h))
+	app.POST("/friends/requests/{userId}", handler.WithJWTAuth(h.HandleSendFriendRequest, authStore, h))
+	app.DELETE("/friends/requests/{userId}", handler.WithJWTAuth(h.HandleCancelFriendRequest, authStore, h))
+	app.POST("/friends/requests/{userId}/accept", handler.WithJWTAuth(h.HandleAcceptFriendRequest, authStore, h))
+	app.POST("/friends/requests/{userId}/decline", handler.WithJWTAuth(h.HandleDeclineFriendRequest, authStore, h))
+
+	app.POST("/contacts/discover", handler.WithJWTAuth(h.HandleDiscoverContacts, authStore, h))
+
+	app.GET("/blocks", handler.WithJWTAuth(h.HandleGetBlockedUsers, authStore, h))
+	app.POST("/blocks/{userId}", handler.WithJWTAuth(h.HandleBlockUser, authStore, h))
+	app.DELETE("/blocks/{userId}", handler.WithJWTAuth(h.HandleUnblockUser, authStore, h))
+
+	app.GET("/conversations", handler.WithJWTAuth(h.HandleGetConversations, authStore, h))
+	app.GET("/conversations/requests", handler.WithJWTAuth(h.HandleGetMessageRequests, authStore, h))
+	app.POST("/conversations/{id}/accept", handler.WithJWTAuth(h.HandleAcceptMessageRequest, authStore, h))
+	app.POST("/conversations/{id}/read", handler.WithJWTAuth(h.HandleMarkConversationRead, authStore, h))
+	app.GET("/conversations/{id}/pins", handler.WithJWTAuth(h.HandleGetPinnedMessages, authStore, h))
+	app.PUT("/conversations/{id}/disappearing", handler.WithJWTAuth(h.HandleSetDisappearingTimer, authStore, h))
+	app.PUT("/conversations/{id}/draft", handler.WithJWTAuth(h.HandleSaveDraft, authStore, h))
+	app.GET("/conversations/{id}/draft", handler.WithJWTAuth(h.HandleGetDraft, authStore, h))
+	app.DELETE("/conversations/{id}/draft", handler.WithJWTAuth(h.HandleDeleteDraft, authStore, h))
+	app.PUT("/conversations/{id}/mute", handler.WithJWTAuth(h.HandleMuteConversation, authStore, h))
+	app.DELETE("/conversations/{id}/mute", handler.WithJWTAuth(h.HandleUnmuteConversation, authStore, h))
 
-	app.GET("/friends", handler.WithJWTAuth(h.GetFriends, authStore))
+	app.POST("/groups", handler.WithJWTAuth(h.HandleCreateGroup, authStore, h))
+	app.GET("/groups/{id}", handler.WithJWTAuth(h.HandleGetGroup, authStore, h))
+	app.PUT("/groups/{id}", handler.WithJWTAuth(h.HandleRenameGroup, authStore, h))
+	app.POST("/groups/{id}/members", handler.WithJWTAuth(h.HandleAddGroupMembers, authStore, h))
+	app.PUT("/groups/{id}/members/{memberId}", handler.WithJWTAuth(h.HandleUpdateGroupMember, authStore, h))
+	app.DELETE("/groups/{id}/members/{memberId}", handler.WithJWTAuth(h.HandleRemoveGroupMember, authStore, h))
+	app.POST("/groups/{id}/leave", handler.WithJWTAuth(h.HandleLeaveGroup, authStore, h))
+	app.POST("/groups/{id}/messages", handler.WithJWTAuth(h.HandleSendGroupMessage, authStore, h))
+	app.GET("/groups/{id}/messages", handler.WithJWTAuth(h.HandleGetGroupMessages, authStore, h))
+
+	app.POST("/channels", handler.WithJWTAuth(h.HandleCreateChannel, authStore, h))
+	app.GET("/channels/{handle}", handler.WithJWTAuth(h.HandleGetChannel, authStore, h))
+	app.POST("/channels/{handle}/subscribe", handler.WithJWTAuth(h.HandleSubscribeChannel, authStore, h))
+	app.POST("/channels/{handle}/unsubscribe", handler.WithJWTAuth(h.HandleUnsubscribeChannel, authStore, h))
+	app.PUT("/channels/{handle}/subscribers/{subscriberId}", handler.WithJWTAuth(h.HandleUpdateChannelSubscriber, authStore, h))
+	app.POST("/channels/{handle}/messages", handler.WithJWTAuth(h.HandleSendChannelMessage, authStore, h))
+	app.GET("/channels/{handle}/messages", handler.WithJWTAuth(h.HandleGetChannelMessages, authStore, h))
+
+	app.GET("/presence", handler.WithJWTAuth(h.HandleGetPresence, authStore, h))
+
+	app.GET("/settings", handler.WithJWTAuth(h.HandleGetSettings, authStore, h))
+	app.PUT("/settings", handler.WithJWTAuth(h.HandleUpdateSettings, authStore, h))
+
+	jobs.Every(app, "purge deleted messages", time.Hour, h.PurgeDeletedMessages)
+
+	// Typing rate limits outlive connections, so reconnecting does not refill them; idle ones are dropped once refilled.
+	jobs.Every(app, "prune typing limits", time.Minute, func(ctx *gofr.Context) error {
+		typingLimiter.Prune()
+		return nil
+	})
+
+	// Users without connections go offline once they stop making requests, which nothing else notices.
+	jobs.Every(app, "expire presence", time.Minute, h.ExpirePresence)
+
+	jobs.Every(app, "release scheduled messages", handler.SecondsConfig(app.Config, "MESSAGE_SCHEDULER_INTERVAL", 5), h.ReleaseScheduledMessages)
+	jobs.Every(app, "delete expired messages", handler.SecondsConfig(app.Config, "MESSAGE_EXPIRY_SWEEP_INTERVAL", 60), h.DeleteExpiredMessages)
+
+	// Picks up images that were uploaded while the queue was full or the server restarted.
+	jobs.Every(app, "process pending attachments", time.Minute, func(ctx *gofr.Context) error {
+		attachments, err := attachmentStore.GetPendingAttachments(ctx, time.Now().Add(-time.Minute), 100)
+		if err != nil {
+			return err
+		}
+		for _, attachment := range *attachments {
+			h.Media.Enqueue(attachment)
+		}
+		return nil
+	})
 
 	port, err := strconv.Atoi(app.Config.Get("HTTP_PORT"))
 	if err == nil {
@@ -36,3 +173,17 @@ func main() {
 
 	app.Start()
 }
+
+// newBlobStore keeps attachments on the local filesystem unless BLOB_STORE is set to s3.
+func newBlobStore(config gofr.Config) (blob.BlobStore, error) {
+	if config.Get("BLOB_STORE") == "s3" {
+		return blob.NewS3Store(blob.S3Config{
+			Endpoint:  config.Get("S3_ENDPOINT"),
+			Region:    config.Get("S3_REGION"),
+			Bucket:    config.Get("S3_BUCKET"),
+			AccessKey: config.Get("S3_ACCESS_KEY"),
+			SecretKey: config.Get("S3_SECRET_KEY"),
+		}, nil), nil
+	}
+	return blob.NewLocalStore(config.GetOrDefault("BLOB_LOCAL_DIR", "./data/blobs"))
+}
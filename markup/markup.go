@@ -0,0 +1,359 @@
+// Package markup parses the restricted markup messages are written in into plain text and the entities
+// formatting it, and renders formatted text as HTML that is safe to embed in web pages.
+//
+// The markup knows five constructs:
+//
+//	*bold*  _italic_  `code`  [label](https://example.com)  <@user-id>
+//
+// Bold and italic text nest within each other and within the labels of links, while code is taken as written.
+// Mentions of users become @ followed by their name. A backslash escapes the punctuation after it, and markup
+// that is not closed is kept as written.
+package markup
+
+import (
+	"errors"
+	"html"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/google/uuid"
+)
+
+// ErrLink is returned for links to anything other than http and https URLs.
+var ErrLink = errors.New("links must point to http or https URLs")
+
+// Resolver returns the name of a user who may be mentioned, or false if the user may not be.
+type Resolver func(userId string) (string, bool)
+
+// the constructs open at a point of the text, which are not started again within themselves.
+const (
+	inBold = 1 << iota
+	inItalic
+	inLink
+)
+
+type parser struct {
+	in       []rune
+	out      []rune
+	entities []model.Entity
+	mention  Resolver
+}
+
+// Parse returns the plain text written in markup along with the entities formatting it. Users are mentioned
+// under the name mention gives them; mentions of other users, or any mention if mention is nil, are kept as written.
+func Parse(text string, mention Resolver) (string, []model.Entity, error) {
+	p := parser{in: []rune(text), out: make([]rune, 0, len(text)), mention: mention}
+	err := p.parse(0, len(p.in), 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(p.out), p.entities, nil
+}
+
+// Mentions returns the IDs of the users text mentions, each once, in the order they are first mentioned.
+func Mentions(text string) []string {
+	userIds := make([]string, 0)
+	seen := make(map[string]bool)
+	Parse(text, func(userId string) (string, bool) {
+		if !seen[userId] {
+			seen[userId] = true
+			userIds = append(userIds, userId)
+		}
+		return "", false
+	})
+	return userIds
+}
+
+// parse writes in[from:to] to the output, formatting it within the constructs in open.
+func (p *parser) parse(from, to int, open int) error {
+	for i := from; i < to; {
+		r := p.in[i]
+
+		if r == '\\' && i+1 < to && escapable(p.in[i+1]) {
+			p.out = append(p.out, p.in[i+1])
+			i += 2
+			continue
+		}
+
+		if r == '`' {
+			end := p.code(i, to)
+			if end > 0 {
+				p.entities = append(p.entities, model.Entity{Type: model.EntityCode, Offset: len(p.out), Length: end - i - 2})
+				p.out = append(p.out, p.in[i+1:end-1]...)
+				i = end
+				continue
+			}
+		}
+
+		if (r == '*' && open&inBold == 0) || (r == '_' && open&inItalic == 0) {
+			end := p.emphasis(i, to)
+			if end > 0 {
+				kind, flag := model.EntityBold, inBold
+				if r == '_' {
+					kind, flag = model.EntityItalic, inItalic
+				}
+
+				err := p.enclose(model.Entity{Type: kind}, i+1, end-1, open|flag)
+				if err != nil {
+					return err
+				}
+				i = end
+				continue
+			}
+		}
+
+		if r == '[' && open&inLink == 0 {
+			labelEnd, end := p.link(i, to)
+			if end > 0 {
+				link := string(p.in[labelEnd+2 : end-1])
+				if !safeURL(link) {
+					return ErrLink
+				}
+
+				err := p.enclose(model.Entity{Type: model.EntityLink, URL: link}, i+1, labelEnd, open|inLink)
+				if err != nil {
+					return err
+				}
+				i = end
+				continue
+			}
+		}
+
+		if r == '<' && open&inLink == 0 && p.mention != nil {
+			userId, end := p.mentioned(i, to)
+			if end > 0 {
+				name, ok := p.mention(userId)
+				if ok {
+					text := []rune("@" + name)
+					p.entities = append(p.entities, model.Entity{Type: model.EntityMention, Offset: len(p.out), Length: len(text), UserID: userId})
+					p.out = append(p.out, text...)
+					i = end
+					continue
+				}
+			}
+		}
+
+		p.out = append(p.out, r)
+		i++
+	}
+	return nil
+}
+
+// enclose formats in[from:to] as entity, recording it ahead of the entities within it.
+func (p *parser) enclose(entity model.Entity, from, to int, open int) error {
+	index := len(p.entities)
+	entity.Offset = len(p.out)
+	p.entities = append(p.entities, entity)
+
+	err := p.parse(from, to, open)
+	p.entities[index].Length = len(p.out) - entity.Offset
+	return err
+}
+
+// code returns the end of the code span starting at start, or 0 if it is not closed before to or empty.
+func (p *parser) code(start, to int) int {
+	for i := start + 1; i < to; i++ {
+		if p.in[i] == '`' {
+			if i == start+1 {
+				return 0
+			}
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// emphasis returns the end of the bold or italic text starting at start, or 0 if it is not closed before to.
+// Delimiters only open and close at the edges of words, so snake_case and 2*3*4 stay as written.
+func (p *parser) emphasis(start, to int) int {
+	delimiter := p.in[start]
+	if start+1 >= to || unicode.IsSpace(p.in[start+1]) || (start > 0 && wordRune(p.in[start-1])) {
+		return 0
+	}
+
+	for i := start + 1; i < to; i++ {
+		switch r := p.in[i]; {
+		case r == '\\' && i+1 < to && escapable(p.in[i+1]):
+			i++
+		case r == '`':
+			if end := p.code(i, to); end > 0 {
+				i = end - 1
+			}
+		case r == '[':
+			if _, end := p.link(i, to); end > 0 {
+				i = end - 1
+			}
+		case r == delimiter && i > start+1 && !unicode.IsSpace(p.in[i-1]) && (i+1 == len(p.in) || !wordRune(p.in[i+1])):
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// link returns the end of the label and the end of the link starting at start, or 0s if there is none before to.
+// The label is followed by the URL in parentheses, which holds no spaces.
+func (p *parser) link(start, to int) (int, int) {
+	labelEnd := -1
+	for i := start + 1; i < to && labelEnd < 0; i++ {
+		switch p.in[i] {
+		case '\\':
+			i++
+		case '[', '\n':
+			return 0, 0
+		case ']':
+			labelEnd = i
+		}
+	}
+	if labelEnd <= start+1 || labelEnd+1 >= to || p.in[labelEnd+1] != '(' {
+		return 0, 0
+	}
+
+	for i := labelEnd + 2; i < to; i++ {
+		switch r := p.in[i]; {
+		case r == ')':
+			if i == labelEnd+2 {
+				return 0, 0
+			}
+			return labelEnd, i + 1
+		case unicode.IsSpace(r):
+			return 0, 0
+		}
+	}
+	return 0, 0
+}
+
+// mentioned returns the ID of the user mentioned at start in canonical form and the end of the mention,
+// or a 0 end if there is none before to.
+func (p *parser) mentioned(start, to int) (string, int) {
+	if start+1 >= to || p.in[start+1] != '@' {
+		return "", 0
+	}
+
+	for i := start + 2; i < to && i-start < 40; i++ {
+		if p.in[i] == '>' {
+			id, err := uuid.Parse(string(p.in[start+2 : i]))
+			if err != nil {
+				return "", 0
+			}
+			return id.String(), i + 1
+		}
+	}
+	return "", 0
+}
+
+// HTML renders text formatted by entities as HTML. The text is escaped, line breaks become <br>, entities that are
+// malformed or overlap others without enclosing them are left out, and only http and https URLs are linked to,
+// so the result is safe to embed in a page whatever entities it is given.
+func HTML(text string, entities []model.Entity) string {
+	runes := []rune(text)
+
+	sorted := append([]model.Entity(nil), entities...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Offset != sorted[j].Offset {
+			return sorted[i].Offset < sorted[j].Offset
+		}
+		return sorted[i].Length > sorted[j].Length
+	})
+
+	starts := make(map[int][]model.Entity)
+	enclosing := make([]int, 0)
+	for _, entity := range sorted {
+		end := entity.Offset + entity.Length
+		if entity.Offset < 0 || entity.Length <= 0 || end > len(runes) || openingTag(entity) == "" {
+			continue
+		}
+
+		for len(enclosing) > 0 && enclosing[len(enclosing)-1] <= entity.Offset {
+			enclosing = enclosing[:len(enclosing)-1]
+		}
+		if len(enclosing) > 0 && enclosing[len(enclosing)-1] < end {
+			continue
+		}
+
+		enclosing = append(enclosing, end)
+		starts[entity.Offset] = append(starts[entity.Offset], entity)
+	}
+
+	var rendered strings.Builder
+	type openEntity struct {
+		end int
+		tag string
+	}
+	stack := make([]openEntity, 0)
+	for i := 0; i <= len(runes); i++ {
+		for len(stack) > 0 && stack[len(stack)-1].end == i {
+			rendered.WriteString(stack[len(stack)-1].tag)
+			stack = stack[:len(stack)-1]
+		}
+		if i == len(runes) {
+			break
+		}
+
+		for _, entity := range starts[i] {
+			rendered.WriteString(openingTag(entity))
+			stack = append(stack, openEntity{end: entity.Offset + entity.Length, tag: closingTag(entity.Type)})
+		}
+
+		if runes[i] == '\n' {
+			rendered.WriteString("<br>")
+		} else {
+			rendered.WriteString(html.EscapeString(string(runes[i])))
+		}
+	}
+	return rendered.String()
+}
+
+// openingTag returns the tag an entity opens with, or nothing for entities that are not rendered.
+func openingTag(entity model.Entity) string {
+	switch entity.Type {
+	case model.EntityBold:
+		return "<strong>"
+	case model.EntityItalic:
+		return "<em>"
+	case model.EntityCode:
+		return "<code>"
+	case model.EntityLink:
+		if !safeURL(entity.URL) {
+			return ""
+		}
+		return `<a href="` + html.EscapeString(entity.URL) + `" rel="nofollow noopener noreferrer" target="_blank">`
+	case model.EntityMention:
+		return `<span class="mention" data-user-id="` + html.EscapeString(entity.UserID) + `">`
+	}
+	return ""
+}
+
+func closingTag(kind string) string {
+	switch kind {
+	case model.EntityBold:
+		return "</strong>"
+	case model.EntityItalic:
+		return "</em>"
+	case model.EntityCode:
+		return "</code>"
+	case model.EntityLink:
+		return "</a>"
+	}
+	return "</span>"
+}
+
+// safeURL tells whether link is an absolute http or https URL.
+func safeURL(link string) bool {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// escapable tells whether a backslash escapes r, which is any ASCII punctuation.
+func escapable(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsPunct(r) || unicode.IsSymbol(r))
+}
+
+func wordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,103 @@
+package markup
+
+import (
+	"testing"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const testUserID = "3b241101-e2bb-4255-8caf-4136c566a962"
+
+func testResolver(userId string) (string, bool) {
+	if userId == testUserID {
+		return "Legoshi", true
+	}
+	return "", false
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		text     string
+		entities []model.Entity
+		err      error
+	}{
+		{desc: "plain", input: "Hello there", text: "Hello there"},
+		{desc: "bold", input: "a *big* deal", text: "a big deal",
+			entities: []model.Entity{{Type: model.EntityBold, Offset: 2, Length: 3}}},
+		{desc: "italic in bold", input: "*very _much_ so*", text: "very much so",
+			entities: []model.Entity{{Type: model.EntityBold, Offset: 0, Length: 12}, {Type: model.EntityItalic, Offset: 5, Length: 4}}},
+		{desc: "code is literal", input: "run `*go* test`", text: "run *go* test",
+			entities: []model.Entity{{Type: model.EntityCode, Offset: 4, Length: 9}}},
+		{desc: "link", input: "see [the _docs_](https://gofr.dev/docs?a=1)", text: "see the docs",
+			entities: []model.Entity{{Type: model.EntityLink, Offset: 4, Length: 8, URL: "https://gofr.dev/docs?a=1"}, {Type: model.EntityItalic, Offset: 8, Length: 4}}},
+		{desc: "mention", input: "hi <@" + testUserID + ">!", text: "hi @Legoshi!",
+			entities: []model.Entity{{Type: model.EntityMention, Offset: 3, Length: 8, UserID: testUserID}}},
+		{desc: "mention in upper case", input: "<@3B241101-E2BB-4255-8CAF-4136C566A962>", text: "@Legoshi",
+			entities: []model.Entity{{Type: model.EntityMention, Offset: 0, Length: 8, UserID: testUserID}}},
+		{desc: "mention of someone else", input: "<@00000000-0000-4000-8000-000000000000>", text: "<@00000000-0000-4000-8000-000000000000>"},
+		{desc: "offsets count characters", input: "🐺 *Légoshi*", text: "🐺 Légoshi",
+			entities: []model.Entity{{Type: model.EntityBold, Offset: 2, Length: 7}}},
+		{desc: "escapes", input: `\*not bold\* and a \\`, text: `*not bold* and a \`},
+		{desc: "unclosed markup", input: "*bold and [label](", text: "*bold and [label]("},
+		{desc: "delimiters inside words", input: "snake_case_name and 2*3*4", text: "snake_case_name and 2*3*4"},
+		{desc: "delimiters around spaces", input: "* not bold *", text: "* not bold *"},
+		{desc: "empty markup", input: "** `` []()", text: "** `` []()"},
+		{desc: "unsafe link", input: "[click](javascript:alert(1))", err: ErrLink},
+		{desc: "relative link", input: "[click](/etc/passwd)", err: ErrLink},
+	}
+
+	for _, tc := range testCases {
+		text, entities, err := Parse(tc.input, testResolver)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		assert.Equal(t, tc.text, text, "TEST: %s: mismatch in text", tc.desc)
+		assert.Equal(t, tc.entities, entities, "TEST: %s: mismatch in entities", tc.desc)
+	}
+}
+
+func TestParseWithoutMentions(t *testing.T) {
+	text, entities, err := Parse("hi <@"+testUserID+">", nil)
+
+	assert.NoError(t, err, "Unexpected error")
+	assert.Equal(t, "hi <@"+testUserID+">", text, "Expected mentions to be kept as written")
+	assert.Empty(t, entities, "Expected no entities")
+}
+
+func TestMentions(t *testing.T) {
+	other := "00000000-0000-4000-8000-000000000000"
+	input := "<@" + testUserID + "> and <@" + other + ">, again <@" + testUserID + "> but not `<@" + other + ">` or [<@" + other + ">](https://a.b)"
+
+	assert.Equal(t, []string{testUserID, other}, Mentions(input), "Mismatch in mentioned users")
+}
+
+func TestHTML(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		text     string
+		entities []model.Entity
+		html     string
+	}{
+		{"plain text is escaped", "<b>1 & 2</b>\nnext", nil, "&lt;b&gt;1 &amp; 2&lt;/b&gt;<br>next"},
+		{"nested entities", "very much so",
+			[]model.Entity{{Type: model.EntityBold, Offset: 0, Length: 12}, {Type: model.EntityItalic, Offset: 5, Length: 4}},
+			"<strong>very <em>much</em> so</strong>"},
+		{"link", "the docs", []model.Entity{{Type: model.EntityLink, Offset: 4, Length: 4, URL: `https://gofr.dev/?q="x"`}},
+			`the <a href="https://gofr.dev/?q=&#34;x&#34;" rel="nofollow noopener noreferrer" target="_blank">docs</a>`},
+		{"mention", "hi @Legoshi", []model.Entity{{Type: model.EntityMention, Offset: 3, Length: 8, UserID: testUserID}},
+			`hi <span class="mention" data-user-id="` + testUserID + `">@Legoshi</span>`},
+		{"unsafe link", "click", []model.Entity{{Type: model.EntityLink, Offset: 0, Length: 5, URL: "javascript:alert(1)"}}, "click"},
+		{"overlapping entities", "abcdef",
+			[]model.Entity{{Type: model.EntityBold, Offset: 0, Length: 4}, {Type: model.EntityItalic, Offset: 2, Length: 4}},
+			"<strong>abcd</strong>ef"},
+		{"entities out of range", "abc",
+			[]model.Entity{{Type: model.EntityBold, Offset: 2, Length: 5}, {Type: model.EntityCode, Offset: -1, Length: 2}, {Type: "script", Offset: 0, Length: 1}},
+			"abc"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.html, HTML(tc.text, tc.entities), "TEST: %s: mismatch in HTML", tc.desc)
+	}
+}
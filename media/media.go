@@ -0,0 +1,216 @@
+// Package media prepares uploaded images for display: it strips privacy sensitive
+// metadata, records dimensions, renders thumbnails and computes a BlurHash placeholder.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	_ "image/gif"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MaxPixels bounds the images that are decoded, so a small file cannot expand into gigabytes of pixels.
+	MaxPixels = 50_000_000
+
+	blurHashSize     = 32
+	blurHashX        = 4
+	blurHashY        = 3
+	jpegQuality      = 90
+	thumbnailQuality = 80
+)
+
+// ThumbnailSize is a named bound on the longest side of a thumbnail.
+type ThumbnailSize struct {
+	Name string
+	Max  int
+}
+
+// ThumbnailSizes are rendered for every image, smallest first.
+var ThumbnailSizes = []ThumbnailSize{{Name: "small", Max: 96}, {Name: "medium", Max: 320}, {Name: "large", Max: 1024}}
+
+var (
+	ErrUnsupported = errors.New("unsupported image type")
+	ErrTooLarge    = errors.New("image has too many pixels")
+)
+
+var supported = map[string]bool{"image/jpeg": true, "image/png": true, "image/gif": true, "image/webp": true}
+
+// Supported reports whether images of contentType can be processed.
+func Supported(contentType string) bool {
+	return supported[contentType]
+}
+
+type Thumbnail struct {
+	Name        string
+	Width       int
+	Height      int
+	ContentType string
+	Data        []byte
+}
+
+type Image struct {
+	// Original replaces the uploaded file when stripping its metadata changed it, and is nil otherwise.
+	Original   []byte
+	Width      int
+	Height     int
+	BlurHash   string
+	Thumbnails []Thumbnail
+}
+
+// Process strips the metadata from an uploaded image and renders its thumbnails and BlurHash.
+// JPEGs that rely on an EXIF orientation are rotated and re-encoded, as the tag goes with the rest of the EXIF data.
+func Process(data []byte, contentType string) (*Image, error) {
+	if !Supported(contentType) {
+		return nil, ErrUnsupported
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	stripped, orientation := data, 1
+	switch contentType {
+	case "image/jpeg":
+		stripped, orientation, err = stripJPEG(data)
+	case "image/png":
+		stripped, err = stripPNG(data)
+	case "image/webp":
+		stripped, err = stripWebP(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Image{}
+	if orientation != 1 {
+		img = orient(img, orientation)
+		var original bytes.Buffer
+		if err := jpeg.Encode(&original, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		result.Original = original.Bytes()
+	} else if !bytes.Equal(stripped, data) {
+		result.Original = stripped
+	}
+
+	bounds := img.Bounds()
+	result.Width, result.Height = bounds.Dx(), bounds.Dy()
+
+	longest := result.Width
+	if result.Height > longest {
+		longest = result.Height
+	}
+	for i, size := range ThumbnailSizes {
+		// Never upscale, and only render the sizes that are smaller than the image itself.
+		if i > 0 && size.Max >= longest {
+			break
+		}
+		thumbnail, err := renderThumbnail(img, size)
+		if err != nil {
+			return nil, err
+		}
+		result.Thumbnails = append(result.Thumbnails, *thumbnail)
+	}
+
+	result.BlurHash = blurHash(scale(img, blurHashSize, draw.ApproxBiLinear), blurHashX, blurHashY)
+	return result, nil
+}
+
+func renderThumbnail(img image.Image, size ThumbnailSize) (*Thumbnail, error) {
+	scaled := scale(img, size.Max, draw.CatmullRom)
+	thumbnail := &Thumbnail{Name: size.Name, Width: scaled.Bounds().Dx(), Height: scaled.Bounds().Dy()}
+
+	var encoded bytes.Buffer
+	var err error
+	if scaled.Opaque() {
+		thumbnail.ContentType = "image/jpeg"
+		err = jpeg.Encode(&encoded, scaled, &jpeg.Options{Quality: thumbnailQuality})
+	} else {
+		thumbnail.ContentType = "image/png"
+		err = png.Encode(&encoded, scaled)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnail.Data = encoded.Bytes()
+	return thumbnail, nil
+}
+
+// scale fits img within max pixels on its longest side, keeping the aspect ratio.
+func scale(img image.Image, max int, scaler draw.Scaler) *image.NRGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > max || height > max {
+		if width >= height {
+			width, height = max, height*max/width
+		} else {
+			width, height = width*max/height, max
+		}
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	scaled := image.NewNRGBA(image.Rect(0, 0, width, height))
+	scaler.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+	return scaled
+}
+
+// orient applies an EXIF orientation, returning the image as it is meant to be displayed.
+func orient(img image.Image, orientation int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	oriented := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = width-1-x, y
+			case 3:
+				sx, sy = width-1-x, height-1-y
+			case 4:
+				sx, sy = x, height-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, height-1-x
+			case 7:
+				sx, sy = width-1-y, height-1-x
+			case 8:
+				sx, sy = width-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			oriented.Set(x, y, color.NRGBAModel.Convert(img.At(bounds.Min.X+sx, bounds.Min.Y+sy)))
+		}
+	}
+	return oriented
+}
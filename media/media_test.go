@@ -0,0 +1,195 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newImage is a width by height image, red in its top left quarter and blue elsewhere.
+func newImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.RGBA{B: 255, A: 255}}, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, width/2, height/2), &image.Uniform{C: color.RGBA{R: 255, A: 255}}, image.Point{}, draw.Src)
+	return img
+}
+
+// exifSegment is an APP1 segment with the given orientation and a GPS tag carrying marker.
+func exifSegment(orientation uint16, marker string) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 2)
+	tiff = append(tiff, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0x00, 0x00)
+	tiff = append(tiff, 0x88, 0x25, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x26)
+	tiff = append(tiff, marker...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image, segments ...[]byte) []byte {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, nil); err != nil {
+		t.Fatalf("Error encoding JPEG: %v", err)
+	}
+	data := encoded.Bytes()
+
+	withSegments := append([]byte(nil), data[:2]...)
+	for _, segment := range segments {
+		withSegments = append(withSegments, segment...)
+	}
+	return append(withSegments, data[2:]...)
+}
+
+func pngChunk(kind string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, kind...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func TestStripJPEG(t *testing.T) {
+	comment := append([]byte{0xFF, 0xFE, 0x00, 0x0A}, "comment!"...)
+	data := encodeJPEG(t, newImage(40, 20), exifSegment(6, "GPSLatitude"), comment)
+
+	stripped, orientation, err := stripJPEG(data)
+
+	assert.NoError(t, err, "Unexpected error while stripping a JPEG")
+	assert.Equal(t, 6, orientation, "Mismatch in orientation")
+	assert.NotContains(t, string(stripped), "GPSLatitude", "Expected the EXIF data to be removed")
+	assert.NotContains(t, string(stripped), "comment!", "Expected the comment to be removed")
+
+	decoded, err := jpeg.Decode(bytes.NewReader(stripped))
+	assert.NoError(t, err, "Expected the stripped JPEG to decode")
+	assert.Equal(t, image.Rect(0, 0, 40, 20), decoded.Bounds(), "Mismatch in bounds of the stripped JPEG")
+
+	plain := encodeJPEG(t, newImage(40, 20))
+	stripped, orientation, err = stripJPEG(plain)
+	assert.NoError(t, err, "Unexpected error while stripping a JPEG without metadata")
+	assert.Equal(t, 1, orientation, "Expected no orientation")
+	assert.Equal(t, plain, stripped, "Expected a JPEG without metadata to be left alone")
+
+	_, _, err = stripJPEG(data[:30])
+	assert.Error(t, err, "Expected a truncated JPEG to be refused")
+}
+
+func TestStripPNG(t *testing.T) {
+	var encoded bytes.Buffer
+	png.Encode(&encoded, newImage(10, 10))
+	data := encoded.Bytes()
+
+	// Metadata chunks go right after the IHDR chunk, which is 25 bytes long.
+	header := len(pngSignature) + 25
+	withText := append(append(append([]byte(nil), data[:header]...), pngChunk("tEXt", []byte("Author\x00Someone"))...), data[header:]...)
+	withText = append(withText[:len(withText)-12], append(pngChunk("eXIf", []byte("GPS")), withText[len(withText)-12:]...)...)
+
+	stripped, err := stripPNG(withText)
+
+	assert.NoError(t, err, "Unexpected error while stripping a PNG")
+	assert.Equal(t, data, stripped, "Expected only the metadata chunks to be removed")
+
+	_, err = stripPNG([]byte("not a png"))
+	assert.Error(t, err, "Expected something other than a PNG to be refused")
+}
+
+func TestStripWebP(t *testing.T) {
+	chunk := func(kind string, data []byte) []byte {
+		c := append([]byte(kind), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+		c = append(c, data...)
+		if len(data)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	riff := func(chunks ...[]byte) []byte {
+		body := []byte("WEBP")
+		for _, c := range chunks {
+			body = append(body, c...)
+		}
+		return append(append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...), body...)
+	}
+
+	vp8x := []byte{webpEXIFFlag | webpXMPFlag | 0x10, 0, 0, 0, 9, 0, 0, 9, 0, 0}
+	bitstream := chunk("VP8L", []byte("pixels"))
+	data := riff(chunk("VP8X", vp8x), bitstream, chunk("EXIF", []byte("GPS data")), chunk("XMP ", []byte("<xmp/>!")))
+
+	stripped, err := stripWebP(data)
+
+	assert.NoError(t, err, "Unexpected error while stripping a WebP")
+	assert.Equal(t, riff(chunk("VP8X", []byte{0x10, 0, 0, 0, 9, 0, 0, 9, 0, 0}), bitstream), stripped, "Expected the metadata chunks and their flags to be removed")
+
+	_, err = stripWebP(data[:len(data)-3])
+	assert.Error(t, err, "Expected a truncated WebP to be refused")
+}
+
+func TestProcess(t *testing.T) {
+	rotated := encodeJPEG(t, newImage(400, 200), exifSegment(6, "GPSLatitude"))
+
+	result, err := Process(rotated, "image/jpeg")
+
+	assert.NoError(t, err, "Unexpected error while processing a JPEG")
+	assert.Equal(t, 200, result.Width, "Expected the width after rotation")
+	assert.Equal(t, 400, result.Height, "Expected the height after rotation")
+	assert.NotContains(t, string(result.Original), "GPSLatitude", "Expected the EXIF data to be removed")
+	assert.Len(t, result.BlurHash, 28, "Mismatch in BlurHash length")
+	assert.Len(t, result.Thumbnails, 2, "Expected the sizes smaller than the image")
+
+	original, err := jpeg.Decode(bytes.NewReader(result.Original))
+	assert.NoError(t, err, "Expected the rotated original to decode")
+	assert.Equal(t, image.Rect(0, 0, 200, 400), original.Bounds(), "Mismatch in bounds of the rotated original")
+	// The red quarter moves from the top left to the top right when turned clockwise.
+	r, _, b, _ := original.At(150, 50).RGBA()
+	assert.True(t, r > b, "Expected the red quarter in the top right")
+
+	thumbnail, err := jpeg.Decode(bytes.NewReader(result.Thumbnails[0].Data))
+	assert.NoError(t, err, "Expected the thumbnail to decode")
+	assert.Equal(t, image.Rect(0, 0, 48, 96), thumbnail.Bounds(), "Mismatch in bounds of the thumbnail")
+	assert.Equal(t, Thumbnail{Name: "medium", Width: 160, Height: 320, ContentType: "image/jpeg"},
+		Thumbnail{Name: result.Thumbnails[1].Name, Width: result.Thumbnails[1].Width, Height: result.Thumbnails[1].Height, ContentType: result.Thumbnails[1].ContentType},
+		"Mismatch in medium thumbnail")
+
+	plain := encodeJPEG(t, newImage(50, 50))
+	result, err = Process(plain, "image/jpeg")
+	assert.NoError(t, err, "Unexpected error while processing a small JPEG")
+	assert.Nil(t, result.Original, "Expected an image without metadata to be kept as uploaded")
+	assert.Len(t, result.Thumbnails, 1, "Expected a single thumbnail for a small image")
+	assert.Equal(t, 50, result.Thumbnails[0].Width, "Expected small images not to be upscaled")
+
+	transparent := image.NewNRGBA(image.Rect(0, 0, 20, 20))
+	var encoded bytes.Buffer
+	png.Encode(&encoded, transparent)
+	result, err = Process(encoded.Bytes(), "image/png")
+	assert.NoError(t, err, "Unexpected error while processing a PNG")
+	assert.Equal(t, "image/png", result.Thumbnails[0].ContentType, "Expected transparent thumbnails to be PNGs")
+
+	_, err = Process(plain, "application/pdf")
+	assert.Equal(t, ErrUnsupported, err, "Expected other types to be refused")
+
+	huge := append([]byte(nil), pngSignature...)
+	ihdr := binary.BigEndian.AppendUint32(nil, 10000)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, 10000)
+	ihdr = append(ihdr, 8, 2, 0, 0, 0)
+	huge = append(huge, pngChunk("IHDR", ihdr)...)
+	_, err = Process(huge, "image/png")
+	assert.Equal(t, ErrTooLarge, err, "Expected an image with too many pixels to be refused")
+}
+
+func TestBlurHash(t *testing.T) {
+	white := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(white, white.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+
+	assert.Equal(t, "LfTSUA~qfQ~q~qt7fQt7fQfQfQfQ", blurHash(white, 4, 3), "Mismatch in BlurHash of a white image")
+	assert.Len(t, blurHash(newImage(32, 16), 4, 3), 28, "Mismatch in BlurHash length")
+	assert.NotEqual(t, blurHash(newImage(32, 16), 4, 3), blurHash(white, 4, 3), "Expected different images to hash differently")
+}
@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// AttachmentTypes are the media types files may be uploaded as, identified by their content rather than their name.
+var AttachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"video/mp4":       true,
+	"audio/mpeg":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+const (
+	AttachmentPending = "pending"
+	AttachmentReady   = "ready"
+	AttachmentFailed  = "failed"
+)
+
+// Attachment is an uploaded file. It belongs to its uploader until it is sent with a message,
+// from then on it is available to both participants of the conversation.
+type Attachment struct {
+	ID          string    `json:"id"`
+	OwnerID     string    `json:"ownerId"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// Status is pending while an image is being processed, and ready once its metadata and thumbnails are available.
+	Status     string      `json:"status"`
+	Width      int         `json:"width,omitempty"`
+	Height     int         `json:"height,omitempty"`
+	BlurHash   string      `json:"blurhash,omitempty"`
+	Thumbnails []Thumbnail `json:"thumbnails,omitempty"`
+	// BlobID is the attachment whose file and thumbnails this one shares; the ID itself unless it was forwarded.
+	BlobID string `json:"-"`
+}
+
+// Thumbnail is a scaled down copy of an image attachment, named after its size.
+type Thumbnail struct {
+	Size        string `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ContentType string `json:"contentType"`
+}
+
+type AttachmentURLResponse struct {
+	Attachment Attachment `json:"attachment"`
+	URL        string     `json:"url"`
+	// ThumbnailURLs are signed like URL, keyed by thumbnail size.
+	ThumbnailURLs map[string]string `json:"thumbnailUrls,omitempty"`
+	ExpiresAt     time.Time         `json:"expiresAt"`
+}
@@ -0,0 +1,8 @@
+package model
+
+import "time"
+
+type BlockedUser struct {
+	User      User      `json:"user"`
+	BlockedAt time.Time `json:"blockedAt"`
+}
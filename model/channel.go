@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Channel is a broadcast conversation: its admins post and anyone may subscribe to read along.
+// Role is the role of the user asking, empty unless they are subscribed.
+type Channel struct {
+	ID              string    `json:"id"`
+	Handle          string    `json:"handle"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description,omitempty"`
+	CreatedBy       string    `json:"createdBy"`
+	CreatedAt       time.Time `json:"createdAt"`
+	SubscriberCount uint      `json:"subscriberCount"`
+	Subscribed      bool      `json:"subscribed"`
+	Role            string    `json:"role,omitempty"`
+}
+
+type CreateChannelRequest struct {
+	Handle      string `json:"handle" validate:"required,min=3,max=32,alphanum"`
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description,omitempty" validate:"max=500"`
+}
+
+type UpdateSubscriberRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin subscriber"`
+}
+
+type SendChannelMessageRequest struct {
+	Content       string   `json:"content" validate:"required_without=AttachmentIDs"`
+	ReplyToID     string   `json:"replyToId,omitempty"`
+	AttachmentIDs []string `json:"attachmentIds,omitempty" validate:"max=10,unique"`
+}
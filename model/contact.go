@@ -0,0 +1,7 @@
+package model
+
+// DiscoverContactsRequest carries the hashes of the phone numbers in a user's phone book. A hash is the
+// lowercase hex SHA-256 of the configured salt followed by the phone number in decimal, or a prefix of it.
+type DiscoverContactsRequest struct {
+	Hashes []string `json:"hashes" validate:"required,min=1,max=500,dive,hexadecimal,min=10,max=64"`
+}
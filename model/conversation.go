@@ -0,0 +1,118 @@
+package model
+
+import "time"
+
+const RequestConversationLimit = 20
+
+const (
+	ConversationDirect  = "direct"
+	ConversationGroup   = "group"
+	ConversationChannel = "channel"
+)
+
+const (
+	RoleAdmin      = "admin"
+	RoleMember     = "member"
+	RoleSubscriber = "subscriber"
+)
+
+// Conversation is an entry in the user's inbox. One-to-one conversations are direct conversations
+// between their two members, identified by Peer; groups and channels are identified by their Name,
+// channels also by their Handle.
+type Conversation struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	Name          string    `json:"name,omitempty"`
+	Handle        string    `json:"handle,omitempty"`
+	Peer          *User     `json:"peer,omitempty"`
+	LastMessage   *Message  `json:"lastMessage"`
+	LastMessageAt time.Time `json:"lastMessageAt"`
+	UnreadCount   uint      `json:"unreadCount"`
+
+	DisappearingTimer *DisappearingTimer `json:"disappearingTimer,omitempty"`
+	Draft             *Draft             `json:"draft,omitempty"`
+	Muted             bool               `json:"muted"`
+	MutedUntil        *time.Time         `json:"mutedUntil,omitempty"`
+}
+
+// Draft is the unsent message the user is composing in a conversation, kept for every device of the user.
+// When devices disagree the draft updated last wins.
+type Draft struct {
+	ConversationID string    `json:"conversationId"`
+	Content        string    `json:"content"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// SaveDraftRequest saves a draft as of UpdatedAt, the time it was last edited on the device, which defaults to now.
+type SaveDraftRequest struct {
+	Content   string     `json:"content" validate:"required"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// MuteRequest mutes a conversation until Until, or until it is unmuted if not set.
+type MuteRequest struct {
+	Until *time.Time `json:"until,omitempty"`
+}
+
+const (
+	DisappearFromSent = "sent"
+	DisappearFromRead = "read"
+)
+
+// DisappearingTimer makes the messages of a conversation delete themselves After seconds, counted from the time
+// they are sent or the time they are first read by a recipient. Timers apply to messages sent after they are set.
+type DisappearingTimer struct {
+	After uint   `json:"after"`
+	From  string `json:"from"`
+}
+
+// SetDisappearingTimerRequest sets the disappearing timer of a conversation; an After of 0 turns it off.
+type SetDisappearingTimerRequest struct {
+	After uint   `json:"after" validate:"omitempty,min=30,max=7776000"`
+	From  string `json:"from" validate:"required_with=After,omitempty,oneof=sent read"`
+}
+
+type GetConversationsResponse struct {
+	Page          uint           `json:"page"`
+	LastPage      bool           `json:"lastPage"`
+	Conversations []Conversation `json:"conversations"`
+}
+
+type Group struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	CreatedBy string        `json:"createdBy"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Members   []GroupMember `json:"members"`
+}
+
+type GroupMember struct {
+	User     User      `json:"user"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+type CreateGroupRequest struct {
+	Name      string   `json:"name" validate:"required,max=100"`
+	MemberIDs []string `json:"memberIds" validate:"unique,dive,uuid"`
+}
+
+type RenameGroupRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type AddGroupMembersRequest struct {
+	MemberIDs []string `json:"memberIds" validate:"required,min=1,unique,dive,uuid"`
+}
+
+type UpdateGroupMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin member"`
+}
+
+type SendGroupMessageRequest struct {
+	Content       string          `json:"content" validate:"required_without_all=AttachmentIDs Payload"`
+	Type          string          `json:"type,omitempty" validate:"omitempty,oneof=text location contact poll"`
+	Payload       *MessagePayload `json:"payload,omitempty"`
+	ReplyToID     string          `json:"replyToId,omitempty"`
+	AttachmentIDs []string        `json:"attachmentIds,omitempty" validate:"max=10,unique"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import "encoding/json"
+
+const (
+	EventPresenceChanged = "presence.changed"
+	EventTypingStart     = "typing.start"
+	EventTypingStop      = "typing.stop"
+	EventReactionAdded   = "reaction.added"
+	EventReactionRemoved = "reaction.removed"
+	EventMessagePinned   = "message.pinned"
+	EventMessageUnpinned = "message.unpinned"
+	EventDraftUpdated    = "draft.updated"
+	EventDraftDeleted    = "draft.deleted"
+	EventPollUpdated     = "poll.updated"
+	EventMentioned       = "message.mentioned"
+	EventLinkPreview     = "message.preview"
+)
+
+// Event is the envelope of everything pushed to clients over the real-time connection.
+type Event struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// ClientEvent is the envelope of everything clients send over the real-time connection.
+type ClientEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// TypingRequest is the data of typing events sent by clients.
+type TypingRequest struct {
+	To string `json:"to" validate:"required"`
+}
+
+// TypingEvent is the data of typing events relayed to clients.
+type TypingEvent struct {
+	From string `json:"from"`
+}
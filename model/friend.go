@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+const (
+	FriendRequestPending  = "pending"
+	FriendRequestAccepted = "accepted"
+	FriendRequestDeclined = "declined"
+	FriendRequestCanceled = "canceled"
+)
+
+// FriendRequest is a request to become friends, seen by one of its parties. User is the other party:
+// the sender of an incoming request, or the recipient of an outgoing one.
+type FriendRequest struct {
+	User      User      `json:"user"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,27 @@
+package model
+
+const (
+	EntityBold    = "bold"
+	EntityItalic  = "italic"
+	EntityCode    = "code"
+	EntityLink    = "link"
+	EntityMention = "mention"
+)
+
+// Entity formats a span of the content of a message, Length characters from Offset, counted in Unicode code points.
+// Links carry the URL they point to, and mentions the ID of the user they mention. Entities are ordered by Offset,
+// enclosing entities first, and never overlap without one enclosing the other.
+type Entity struct {
+	Type   string `json:"type"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	URL    string `json:"url,omitempty"`
+	UserID string `json:"userId,omitempty"`
+}
+
+// MentionEvent is the data of mention events pushed to the users mentioned in a message, whether or not they muted
+// its conversation.
+type MentionEvent struct {
+	ConversationID string         `json:"conversationId"`
+	Message        MessagePreview `json:"message"`
+}
@@ -4,17 +4,52 @@ import "time"
 
 const RequestMessageLimit=5
 
+// ScheduledMessageBatch is the number of due scheduled messages released at once.
+const ScheduledMessageBatch = 100
+
+// ReplySnippetLength is the number of characters of a quoted message shown in the preview of a reply.
+const ReplySnippetLength = 100
+
+const (
+	DeleteForEveryone = "everyone"
+	DeleteForMe       = "me"
+)
+
+// SendMessageByIDRequest sends a message right away, or schedules it for delivery at SendAt.
+// Messages of a Type other than text carry a Payload of that type instead of content.
 type SendMessageByIDRequest struct {
-	Content     string `json:"content" validate:"required,min=1"`
-	RecipientID string `json:"recipientId" validate:"required"`
+	Content       string          `json:"content" validate:"required_without_all=AttachmentIDs Payload"`
+	Type          string          `json:"type,omitempty" validate:"omitempty,oneof=text location contact poll"`
+	Payload       *MessagePayload `json:"payload,omitempty"`
+	RecipientID   string          `json:"recipientId" validate:"required"`
+	ReplyToID     string          `json:"replyToId,omitempty"`
+	AttachmentIDs []string        `json:"attachmentIds,omitempty" validate:"max=10,unique"`
+	SendAt        *time.Time      `json:"sendAt,omitempty"`
 }
 type SendMessageByPhoneNumberRequest struct {
-	Content     string `json:"content" validate:"required,min=1"`
-	RecipientPhoneNumber uint64 `json:"recipientPhoneNumber" validate:"required,min=1000000000,max=9999999999"`
+	Content              string          `json:"content" validate:"required_without_all=AttachmentIDs Payload"`
+	Type                 string          `json:"type,omitempty" validate:"omitempty,oneof=text location contact poll"`
+	Payload              *MessagePayload `json:"payload,omitempty"`
+	RecipientPhoneNumber uint64          `json:"recipientPhoneNumber" validate:"required,min=1000000000,max=9999999999"`
+	ReplyToID            string          `json:"replyToId,omitempty"`
+	AttachmentIDs        []string        `json:"attachmentIds,omitempty" validate:"max=10,unique"`
+	SendAt               *time.Time      `json:"sendAt,omitempty"`
 }
+// ForwardMessageRequest forwards a message to each of RecipientIDs.
+type ForwardMessageRequest struct {
+	RecipientIDs []string `json:"recipientIds" validate:"required,min=1,max=10,unique"`
+}
+
 type UpdateMessageRequest struct {
 	Content     string `json:"content" validate:"required,min=1"`
 }
+
+// UpdateScheduledMessageRequest changes the content of a scheduled message, the time it is sent at, or both.
+type UpdateScheduledMessageRequest struct {
+	Content *string    `json:"content,omitempty" validate:"omitempty,min=1"`
+	SendAt  *time.Time `json:"sendAt,omitempty" validate:"required_without=Content"`
+}
+
 type GetMessagesRequest struct {
 	Page uint `json:"page" validate:"required"`
 	SenderID string `json:"senderId" validate:"required"`
@@ -27,10 +62,81 @@ type GetMessagesResponse struct {
 	Messages []Message `json:"messages" validate:"required"`
 }
 
+// Message is sent to a conversation. To is the recipient of messages in direct conversations and empty in groups.
+// A scheduled message waits, seen by its sender only, until ScheduledAt; it is then sent with that timestamp.
+// A disappearing message is deleted DisappearAfter seconds after it is sent or read, at ExpiresAt once that is known.
+// A forwarded message carries the content of the message it was forwarded from; ForwardCount counts the forwards
+// along the chain that led to it. Messages of a Type other than text carry their Payload, which their Content renders.
+// Text messages are written in markup, which is stored as plain Content and the Entities formatting it; HTML renders
+// the two for web clients. LinkPreview is attached to text messages with links once the linked page was fetched.
 type Message struct {
-	ID        string    `json:"id"`
-	Content   string    `json:"content"`
-	From      string    `json:"from"`
-	To        string    `json:"to"`
-	Timestamp time.Time `json:"timestamp"`
+	ID             string          `json:"id"`
+	ConversationID string          `json:"conversationId"`
+	Type           string          `json:"type"`
+	Content        string          `json:"content"`
+	Entities       []Entity        `json:"entities,omitempty"`
+	HTML           string          `json:"html,omitempty"`
+	LinkPreview    *LinkPreview    `json:"linkPreview,omitempty"`
+	Payload        *MessagePayload `json:"payload,omitempty"`
+	From           string          `json:"from"`
+	To             string          `json:"to,omitempty"`
+	Timestamp      time.Time       `json:"timestamp"`
+	Deleted        bool            `json:"deleted"`
+	DeletedAt      *time.Time      `json:"deletedAt,omitempty"`
+	EditedAt       *time.Time      `json:"editedAt,omitempty"`
+	EditCount      uint            `json:"editCount"`
+	ReplyTo        *MessagePreview `json:"replyTo,omitempty"`
+	Reactions      []Reaction      `json:"reactions,omitempty"`
+	Attachments    []Attachment    `json:"attachments,omitempty"`
+	ScheduledAt    *time.Time      `json:"scheduledAt,omitempty"`
+	DisappearAfter uint            `json:"disappearAfter,omitempty"`
+	ExpiresAt      *time.Time      `json:"expiresAt,omitempty"`
+	System         *SystemEvent    `json:"system,omitempty"`
+	Forwarded      bool            `json:"forwarded"`
+	ForwardCount   uint            `json:"forwardCount,omitempty"`
+}
+
+const SystemDisappearingTimerChanged = "disappearing_timer_changed"
+
+// SystemEvent marks a message the server posted on behalf of its sender to record a change to the conversation.
+// Its content describes the change for display.
+type SystemEvent struct {
+	Event             string             `json:"event"`
+	DisappearingTimer *DisappearingTimer `json:"disappearingTimer,omitempty"`
+}
+
+// MessagePreview is the compact form of a quoted message embedded in its replies.
+// A quoted message that was deleted for everyone shows as a tombstone without author or snippet.
+type MessagePreview struct {
+	ID      string `json:"id"`
+	From    string `json:"from,omitempty"`
+	Snippet string `json:"snippet"`
+	Deleted bool   `json:"deleted"`
+}
+
+// Preview returns the preview of the message shown in replies to it.
+func (m Message) Preview() MessagePreview {
+	if m.Deleted {
+		return MessagePreview{ID: m.ID, Deleted: true}
+	}
+
+	snippet := []rune(m.Content)
+	if len(snippet) > ReplySnippetLength {
+		snippet = snippet[:ReplySnippetLength]
+	}
+	return MessagePreview{ID: m.ID, From: m.From, Snippet: string(snippet)}
+}
+
+// MessageRevision is a version of a message that was replaced by an edit.
+// Version 0 is the content the message was sent with.
+type MessageRevision struct {
+	Version    uint      `json:"version"`
+	Content    string    `json:"content"`
+	WrittenAt  time.Time `json:"writtenAt"`
+	ReplacedAt time.Time `json:"replacedAt"`
+}
+
+type MessageHistory struct {
+	Message   Message           `json:"message"`
+	Revisions []MessageRevision `json:"revisions"`
 }
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+const (
+	MessageText     = "text"
+	MessageLocation = "location"
+	MessageContact  = "contact"
+	MessagePoll     = "poll"
+)
+
+// MessagePayload is the structured content of a message of a type other than text. Exactly the field named
+// by the type of the message is set. The content of such a message is a plain-text rendering of its payload
+// for clients that do not know the type.
+type MessagePayload struct {
+	Location *Location    `json:"location,omitempty"`
+	Contact  *ContactCard `json:"contact,omitempty"`
+	Poll     *Poll        `json:"poll,omitempty"`
+}
+
+// Location is a point on the map, with the radius in meters it is accurate to, if known.
+type Location struct {
+	Latitude  *float64 `json:"latitude" validate:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" validate:"required,min=-180,max=180"`
+	Accuracy  float64  `json:"accuracy,omitempty" validate:"min=0,max=100000"`
+	Name      string   `json:"name,omitempty" validate:"max=200"`
+}
+
+// ContactCard shares a contact: a user of the app by UserID, or anyone else as a vCard, but not both.
+type ContactCard struct {
+	UserID string `json:"userId,omitempty" validate:"required_without=VCard,omitempty,uuid"`
+	VCard  string `json:"vcard,omitempty" validate:"required_without=UserID,omitempty,startswith=BEGIN:VCARD,max=16384"`
+	Name   string `json:"name,omitempty" validate:"max=200"`
+}
+
+// Poll asks the members of a conversation a question with a choice of Options. Members may pick one option,
+// or several if MultiSelect; nobody may vote after ClosesAt, if set. The results of an Anonymous poll do not
+// tell who voted for what.
+type Poll struct {
+	Question    string     `json:"question" validate:"required,max=300"`
+	Options     []string   `json:"options" validate:"required,min=2,max=12,unique,dive,required,max=100"`
+	MultiSelect bool       `json:"multiSelect"`
+	Anonymous   bool       `json:"anonymous"`
+	ClosesAt    *time.Time `json:"closesAt,omitempty"`
+}
+
+// Closed tells whether the poll no longer takes votes at the given time.
+func (p Poll) Closed(now time.Time) bool {
+	return p.ClosesAt != nil && !p.ClosesAt.After(now)
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// PinnedMessage is a message pinned to its conversation, seen by every member.
+type PinnedMessage struct {
+	Message  Message   `json:"message"`
+	PinnedBy string    `json:"pinnedBy"`
+	PinnedAt time.Time `json:"pinnedAt"`
+}
+
+// PinEvent is the data of pin events pushed to the participants of a conversation.
+type PinEvent struct {
+	MessageID      string `json:"messageId"`
+	ConversationID string `json:"conversationId"`
+	By             string `json:"by"`
+}
@@ -0,0 +1,25 @@
+package model
+
+// VoteRequest casts the vote of the user in a poll for the options at the given indexes, replacing any earlier vote.
+type VoteRequest struct {
+	Options []int `json:"options" validate:"required,min=1,max=12,unique,dive,min=0"`
+}
+
+// PollResults are the tallies of a poll. MyVotes are the options the user voted for, left out of the results
+// pushed to every member.
+type PollResults struct {
+	MessageID      string      `json:"messageId"`
+	ConversationID string      `json:"conversationId"`
+	Options        []PollTally `json:"options"`
+	Voters         uint        `json:"voters"`
+	Closed         bool        `json:"closed"`
+	MyVotes        []int       `json:"myVotes,omitempty"`
+}
+
+// PollTally counts the votes for an option of a poll. Unless the poll is anonymous, it also names their voters.
+type PollTally struct {
+	Option   int      `json:"option"`
+	Text     string   `json:"text"`
+	Count    uint     `json:"count"`
+	VoterIDs []string `json:"voterIds,omitempty"`
+}
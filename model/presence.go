@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+const RequestPresenceLimit = 100
+
+type PresenceStatus string
+
+const (
+	PresenceOnline  PresenceStatus = "online"
+	PresenceAway    PresenceStatus = "away"
+	PresenceOffline PresenceStatus = "offline"
+)
+
+// Presence is only ever shared with friends, so last-seen is either shared with all of them or with none.
+const (
+	LastSeenFriends = "friends"
+	LastSeenNobody  = "nobody"
+)
+
+type Presence struct {
+	UserID   string         `json:"userId"`
+	Status   PresenceStatus `json:"status"`
+	LastSeen *time.Time     `json:"lastSeen,omitempty"`
+}
+
+// PresenceRecord is the persisted part of a user's presence along with the privacy setting that guards it.
+type PresenceRecord struct {
+	UserID             string
+	LastSeen           *time.Time
+	LastSeenVisibility string
+}
+
+// Settings are the privacy settings of a user. An update leaves the settings it omits as they were,
+// but has to change at least one of them.
+type Settings struct {
+	LastSeenVisibility *string `json:"lastSeenVisibility" validate:"omitempty,oneof=friends nobody"`
+	Discoverable       *bool   `json:"discoverable" validate:"required_without=LastSeenVisibility"`
+}
@@ -0,0 +1,19 @@
+package model
+
+// LinkPreview summarizes the page a link in a message points to, as described by its OpenGraph or Twitter card
+// metadata. The server fetches it after the message is sent; clients fetch the image themselves.
+type LinkPreview struct {
+	URL         string `json:"url"`
+	SiteName    string `json:"siteName,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"imageUrl,omitempty"`
+}
+
+// LinkPreviewEvent is the data of preview events pushed to the participants of a conversation once the preview of a
+// link in one of its messages is ready.
+type LinkPreviewEvent struct {
+	MessageID      string      `json:"messageId"`
+	ConversationID string      `json:"conversationId"`
+	Preview        LinkPreview `json:"preview"`
+}
@@ -0,0 +1,15 @@
+package model
+
+// Reaction is the aggregate of every reaction made with an emoji on a message.
+type Reaction struct {
+	Emoji string `json:"emoji"`
+	Count uint   `json:"count"`
+	Me    bool   `json:"me"`
+}
+
+// ReactionEvent is the data of reaction events pushed to the participants of a conversation.
+type ReactionEvent struct {
+	MessageID string `json:"messageId"`
+	From      string `json:"from"`
+	Emoji     string `json:"emoji"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// RequestSearchLimit is the number of search results returned per page.
+const RequestSearchLimit = 20
+
+// MaxSearchQueryLength is the longest search query accepted, in characters.
+const MaxSearchQueryLength = 256
+
+// MessageSearch describes a search of the messages of the conversations a user is a member of.
+// PeerID narrows it down to the direct conversation with that user, and Since and Until to the messages sent in [Since, Until).
+type MessageSearch struct {
+	UserID string
+	Query  string
+	PeerID string
+	Since  *time.Time
+	Until  *time.Time
+	After  *SearchCursor
+	Limit  uint
+}
+
+// SearchCursor points at the last result of a page, so that the next page starts right after it.
+type SearchCursor struct {
+	Rank      float32   `json:"r"`
+	Timestamp time.Time `json:"t"`
+	ID        string    `json:"i"`
+}
+
+// SearchResult is a message matching a search. The snippet is HTML escaped, with the matching words wrapped in <mark> tags.
+type SearchResult struct {
+	Message Message `json:"message"`
+	Snippet string  `json:"snippet"`
+	Rank    float32 `json:"rank"`
+}
+
+// Cursor returns the cursor of the page following the result.
+func (r SearchResult) Cursor() SearchCursor {
+	return SearchCursor{Rank: r.Rank, Timestamp: r.Message.Timestamp, ID: r.Message.ID}
+}
+
+type SearchMessagesResponse struct {
+	Results    []SearchResult `json:"results"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
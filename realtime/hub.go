@@ -0,0 +1,149 @@
+package realtime
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	sendBufferSize = 32
+)
+
+// Client is a single real-time connection of a user. A user may hold several at once, one per device.
+type Client struct {
+	UserID string
+	conn   *websocket.Conn
+	send   chan []byte
+}
+
+// Hub keeps track of the live connections of every user and fans events out to them.
+// A nil Hub can be published to and asked who is connected, and behaves as if nobody is.
+// Connections can only be registered on a Hub made by NewHub.
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[string]map[*Client]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{clients: make(map[string]map[*Client]struct{})}
+}
+
+// Register adds a connection for the user and starts writing events to it.
+// It reports whether this is the user's only live connection.
+func (h *Hub) Register(userId string, conn *websocket.Conn) (*Client, bool) {
+	client := &Client{UserID: userId, conn: conn, send: make(chan []byte, sendBufferSize)}
+
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	h.mu.Lock()
+	if h.clients[userId] == nil {
+		h.clients[userId] = make(map[*Client]struct{})
+	}
+	h.clients[userId][client] = struct{}{}
+	first := len(h.clients[userId]) == 1
+	h.mu.Unlock()
+
+	go client.writePump()
+	return client, first
+}
+
+// Unregister removes a connection and stops writing to it.
+// It reports whether the user has no live connections left.
+func (h *Hub) Unregister(client *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients, ok := h.clients[client.UserID]
+	if !ok {
+		return true
+	}
+	if _, ok := clients[client]; ok {
+		delete(clients, client)
+		close(client.send)
+	}
+	if len(clients) == 0 {
+		delete(h.clients, client.UserID)
+		return true
+	}
+	return false
+}
+
+// Publish delivers an event to every live connection of the user. Connections that cannot keep up drop the event.
+func (h *Hub) Publish(userId string, event model.Event) {
+	if h == nil {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients[userId] {
+		select {
+		case client.send <- payload:
+		default:
+		}
+	}
+}
+
+func (h *Hub) Connected(userId string) bool {
+	if h == nil {
+		return false
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.clients[userId]) > 0
+}
+
+// ReadEvent blocks until the client sends the next event. Any error means the connection is gone.
+func (c *Client) ReadEvent() (*model.ClientEvent, error) {
+	var event model.ClientEvent
+	err := c.conn.ReadJSON(&event)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case payload, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package realtime
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestConnection connects a websocket client to a test server and returns both ends of the connection.
+func newTestConnection(t *testing.T) (server *websocket.Conn, client *websocket.Conn) {
+	serverConns := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Error upgrading connection: %v", err)
+			return
+		}
+		serverConns <- conn
+	}))
+	t.Cleanup(ts.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Error dialing test server: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return <-serverConns, client
+}
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub()
+
+	serverConn, clientConn := newTestConnection(t)
+
+	client, first := hub.Register("user-1", serverConn)
+	assert.True(t, first, "Expected the first connection of a user to be reported")
+	assert.True(t, hub.Connected("user-1"), "Expected the user to be connected")
+	assert.False(t, hub.Connected("user-2"), "Expected an unknown user not to be connected")
+
+	hub.Publish("user-1", model.Event{Type: model.EventPresenceChanged, Data: model.Presence{UserID: "user-2", Status: model.PresenceOnline}})
+	hub.Publish("user-2", model.Event{Type: model.EventPresenceChanged, Data: "not for user-1"})
+
+	var event struct {
+		Type string         `json:"type"`
+		Data model.Presence `json:"data"`
+	}
+	clientConn.SetReadDeadline(time.Now().Add(time.Second))
+	err := clientConn.ReadJSON(&event)
+
+	assert.NoError(t, err, "Unexpected error reading published event")
+	assert.Equal(t, model.EventPresenceChanged, event.Type, "Mismatch in event type")
+	assert.Equal(t, "user-2", event.Data.UserID, "Mismatch in event data")
+
+	last := hub.Unregister(client)
+	assert.True(t, last, "Expected the last connection of a user to be reported")
+	assert.False(t, hub.Connected("user-1"), "Expected the user to be disconnected")
+}
+
+func TestHubMultipleConnections(t *testing.T) {
+	hub := NewHub()
+
+	serverConn1, _ := newTestConnection(t)
+	serverConn2, _ := newTestConnection(t)
+
+	client1, first := hub.Register("user-1", serverConn1)
+	assert.True(t, first, "Expected the first connection to be reported")
+
+	client2, first := hub.Register("user-1", serverConn2)
+	assert.False(t, first, "Expected the second connection not to be reported as first")
+
+	assert.False(t, hub.Unregister(client1), "Expected a connection to remain")
+	assert.True(t, hub.Connected("user-1"), "Expected the user to still be connected")
+	assert.True(t, hub.Unregister(client2), "Expected no connections to remain")
+}
+
+func TestNilHub(t *testing.T) {
+	var hub *Hub
+
+	assert.False(t, hub.Connected("user-1"), "Expected nobody to be connected to a nil hub")
+	assert.NotPanics(t, func() { hub.Publish("user-1", model.Event{}) }, "Expected publishing to a nil hub to be a no-op")
+}
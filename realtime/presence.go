@@ -0,0 +1,115 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+)
+
+// Presence derives a user's online state from their live connections on the Hub and
+// from the authenticated requests they have made recently.
+// A nil Presence is valid and reports everybody as offline.
+type Presence struct {
+	hub          *Hub
+	awayAfter    time.Duration
+	persistEvery time.Duration
+	now          func() time.Time
+
+	mu          sync.Mutex
+	lastActive  map[string]time.Time
+	lastPersist map[string]time.Time
+}
+
+// NewPresence creates a tracker in which a user without live connections stays away for awayAfter
+// since their last request, and asks for last-seen to be persisted at most once per persistEvery.
+func NewPresence(hub *Hub, awayAfter, persistEvery time.Duration) *Presence {
+	return &Presence{
+		hub:          hub,
+		awayAfter:    awayAfter,
+		persistEvery: persistEvery,
+		now:          time.Now,
+		lastActive:   make(map[string]time.Time),
+		lastPersist:  make(map[string]time.Time),
+	}
+}
+
+// Touch records activity of the user. It reports whether the user's status changed because of it
+// and whether their last-seen is due to be persisted.
+func (p *Presence) Touch(userId string) (changed, persist bool) {
+	if p == nil {
+		return false, false
+	}
+
+	before := p.Status(userId)
+	now := p.now()
+
+	p.mu.Lock()
+	p.lastActive[userId] = now
+	if now.Sub(p.lastPersist[userId]) >= p.persistEvery {
+		p.lastPersist[userId] = now
+		persist = true
+	}
+	p.mu.Unlock()
+
+	return before != p.Status(userId), persist
+}
+
+// Forget drops the recorded activity of the user, typically once their last connection closes.
+func (p *Presence) Forget(userId string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.lastActive, userId)
+	delete(p.lastPersist, userId)
+}
+
+func (p *Presence) Status(userId string) model.PresenceStatus {
+	if p == nil {
+		return model.PresenceOffline
+	}
+	if p.hub.Connected(userId) {
+		return model.PresenceOnline
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lastActive, ok := p.lastActive[userId]
+	if ok && p.now().Sub(lastActive) < p.awayAfter {
+		return model.PresenceAway
+	}
+	return model.PresenceOffline
+}
+
+// Expire drops the recorded activity of users who have been inactive for awayAfter, so that it does not pile up.
+// It returns when those without live connections were last active, as they just went from away to offline.
+func (p *Presence) Expire() map[string]time.Time {
+	if p == nil {
+		return nil
+	}
+
+	now := p.now()
+	expired := make(map[string]time.Time)
+
+	p.mu.Lock()
+	for userId, lastActive := range p.lastActive {
+		if now.Sub(lastActive) >= p.awayAfter {
+			delete(p.lastActive, userId)
+			delete(p.lastPersist, userId)
+			expired[userId] = lastActive
+		}
+	}
+	p.mu.Unlock()
+
+	for userId := range expired {
+		if p.hub.Connected(userId) {
+			delete(expired, userId)
+		}
+	}
+	return expired
+}
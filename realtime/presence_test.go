@@ -0,0 +1,88 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresenceStatus(t *testing.T) {
+	now := time.Now()
+	presence := NewPresence(NewHub(), 5*time.Minute, time.Minute)
+	presence.now = func() time.Time { return now }
+
+	assert.Equal(t, model.PresenceOffline, presence.Status("user-1"), "Expected an unknown user to be offline")
+
+	changed, persist := presence.Touch("user-1")
+	assert.True(t, changed, "Expected the first request to change the status")
+	assert.True(t, persist, "Expected the first request to persist last-seen")
+	assert.Equal(t, model.PresenceAway, presence.Status("user-1"), "Expected a recently active user without connections to be away")
+
+	now = now.Add(30 * time.Second)
+	changed, persist = presence.Touch("user-1")
+	assert.False(t, changed, "Expected a repeated request not to change the status")
+	assert.False(t, persist, "Expected last-seen persistence to be throttled")
+
+	now = now.Add(time.Minute)
+	_, persist = presence.Touch("user-1")
+	assert.True(t, persist, "Expected last-seen to be persisted once the interval passed")
+
+	now = now.Add(6 * time.Minute)
+	assert.Equal(t, model.PresenceOffline, presence.Status("user-1"), "Expected an inactive user to be offline")
+
+	presence.Touch("user-1")
+	presence.Forget("user-1")
+	assert.Equal(t, model.PresenceOffline, presence.Status("user-1"), "Expected a forgotten user to be offline")
+}
+
+func TestPresenceExpire(t *testing.T) {
+	now := time.Now()
+	hub := NewHub()
+	presence := NewPresence(hub, 5*time.Minute, time.Minute)
+	presence.now = func() time.Time { return now }
+
+	serverConn, _ := newTestConnection(t)
+	hub.Register("user-2", serverConn)
+
+	presence.Touch("user-1")
+	presence.Touch("user-2")
+	lastActive := now
+
+	now = now.Add(4 * time.Minute)
+	presence.Touch("user-3")
+
+	assert.Empty(t, presence.Expire(), "Expected recently active users to be kept")
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, map[string]time.Time{"user-1": lastActive}, presence.Expire(), "Expected only inactive users without connections to go offline")
+	assert.Len(t, presence.lastActive, 1, "Expected the activity of inactive users to be dropped")
+	assert.Len(t, presence.lastPersist, 1, "Expected the activity of inactive users to be dropped")
+	assert.Equal(t, model.PresenceAway, presence.Status("user-3"), "Expected recently active users to stay away")
+
+	var nilPresence *Presence
+	assert.Nil(t, nilPresence.Expire(), "Expected a nil tracker to expire nobody")
+}
+
+func TestPresenceOnline(t *testing.T) {
+	hub := NewHub()
+	presence := NewPresence(hub, 5*time.Minute, time.Minute)
+
+	serverConn, _ := newTestConnection(t)
+	client, _ := hub.Register("user-1", serverConn)
+
+	assert.Equal(t, model.PresenceOnline, presence.Status("user-1"), "Expected a connected user to be online")
+
+	hub.Unregister(client)
+	assert.Equal(t, model.PresenceOffline, presence.Status("user-1"), "Expected a disconnected user to be offline")
+}
+
+func TestNilPresence(t *testing.T) {
+	var presence *Presence
+
+	changed, persist := presence.Touch("user-1")
+	assert.False(t, changed, "Expected a nil tracker to never report changes")
+	assert.False(t, persist, "Expected a nil tracker to never ask for persistence")
+	assert.Equal(t, model.PresenceOffline, presence.Status("user-1"), "Expected everybody to be offline for a nil tracker")
+}
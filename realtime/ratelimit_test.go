@@ -0,0 +1,38 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(1, 2)
+	limiter.now = func() time.Time { return now }
+
+	assert.True(t, limiter.Allow("user-1"), "Expected the burst to be allowed")
+	assert.True(t, limiter.Allow("user-1"), "Expected the burst to be allowed")
+	assert.False(t, limiter.Allow("user-1"), "Expected events beyond the burst to be limited")
+	assert.True(t, limiter.Allow("user-2"), "Expected keys to be limited independently")
+
+	now = now.Add(time.Second)
+	assert.True(t, limiter.Allow("user-1"), "Expected a token to be regained after a second")
+	assert.False(t, limiter.Allow("user-1"), "Expected a single token to be regained after a second")
+
+	now = now.Add(time.Hour)
+	assert.True(t, limiter.Allow("user-1"), "Expected tokens to be regained")
+	assert.True(t, limiter.Allow("user-1"), "Expected tokens to be regained")
+	assert.False(t, limiter.Allow("user-1"), "Expected regained tokens to be capped at the burst")
+
+	limiter.Allow("user-1")
+	limiter.Prune()
+	assert.Len(t, limiter.buckets, 1, "Expected only the bucket that has not refilled to be kept")
+	assert.False(t, limiter.Allow("user-1"), "Expected pruning not to refill a bucket")
+
+	now = now.Add(2 * time.Second)
+	limiter.Prune()
+	assert.Len(t, limiter.buckets, 0, "Expected refilled buckets to be dropped")
+	assert.True(t, limiter.Allow("user-1"), "Expected a dropped key to start with a full bucket")
+}
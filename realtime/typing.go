@@ -0,0 +1,98 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+)
+
+type typingKey struct {
+	from string
+	to   string
+}
+
+type typingSignal struct {
+	timer *time.Timer
+}
+
+// Typing relays "is typing" signals between users. Nothing is persisted: a signal that is not
+// refreshed within the timeout is stopped on the sender's behalf.
+type Typing struct {
+	hub     *Hub
+	timeout time.Duration
+
+	mu     sync.Mutex
+	timers map[typingKey]*typingSignal
+}
+
+func NewTyping(hub *Hub, timeout time.Duration) *Typing {
+	return &Typing{hub: hub, timeout: timeout, timers: make(map[typingKey]*typingSignal)}
+}
+
+// expire stops a signal on behalf of its sender, unless it was refreshed in the meantime.
+func (t *Typing) expire(key typingKey, signal *typingSignal) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timers[key] != signal {
+		return
+	}
+
+	delete(t.timers, key)
+	t.hub.Publish(key.to, model.Event{Type: model.EventTypingStop, Data: model.TypingEvent{From: key.from}})
+}
+
+// Start tells to that from is typing. Repeated starts only push the expiry back.
+func (t *Typing) Start(from, to string) {
+	key := typingKey{from: from, to: to}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous, active := t.timers[key]
+	if active {
+		previous.timer.Stop()
+	}
+
+	signal := &typingSignal{}
+	signal.timer = time.AfterFunc(t.timeout, func() { t.expire(key, signal) })
+	t.timers[key] = signal
+
+	if !active {
+		t.hub.Publish(to, model.Event{Type: model.EventTypingStart, Data: model.TypingEvent{From: from}})
+	}
+}
+
+// Stop tells to that from stopped typing, if they were.
+func (t *Typing) Stop(from, to string) {
+	key := typingKey{from: from, to: to}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	signal, ok := t.timers[key]
+	if !ok {
+		return
+	}
+
+	signal.timer.Stop()
+	delete(t.timers, key)
+	t.hub.Publish(to, model.Event{Type: model.EventTypingStop, Data: model.TypingEvent{From: from}})
+}
+
+// StopAll stops every signal sent by from, typically once their last connection closes.
+func (t *Typing) StopAll(from string) {
+	t.mu.Lock()
+	recipients := make([]string, 0)
+	for key := range t.timers {
+		if key.from == from {
+			recipients = append(recipients, key.to)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, to := range recipients {
+		t.Stop(from, to)
+	}
+}
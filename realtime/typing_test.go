@@ -0,0 +1,75 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+type typingTestEvent struct {
+	Type string            `json:"type"`
+	Data model.TypingEvent `json:"data"`
+}
+
+func readTypingEvent(t *testing.T, conn *websocket.Conn) typingTestEvent {
+	var event typingTestEvent
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	err := conn.ReadJSON(&event)
+	assert.NoError(t, err, "Unexpected error reading typing event")
+	return event
+}
+
+func TestTypingStartStop(t *testing.T) {
+	hub := NewHub()
+	typing := NewTyping(hub, time.Minute)
+
+	serverConn, peerConn := newTestConnection(t)
+	hub.Register("peer", serverConn)
+
+	typing.Start("user-1", "peer")
+	typing.Start("user-1", "peer")
+	typing.Stop("user-1", "peer")
+	typing.Stop("user-1", "peer")
+
+	assert.Equal(t, typingTestEvent{Type: model.EventTypingStart, Data: model.TypingEvent{From: "user-1"}}, readTypingEvent(t, peerConn), "Expected a single start event")
+	assert.Equal(t, typingTestEvent{Type: model.EventTypingStop, Data: model.TypingEvent{From: "user-1"}}, readTypingEvent(t, peerConn), "Expected a single stop event")
+
+	peerConn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err := peerConn.ReadMessage()
+	assert.Error(t, err, "Expected no further events")
+}
+
+func TestTypingExpiry(t *testing.T) {
+	hub := NewHub()
+	typing := NewTyping(hub, 50*time.Millisecond)
+
+	serverConn, peerConn := newTestConnection(t)
+	hub.Register("peer", serverConn)
+
+	typing.Start("user-1", "peer")
+
+	assert.Equal(t, model.EventTypingStart, readTypingEvent(t, peerConn).Type, "Expected a start event")
+	assert.Equal(t, model.EventTypingStop, readTypingEvent(t, peerConn).Type, "Expected the signal to expire")
+}
+
+func TestTypingStopAll(t *testing.T) {
+	hub := NewHub()
+	typing := NewTyping(hub, time.Minute)
+
+	serverConn1, peerConn1 := newTestConnection(t)
+	serverConn2, peerConn2 := newTestConnection(t)
+	hub.Register("peer-1", serverConn1)
+	hub.Register("peer-2", serverConn2)
+
+	typing.Start("user-1", "peer-1")
+	typing.Start("user-1", "peer-2")
+	typing.StopAll("user-1")
+
+	assert.Equal(t, model.EventTypingStart, readTypingEvent(t, peerConn1).Type, "Expected a start event")
+	assert.Equal(t, model.EventTypingStop, readTypingEvent(t, peerConn1).Type, "Expected a stop event")
+	assert.Equal(t, model.EventTypingStart, readTypingEvent(t, peerConn2).Type, "Expected a start event")
+	assert.Equal(t, model.EventTypingStop, readTypingEvent(t, peerConn2).Type, "Expected a stop event")
+}
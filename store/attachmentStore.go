@@ -0,0 +1,216 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+type attachment struct {
+}
+
+type AttachmentStore interface {
+	CreateAttachment(ctx *gofr.Context, attachment model.Attachment) error
+	GetAttachment(ctx *gofr.Context, userId, attachmentId string) (*model.Attachment, error)
+	GetUnsentAttachments(ctx *gofr.Context, userId string, attachmentIds []string) (*[]model.Attachment, error)
+	GetPendingAttachments(ctx *gofr.Context, createdBefore time.Time, limit uint) (*[]model.Attachment, error)
+	UpdateAttachment(ctx *gofr.Context, attachment model.Attachment) error
+}
+
+// attachmentColumns are the columns scanned by scanAttachment, in order.
+const attachmentColumns = "a.id,a.owner_id,a.file_name,a.content_type,a.size,a.created_at,a.status,a.width,a.height,a.blurhash,a.thumbnails,COALESCE(a.blob_id, a.id)"
+
+func scanAttachment(row scanner, dest ...interface{}) (*model.Attachment, error) {
+	var attachment model.Attachment
+	var width, height sql.NullInt64
+	var blurHash sql.NullString
+	var thumbnails []byte
+	dest = append([]interface{}{&attachment.ID, &attachment.OwnerID, &attachment.FileName, &attachment.ContentType, &attachment.Size, &attachment.CreatedAt,
+		&attachment.Status, &width, &height, &blurHash, &thumbnails, &attachment.BlobID}, dest...)
+
+	err := row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment.Width, attachment.Height, attachment.BlurHash = int(width.Int64), int(height.Int64), blurHash.String
+	if len(thumbnails) > 0 {
+		err = json.Unmarshal(thumbnails, &attachment.Thumbnails)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &attachment, nil
+}
+
+func NewAttachmentStore(db *datastore.SQLClient) AttachmentStore {
+	a := attachment{}
+	err := a.init(db)
+	if err != nil {
+		fmt.Println("errr:", err)
+	}
+	return a
+}
+
+func (a attachment) init(db *datastore.SQLClient) error {
+	return a.createAttachmentsTable(db)
+}
+
+func (a attachment) CreateAttachment(ctx *gofr.Context, attachment model.Attachment) error {
+	_, err := ctx.DB().ExecContext(ctx, `INSERT INTO attachments (id, owner_id, file_name, content_type, size, created_at, status)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`, attachment.ID, attachment.OwnerID, attachment.FileName, attachment.ContentType, attachment.Size, attachment.CreatedAt, attachment.Status)
+	return err
+}
+
+// GetAttachment returns an attachment the user may download: one they uploaded and have not sent yet,
+// or one sent with a message of a conversation they are a member of that is still visible to them.
+// Attachments of scheduled messages count as unsent until the message is sent, and those of expired messages as deleted.
+func (a attachment) GetAttachment(ctx *gofr.Context, userId, attachmentId string) (*model.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + `, m.id, m.deletedAt, COALESCE(m.expiresAt <= now(), false),
+		EXISTS (SELECT 1 FROM conversation_members cm WHERE cm.conversation_id = m.conversationId AND cm.account_id = $2)
+	FROM attachments a LEFT JOIN messages m ON m.id = a.message_id AND m.scheduledAt IS NULL
+		AND NOT EXISTS (SELECT 1 FROM message_deletions d WHERE d.message_id = m.id AND d.account_id = $2)
+	WHERE a.id=$1`
+
+	var messageId sql.NullString
+	var deletedAt sql.NullTime
+	var expired, member bool
+
+	attachment, err := scanAttachment(ctx.DB().QueryRowContext(ctx, query, attachmentId, userId), &messageId, &deletedAt, &expired, &member)
+	if err != nil {
+		return nil, err
+	}
+
+	if !messageId.Valid {
+		if attachment.OwnerID != userId {
+			return nil, e.NewError("You are not authorized to see that attachment")
+		}
+		return attachment, nil
+	}
+	if !member {
+		return nil, e.NewError("You are not authorized to see that attachment")
+	}
+	if deletedAt.Valid || expired {
+		return nil, sql.ErrNoRows
+	}
+	return attachment, nil
+}
+
+// GetUnsentAttachments returns those of the attachments that the user uploaded and has not sent with a message yet.
+// Attachments that are still being processed, or failed to be, are left out.
+func (a attachment) GetUnsentAttachments(ctx *gofr.Context, userId string, attachmentIds []string) (*[]model.Attachment, error) {
+	attachments := make([]model.Attachment, 0, len(attachmentIds))
+	if len(attachmentIds) == 0 {
+		return &attachments, nil
+	}
+
+	args := []interface{}{userId, model.AttachmentReady}
+	for _, attachmentId := range attachmentIds {
+		args = append(args, attachmentId)
+	}
+
+	query := fmt.Sprintf(`SELECT `+attachmentColumns+` FROM attachments a
+	WHERE a.owner_id=$1 AND a.message_id IS NULL AND a.status=$2 AND a.id IN (%s)
+	ORDER BY a.created_at`, placeholders(3, len(attachmentIds)))
+
+	rows, err := ctx.DB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, *attachment)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &attachments, nil
+}
+
+// GetPendingAttachments returns the oldest attachments still waiting to be processed that were uploaded before createdBefore.
+// Forwarded copies are left out, they are updated along with the attachment they copy.
+func (a attachment) GetPendingAttachments(ctx *gofr.Context, createdBefore time.Time, limit uint) (*[]model.Attachment, error) {
+	rows, err := ctx.DB().QueryContext(ctx, `SELECT `+attachmentColumns+` FROM attachments a
+	WHERE a.status=$1 AND a.created_at < $2 AND a.blob_id IS NULL
+	ORDER BY a.created_at LIMIT $3`, model.AttachmentPending, createdBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	attachments := []model.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, *attachment)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &attachments, nil
+}
+
+// UpdateAttachment records the outcome of processing an attachment, including its size,
+// which changes when metadata is stripped from the file. Copies of the attachment that were forwarded meanwhile share the outcome.
+func (a attachment) UpdateAttachment(ctx *gofr.Context, attachment model.Attachment) error {
+	var thumbnails sql.NullString
+	if len(attachment.Thumbnails) > 0 {
+		encoded, err := json.Marshal(attachment.Thumbnails)
+		if err != nil {
+			return err
+		}
+		thumbnails = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	_, err := ctx.DB().ExecContext(ctx, `UPDATE attachments SET size=$2, status=$3, width=$4, height=$5, blurhash=$6, thumbnails=$7
+	WHERE id=$1 OR blob_id=$1`, attachment.ID, attachment.Size, attachment.Status, sql.NullInt64{Int64: int64(attachment.Width), Valid: attachment.Width > 0},
+		sql.NullInt64{Int64: int64(attachment.Height), Valid: attachment.Height > 0}, sql.NullString{String: attachment.BlurHash, Valid: attachment.BlurHash != ""}, thumbnails)
+	return err
+}
+
+func (attachment) createAttachmentsTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS attachments (
+		id UUID PRIMARY KEY,
+		owner_id UUID NOT NULL,
+		message_id UUID,
+		file_name TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		size BIGINT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (owner_id) REFERENCES accounts(id),
+		FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS attachments_message_idx ON attachments (message_id);
+	ALTER TABLE attachments ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'ready';
+	ALTER TABLE attachments ADD COLUMN IF NOT EXISTS width INT;
+	ALTER TABLE attachments ADD COLUMN IF NOT EXISTS height INT;
+	ALTER TABLE attachments ADD COLUMN IF NOT EXISTS blurhash TEXT;
+	ALTER TABLE attachments ADD COLUMN IF NOT EXISTS thumbnails JSONB;
+	ALTER TABLE attachments ADD COLUMN IF NOT EXISTS blob_id UUID;
+	CREATE INDEX IF NOT EXISTS attachments_pending_idx ON attachments (created_at) WHERE status = 'pending';`
+	_, err := db.Exec(query)
+	return err
+}
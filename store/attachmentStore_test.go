@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+// attachmentColumnNames name the columns of attachmentColumns for mocked rows.
+var attachmentColumnNames = []string{"id", "owner_id", "file_name", "content_type", "size", "created_at", "status", "width", "height", "blurhash", "thumbnails", "blob_id"}
+
+func TestCreateAttachment(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	attachmentStore := attachment{}
+
+	sampleAttachment := model.Attachment{
+		ID:          "attachment-id",
+		OwnerID:     "owner-id",
+		FileName:    "cat.png",
+		ContentType: "image/png",
+		Size:        1024,
+		CreatedAt:   time.Now(),
+		Status:      model.AttachmentPending,
+	}
+
+	mock.ExpectExec("INSERT INTO attachments").
+		WithArgs(sampleAttachment.ID, sampleAttachment.OwnerID, sampleAttachment.FileName, sampleAttachment.ContentType, sampleAttachment.Size, sampleAttachment.CreatedAt, sampleAttachment.Status).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = attachmentStore.CreateAttachment(ctx, sampleAttachment)
+
+	assert.NoError(t, err, "Unexpected error during attachment insertion")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetAttachment(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	attachmentStore := attachment{}
+	columns := []string{"id", "owner_id", "file_name", "content_type", "size", "created_at", "status", "width", "height", "blurhash", "thumbnails", "blob_id", "message_id", "deletedAt", "expired", "member"}
+
+	testCases := []struct {
+		desc      string
+		userID    string
+		messageID interface{}
+		deletedAt interface{}
+		expired   bool
+		err       error
+	}{
+		{"owner of an unsent attachment", "owner-id", nil, nil, false, nil},
+		{"someone else before it is sent", "stranger-id", nil, nil, false, e.NewError("You are not authorized to see that attachment")},
+		{"member of the conversation", "recipient-id", "message-id", nil, false, nil},
+		{"someone else after it is sent", "stranger-id", "message-id", nil, false, e.NewError("You are not authorized to see that attachment")},
+		{"message deleted for everyone", "recipient-id", "message-id", time.Now(), false, sql.ErrNoRows},
+		{"message disappeared", "recipient-id", "message-id", nil, true, sql.ErrNoRows},
+	}
+
+	for _, tc := range testCases {
+		member := tc.messageID != nil && tc.userID != "stranger-id"
+
+		mock.ExpectQuery("SELECT a.id,a.owner_id,.* FROM attachments a LEFT JOIN messages m").
+			WithArgs("attachment-id", tc.userID).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("attachment-id", "owner-id", "cat.png", "image/png", 1024, time.Now(), "ready", nil, nil, nil, nil, "attachment-id", tc.messageID, tc.deletedAt, tc.expired, member))
+
+		attachment, err := attachmentStore.GetAttachment(ctx, tc.userID, "attachment-id")
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if tc.err == nil {
+			assert.Equal(t, "cat.png", attachment.FileName, "TEST: %s: mismatch in attachment", tc.desc)
+		}
+	}
+
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.* FROM attachments a LEFT JOIN messages m").
+		WithArgs("attachment-id", "owner-id").
+		WillReturnError(fmt.Errorf(""))
+
+	_, err = attachmentStore.GetAttachment(ctx, "owner-id", "attachment-id")
+
+	assert.Error(t, err, "Expected an error during failed attachment retrieval")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetUnsentAttachments(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	attachmentStore := attachment{}
+
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.* FROM attachments a WHERE a.owner_id=\\$1 AND a.message_id IS NULL AND a.status=\\$2 AND a.id IN \\(\\$3,\\$4\\)").
+		WithArgs("owner-id", "ready", "attachment-id-1", "attachment-id-2").
+		WillReturnRows(sqlmock.NewRows(attachmentColumnNames).
+			AddRow("attachment-id-1", "owner-id", "cat.png", "image/png", 1024, time.Now(), "ready", nil, nil, nil, nil, "attachment-id-1"))
+
+	attachments, err := attachmentStore.GetUnsentAttachments(ctx, "owner-id", []string{"attachment-id-1", "attachment-id-2"})
+
+	assert.NoError(t, err, "Unexpected error during attachment retrieval")
+	assert.Len(t, *attachments, 1, "Expected only unsent attachments of the user")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	attachments, err = attachmentStore.GetUnsentAttachments(ctx, "owner-id", nil)
+
+	assert.NoError(t, err, "Unexpected error for no attachments")
+	assert.Empty(t, *attachments, "Expected no attachments")
+}
+
+func TestGetPendingAttachments(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	attachmentStore := attachment{}
+	createdBefore := time.Now().Add(-time.Minute)
+
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.* FROM attachments a WHERE a.status=\\$1 AND a.created_at < \\$2 AND a.blob_id IS NULL ORDER BY a.created_at LIMIT \\$3").
+		WithArgs(model.AttachmentPending, createdBefore, 10).
+		WillReturnRows(sqlmock.NewRows(attachmentColumnNames).
+			AddRow("attachment-id-1", "owner-id", "cat.png", "image/png", 1024, time.Now(), "pending", nil, nil, nil, nil, "attachment-id-1").
+			AddRow("attachment-id-2", "owner-id", "dog.jpg", "image/jpeg", 2048, time.Now(), "pending", nil, nil, nil, nil, "attachment-id-2"))
+
+	attachments, err := attachmentStore.GetPendingAttachments(ctx, createdBefore, 10)
+
+	assert.NoError(t, err, "Unexpected error during attachment retrieval")
+	assert.Len(t, *attachments, 2, "Mismatch in number of pending attachments")
+	assert.Equal(t, "dog.jpg", (*attachments)[1].FileName, "Mismatch in attachment")
+
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.* FROM attachments a WHERE a.status").
+		WillReturnError(fmt.Errorf(""))
+
+	_, err = attachmentStore.GetPendingAttachments(ctx, createdBefore, 10)
+
+	assert.Error(t, err, "Expected an error during failed attachment retrieval")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateAttachment(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	attachmentStore := attachment{}
+
+	processed := model.Attachment{ID: "attachment-id", Size: 900, Status: model.AttachmentReady, Width: 640, Height: 480, BlurHash: "LEHV6nWB2yk8pyo0adR*.7kCMdnj",
+		Thumbnails: []model.Thumbnail{{Size: "small", Width: 96, Height: 72, ContentType: "image/jpeg"}}}
+
+	mock.ExpectExec("UPDATE attachments SET size=\\$2, status=\\$3, width=\\$4, height=\\$5, blurhash=\\$6, thumbnails=\\$7 WHERE id=\\$1").
+		WithArgs("attachment-id", int64(900), model.AttachmentReady, int64(640), int64(480), processed.BlurHash, `[{"size":"small","width":96,"height":72,"contentType":"image/jpeg"}]`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = attachmentStore.UpdateAttachment(ctx, processed)
+
+	assert.NoError(t, err, "Unexpected error during attachment update")
+
+	failed := model.Attachment{ID: "attachment-id", Size: 1024, Status: model.AttachmentFailed}
+
+	mock.ExpectExec("UPDATE attachments SET").
+		WithArgs("attachment-id", int64(1024), model.AttachmentFailed, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = attachmentStore.UpdateAttachment(ctx, failed)
+
+	assert.NoError(t, err, "Unexpected error during attachment update")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
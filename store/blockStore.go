@@ -0,0 +1,111 @@
+package store
+
+import (
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+type block struct {
+}
+
+// BlockStore manages the users each user has blocked. Friendships, friend requests, presence and the
+// profiles of peers are filtered by blocks where they are queried; see friend and conversation.
+type BlockStore interface {
+	Block(ctx *gofr.Context, userId, blockedId string) error
+	Unblock(ctx *gofr.Context, userId, blockedId string) error
+	GetBlockedUsers(ctx *gofr.Context, userId string) (*[]model.BlockedUser, error)
+	IsBlocked(ctx *gofr.Context, blockerId, userId string) (bool, error)
+}
+
+// blockedEither is true when either of the users in the given expressions blocked the other.
+const blockedEither = `EXISTS (SELECT 1 FROM blocks
+	WHERE (blocker_id = %[1]s AND blocked_id = %[2]s) OR (blocker_id = %[2]s AND blocked_id = %[1]s))`
+
+func NewBlockStore(db *datastore.SQLClient) BlockStore {
+	b := block{}
+	b.init(db)
+	return b
+}
+
+func (b block) init(db *datastore.SQLClient) {
+	createBlocksTable(db)
+}
+
+// Block blocks a user for the user. Blocking again changes nothing.
+func (b block) Block(ctx *gofr.Context, userId, blockedId string) error {
+	_, err := ctx.DB().ExecContext(ctx, `INSERT INTO blocks (blocker_id, blocked_id, created_at) VALUES ($1, $2, $3)
+	ON CONFLICT DO NOTHING`, userId, blockedId, time.Now())
+	return err
+}
+
+// Unblock unblocks a user for the user. Unblocking a user who is not blocked changes nothing.
+func (b block) Unblock(ctx *gofr.Context, userId, blockedId string) error {
+	_, err := ctx.DB().ExecContext(ctx, "DELETE FROM blocks WHERE blocker_id=$1 AND blocked_id=$2", userId, blockedId)
+	return err
+}
+
+// GetBlockedUsers returns the users the user blocked, most recently blocked first.
+func (b block) GetBlockedUsers(ctx *gofr.Context, userId string) (*[]model.BlockedUser, error) {
+	query := `SELECT u.id, u.name, u.phoneNumber, b.created_at
+	FROM blocks b
+	JOIN accounts u ON u.id = b.blocked_id
+	WHERE b.blocker_id = $1
+	ORDER BY b.created_at DESC`
+
+	rows, err := ctx.DB().QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	blocked := make([]model.BlockedUser, 0)
+
+	for rows.Next() {
+		var user model.BlockedUser
+
+		err = rows.Scan(&user.User.ID, &user.User.Name, &user.User.PhoneNumber, &user.BlockedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		blocked = append(blocked, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &blocked, nil
+}
+
+// IsBlocked reports whether the blocker blocked the user.
+func (b block) IsBlocked(ctx *gofr.Context, blockerId, userId string) (bool, error) {
+	var blocked bool
+	err := ctx.DB().QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM blocks WHERE blocker_id=$1 AND blocked_id=$2)", blockerId, userId).
+		Scan(&blocked)
+
+	if err != nil {
+		return false, err
+	}
+
+	return blocked, nil
+}
+
+func createBlocksTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS blocks (
+		blocker_id UUID NOT NULL,
+		blocked_id UUID NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (blocker_id) REFERENCES accounts(id),
+		FOREIGN KEY (blocked_id) REFERENCES accounts(id),
+		PRIMARY KEY (blocker_id, blocked_id)
+	);
+	CREATE INDEX IF NOT EXISTS blocks_blocked_idx ON blocks (blocked_id);`
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlock(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	blockStore := block{}
+
+	mock.ExpectExec("INSERT INTO blocks \\(blocker_id, blocked_id, created_at\\) VALUES \\(\\$1, \\$2, \\$3\\)\\s+ON CONFLICT DO NOTHING").
+		WithArgs("user-1", "user-2", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := blockStore.Block(ctx, "user-1", "user-2")
+
+	assert.NoError(t, err, "Unexpected error while blocking")
+
+	mock.ExpectExec("DELETE FROM blocks WHERE blocker_id=\\$1 AND blocked_id=\\$2").
+		WithArgs("user-1", "user-2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = blockStore.Unblock(ctx, "user-1", "user-2")
+
+	assert.NoError(t, err, "Expected unblocking a user who is not blocked to change nothing")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetBlockedUsers(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	blockStore := block{}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT u.id, u.name, u.phoneNumber, b.created_at\\s+FROM blocks b\\s+JOIN accounts u ON u.id = b.blocked_id\\s+WHERE b.blocker_id = \\$1").
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "phoneNumber", "created_at"}).
+			AddRow("user-2", "User Two", uint64(1234567891), now).
+			AddRow("user-3", "User Three", uint64(1234567892), now))
+
+	blocked, err := blockStore.GetBlockedUsers(ctx, "user-1")
+
+	assert.NoError(t, err, "Unexpected error while retrieving blocked users")
+	assert.Len(t, *blocked, 2, "Unexpected number of blocked users")
+	assert.Equal(t, "user-2", (*blocked)[0].User.ID, "Mismatch in blocked user")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestIsBlocked(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	blockStore := block{}
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM blocks WHERE blocker_id=\\$1 AND blocked_id=\\$2\\)").
+		WithArgs("user-1", "user-2").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	blocked, err := blockStore.IsBlocked(ctx, "user-1", "user-2")
+
+	assert.NoError(t, err, "Unexpected error during block check")
+	assert.True(t, blocked, "Expected the user to be blocked")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
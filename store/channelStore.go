@@ -0,0 +1,140 @@
+package store
+
+import (
+	"database/sql"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+type channel struct {
+}
+
+// ChannelStore manages broadcast channels and their subscribers. Subscriptions are memberships of
+// the channel's conversation, so subscribers read channel messages like members read group messages.
+type ChannelStore interface {
+	CreateChannel(ctx *gofr.Context, channel model.Channel) error
+	GetChannel(ctx *gofr.Context, userId, handle string) (*model.Channel, error)
+	Subscribe(ctx *gofr.Context, userId, channelId string) error
+	Unsubscribe(ctx *gofr.Context, userId, channelId string) error
+	UpdateSubscriberRole(ctx *gofr.Context, channelId, subscriberId, role string) error
+}
+
+func NewChannelStore(db *datastore.SQLClient) ChannelStore {
+	return channel{}
+}
+
+// CreateChannel creates a channel with its creator as its first admin. Handles are unique.
+func (c channel) CreateChannel(ctx *gofr.Context, channel model.Channel) error {
+	query := `WITH created AS (
+		INSERT INTO conversations (id, kind, name, handle, description, created_by, created_at)
+		VALUES ($1, 'channel', $2, $3, NULLIF($4, ''), $5, $6)
+		ON CONFLICT DO NOTHING
+		RETURNING id
+	)
+	INSERT INTO conversation_members (conversation_id, account_id, role, joined_at, last_message_at)
+	SELECT id, $5, 'admin', $6, $6 FROM created`
+
+	result, err := ctx.DB().ExecContext(ctx, query, channel.ID, channel.Name, channel.Handle, channel.Description, channel.CreatedBy, channel.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	created, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if created == 0 {
+		return e.NewError("That handle is taken")
+	}
+	return nil
+}
+
+// GetChannel returns the channel with a handle, as seen by the user. Channels are public.
+func (c channel) GetChannel(ctx *gofr.Context, userId, handle string) (*model.Channel, error) {
+	query := `SELECT c.id, c.handle, c.name, c.description, c.created_by, c.created_at,
+		(SELECT COUNT(*) FROM conversation_members WHERE conversation_id = c.id),
+		(SELECT role FROM conversation_members WHERE conversation_id = c.id AND account_id = $2)
+	FROM conversations c WHERE c.kind='channel' AND c.handle=$1`
+
+	var channel model.Channel
+	var description, createdBy, role sql.NullString
+
+	err := ctx.DB().QueryRowContext(ctx, query, handle, userId).Scan(&channel.ID, &channel.Handle, &channel.Name, &description,
+		&createdBy, &channel.CreatedAt, &channel.SubscriberCount, &role)
+	if err != nil {
+		return nil, err
+	}
+
+	channel.Description, channel.CreatedBy = description.String, createdBy.String
+	channel.Subscribed, channel.Role = role.Valid, role.String
+	return &channel, nil
+}
+
+// Subscribe subscribes the user to a channel. Subscribing again changes nothing.
+func (c channel) Subscribe(ctx *gofr.Context, userId, channelId string) error {
+	_, err := ctx.DB().ExecContext(ctx, `INSERT INTO conversation_members (conversation_id, account_id, role, joined_at, last_message_at)
+	VALUES ($1, $2, 'subscriber', NOW(), NOW())
+	ON CONFLICT DO NOTHING`, channelId, userId)
+	return err
+}
+
+// Unsubscribe unsubscribes the user from a channel. The last admin of a channel cannot leave it.
+func (c channel) Unsubscribe(ctx *gofr.Context, userId, channelId string) error {
+	result, err := ctx.DB().ExecContext(ctx, `DELETE FROM conversation_members
+	WHERE conversation_id=$1 AND account_id=$2
+		AND (role <> 'admin' OR EXISTS (SELECT 1 FROM conversation_members WHERE conversation_id=$1 AND role='admin' AND account_id<>$2))`,
+		channelId, userId)
+	if err != nil {
+		return err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		var role string
+		err = ctx.DB().QueryRowContext(ctx, "SELECT role FROM conversation_members WHERE conversation_id=$1 AND account_id=$2", channelId, userId).Scan(&role)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return e.NewError("A channel needs at least one admin")
+	}
+	return nil
+}
+
+// UpdateSubscriberRole makes a subscriber of a channel an admin or a plain subscriber.
+// The last admin of a channel cannot step down.
+func (c channel) UpdateSubscriberRole(ctx *gofr.Context, channelId, subscriberId, role string) error {
+	var current string
+	err := ctx.DB().QueryRowContext(ctx, "SELECT role FROM conversation_members WHERE conversation_id=$1 AND account_id=$2", channelId, subscriberId).Scan(&current)
+	if err == sql.ErrNoRows {
+		return e.NewError("That user is not subscribed to the channel")
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := ctx.DB().ExecContext(ctx, `UPDATE conversation_members SET role=$3
+	WHERE conversation_id=$1 AND account_id=$2
+		AND ($3 = 'admin' OR EXISTS (SELECT 1 FROM conversation_members WHERE conversation_id=$1 AND role='admin' AND account_id<>$2))`,
+		channelId, subscriberId, role)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return e.NewError("A channel needs at least one admin")
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateChannel(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	channelStore := channel{}
+	now := time.Now()
+	sampleChannel := model.Channel{ID: "channel-id", Handle: "catnews", Name: "Cat News", CreatedBy: "admin-id", CreatedAt: now}
+
+	mock.ExpectExec("WITH created AS \\(\\s*INSERT INTO conversations .* 'channel'.* ON CONFLICT DO NOTHING\\s+RETURNING id\\s*\\)\\s*INSERT INTO conversation_members .* 'admin'").
+		WithArgs("channel-id", "Cat News", "catnews", "", "admin-id", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := channelStore.CreateChannel(ctx, sampleChannel)
+
+	assert.NoError(t, err, "Unexpected error while creating a channel")
+
+	mock.ExpectExec("WITH created AS").
+		WithArgs("channel-id", "Cat News", "catnews", "", "admin-id", now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = channelStore.CreateChannel(ctx, sampleChannel)
+
+	assert.Equal(t, e.NewError("That handle is taken"), err, "Expected handles to be unique")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetChannel(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	channelStore := channel{}
+	now := time.Now()
+	columns := []string{"id", "handle", "name", "description", "created_by", "created_at", "count", "role"}
+
+	mock.ExpectQuery("SELECT c.id, c.handle, c.name, c.description, c.created_by, c.created_at,.* FROM conversations c WHERE c.kind='channel' AND c.handle=\\$1").
+		WithArgs("catnews", "subscriber-id").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("channel-id", "catnews", "Cat News", nil, "admin-id", now, 42, "subscriber"))
+
+	channel, err := channelStore.GetChannel(ctx, "subscriber-id", "catnews")
+
+	assert.NoError(t, err, "Unexpected error while retrieving a channel")
+	assert.Equal(t, uint(42), channel.SubscriberCount, "Mismatch in subscriber count")
+	assert.True(t, channel.Subscribed, "Expected the user to be subscribed")
+	assert.Equal(t, model.RoleSubscriber, channel.Role, "Mismatch in role")
+
+	mock.ExpectQuery("SELECT c.id, c.handle").
+		WithArgs("catnews", "stranger-id").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("channel-id", "catnews", "Cat News", "All about cats", "admin-id", now, 42, nil))
+
+	channel, err = channelStore.GetChannel(ctx, "stranger-id", "catnews")
+
+	assert.NoError(t, err, "Unexpected error while retrieving a channel")
+	assert.False(t, channel.Subscribed, "Expected the user not to be subscribed")
+	assert.Equal(t, "All about cats", channel.Description, "Mismatch in description")
+
+	mock.ExpectQuery("SELECT c.id, c.handle").
+		WithArgs("dognews", "stranger-id").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = channelStore.GetChannel(ctx, "stranger-id", "dognews")
+
+	assert.Equal(t, sql.ErrNoRows, err, "Expected a missing channel not to be found")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	channelStore := channel{}
+
+	mock.ExpectExec("INSERT INTO conversation_members .* 'subscriber'.* ON CONFLICT DO NOTHING").
+		WithArgs("channel-id", "user-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := channelStore.Subscribe(ctx, "user-id", "channel-id")
+
+	assert.NoError(t, err, "Unexpected error while subscribing")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	channelStore := channel{}
+
+	mock.ExpectExec("DELETE FROM conversation_members").
+		WithArgs("channel-id", "user-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := channelStore.Unsubscribe(ctx, "user-id", "channel-id")
+
+	assert.NoError(t, err, "Unexpected error while unsubscribing")
+
+	mock.ExpectExec("DELETE FROM conversation_members").
+		WithArgs("channel-id", "user-id").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT role FROM conversation_members").
+		WithArgs("channel-id", "user-id").
+		WillReturnError(sql.ErrNoRows)
+
+	err = channelStore.Unsubscribe(ctx, "user-id", "channel-id")
+
+	assert.NoError(t, err, "Expected unsubscribing twice to change nothing")
+
+	mock.ExpectExec("DELETE FROM conversation_members").
+		WithArgs("channel-id", "admin-id").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT role FROM conversation_members").
+		WithArgs("channel-id", "admin-id").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("admin"))
+
+	err = channelStore.Unsubscribe(ctx, "admin-id", "channel-id")
+
+	assert.Equal(t, e.NewError("A channel needs at least one admin"), err, "Expected the last admin not to leave")
+
+	mock.ExpectExec("DELETE FROM conversation_members").
+		WithArgs("channel-id", "user-id").
+		WillReturnError(fmt.Errorf(""))
+
+	err = channelStore.Unsubscribe(ctx, "user-id", "channel-id")
+
+	assert.Error(t, err, "Expected an error during failed unsubscription")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateSubscriberRole(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	channelStore := channel{}
+
+	mock.ExpectQuery("SELECT role FROM conversation_members").
+		WithArgs("channel-id", "subscriber-id").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("subscriber"))
+	mock.ExpectExec("UPDATE conversation_members SET role=\\$3").
+		WithArgs("channel-id", "subscriber-id", "admin").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := channelStore.UpdateSubscriberRole(ctx, "channel-id", "subscriber-id", "admin")
+
+	assert.NoError(t, err, "Unexpected error while promoting a subscriber")
+
+	mock.ExpectQuery("SELECT role FROM conversation_members").
+		WithArgs("channel-id", "admin-id").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("admin"))
+	mock.ExpectExec("UPDATE conversation_members SET role=\\$3").
+		WithArgs("channel-id", "admin-id", "subscriber").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = channelStore.UpdateSubscriberRole(ctx, "channel-id", "admin-id", "subscriber")
+
+	assert.Equal(t, e.NewError("A channel needs at least one admin"), err, "Expected the last admin not to step down")
+
+	mock.ExpectQuery("SELECT role FROM conversation_members").
+		WithArgs("channel-id", "stranger-id").
+		WillReturnError(sql.ErrNoRows)
+
+	err = channelStore.UpdateSubscriberRole(ctx, "channel-id", "stranger-id", "admin")
+
+	assert.Equal(t, e.NewError("That user is not subscribed to the channel"), err, "Expected only subscribers to get roles")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
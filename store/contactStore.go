@@ -0,0 +1,123 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+type contact struct {
+}
+
+// ContactStore finds the users behind hashed phone numbers, and meters how many hashes each user looks up a day.
+type ContactStore interface {
+	DiscoverUsers(ctx *gofr.Context, userId string, hashes []string) (*[]model.User, error)
+	UseDiscoveryQuota(ctx *gofr.Context, userId string, hashes, limit int) (bool, error)
+}
+
+// NewContactStore hashes the phone numbers of accounts with salt. The salt is fixed the first time the
+// store starts; changing it later takes dropping the accounts.phoneHash column.
+func NewContactStore(db *datastore.SQLClient, salt string) ContactStore {
+	c := contact{}
+	err := c.init(db, salt)
+	if err != nil {
+		fmt.Println("errr:", err)
+	}
+	return c
+}
+
+func (c contact) init(db *datastore.SQLClient, salt string) error {
+	err := c.addPhoneHashColumn(db, salt)
+	if err != nil {
+		return err
+	}
+	return c.createDiscoveryUsageTable(db)
+}
+
+// DiscoverUsers returns the discoverable users whose phone number hashes start with any of the hashes,
+// leaving out the user and anyone either of them blocked.
+func (c contact) DiscoverUsers(ctx *gofr.Context, userId string, hashes []string) (*[]model.User, error) {
+	query := `SELECT a.id, a.name, a.phoneNumber
+	FROM accounts a
+	LEFT JOIN account_settings s ON s.account_id = a.id
+	WHERE a.phoneHash LIKE ANY (ARRAY[` + placeholders(2, len(hashes)) + `]) AND a.id <> $1
+		AND COALESCE(s.discoverable, true) AND NOT ` + fmt.Sprintf(blockedEither, "a.id", "$1")
+
+	args := make([]interface{}, 0, len(hashes)+1)
+	args = append(args, userId)
+	for _, hash := range hashes {
+		args = append(args, hash+"%")
+	}
+
+	rows, err := ctx.DB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := make([]model.User, 0)
+
+	for rows.Next() {
+		var user model.User
+
+		err = rows.Scan(&user.ID, &user.Name, &user.PhoneNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &users, nil
+}
+
+// UseDiscoveryQuota counts hashes against the user's lookups today, unless that takes them over limit.
+// It reports whether the hashes were within the quota.
+func (c contact) UseDiscoveryQuota(ctx *gofr.Context, userId string, hashes, limit int) (bool, error) {
+	if hashes > limit {
+		return false, nil
+	}
+
+	result, err := ctx.DB().ExecContext(ctx, `INSERT INTO contact_discovery_usage (account_id, day, hashes) VALUES ($1, CURRENT_DATE, $2)
+	ON CONFLICT (account_id, day) DO UPDATE SET hashes = contact_discovery_usage.hashes + EXCLUDED.hashes
+	WHERE contact_discovery_usage.hashes + EXCLUDED.hashes <= $3`, userId, hashes, limit)
+	if err != nil {
+		return false, err
+	}
+
+	counted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return counted > 0, nil
+}
+
+// addPhoneHashColumn keeps the salted hash of every account's phone number next to it, indexed for prefix lookups.
+func (contact) addPhoneHashColumn(db *datastore.SQLClient, salt string) error {
+	query := `ALTER TABLE accounts ADD COLUMN IF NOT EXISTS phoneHash TEXT
+		GENERATED ALWAYS AS (encode(sha256(('` + strings.ReplaceAll(salt, "'", "''") + `' || phoneNumber::text)::bytea), 'hex')) STORED;
+	CREATE INDEX IF NOT EXISTS accounts_phone_hash_idx ON accounts (phoneHash text_pattern_ops);`
+	_, err := db.Exec(query)
+	return err
+}
+
+func (contact) createDiscoveryUsageTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS contact_discovery_usage (
+		account_id UUID NOT NULL,
+		day DATE NOT NULL,
+		hashes INT NOT NULL,
+		FOREIGN KEY (account_id) REFERENCES accounts(id),
+		PRIMARY KEY (account_id, day)
+	);`
+	_, err := db.Exec(query)
+	return err
+}
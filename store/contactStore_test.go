@@ -0,0 +1,69 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoverUsers(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	contactStore := contact{}
+
+	mock.ExpectQuery("SELECT a.id, a.name, a.phoneNumber\\s+FROM accounts a\\s+LEFT JOIN account_settings s ON s.account_id = a.id\\s+WHERE a.phoneHash LIKE ANY \\(ARRAY\\[\\$2,\\$3\\]\\) AND a.id <> \\$1\\s+AND COALESCE\\(s.discoverable, true\\) AND NOT EXISTS \\(SELECT 1 FROM blocks").
+		WithArgs("user-1", "0123456789%", "abcdefabcdef%").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "phoneNumber"}).AddRow("user-2", "User Two", uint64(1234567891)))
+
+	users, err := contactStore.DiscoverUsers(ctx, "user-1", []string{"0123456789", "abcdefabcdef"})
+
+	assert.NoError(t, err, "Unexpected error during contact discovery")
+	assert.Len(t, *users, 1, "Unexpected number of discovered users")
+	assert.Equal(t, "user-2", (*users)[0].ID, "Mismatch in discovered user")
+
+	mock.ExpectQuery("SELECT a.id, a.name, a.phoneNumber").
+		WillReturnError(fmt.Errorf(""))
+
+	_, err = contactStore.DiscoverUsers(ctx, "user-1", []string{"0123456789"})
+
+	assert.Error(t, err, "Expected an error during failed contact discovery")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUseDiscoveryQuota(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	contactStore := contact{}
+
+	mock.ExpectExec("INSERT INTO contact_discovery_usage .* VALUES \\(\\$1, CURRENT_DATE, \\$2\\)\\s+ON CONFLICT \\(account_id, day\\) DO UPDATE .* WHERE contact_discovery_usage.hashes \\+ EXCLUDED.hashes <= \\$3").
+		WithArgs("user-1", 10, 100).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ok, err := contactStore.UseDiscoveryQuota(ctx, "user-1", 10, 100)
+
+	assert.NoError(t, err, "Unexpected error while using the discovery quota")
+	assert.True(t, ok, "Expected the hashes to be within the quota")
+
+	mock.ExpectExec("INSERT INTO contact_discovery_usage").
+		WithArgs("user-1", 10, 100).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ok, err = contactStore.UseDiscoveryQuota(ctx, "user-1", 10, 100)
+
+	assert.NoError(t, err, "Unexpected error while using the discovery quota")
+	assert.False(t, ok, "Expected the hashes to exceed the quota")
+
+	ok, err = contactStore.UseDiscoveryQuota(ctx, "user-1", 101, 100)
+
+	assert.NoError(t, err, "Unexpected error while using the discovery quota")
+	assert.False(t, ok, "Expected a batch larger than the quota to exceed it")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,518 @@
+package store
+
+import (
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+type conversation struct {
+}
+
+type ConversationStore interface {
+	EnsureDirectConversation(ctx *gofr.Context, userId, peerId string, accepted bool) (string, error)
+	RecordMessage(ctx *gofr.Context, message model.Message) error
+	GetConversations(ctx *gofr.Context, userId string, accepted bool, page, limit uint) (*[]model.Conversation, error)
+	GetMemberIds(ctx *gofr.Context, conversationId string) ([]string, error)
+	MarkRead(ctx *gofr.Context, userId, conversationId string) error
+	AcceptMessageRequest(ctx *gofr.Context, userId, conversationId string) error
+	GetDisappearingTimer(ctx *gofr.Context, conversationId string) (*model.DisappearingTimer, error)
+	SetDisappearingTimer(ctx *gofr.Context, userId, conversationId string, timer model.DisappearingTimer) (*model.Conversation, error)
+	SaveDraft(ctx *gofr.Context, userId, conversationId string, content string, updatedAt time.Time) (*model.Draft, bool, error)
+	GetDraft(ctx *gofr.Context, userId, conversationId string) (*model.Draft, error)
+	DeleteDraft(ctx *gofr.Context, userId, conversationId string) (*model.Draft, error)
+	GetMemberNames(ctx *gofr.Context, conversationId string, userIds []string) (map[string]string, error)
+	Mute(ctx *gofr.Context, userId, conversationId string, until *time.Time) error
+	Unmute(ctx *gofr.Context, userId, conversationId string) error
+}
+
+// directConversationID is DirectConversationID in SQL, for the participants in the given expressions.
+const directConversationID = "md5(LEAST(%[1]s, %[2]s)::text || GREATEST(%[1]s, %[2]s)::text)::uuid"
+
+// DirectConversationID is the ID of the one-to-one conversation between two users, the same whichever of them asks.
+func DirectConversationID(userId1, userId2 string) string {
+	first, second := strings.ToLower(userId1), strings.ToLower(userId2)
+	if second < first {
+		first, second = second, first
+	}
+
+	sum := md5.Sum([]byte(first + second))
+	id := hex.EncodeToString(sum[:])
+	return id[0:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:32]
+}
+
+func NewConversationStore(db *datastore.SQLClient) ConversationStore {
+	c := conversation{}
+	err := c.init(db)
+	if err != nil {
+		fmt.Println("errr:", err)
+	}
+	return c
+}
+
+func (c conversation) init(db *datastore.SQLClient) error {
+	err := c.createConversationsTable(db)
+	if err != nil {
+		return err
+	}
+	err = c.createConversationMembersTable(db)
+	if err != nil {
+		return err
+	}
+	err = c.createDraftsTable(db)
+	if err != nil {
+		return err
+	}
+	return c.backfillDirectConversations(db)
+}
+
+// EnsureDirectConversation returns the direct conversation between the user and the peer, creating it on first use.
+// Writing to the peer accepts the conversation for the user. Unless accepted, it is a message request for the peer
+// until the peer accepts it; a conversation the peer already accepted stays accepted.
+func (c conversation) EnsureDirectConversation(ctx *gofr.Context, userId, peerId string, accepted bool) (string, error) {
+	conversationId := DirectConversationID(userId, peerId)
+
+	query := `WITH created AS (
+		INSERT INTO conversations (id, kind, created_at) VALUES ($1, 'direct', $4) ON CONFLICT DO NOTHING
+	)
+	INSERT INTO conversation_members (conversation_id, account_id, joined_at, last_message_at, accepted)
+	SELECT $1, v.account_id, $4, $4, bool_or(v.accepted) FROM (VALUES ($2::UUID, true), ($3::UUID, $5::BOOLEAN)) v(account_id, accepted)
+	GROUP BY v.account_id
+	ON CONFLICT (conversation_id, account_id) DO UPDATE SET accepted = conversation_members.accepted OR EXCLUDED.accepted`
+
+	_, err := ctx.DB().ExecContext(ctx, query, conversationId, userId, peerId, time.Now(), accepted)
+	if err != nil {
+		return "", err
+	}
+	return conversationId, nil
+}
+
+// RecordMessage fans a newly sent message out to the inboxes of every member of its conversation.
+// The sender's unread count is left untouched while everyone else's is incremented.
+func (c conversation) RecordMessage(ctx *gofr.Context, message model.Message) error {
+	query := `UPDATE conversation_members SET
+		last_message_id = $2,
+		last_message_at = $3,
+		unread_count = unread_count + CASE WHEN account_id = $4 THEN 0 ELSE 1 END
+	WHERE conversation_id = $1`
+
+	_, err := ctx.DB().ExecContext(ctx, query, message.ConversationID, message.ID, message.Timestamp, message.From)
+	return err
+}
+
+// GetConversations returns the inbox of the user, most recently active conversations first, or the message
+// requests of the user unless accepted. The peer of a direct conversation with oneself is oneself, and the
+// profile of a peer who blocked the user is left out.
+func (c conversation) GetConversations(ctx *gofr.Context, userId string, accepted bool, page, limit uint) (*[]model.Conversation, error) {
+	query := `SELECT c.id, c.kind, c.name, c.handle, peer.id, peer.name, peer.phoneNumber, me.unread_count, me.last_message_at,
+		m.id, m.content, m.senderId, m.recieverId, m.timestamp, m.deletedAt, c.disappear_after, c.disappear_from, dr.content, dr.updated_at,
+		me.muted AND (me.muted_until IS NULL OR me.muted_until > now()), me.muted_until
+	FROM conversation_members me
+	JOIN conversations c ON c.id = me.conversation_id
+	LEFT JOIN LATERAL (
+		SELECT a.id, CASE WHEN b.blocker_id IS NULL THEN a.name END AS name, CASE WHEN b.blocker_id IS NULL THEN a.phoneNumber END AS phoneNumber
+		FROM conversation_members p JOIN accounts a ON a.id = p.account_id
+		LEFT JOIN blocks b ON b.blocker_id = a.id AND b.blocked_id = me.account_id
+		WHERE c.kind = 'direct' AND p.conversation_id = c.id
+		ORDER BY p.account_id = me.account_id LIMIT 1
+	) peer ON true
+	LEFT JOIN messages m ON m.id = me.last_message_id AND (m.expiresAt IS NULL OR m.expiresAt > now())
+		AND NOT EXISTS (SELECT 1 FROM message_deletions d WHERE d.message_id = m.id AND d.account_id = me.account_id)
+	LEFT JOIN drafts dr ON dr.account_id = me.account_id AND dr.conversation_id = me.conversation_id
+	WHERE me.account_id = $1 AND me.accepted = $4
+	ORDER BY me.last_message_at DESC LIMIT $2 OFFSET $3`
+
+	rows, err := ctx.DB().QueryContext(ctx, query, userId, limit, (page-1)*limit, accepted)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	conversations := make([]model.Conversation, 0)
+
+	for rows.Next() {
+		var conversation model.Conversation
+		var name, handle, peerId, peerName sql.NullString
+		var peerPhoneNumber sql.NullInt64
+		var messageId, content, from, to, disappearFrom, draft sql.NullString
+		var timestamp, deletedAt, draftUpdatedAt, mutedUntil sql.NullTime
+		var disappearAfter sql.NullInt64
+
+		err = rows.Scan(&conversation.ID, &conversation.Type, &name, &handle, &peerId, &peerName, &peerPhoneNumber,
+			&conversation.UnreadCount, &conversation.LastMessageAt,
+			&messageId, &content, &from, &to, &timestamp, &deletedAt, &disappearAfter, &disappearFrom, &draft, &draftUpdatedAt,
+			&conversation.Muted, &mutedUntil)
+		if err != nil {
+			return nil, err
+		}
+
+		if draft.Valid {
+			conversation.Draft = &model.Draft{ConversationID: conversation.ID, Content: draft.String, UpdatedAt: draftUpdatedAt.Time}
+		}
+
+		if conversation.Muted && mutedUntil.Valid {
+			conversation.MutedUntil = &mutedUntil.Time
+		}
+
+		if disappearAfter.Valid {
+			conversation.DisappearingTimer = &model.DisappearingTimer{After: uint(disappearAfter.Int64), From: disappearFrom.String}
+		}
+
+		conversation.Name, conversation.Handle = name.String, handle.String
+		if peerId.Valid {
+			conversation.Peer = &model.User{ID: peerId.String, Name: peerName.String, PhoneNumber: uint64(peerPhoneNumber.Int64)}
+		}
+
+		if messageId.Valid {
+			conversation.LastMessage = &model.Message{
+				ID:             messageId.String,
+				ConversationID: conversation.ID,
+				Content:        content.String,
+				From:           from.String,
+				To:             to.String,
+				Timestamp:      timestamp.Time,
+			}
+			if deletedAt.Valid {
+				conversation.LastMessage.Content = ""
+				conversation.LastMessage.Deleted = true
+				conversation.LastMessage.DeletedAt = &deletedAt.Time
+			}
+		}
+
+		conversations = append(conversations, conversation)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &conversations, nil
+}
+
+// GetMemberIds returns the members of a conversation, for events to be fanned out to.
+func (c conversation) GetMemberIds(ctx *gofr.Context, conversationId string) ([]string, error) {
+	rows, err := ctx.DB().QueryContext(ctx, "SELECT account_id FROM conversation_members WHERE conversation_id=$1", conversationId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	memberIds := make([]string, 0)
+
+	for rows.Next() {
+		var memberId string
+		err = rows.Scan(&memberId)
+		if err != nil {
+			return nil, err
+		}
+
+		memberIds = append(memberIds, memberId)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return memberIds, nil
+}
+
+// MarkRead clears the unread count of a conversation of the user, and starts the timers of the disappearing messages
+// the user received that count from the time they are read. Direct conversations may also be referred to by the ID
+// of the peer, as they were before groups existed.
+func (c conversation) MarkRead(ctx *gofr.Context, userId, conversationId string) error {
+	query := `WITH started AS (
+		UPDATE messages SET expiresAt = $4 + disappearAfter * interval '1 second'
+		WHERE conversationId::text IN ($2, $3) AND senderId <> $1 AND disappearAfter IS NOT NULL AND expiresAt IS NULL AND scheduledAt IS NULL
+		AND EXISTS (SELECT 1 FROM conversation_members cm WHERE cm.conversation_id = messages.conversationId AND cm.account_id = $1)
+	)
+	UPDATE conversation_members SET unread_count=0 WHERE account_id=$1 AND conversation_id::text IN ($2, $3)`
+
+	_, err := ctx.DB().ExecContext(ctx, query, userId, conversationId, DirectConversationID(userId, conversationId), time.Now())
+	return err
+}
+
+// GetDisappearingTimer returns the disappearing timer of a conversation, or nil when its messages do not disappear.
+func (c conversation) GetDisappearingTimer(ctx *gofr.Context, conversationId string) (*model.DisappearingTimer, error) {
+	var after sql.NullInt64
+	var from sql.NullString
+	err := ctx.DB().QueryRowContext(ctx, "SELECT disappear_after, disappear_from FROM conversations WHERE id=$1", conversationId).Scan(&after, &from)
+	if err != nil {
+		return nil, err
+	}
+
+	if !after.Valid {
+		return nil, nil
+	}
+	return &model.DisappearingTimer{After: uint(after.Int64), From: from.String}, nil
+}
+
+// SetDisappearingTimer sets the disappearing timer of a direct conversation or group the user is a member of,
+// turning it off for a timer of 0 seconds. Direct conversations may also be referred to by the ID of the peer.
+// It returns the ID and type of the conversation.
+func (c conversation) SetDisappearingTimer(ctx *gofr.Context, userId, conversationId string, timer model.DisappearingTimer) (*model.Conversation, error) {
+	after := sql.NullInt64{Int64: int64(timer.After), Valid: timer.After > 0}
+	from := sql.NullString{String: timer.From, Valid: timer.After > 0}
+
+	query := `UPDATE conversations c SET disappear_after=$4, disappear_from=$5
+	WHERE c.id::text IN ($2, $3) AND c.kind IN ('direct', 'group')
+	AND EXISTS (SELECT 1 FROM conversation_members cm WHERE cm.conversation_id = c.id AND cm.account_id = $1)
+	RETURNING c.id, c.kind`
+
+	var conversation model.Conversation
+	err := ctx.DB().QueryRowContext(ctx, query, userId, conversationId, DirectConversationID(userId, conversationId), after, from).
+		Scan(&conversation.ID, &conversation.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	if after.Valid {
+		conversation.DisappearingTimer = &timer
+	}
+	return &conversation, nil
+}
+
+// AcceptMessageRequest moves a message request of the user to the user's inbox.
+func (c conversation) AcceptMessageRequest(ctx *gofr.Context, userId, conversationId string) error {
+	result, err := ctx.DB().ExecContext(ctx, "UPDATE conversation_members SET accepted=true WHERE account_id=$1 AND conversation_id::text=$2 AND NOT accepted",
+		userId, conversationId)
+	if err != nil {
+		return err
+	}
+
+	accepted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if accepted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SaveDraft saves the draft of the user in a conversation the user is a member of, or in the direct conversation
+// with a peer, which need not have been started yet. Conversations may also be referred to by the ID of the peer.
+// A draft older than the one saved already is not saved. It returns the draft now saved and whether it was updated.
+func (c conversation) SaveDraft(ctx *gofr.Context, userId, conversationId string, content string, updatedAt time.Time) (*model.Draft, bool, error) {
+	query := `INSERT INTO drafts (account_id, conversation_id, content, updated_at)
+	SELECT $1, t.id, $4, $5 FROM (
+		SELECT conversation_id AS id FROM conversation_members WHERE account_id = $1 AND conversation_id::text IN ($2, $3)
+		UNION SELECT $3::uuid FROM accounts WHERE id::text = $2
+	) t LIMIT 1
+	ON CONFLICT (account_id, conversation_id) DO UPDATE SET content = EXCLUDED.content, updated_at = EXCLUDED.updated_at
+	WHERE drafts.updated_at < EXCLUDED.updated_at
+	RETURNING conversation_id, content, updated_at`
+
+	var draft model.Draft
+	err := ctx.DB().QueryRowContext(ctx, query, userId, conversationId, DirectConversationID(userId, conversationId), content, updatedAt).
+		Scan(&draft.ConversationID, &draft.Content, &draft.UpdatedAt)
+	if err == sql.ErrNoRows {
+		// Either there is no such conversation, or a newer draft was saved already.
+		current, err := c.GetDraft(ctx, userId, conversationId)
+		return current, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &draft, true, nil
+}
+
+// GetDraft returns the draft of the user in a conversation. Direct conversations may also be referred to by the ID of the peer.
+func (c conversation) GetDraft(ctx *gofr.Context, userId, conversationId string) (*model.Draft, error) {
+	query := `SELECT conversation_id, content, updated_at FROM drafts WHERE account_id = $1 AND conversation_id::text IN ($2, $3)`
+
+	var draft model.Draft
+	err := ctx.DB().QueryRowContext(ctx, query, userId, conversationId, DirectConversationID(userId, conversationId)).
+		Scan(&draft.ConversationID, &draft.Content, &draft.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// DeleteDraft deletes the draft of the user in a conversation, returning it, or nil when there was none.
+// Direct conversations may also be referred to by the ID of the peer.
+func (c conversation) DeleteDraft(ctx *gofr.Context, userId, conversationId string) (*model.Draft, error) {
+	query := `DELETE FROM drafts WHERE account_id = $1 AND conversation_id::text IN ($2, $3) RETURNING conversation_id, content, updated_at`
+
+	var draft model.Draft
+	err := ctx.DB().QueryRowContext(ctx, query, userId, conversationId, DirectConversationID(userId, conversationId)).
+		Scan(&draft.ConversationID, &draft.Content, &draft.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// GetMemberNames returns the names of those of the given users who are members of a conversation, by their IDs.
+func (c conversation) GetMemberNames(ctx *gofr.Context, conversationId string, userIds []string) (map[string]string, error) {
+	names := make(map[string]string)
+	if len(userIds) == 0 {
+		return names, nil
+	}
+
+	args := []interface{}{conversationId}
+	for _, userId := range userIds {
+		args = append(args, userId)
+	}
+
+	query := `SELECT a.id, a.name FROM conversation_members m JOIN accounts a ON a.id = m.account_id
+	WHERE m.conversation_id = $1 AND m.account_id::text IN (` + placeholders(2, len(userIds)) + `)`
+
+	rows, err := ctx.DB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, name string
+		err = rows.Scan(&id, &name)
+		if err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// Mute mutes a conversation of the user until the given time, or until it is unmuted if nil.
+// Direct conversations may also be referred to by the ID of the peer.
+func (c conversation) Mute(ctx *gofr.Context, userId, conversationId string, until *time.Time) error {
+	query := `UPDATE conversation_members SET muted = true, muted_until = $4 WHERE account_id = $1 AND conversation_id::text IN ($2, $3)`
+	return c.setMuted(ctx, query, userId, conversationId, until)
+}
+
+// Unmute unmutes a conversation of the user. Direct conversations may also be referred to by the ID of the peer.
+func (c conversation) Unmute(ctx *gofr.Context, userId, conversationId string) error {
+	query := `UPDATE conversation_members SET muted = false, muted_until = NULL WHERE account_id = $1 AND conversation_id::text IN ($2, $3)`
+	return c.setMuted(ctx, query, userId, conversationId)
+}
+
+// setMuted runs a query muting or unmuting a conversation, returning sql.ErrNoRows if the user is not a member.
+func (c conversation) setMuted(ctx *gofr.Context, query, userId, conversationId string, args ...interface{}) error {
+	args = append([]interface{}{userId, conversationId, DirectConversationID(userId, conversationId)}, args...)
+	result, err := ctx.DB().ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (conversation) createConversationsTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS conversations (
+		id UUID PRIMARY KEY,
+		kind TEXT NOT NULL,
+		name TEXT,
+		created_by UUID,
+		created_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (created_by) REFERENCES accounts(id)
+	);
+	ALTER TABLE conversations ADD COLUMN IF NOT EXISTS handle TEXT UNIQUE;
+	ALTER TABLE conversations ADD COLUMN IF NOT EXISTS description TEXT;
+	ALTER TABLE conversations ADD COLUMN IF NOT EXISTS disappear_after INT;
+	ALTER TABLE conversations ADD COLUMN IF NOT EXISTS disappear_from TEXT;`
+	_, err := db.Exec(query)
+	return err
+}
+
+// createConversationMembersTable creates the memberships of conversations. Each membership
+// also keeps the member's inbox entry for the conversation: its last message and unread count,
+// and whether it is in the inbox at all or still a message request.
+func (conversation) createConversationMembersTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS conversation_members (
+		conversation_id UUID NOT NULL,
+		account_id UUID NOT NULL,
+		role TEXT NOT NULL DEFAULT 'member',
+		joined_at TIMESTAMP NOT NULL,
+		last_message_id UUID,
+		last_message_at TIMESTAMP NOT NULL,
+		unread_count INT NOT NULL DEFAULT 0,
+		FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+		FOREIGN KEY (account_id) REFERENCES accounts(id),
+		PRIMARY KEY (conversation_id, account_id)
+	);
+	CREATE INDEX IF NOT EXISTS conversation_members_recent_idx ON conversation_members (account_id, last_message_at DESC);
+	ALTER TABLE conversation_members ADD COLUMN IF NOT EXISTS accepted BOOLEAN NOT NULL DEFAULT true;
+	ALTER TABLE conversation_members ADD COLUMN IF NOT EXISTS muted BOOLEAN NOT NULL DEFAULT false;
+	ALTER TABLE conversation_members ADD COLUMN IF NOT EXISTS muted_until TIMESTAMP;`
+	_, err := db.Exec(query)
+	return err
+}
+
+// createDraftsTable creates the drafts of users, one per conversation. Drafts to a peer may predate
+// the direct conversation with them, so they do not refer to conversations.
+func (conversation) createDraftsTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS drafts (
+		account_id UUID NOT NULL,
+		conversation_id UUID NOT NULL,
+		content TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+		PRIMARY KEY (account_id, conversation_id)
+	);`
+	_, err := db.Exec(query)
+	return err
+}
+
+// backfillDirectConversations creates direct conversations for messages that predate conversations,
+// and carries the unread counts over from the conversation summaries the memberships replace.
+func (conversation) backfillDirectConversations(db *datastore.SQLClient) error {
+	byMessage := fmt.Sprintf(directConversationID, "senderId", "recieverId")
+	bySummary := fmt.Sprintf(directConversationID, "s.owner_id", "s.peer_id")
+
+	query := `INSERT INTO conversations (id, kind, created_at)
+	SELECT ` + byMessage + `, 'direct', MIN(timestamp) FROM messages WHERE conversationId IS NULL GROUP BY 1
+	ON CONFLICT DO NOTHING;
+
+	INSERT INTO conversation_members (conversation_id, account_id, joined_at, last_message_id, last_message_at)
+	SELECT p.conversation_id, p.account_id, MIN(p.timestamp), (ARRAY_AGG(p.id ORDER BY p.timestamp DESC))[1], MAX(p.timestamp)
+	FROM (
+		SELECT ` + byMessage + ` AS conversation_id, senderId AS account_id, id, timestamp FROM messages WHERE conversationId IS NULL
+		UNION ALL
+		SELECT ` + byMessage + ` AS conversation_id, recieverId AS account_id, id, timestamp FROM messages WHERE conversationId IS NULL
+	) p
+	JOIN accounts a ON a.id = p.account_id
+	GROUP BY p.conversation_id, p.account_id
+	ON CONFLICT DO NOTHING;
+
+	UPDATE messages SET conversationId = ` + byMessage + ` WHERE conversationId IS NULL;
+
+	DO $$ BEGIN
+		IF to_regclass('conversation_summaries') IS NOT NULL THEN
+			UPDATE conversation_members m SET unread_count = s.unread_count FROM conversation_summaries s
+			WHERE m.account_id = s.owner_id AND m.conversation_id = ` + bySummary + `;
+			DROP TABLE conversation_summaries;
+		END IF;
+	END $$;`
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,441 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+func TestDirectConversationID(t *testing.T) {
+	id := DirectConversationID("user-id", "peer-id")
+
+	assert.Equal(t, "163f2dcd-7628-3597-4cb9-b5119d077d87", id, "Mismatch in direct conversation ID")
+	assert.Equal(t, id, DirectConversationID("peer-id", "user-id"), "Expected both participants to get the same conversation")
+	assert.Equal(t, id, DirectConversationID("PEER-ID", "user-id"), "Expected IDs to be compared case insensitively")
+	assert.NotEqual(t, id, DirectConversationID("user-id", "other-id"), "Expected different peers to get different conversations")
+}
+
+func TestEnsureDirectConversation(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	conversationStore := conversation{}
+
+	mock.ExpectExec(`INSERT INTO conversations .* 'direct'.* INSERT INTO conversation_members .*\(VALUES \(\$2::UUID, true\), \(\$3::UUID, \$5::BOOLEAN\).* DO UPDATE SET accepted = conversation_members.accepted OR EXCLUDED.accepted`).
+		WithArgs(DirectConversationID("user-id", "peer-id"), "user-id", "peer-id", sqlmock.AnyArg(), false).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	conversationId, err := conversationStore.EnsureDirectConversation(ctx, "user-id", "peer-id", false)
+
+	assert.NoError(t, err, "Unexpected error while ensuring a direct conversation")
+	assert.Equal(t, DirectConversationID("user-id", "peer-id"), conversationId, "Mismatch in conversation ID")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectExec("INSERT INTO conversations").
+		WillReturnError(fmt.Errorf(""))
+
+	_, err = conversationStore.EnsureDirectConversation(ctx, "user-id", "peer-id", true)
+
+	assert.Error(t, err, "Expected an error while ensuring a direct conversation")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRecordMessage(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	conversationStore := conversation{}
+
+	sampleMessage := model.Message{
+		ID:             "test-message-id",
+		ConversationID: "test-conversation-id",
+		Content:        "Hello, world!",
+		From:           "sender-user-id",
+		Timestamp:      time.Now(),
+	}
+
+	mock.ExpectExec(`UPDATE conversation_members SET .* unread_count = unread_count \+ CASE WHEN account_id = \$4 THEN 0 ELSE 1 END\s+WHERE conversation_id = \$1`).
+		WithArgs(sampleMessage.ConversationID, sampleMessage.ID, sampleMessage.Timestamp, sampleMessage.From).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	err = conversationStore.RecordMessage(ctx, sampleMessage)
+
+	assert.NoError(t, err, "Unexpected error while recording message")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectExec("UPDATE conversation_members").
+		WillReturnError(fmt.Errorf(""))
+
+	err = conversationStore.RecordMessage(ctx, sampleMessage)
+
+	assert.Error(t, err, "Expected an error while recording message")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetConversations(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	conversationStore := conversation{}
+
+	userID := "test-user-id"
+	page := uint(2)
+	limit := uint(10)
+	now := time.Now()
+
+	columns := []string{"id", "kind", "name", "handle", "id", "name", "phoneNumber", "unread_count", "last_message_at", "id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "disappear_after", "disappear_from", "content", "updated_at", "muted", "muted_until"}
+
+	mock.ExpectQuery("SELECT c.id, c.kind, c.name, c.handle, peer.id, peer.name, peer.phoneNumber, me.unread_count, me.last_message_at").
+		WithArgs(userID, limit, (page-1)*limit, true).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("conversation-1", "direct", nil, nil, "peer-1", "Peer One", uint64(1234567890), uint(3), now, "message-id-1", "Hello", "peer-1", userID, now, nil, 86400, "read", "See you", now, false, nil).
+			AddRow("conversation-2", "direct", nil, nil, "peer-2", "Peer Two", uint64(1234567891), uint(0), now, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, true, nil).
+			AddRow("conversation-3", "direct", nil, nil, "peer-3", "Peer Three", uint64(1234567892), uint(1), now, "message-id-3", "", "peer-3", userID, now, now, nil, nil, nil, nil, false, now).
+			AddRow("group-1", "group", "Cats", nil, nil, nil, nil, uint(2), now, "message-id-4", "Meow", "peer-1", nil, now, nil, nil, nil, nil, nil, true, now).
+			AddRow("channel-1", "channel", "Cat News", "catnews", nil, nil, nil, uint(0), now, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, nil))
+
+	conversations, err := conversationStore.GetConversations(ctx, userID, true, page, limit)
+
+	assert.NoError(t, err, "Unexpected error during conversation retrieval")
+	assert.Len(t, *conversations, 5, "Unexpected number of retrieved conversations")
+	assert.Equal(t, uint(3), (*conversations)[0].UnreadCount, "Mismatch in unread count")
+	assert.Equal(t, "peer-1", (*conversations)[0].Peer.ID, "Mismatch in peer of a direct conversation")
+	assert.Equal(t, "Hello", (*conversations)[0].LastMessage.Content, "Mismatch in last message preview")
+	assert.Equal(t, &model.DisappearingTimer{After: 86400, From: model.DisappearFromRead}, (*conversations)[0].DisappearingTimer, "Mismatch in disappearing timer")
+	assert.Nil(t, (*conversations)[1].LastMessage, "Expected no preview for a purged or hidden last message")
+	assert.Nil(t, (*conversations)[1].DisappearingTimer, "Expected no disappearing timer for a conversation without one")
+	assert.Equal(t, &model.Draft{ConversationID: "conversation-1", Content: "See you", UpdatedAt: now}, (*conversations)[0].Draft, "Mismatch in draft")
+	assert.Nil(t, (*conversations)[1].Draft, "Expected no draft for a conversation without one")
+	assert.True(t, (*conversations)[2].LastMessage.Deleted, "Expected a tombstone preview for a last message deleted for everyone")
+	assert.Equal(t, model.ConversationGroup, (*conversations)[3].Type, "Mismatch in conversation type")
+	assert.Equal(t, "Cats", (*conversations)[3].Name, "Mismatch in group name")
+	assert.Nil(t, (*conversations)[3].Peer, "Expected no peer for a group")
+	assert.Equal(t, "group-1", (*conversations)[3].LastMessage.ConversationID, "Mismatch in conversation of the last message")
+	assert.Equal(t, "catnews", (*conversations)[4].Handle, "Mismatch in channel handle")
+	assert.False(t, (*conversations)[0].Muted, "Expected an unmuted conversation")
+	assert.True(t, (*conversations)[1].Muted, "Expected a conversation muted until unmuted")
+	assert.Nil(t, (*conversations)[1].MutedUntil, "Expected no end to a mute until unmuted")
+	assert.Nil(t, (*conversations)[2].MutedUntil, "Expected no end to the mute of a conversation no longer muted")
+	assert.Equal(t, &now, (*conversations)[3].MutedUntil, "Mismatch in the end of a mute")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT c.id, c.kind, c.name").
+		WithArgs(userID, limit, (page-1)*limit, true).
+		WillReturnError(fmt.Errorf(""))
+
+	_, err = conversationStore.GetConversations(ctx, userID, true, page, limit)
+
+	assert.Error(t, err, "Expected an error during failed conversation retrieval")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetMemberIds(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	conversationStore := conversation{}
+
+	mock.ExpectQuery("SELECT account_id FROM conversation_members WHERE conversation_id=\\$1").
+		WithArgs("test-conversation-id").
+		WillReturnRows(sqlmock.NewRows([]string{"account_id"}).AddRow("user-1").AddRow("user-2"))
+
+	memberIds, err := conversationStore.GetMemberIds(ctx, "test-conversation-id")
+
+	assert.NoError(t, err, "Unexpected error while retrieving members")
+	assert.Equal(t, []string{"user-1", "user-2"}, memberIds, "Mismatch in members")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	conversationStore := conversation{}
+
+	mock.ExpectExec("WITH started AS \\( UPDATE messages SET expiresAt = \\$4 \\+ disappearAfter \\* interval '1 second' "+
+		"WHERE conversationId::text IN \\(\\$2, \\$3\\) AND senderId <> \\$1 AND disappearAfter IS NOT NULL AND expiresAt IS NULL AND scheduledAt IS NULL "+
+		"AND EXISTS .* cm.account_id = \\$1\\) \\) UPDATE conversation_members SET unread_count=0 WHERE account_id=\\$1 AND conversation_id::text IN \\(\\$2, \\$3\\)").
+		WithArgs("test-user-id", "test-peer-id", DirectConversationID("test-user-id", "test-peer-id"), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = conversationStore.MarkRead(ctx, "test-user-id", "test-peer-id")
+
+	assert.NoError(t, err, "Unexpected error while marking conversation read")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAcceptMessageRequest(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	conversationStore := conversation{}
+
+	mock.ExpectExec("UPDATE conversation_members SET accepted=true WHERE account_id=\\$1 AND conversation_id::text=\\$2 AND NOT accepted").
+		WithArgs("test-user-id", "test-conversation-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := conversationStore.AcceptMessageRequest(ctx, "test-user-id", "test-conversation-id")
+
+	assert.NoError(t, err, "Unexpected error while accepting a message request")
+
+	mock.ExpectExec("UPDATE conversation_members SET accepted=true").
+		WithArgs("test-user-id", "test-conversation-id").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = conversationStore.AcceptMessageRequest(ctx, "test-user-id", "test-conversation-id")
+
+	assert.Equal(t, sql.ErrNoRows, err, "Expected only pending message requests to be accepted")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDisappearingTimer(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	conversationStore := conversation{}
+
+	mock.ExpectQuery("SELECT disappear_after, disappear_from FROM conversations WHERE id=\\$1").
+		WithArgs("conversation-1").
+		WillReturnRows(sqlmock.NewRows([]string{"disappear_after", "disappear_from"}).AddRow(3600, "sent"))
+	mock.ExpectQuery("SELECT disappear_after, disappear_from FROM conversations WHERE id=\\$1").
+		WithArgs("conversation-2").
+		WillReturnRows(sqlmock.NewRows([]string{"disappear_after", "disappear_from"}).AddRow(nil, nil))
+
+	timer, err := conversationStore.GetDisappearingTimer(ctx, "conversation-1")
+
+	assert.NoError(t, err, "Unexpected error while retrieving the disappearing timer")
+	assert.Equal(t, &model.DisappearingTimer{After: 3600, From: model.DisappearFromSent}, timer, "Mismatch in disappearing timer")
+
+	timer, err = conversationStore.GetDisappearingTimer(ctx, "conversation-2")
+
+	assert.NoError(t, err, "Unexpected error while retrieving the disappearing timer")
+	assert.Nil(t, timer, "Expected no timer for a conversation whose messages do not disappear")
+
+	mock.ExpectQuery("UPDATE conversations c SET disappear_after=\\$4, disappear_from=\\$5 WHERE c.id::text IN \\(\\$2, \\$3\\) AND c.kind IN \\('direct', 'group'\\) "+
+		"AND EXISTS .* cm.account_id = \\$1\\) RETURNING c.id, c.kind").
+		WithArgs("user-1", "user-2", DirectConversationID("user-1", "user-2"), sql.NullInt64{Int64: 3600, Valid: true}, sql.NullString{String: "read", Valid: true}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind"}).AddRow(DirectConversationID("user-1", "user-2"), model.ConversationDirect))
+
+	conversation, err := conversationStore.SetDisappearingTimer(ctx, "user-1", "user-2", model.DisappearingTimer{After: 3600, From: model.DisappearFromRead})
+
+	assert.NoError(t, err, "Unexpected error while setting the disappearing timer")
+	assert.Equal(t, &model.Conversation{ID: DirectConversationID("user-1", "user-2"), Type: model.ConversationDirect,
+		DisappearingTimer: &model.DisappearingTimer{After: 3600, From: model.DisappearFromRead}}, conversation, "Mismatch in updated conversation")
+
+	mock.ExpectQuery("UPDATE conversations c SET disappear_after=\\$4").
+		WithArgs("user-1", "group-1", DirectConversationID("user-1", "group-1"), sql.NullInt64{}, sql.NullString{}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind"}).AddRow("group-1", model.ConversationGroup))
+
+	conversation, err = conversationStore.SetDisappearingTimer(ctx, "user-1", "group-1", model.DisappearingTimer{})
+
+	assert.NoError(t, err, "Unexpected error while turning the disappearing timer off")
+	assert.Nil(t, conversation.DisappearingTimer, "Expected the disappearing timer to be off")
+
+	mock.ExpectQuery("UPDATE conversations c SET disappear_after=\\$4").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = conversationStore.SetDisappearingTimer(ctx, "user-1", "channel-1", model.DisappearingTimer{})
+
+	assert.Equal(t, sql.ErrNoRows, err, "Expected only direct conversations and groups of the user to be updated")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDrafts(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	conversationStore := conversation{}
+
+	conversationID := DirectConversationID("user-1", "user-2")
+	updatedAt := time.Now()
+	columns := []string{"conversation_id", "content", "updated_at"}
+
+	mock.ExpectQuery("INSERT INTO drafts .* FROM conversation_members WHERE account_id = \\$1 AND conversation_id::text IN \\(\\$2, \\$3\\) "+
+		"UNION SELECT \\$3::uuid FROM accounts WHERE id::text = \\$2 .* WHERE drafts.updated_at < EXCLUDED.updated_at RETURNING conversation_id, content, updated_at").
+		WithArgs("user-1", "user-2", conversationID, "See you", updatedAt).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(conversationID, "See you", updatedAt))
+
+	draft, updated, err := conversationStore.SaveDraft(ctx, "user-1", "user-2", "See you", updatedAt)
+
+	assert.NoError(t, err, "Unexpected error while saving a draft")
+	assert.True(t, updated, "Expected the draft to be saved")
+	assert.Equal(t, &model.Draft{ConversationID: conversationID, Content: "See you", UpdatedAt: updatedAt}, draft, "Mismatch in saved draft")
+
+	mock.ExpectQuery("INSERT INTO drafts").
+		WithArgs("user-1", "user-2", conversationID, "See", updatedAt.Add(-time.Second)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT conversation_id, content, updated_at FROM drafts WHERE account_id = \\$1 AND conversation_id::text IN \\(\\$2, \\$3\\)").
+		WithArgs("user-1", "user-2", conversationID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(conversationID, "See you", updatedAt))
+
+	draft, updated, err = conversationStore.SaveDraft(ctx, "user-1", "user-2", "See", updatedAt.Add(-time.Second))
+
+	assert.NoError(t, err, "Unexpected error while saving a stale draft")
+	assert.False(t, updated, "Expected a draft older than the saved one not to be saved")
+	assert.Equal(t, "See you", draft.Content, "Expected the newer draft to be kept")
+
+	mock.ExpectQuery("INSERT INTO drafts").
+		WithArgs("user-1", "group-1", DirectConversationID("user-1", "group-1"), "Meow", updatedAt).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT conversation_id, content, updated_at FROM drafts").
+		WithArgs("user-1", "group-1", DirectConversationID("user-1", "group-1")).
+		WillReturnError(sql.ErrNoRows)
+
+	_, _, err = conversationStore.SaveDraft(ctx, "user-1", "group-1", "Meow", updatedAt)
+
+	assert.Equal(t, sql.ErrNoRows, err, "Expected drafts to be saved only in conversations of the user")
+
+	mock.ExpectQuery("DELETE FROM drafts WHERE account_id = \\$1 AND conversation_id::text IN \\(\\$2, \\$3\\) RETURNING conversation_id, content, updated_at").
+		WithArgs("user-1", "user-2", conversationID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(conversationID, "See you", updatedAt))
+	mock.ExpectQuery("DELETE FROM drafts").
+		WithArgs("user-1", "user-2", conversationID).
+		WillReturnError(sql.ErrNoRows)
+
+	draft, err = conversationStore.DeleteDraft(ctx, "user-1", "user-2")
+
+	assert.NoError(t, err, "Unexpected error while deleting a draft")
+	assert.Equal(t, conversationID, draft.ConversationID, "Mismatch in deleted draft")
+
+	draft, err = conversationStore.DeleteDraft(ctx, "user-1", "user-2")
+
+	assert.NoError(t, err, "Unexpected error while deleting a missing draft")
+	assert.Nil(t, draft, "Expected no draft to be deleted")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetMemberNames(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	conversationStore := conversation{}
+
+	mock.ExpectQuery("SELECT a.id, a.name FROM conversation_members m JOIN accounts a ON a.id = m.account_id "+
+		"WHERE m.conversation_id = \\$1 AND m.account_id::text IN \\(\\$2,\\$3\\)").
+		WithArgs("group-1", "user-1", "stranger").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("user-1", "Legoshi"))
+
+	names, err := conversationStore.GetMemberNames(ctx, "group-1", []string{"user-1", "stranger"})
+
+	assert.NoError(t, err, "Unexpected error while retrieving member names")
+	assert.Equal(t, map[string]string{"user-1": "Legoshi"}, names, "Expected the names of members only")
+
+	names, err = conversationStore.GetMemberNames(ctx, "group-1", nil)
+
+	assert.NoError(t, err, "Unexpected error without users")
+	assert.Empty(t, names, "Expected no names without users")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMute(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	conversationStore := conversation{}
+
+	conversationID := DirectConversationID("user-1", "user-2")
+	until := time.Now().Add(time.Hour)
+
+	mock.ExpectExec("UPDATE conversation_members SET muted = true, muted_until = \\$4 WHERE account_id = \\$1 AND conversation_id::text IN \\(\\$2, \\$3\\)").
+		WithArgs("user-1", "user-2", conversationID, &until).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := conversationStore.Mute(ctx, "user-1", "user-2", &until)
+	assert.NoError(t, err, "Unexpected error while muting a conversation")
+
+	mock.ExpectExec("UPDATE conversation_members SET muted = false, muted_until = NULL").
+		WithArgs("user-1", "user-2", conversationID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = conversationStore.Unmute(ctx, "user-1", "user-2")
+	assert.NoError(t, err, "Unexpected error while unmuting a conversation")
+
+	mock.ExpectExec("UPDATE conversation_members SET muted = true").
+		WithArgs("user-1", "stranger", DirectConversationID("user-1", "stranger"), nil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = conversationStore.Mute(ctx, "user-1", "stranger", nil)
+	assert.Equal(t, sql.ErrNoRows, err, "Expected an error muting a conversation the user is not a member of")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
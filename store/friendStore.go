@@ -1,6 +1,11 @@
 package store
 
 import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
 	"github.com/aryanA101a/legoshichat-backend/model"
 	"gofr.dev/pkg/datastore"
 	"gofr.dev/pkg/gofr"
@@ -9,10 +14,17 @@ import (
 type friend struct {
 }
 
+// FriendStore manages friendships and the requests that lead to them. Users only become
+// friends when one of them accepts a friend request from the other.
 type FriendStore interface {
-	AddFriend(ctx *gofr.Context,senderId,recieverId string)error
 	GetFriends(ctx *gofr.Context,userId string)(*[]model.User,error)
-
+	AreFriends(ctx *gofr.Context, userId1, userId2 string) (bool, error)
+	RemoveFriend(ctx *gofr.Context, userId, friendId string) error
+	SendFriendRequest(ctx *gofr.Context, senderId, recipientId string) error
+	AcceptFriendRequest(ctx *gofr.Context, recipientId, senderId string) error
+	DeclineFriendRequest(ctx *gofr.Context, recipientId, senderId string) error
+	CancelFriendRequest(ctx *gofr.Context, senderId, recipientId string) error
+	GetFriendRequests(ctx *gofr.Context, userId string, incoming bool) (*[]model.FriendRequest, error)
 }
 
 func NewFriendStore(db *datastore.SQLClient) FriendStore {
@@ -23,25 +35,21 @@ func NewFriendStore(db *datastore.SQLClient) FriendStore {
 
 func (f friend) init(db *datastore.SQLClient) {
 	createFriendsTable(db)
+	createFriendRequestsTable(db)
 }
 
-func (f friend)AddFriend(ctx *gofr.Context,senderId,recieverId string)error{
-	_, err := ctx.DB().ExecContext(ctx, `INSERT INTO friends (account_id1, account_id2)
-	VALUES (LEAST($1, $2)::UUID, GREATEST($1, $2)::UUID)
-	ON CONFLICT DO NOTHING`, senderId,recieverId)
-	return err
-}
-
+// GetFriends returns the friends of the user, leaving out friends either of them blocked.
 func (f friend)GetFriends(ctx *gofr.Context,userId string)(*[]model.User,error){
+	notBlocked := "NOT " + fmt.Sprintf(blockedEither, "u.id", "$1")
 	query:=`SELECT u.id, u.name,u.phoneNumber
 	FROM friends f
 	JOIN accounts u ON u.id = f.account_id2
-	WHERE f.account_id1 = $1
+	WHERE f.account_id1 = $1 AND ` + notBlocked + `
 	UNION
 	SELECT u.id, u.name,u.phoneNumber
 	FROM friends f
 	JOIN accounts u ON u.id = f.account_id1
-	WHERE f.account_id2 = $1`
+	WHERE f.account_id2 = $1 AND ` + notBlocked
 	
 	rows, err := ctx.DB().QueryContext(ctx, query, userId)
 	if err != nil {
@@ -71,6 +79,146 @@ func (f friend)GetFriends(ctx *gofr.Context,userId string)(*[]model.User,error){
 	return &users, nil
 }
 
+// AreFriends reports whether two users are friends. Users are not friends while either of them blocked the other.
+func (f friend) AreFriends(ctx *gofr.Context, userId1, userId2 string) (bool, error) {
+	var exists bool
+	err := ctx.DB().QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM friends
+	WHERE account_id1 = LEAST($1, $2)::UUID AND account_id2 = GREATEST($1, $2)::UUID)
+	AND NOT `+fmt.Sprintf(blockedEither, "$1::UUID", "$2::UUID"), userId1, userId2).
+		Scan(&exists)
+
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// RemoveFriend ends the friendship between the user and a friend.
+func (f friend) RemoveFriend(ctx *gofr.Context, userId, friendId string) error {
+	result, err := ctx.DB().ExecContext(ctx, `DELETE FROM friends
+	WHERE account_id1 = LEAST($1, $2)::UUID AND account_id2 = GREATEST($1, $2)::UUID`, userId, friendId)
+	if err != nil {
+		return err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return e.NewError("That user is not your friend")
+	}
+	return nil
+}
+
+// SendFriendRequest asks the recipient to become friends with the sender. Sending a request
+// again reopens it if it was declined or canceled, and changes nothing while it is pending.
+func (f friend) SendFriendRequest(ctx *gofr.Context, senderId, recipientId string) error {
+	_, err := ctx.DB().ExecContext(ctx, `INSERT INTO friend_requests (sender_id, recipient_id, status, created_at)
+	VALUES ($1, $2, 'pending', $3)
+	ON CONFLICT (sender_id, recipient_id) DO UPDATE SET status='pending', created_at=$3, responded_at=NULL
+	WHERE friend_requests.status <> 'pending'`, senderId, recipientId, time.Now())
+	return err
+}
+
+// AcceptFriendRequest makes the recipient of a pending friend request and its sender friends, and
+// accepts the messages the sender may have sent the recipient while they were strangers.
+func (f friend) AcceptFriendRequest(ctx *gofr.Context, recipientId, senderId string) error {
+	query := `WITH accepted AS (
+		UPDATE friend_requests SET status='accepted', responded_at=$3
+		WHERE sender_id=$2 AND recipient_id=$1 AND status='pending'
+		RETURNING sender_id, recipient_id
+	), befriended AS (
+		INSERT INTO friends (account_id1, account_id2)
+		SELECT LEAST(sender_id, recipient_id), GREATEST(sender_id, recipient_id) FROM accepted
+		ON CONFLICT DO NOTHING
+	), requested AS (
+		UPDATE conversation_members SET accepted=true
+		WHERE conversation_id=$4 AND EXISTS (SELECT 1 FROM accepted)
+	)
+	SELECT COUNT(*) FROM accepted`
+
+	var accepted int
+	err := ctx.DB().QueryRowContext(ctx, query, recipientId, senderId, time.Now(), DirectConversationID(recipientId, senderId)).Scan(&accepted)
+	if err != nil {
+		return err
+	}
+	if accepted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeclineFriendRequest turns down a pending friend request to the recipient.
+func (f friend) DeclineFriendRequest(ctx *gofr.Context, recipientId, senderId string) error {
+	return f.closeFriendRequest(ctx, senderId, recipientId, model.FriendRequestDeclined)
+}
+
+// CancelFriendRequest withdraws a pending friend request of the sender.
+func (f friend) CancelFriendRequest(ctx *gofr.Context, senderId, recipientId string) error {
+	return f.closeFriendRequest(ctx, senderId, recipientId, model.FriendRequestCanceled)
+}
+
+func (f friend) closeFriendRequest(ctx *gofr.Context, senderId, recipientId, status string) error {
+	result, err := ctx.DB().ExecContext(ctx, `UPDATE friend_requests SET status=$3, responded_at=$4
+	WHERE sender_id=$1 AND recipient_id=$2 AND status='pending'`, senderId, recipientId, status, time.Now())
+	if err != nil {
+		return err
+	}
+
+	closed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if closed == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetFriendRequests returns the pending friend requests the user received, or the ones the user sent, newest first.
+// Requests between users either of whom blocked the other are left out.
+func (f friend) GetFriendRequests(ctx *gofr.Context, userId string, incoming bool) (*[]model.FriendRequest, error) {
+	user, other := "recipient_id", "sender_id"
+	if !incoming {
+		user, other = other, user
+	}
+
+	query := fmt.Sprintf(`SELECT u.id, u.name, u.phoneNumber, r.status, r.created_at
+	FROM friend_requests r
+	JOIN accounts u ON u.id = r.%s
+	WHERE r.%s = $1 AND r.status = 'pending' AND NOT %s
+	ORDER BY r.created_at DESC`, other, user, fmt.Sprintf(blockedEither, "u.id", "$1"))
+
+	rows, err := ctx.DB().QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	requests := make([]model.FriendRequest, 0)
+
+	for rows.Next() {
+		var request model.FriendRequest
+
+		err = rows.Scan(&request.User.ID, &request.User.Name, &request.User.PhoneNumber, &request.Status, &request.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		requests = append(requests, request)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &requests, nil
+}
+
 func createFriendsTable(db *datastore.SQLClient) error {
 	query := `CREATE TABLE IF NOT EXISTS friends (
 		account_id1 UUID NOT NULL,
@@ -81,4 +229,22 @@ func createFriendsTable(db *datastore.SQLClient) error {
 	);`
 	_, err := db.Exec(query)
 	return err
-}
\ No newline at end of file
+}
+
+// createFriendRequestsTable creates the friend requests. A request is kept once it is answered,
+// with the answer as its status, and a pair of users has at most one request in each direction.
+func createFriendRequestsTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS friend_requests (
+		sender_id UUID NOT NULL,
+		recipient_id UUID NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		created_at TIMESTAMP NOT NULL,
+		responded_at TIMESTAMP,
+		FOREIGN KEY (sender_id) REFERENCES accounts(id),
+		FOREIGN KEY (recipient_id) REFERENCES accounts(id),
+		PRIMARY KEY (sender_id, recipient_id)
+	);
+	CREATE INDEX IF NOT EXISTS friend_requests_recipient_idx ON friend_requests (recipient_id, status);`
+	_, err := db.Exec(query)
+	return err
+}
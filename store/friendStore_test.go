@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+func TestAreFriends(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	friendStore := friend{}
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM friends").
+		WithArgs("user-1", "user-2").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	ok, err := friendStore.AreFriends(ctx, "user-1", "user-2")
+
+	assert.NoError(t, err, "Unexpected error during friendship check")
+	assert.True(t, ok, "Expected users to be friends")
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM friends").
+		WithArgs("user-1", "user-2").
+		WillReturnError(fmt.Errorf(""))
+
+	_, err = friendStore.AreFriends(ctx, "user-1", "user-2")
+
+	assert.Error(t, err, "Expected an error during failed friendship check")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRemoveFriend(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	friendStore := friend{}
+
+	mock.ExpectExec("DELETE FROM friends").
+		WithArgs("user-1", "user-2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := friendStore.RemoveFriend(ctx, "user-1", "user-2")
+
+	assert.NoError(t, err, "Unexpected error while unfriending")
+
+	mock.ExpectExec("DELETE FROM friends").
+		WithArgs("user-1", "user-3").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = friendStore.RemoveFriend(ctx, "user-1", "user-3")
+
+	assert.Equal(t, e.NewError("That user is not your friend"), err, "Expected only friends to be unfriended")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSendFriendRequest(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	friendStore := friend{}
+
+	mock.ExpectExec("INSERT INTO friend_requests .* ON CONFLICT \\(sender_id, recipient_id\\) DO UPDATE SET status='pending'.* WHERE friend_requests.status <> 'pending'").
+		WithArgs("user-1", "user-2", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := friendStore.SendFriendRequest(ctx, "user-1", "user-2")
+
+	assert.NoError(t, err, "Unexpected error while sending a friend request")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAcceptFriendRequest(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	friendStore := friend{}
+
+	mock.ExpectQuery("WITH accepted AS \\(\\s*UPDATE friend_requests SET status='accepted'.* INSERT INTO friends .* UPDATE conversation_members SET accepted=true.* SELECT COUNT\\(\\*\\) FROM accepted").
+		WithArgs("user-2", "user-1", sqlmock.AnyArg(), DirectConversationID("user-1", "user-2")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	err := friendStore.AcceptFriendRequest(ctx, "user-2", "user-1")
+
+	assert.NoError(t, err, "Unexpected error while accepting a friend request")
+
+	mock.ExpectQuery("WITH accepted AS").
+		WithArgs("user-2", "user-3", sqlmock.AnyArg(), DirectConversationID("user-2", "user-3")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	err = friendStore.AcceptFriendRequest(ctx, "user-2", "user-3")
+
+	assert.Equal(t, sql.ErrNoRows, err, "Expected only pending friend requests to be accepted")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCloseFriendRequest(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	friendStore := friend{}
+
+	mock.ExpectExec("UPDATE friend_requests SET status=\\$3").
+		WithArgs("user-1", "user-2", model.FriendRequestDeclined, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := friendStore.DeclineFriendRequest(ctx, "user-2", "user-1")
+
+	assert.NoError(t, err, "Unexpected error while declining a friend request")
+
+	mock.ExpectExec("UPDATE friend_requests SET status=\\$3").
+		WithArgs("user-1", "user-2", model.FriendRequestCanceled, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = friendStore.CancelFriendRequest(ctx, "user-1", "user-2")
+
+	assert.Equal(t, sql.ErrNoRows, err, "Expected only pending friend requests to be canceled")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetFriendRequests(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	friendStore := friend{}
+	now := time.Now()
+	columns := []string{"id", "name", "phoneNumber", "status", "created_at"}
+
+	mock.ExpectQuery("SELECT u.id, u.name, u.phoneNumber, r.status, r.created_at\\s+FROM friend_requests r\\s+JOIN accounts u ON u.id = r.sender_id\\s+WHERE r.recipient_id = \\$1 AND r.status = 'pending'").
+		WithArgs("user-2").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("user-1", "User One", uint64(1234567890), "pending", now))
+
+	requests, err := friendStore.GetFriendRequests(ctx, "user-2", true)
+
+	assert.NoError(t, err, "Unexpected error while retrieving incoming friend requests")
+	assert.Len(t, *requests, 1, "Unexpected number of friend requests")
+	assert.Equal(t, "user-1", (*requests)[0].User.ID, "Expected the sender of an incoming request")
+
+	mock.ExpectQuery("JOIN accounts u ON u.id = r.recipient_id\\s+WHERE r.sender_id = \\$1").
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	requests, err = friendStore.GetFriendRequests(ctx, "user-1", false)
+
+	assert.NoError(t, err, "Unexpected error while retrieving outgoing friend requests")
+	assert.Empty(t, *requests, "Expected no outgoing friend requests")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetFriends(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	friendStore := friend{}
+
+	mock.ExpectQuery("SELECT u.id, u.name,u.phoneNumber\\s+FROM friends f .* WHERE f.account_id1 = \\$1 AND NOT EXISTS \\(SELECT 1 FROM blocks\\s+WHERE \\(blocker_id = u.id AND blocked_id = \\$1\\) OR \\(blocker_id = \\$1 AND blocked_id = u.id\\)\\)\\s+UNION").
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "phoneNumber"}).AddRow("user-2", "User Two", uint64(1234567891)))
+
+	friends, err := friendStore.GetFriends(ctx, "user-1")
+
+	assert.NoError(t, err, "Unexpected error while retrieving friends")
+	assert.Len(t, *friends, 1, "Unexpected number of friends")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,325 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+type group struct {
+}
+
+// GroupStore manages group conversations and their members. Groups share their tables with
+// direct conversations, which are created by the ConversationStore.
+type GroupStore interface {
+	CreateGroup(ctx *gofr.Context, group model.Group, dropBlocked bool) error
+	GetGroup(ctx *gofr.Context, userId, groupId string) (*model.Group, error)
+	GetRole(ctx *gofr.Context, userId, groupId string) (string, error)
+	RenameGroup(ctx *gofr.Context, userId, groupId, name string) error
+	AddMembers(ctx *gofr.Context, userId, groupId string, memberIds []string, maxMembers int, dropBlocked bool) error
+	UpdateMemberRole(ctx *gofr.Context, userId, groupId, memberId, role string) error
+	RemoveMember(ctx *gofr.Context, userId, groupId, memberId string) error
+}
+
+func NewGroupStore(db *datastore.SQLClient) GroupStore {
+	return group{}
+}
+
+// CreateGroup creates a group with its creator as its first admin and everyone else in it as members.
+// Members who blocked the creator, or whom the creator blocked, are refused, or left out when dropBlocked is set.
+func (g group) CreateGroup(ctx *gofr.Context, group model.Group, dropBlocked bool) error {
+	memberIds := make([]string, 0, len(group.Members))
+	for _, member := range group.Members {
+		memberIds = append(memberIds, member.User.ID)
+	}
+
+	err := g.checkAccountsExist(ctx, memberIds)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{group.ID, group.Name, group.CreatedBy, group.CreatedAt}
+	for _, memberId := range memberIds {
+		args = append(args, memberId)
+	}
+
+	query := fmt.Sprintf(`WITH created AS (
+		INSERT INTO conversations (id, kind, name, created_by, created_at) VALUES ($1, 'group', $2, $3, $4)
+	)
+	INSERT INTO conversation_members (conversation_id, account_id, role, joined_at, last_message_at)
+	SELECT $1, a.id, CASE WHEN a.id = $3 THEN 'admin' ELSE 'member' END, $4, $4 FROM accounts a
+	WHERE a.id IN (%s) AND NOT %s`, placeholders(5, len(memberIds)), fmt.Sprintf(blockedEither, "a.id", "$3"))
+
+	return inTransaction(ctx, func(tx *sql.Tx) error {
+		if !dropBlocked {
+			err := g.checkNotBlocked(ctx, tx, group.CreatedBy, group.ID, memberIds)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+}
+
+// GetGroup returns a group the user is a member of, along with all of its members.
+func (g group) GetGroup(ctx *gofr.Context, userId, groupId string) (*model.Group, error) {
+	query := `SELECT c.id, c.name, c.created_by, c.created_at, a.id, a.name, a.phoneNumber, m.role, m.joined_at
+	FROM conversations c
+	JOIN conversation_members m ON m.conversation_id = c.id
+	JOIN accounts a ON a.id = m.account_id
+	WHERE c.id=$1 AND c.kind='group'
+	ORDER BY m.joined_at, a.name`
+
+	rows, err := ctx.DB().QueryContext(ctx, query, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var group *model.Group
+	member := false
+
+	for rows.Next() {
+		var g model.Group
+		var createdBy sql.NullString
+		var m model.GroupMember
+
+		err = rows.Scan(&g.ID, &g.Name, &createdBy, &g.CreatedAt, &m.User.ID, &m.User.Name, &m.User.PhoneNumber, &m.Role, &m.JoinedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if group == nil {
+			g.CreatedBy = createdBy.String
+			g.Members = make([]model.GroupMember, 0)
+			group = &g
+		}
+		group.Members = append(group.Members, m)
+		member = member || m.User.ID == userId
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if group == nil {
+		return nil, sql.ErrNoRows
+	}
+	if !member {
+		return nil, e.NewError("You are not a member of that group")
+	}
+	return group, nil
+}
+
+// RenameGroup renames a group the user is an admin of.
+func (g group) RenameGroup(ctx *gofr.Context, userId, groupId, name string) error {
+	err := g.requireAdmin(ctx, userId, groupId)
+	if err != nil {
+		return err
+	}
+
+	_, err = ctx.DB().ExecContext(ctx, "UPDATE conversations SET name=$2 WHERE id=$1", groupId, name)
+	return err
+}
+
+// AddMembers adds users to a group the user is an admin of, as long as it stays within maxMembers.
+// Users who already are members are left as they are, and blocked users are refused like in CreateGroup.
+func (g group) AddMembers(ctx *gofr.Context, userId, groupId string, memberIds []string, maxMembers int, dropBlocked bool) error {
+	err := g.requireAdmin(ctx, userId, groupId)
+	if err != nil {
+		return err
+	}
+
+	err = g.checkAccountsExist(ctx, memberIds)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{groupId}
+	for _, memberId := range memberIds {
+		args = append(args, memberId)
+	}
+
+	count := fmt.Sprintf(`SELECT COUNT(*), COUNT(*) FILTER (WHERE account_id IN (%s))
+	FROM conversation_members WHERE conversation_id=$1`, placeholders(2, len(memberIds)))
+
+	query := fmt.Sprintf(`INSERT INTO conversation_members (conversation_id, account_id, role, joined_at, last_message_at)
+	SELECT $1, a.id, 'member', $%[1]d, $%[1]d FROM accounts a WHERE a.id IN (%[2]s) AND NOT %[3]s
+	ON CONFLICT DO NOTHING`, len(args)+1, placeholders(2, len(memberIds)), fmt.Sprintf(blockedEither, "a.id", fmt.Sprintf("$%d", len(args)+2)))
+
+	// The group is locked while it is counted, so that concurrent additions cannot take it past maxMembers together.
+	return inTransaction(ctx, func(tx *sql.Tx) error {
+		err := lockConversation(ctx, tx, groupId)
+		if err != nil {
+			return err
+		}
+
+		var members, existing int
+		err = tx.QueryRowContext(ctx, count, args...).Scan(&members, &existing)
+		if err != nil {
+			return err
+		}
+		if members+len(memberIds)-existing > maxMembers {
+			return e.NewError("That group is full")
+		}
+
+		if !dropBlocked {
+			err = g.checkNotBlocked(ctx, tx, userId, groupId, memberIds)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, query, append(args, time.Now(), userId)...)
+		return err
+	})
+}
+
+// UpdateMemberRole makes a member of a group the user is an admin of an admin or a plain member.
+// The last admin of a group cannot step down.
+func (g group) UpdateMemberRole(ctx *gofr.Context, userId, groupId, memberId, role string) error {
+	err := g.requireAdmin(ctx, userId, groupId)
+	if err != nil {
+		return err
+	}
+
+	_, err = g.role(ctx, groupId, memberId)
+	if err != nil {
+		return err
+	}
+
+	result, err := ctx.DB().ExecContext(ctx, `UPDATE conversation_members SET role=$3
+	WHERE conversation_id=$1 AND account_id=$2
+		AND ($3 = 'admin' OR EXISTS (SELECT 1 FROM conversation_members WHERE conversation_id=$1 AND role='admin' AND account_id<>$2))`,
+		groupId, memberId, role)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return e.NewError("A group needs at least one admin")
+	}
+	return nil
+}
+
+// RemoveMember removes a member from a group the user is an admin of. Every member may remove themselves,
+// which is how groups are left. When the last admin leaves, the longest standing member takes over.
+func (g group) RemoveMember(ctx *gofr.Context, userId, groupId, memberId string) error {
+	var err error
+	if memberId == userId {
+		_, err = g.GetRole(ctx, userId, groupId)
+	} else {
+		err = g.requireAdmin(ctx, userId, groupId)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = g.role(ctx, groupId, memberId)
+	if err != nil {
+		return err
+	}
+
+	query := `WITH removed AS (
+		DELETE FROM conversation_members WHERE conversation_id=$1 AND account_id=$2
+	)
+	UPDATE conversation_members SET role='admin'
+	WHERE conversation_id=$1 AND account_id = (
+		SELECT account_id FROM conversation_members WHERE conversation_id=$1 AND account_id<>$2 ORDER BY joined_at, account_id LIMIT 1
+	) AND NOT EXISTS (SELECT 1 FROM conversation_members WHERE conversation_id=$1 AND role='admin' AND account_id<>$2)`
+
+	_, err = ctx.DB().ExecContext(ctx, query, groupId, memberId)
+	return err
+}
+
+// GetRole returns the role of the user in a group, failing if the group does not exist or they are not a member.
+func (g group) GetRole(ctx *gofr.Context, userId, groupId string) (string, error) {
+	var exists bool
+	var role sql.NullString
+	err := ctx.DB().QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM conversations WHERE id=$1 AND kind='group'),
+		(SELECT role FROM conversation_members WHERE conversation_id=$1 AND account_id=$2)`, groupId, userId).Scan(&exists, &role)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", sql.ErrNoRows
+	}
+	if !role.Valid {
+		return "", e.NewError("You are not a member of that group")
+	}
+	return role.String, nil
+}
+
+func (g group) requireAdmin(ctx *gofr.Context, userId, groupId string) error {
+	role, err := g.GetRole(ctx, userId, groupId)
+	if err != nil {
+		return err
+	}
+	if role != model.RoleAdmin {
+		return e.NewError("Only admins can manage that group")
+	}
+	return nil
+}
+
+// role returns the role of a member of a group the user is known to belong to.
+func (g group) role(ctx *gofr.Context, groupId, memberId string) (string, error) {
+	var role string
+	err := ctx.DB().QueryRowContext(ctx, "SELECT role FROM conversation_members WHERE conversation_id=$1 AND account_id=$2", groupId, memberId).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", e.NewError("That user is not a member of the group")
+	}
+	return role, err
+}
+
+func (g group) checkAccountsExist(ctx *gofr.Context, accountIds []string) error {
+	args := make([]interface{}, 0, len(accountIds))
+	for _, accountId := range accountIds {
+		args = append(args, accountId)
+	}
+
+	var count int
+	err := ctx.DB().QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM accounts WHERE id IN (%s)", placeholders(1, len(accountIds))), args...).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != len(accountIds) {
+		return e.NewError("Some of those users do not exist")
+	}
+	return nil
+}
+
+// checkNotBlocked refuses adding users to a group when they blocked userId or userId blocked them.
+// Users who already are members of the group are not checked again.
+func (g group) checkNotBlocked(ctx *gofr.Context, tx *sql.Tx, userId, groupId string, memberIds []string) error {
+	args := []interface{}{userId, groupId}
+	for _, memberId := range memberIds {
+		args = append(args, memberId)
+	}
+
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM accounts a WHERE a.id IN (%s) AND %s
+	AND NOT EXISTS (SELECT 1 FROM conversation_members WHERE conversation_id=$2 AND account_id=a.id)`,
+		placeholders(3, len(memberIds)), fmt.Sprintf(blockedEither, "a.id", "$1"))
+
+	var blocked int
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&blocked)
+	if err != nil {
+		return err
+	}
+	if blocked > 0 {
+		return e.NewError("You cannot add those users to a group")
+	}
+	return nil
+}
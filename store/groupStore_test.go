@@ -0,0 +1,371 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+func newMockDBContext(t *testing.T) (*gofr.Context, sqlmock.Sqlmock, func()) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+	return ctx, mock, func() { db.Close() }
+}
+
+// expectRole expects the lookup of the role of a user in a group; an empty role means they are not a member.
+func expectRole(mock sqlmock.Sqlmock, groupId, userId string, exists bool, role string) {
+	var r interface{}
+	if role != "" {
+		r = role
+	}
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM conversations WHERE id=\\$1 AND kind='group'\\)").
+		WithArgs(groupId, userId).
+		WillReturnRows(sqlmock.NewRows([]string{"exists", "role"}).AddRow(exists, r))
+}
+
+func TestCreateGroup(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	groupStore := group{}
+	now := time.Now()
+
+	sampleGroup := model.Group{ID: "group-id", Name: "Cats", CreatedBy: "admin-id", CreatedAt: now, Members: []model.GroupMember{
+		{User: model.User{ID: "admin-id"}, Role: model.RoleAdmin}, {User: model.User{ID: "member-id"}, Role: model.RoleMember},
+	}}
+
+	expectBlocked := func(blocked int) {
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM accounts a WHERE a.id IN \\(\\$3,\\$4\\) AND EXISTS \\(SELECT 1 FROM blocks").
+			WithArgs("admin-id", "group-id", "admin-id", "member-id").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(blocked))
+	}
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM accounts WHERE id IN \\(\\$1,\\$2\\)").
+		WithArgs("admin-id", "member-id").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectBegin()
+	expectBlocked(0)
+	mock.ExpectExec("WITH created AS \\(\\s*INSERT INTO conversations .* 'group'.* INSERT INTO conversation_members .* AND NOT EXISTS \\(SELECT 1 FROM blocks").
+		WithArgs("group-id", "Cats", "admin-id", now, "admin-id", "member-id").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := groupStore.CreateGroup(ctx, sampleGroup, false)
+
+	assert.NoError(t, err, "Unexpected error while creating a group")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM accounts").
+		WithArgs("admin-id", "member-id").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	err = groupStore.CreateGroup(ctx, sampleGroup, false)
+
+	assert.Equal(t, e.NewError("Some of those users do not exist"), err, "Expected unknown members to be refused")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM accounts").
+		WithArgs("admin-id", "member-id").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectBegin()
+	expectBlocked(1)
+	mock.ExpectRollback()
+
+	err = groupStore.CreateGroup(ctx, sampleGroup, false)
+
+	assert.Equal(t, e.NewError("You cannot add those users to a group"), err, "Expected blocked members to be refused")
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM accounts").
+		WithArgs("admin-id", "member-id").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectBegin()
+	mock.ExpectExec("WITH created AS").
+		WithArgs("group-id", "Cats", "admin-id", now, "admin-id", "member-id").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = groupStore.CreateGroup(ctx, sampleGroup, true)
+
+	assert.NoError(t, err, "Expected blocked members to be left out")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetGroup(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	groupStore := group{}
+	now := time.Now()
+	columns := []string{"id", "name", "created_by", "created_at", "id", "name", "phoneNumber", "role", "joined_at"}
+
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(columns).
+			AddRow("group-id", "Cats", "admin-id", now, "admin-id", "Admin", uint64(1234567890), "admin", now).
+			AddRow("group-id", "Cats", "admin-id", now, "member-id", "Member", uint64(1234567891), "member", now)
+	}
+
+	mock.ExpectQuery("SELECT c.id, c.name, c.created_by, c.created_at, a.id, a.name, a.phoneNumber, m.role, m.joined_at").
+		WithArgs("group-id").
+		WillReturnRows(rows())
+
+	group, err := groupStore.GetGroup(ctx, "member-id", "group-id")
+
+	assert.NoError(t, err, "Unexpected error while retrieving a group")
+	assert.Equal(t, "Cats", group.Name, "Mismatch in group name")
+	assert.Len(t, group.Members, 2, "Mismatch in number of members")
+	assert.Equal(t, model.RoleAdmin, group.Members[0].Role, "Mismatch in role of the creator")
+
+	mock.ExpectQuery("SELECT c.id, c.name").
+		WithArgs("group-id").
+		WillReturnRows(rows())
+
+	_, err = groupStore.GetGroup(ctx, "stranger-id", "group-id")
+
+	assert.Equal(t, e.NewError("You are not a member of that group"), err, "Expected groups to be hidden from non members")
+
+	mock.ExpectQuery("SELECT c.id, c.name").
+		WithArgs("group-id").
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	_, err = groupStore.GetGroup(ctx, "member-id", "group-id")
+
+	assert.Equal(t, sql.ErrNoRows, err, "Expected a missing group not to be found")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRenameGroup(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	groupStore := group{}
+
+	expectRole(mock, "group-id", "admin-id", true, "admin")
+	mock.ExpectExec("UPDATE conversations SET name=\\$2 WHERE id=\\$1").
+		WithArgs("group-id", "Dogs").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := groupStore.RenameGroup(ctx, "admin-id", "group-id", "Dogs")
+
+	assert.NoError(t, err, "Unexpected error while renaming a group")
+
+	expectRole(mock, "group-id", "member-id", true, "member")
+
+	err = groupStore.RenameGroup(ctx, "member-id", "group-id", "Dogs")
+
+	assert.Equal(t, e.NewError("Only admins can manage that group"), err, "Expected members not to rename groups")
+
+	expectRole(mock, "group-id", "stranger-id", true, "")
+
+	err = groupStore.RenameGroup(ctx, "stranger-id", "group-id", "Dogs")
+
+	assert.Equal(t, e.NewError("You are not a member of that group"), err, "Expected non members not to rename groups")
+
+	expectRole(mock, "missing-id", "admin-id", false, "")
+
+	err = groupStore.RenameGroup(ctx, "admin-id", "missing-id", "Dogs")
+
+	assert.Equal(t, sql.ErrNoRows, err, "Expected a missing group not to be found")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAddMembers(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	groupStore := group{}
+	memberIds := []string{"new-id", "member-id"}
+
+	expectAccounts := func() {
+		expectRole(mock, "group-id", "admin-id", true, "admin")
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM accounts").
+			WithArgs("new-id", "member-id").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	}
+
+	expectLock := func() {
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT 1 FROM conversations WHERE id=\\$1 FOR UPDATE").
+			WithArgs("group-id").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	expectCount := func(members int) {
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\), COUNT\\(\\*\\) FILTER \\(WHERE account_id IN \\(\\$2,\\$3\\)\\)").
+			WithArgs("group-id", "new-id", "member-id").
+			WillReturnRows(sqlmock.NewRows([]string{"count", "count"}).AddRow(members, 1))
+	}
+
+	expectBlocked := func(blocked int) {
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM accounts a WHERE a.id IN \\(\\$3,\\$4\\) AND EXISTS \\(SELECT 1 FROM blocks .* AND NOT EXISTS \\(SELECT 1 FROM conversation_members").
+			WithArgs("admin-id", "group-id", "new-id", "member-id").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(blocked))
+	}
+
+	expectInsert := func() {
+		mock.ExpectExec("INSERT INTO conversation_members .* SELECT \\$1, a.id, 'member', \\$4, \\$4 FROM accounts a WHERE a.id IN \\(\\$2,\\$3\\) AND NOT EXISTS \\(SELECT 1 FROM blocks .*\\$5.*\\s+ON CONFLICT DO NOTHING").
+			WithArgs("group-id", "new-id", "member-id", sqlmock.AnyArg(), "admin-id").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	expectAccounts()
+	expectLock()
+	expectCount(2)
+	expectBlocked(0)
+	expectInsert()
+	mock.ExpectCommit()
+
+	err := groupStore.AddMembers(ctx, "admin-id", "group-id", memberIds, 3, false)
+
+	assert.NoError(t, err, "Unexpected error while adding members")
+
+	expectAccounts()
+	expectLock()
+	expectCount(3)
+	mock.ExpectRollback()
+
+	err = groupStore.AddMembers(ctx, "admin-id", "group-id", memberIds, 3, false)
+
+	assert.Equal(t, e.NewError("That group is full"), err, "Expected groups not to grow past their limit")
+
+	expectAccounts()
+	expectLock()
+	expectCount(2)
+	expectBlocked(1)
+	mock.ExpectRollback()
+
+	err = groupStore.AddMembers(ctx, "admin-id", "group-id", memberIds, 3, false)
+
+	assert.Equal(t, e.NewError("You cannot add those users to a group"), err, "Expected blocked members to be refused")
+
+	expectAccounts()
+	expectLock()
+	expectCount(2)
+	expectInsert()
+	mock.ExpectCommit()
+
+	err = groupStore.AddMembers(ctx, "admin-id", "group-id", memberIds, 3, true)
+
+	assert.NoError(t, err, "Expected blocked members to be left out")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateMemberRole(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	groupStore := group{}
+
+	expectMember := func(memberId string) {
+		expectRole(mock, "group-id", "admin-id", true, "admin")
+		mock.ExpectQuery("SELECT role FROM conversation_members WHERE conversation_id=\\$1 AND account_id=\\$2").
+			WithArgs("group-id", memberId).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("member"))
+	}
+
+	expectMember("member-id")
+	mock.ExpectExec("UPDATE conversation_members SET role=\\$3").
+		WithArgs("group-id", "member-id", "admin").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := groupStore.UpdateMemberRole(ctx, "admin-id", "group-id", "member-id", "admin")
+
+	assert.NoError(t, err, "Unexpected error while promoting a member")
+
+	expectMember("admin-id")
+	mock.ExpectExec("UPDATE conversation_members SET role=\\$3").
+		WithArgs("group-id", "admin-id", "member").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = groupStore.UpdateMemberRole(ctx, "admin-id", "group-id", "admin-id", "member")
+
+	assert.Equal(t, e.NewError("A group needs at least one admin"), err, "Expected the last admin not to step down")
+
+	expectRole(mock, "group-id", "admin-id", true, "admin")
+	mock.ExpectQuery("SELECT role FROM conversation_members").
+		WithArgs("group-id", "stranger-id").
+		WillReturnError(sql.ErrNoRows)
+
+	err = groupStore.UpdateMemberRole(ctx, "admin-id", "group-id", "stranger-id", "admin")
+
+	assert.Equal(t, e.NewError("That user is not a member of the group"), err, "Expected only members to get roles")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	groupStore := group{}
+
+	expectRemoval := func(memberId string) {
+		mock.ExpectQuery("SELECT role FROM conversation_members").
+			WithArgs("group-id", memberId).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("member"))
+		mock.ExpectExec("WITH removed AS \\(\\s*DELETE FROM conversation_members .* UPDATE conversation_members SET role='admin'").
+			WithArgs("group-id", memberId).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	expectRole(mock, "group-id", "member-id", true, "member")
+	expectRemoval("member-id")
+
+	err := groupStore.RemoveMember(ctx, "member-id", "group-id", "member-id")
+
+	assert.NoError(t, err, "Unexpected error while leaving a group")
+
+	expectRole(mock, "group-id", "admin-id", true, "admin")
+	expectRemoval("member-id")
+
+	err = groupStore.RemoveMember(ctx, "admin-id", "group-id", "member-id")
+
+	assert.NoError(t, err, "Unexpected error while removing a member")
+
+	expectRole(mock, "group-id", "member-id", true, "member")
+
+	err = groupStore.RemoveMember(ctx, "member-id", "group-id", "admin-id")
+
+	assert.Equal(t, e.NewError("Only admins can manage that group"), err, "Expected members not to remove others")
+
+	expectRole(mock, "group-id", "admin-id", true, "admin")
+	mock.ExpectQuery("SELECT role FROM conversation_members").
+		WithArgs("group-id", "member-id").
+		WillReturnError(fmt.Errorf(""))
+
+	err = groupStore.RemoveMember(ctx, "admin-id", "group-id", "member-id")
+
+	assert.Error(t, err, "Expected an error during failed removal")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
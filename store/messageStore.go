@@ -1,9 +1,15 @@
 package store
 
 import (
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	e "github.com/aryanA101a/legoshichat-backend/error"
+	"github.com/aryanA101a/legoshichat-backend/markup"
 	"github.com/aryanA101a/legoshichat-backend/model"
 	"gofr.dev/pkg/datastore"
 	"gofr.dev/pkg/gofr"
@@ -15,9 +21,128 @@ type message struct {
 type MessageStore interface {
 	AddMessage(ctx *gofr.Context, message model.Message) error
 	GetMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, error)
-	UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string) (*model.Message, error)
-	DeleteMessage(ctx *gofr.Context, userId, messageId string) error
-	GetMessages(ctx *gofr.Context, senderId, recieverId string, page, limit uint) (*[]model.Message, error)
+	UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string, entities []model.Entity, window time.Duration) (*model.Message, error)
+	GetMessageHistory(ctx *gofr.Context, userId, messageId string) (*model.MessageHistory, error)
+	AddReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error)
+	RemoveReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error)
+	DeleteMessage(ctx *gofr.Context, userId, messageId, mode string, window time.Duration) error
+	GetMessages(ctx *gofr.Context, userId, senderId, recieverId string, page, limit uint) (*[]model.Message, error)
+	GetConversationMessages(ctx *gofr.Context, userId, conversationId string, page, limit uint) (*[]model.Message, error)
+	PurgeDeletedMessages(ctx *gofr.Context, deletedBefore time.Time) (int, []string, error)
+	GetScheduledMessages(ctx *gofr.Context, userId string, page, limit uint) (*[]model.Message, error)
+	UpdateScheduledMessage(ctx *gofr.Context, userId, messageId string, content *string, entities []model.Entity, sendAt *time.Time) (*model.Message, error)
+	CancelScheduledMessage(ctx *gofr.Context, userId, messageId string) ([]string, error)
+	ReleaseScheduledMessages(ctx *gofr.Context, now time.Time, limit uint) (*[]model.Message, []string, error)
+	DeleteExpiredMessages(ctx *gofr.Context, now time.Time, limit uint) (int, []string, error)
+	StarMessage(ctx *gofr.Context, userId, messageId string) error
+	UnstarMessage(ctx *gofr.Context, userId, messageId string) error
+	GetStarredMessages(ctx *gofr.Context, userId string, page, limit uint) (*[]model.Message, error)
+	PinMessage(ctx *gofr.Context, userId, messageId string, limit int) (*model.Message, bool, error)
+	UnpinMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, bool, error)
+	GetPinnedMessages(ctx *gofr.Context, userId, conversationId string) (*[]model.PinnedMessage, error)
+	Vote(ctx *gofr.Context, userId, messageId string, options []int) (*model.PollResults, error)
+	GetPollResults(ctx *gofr.Context, userId, messageId string) (*model.PollResults, error)
+	SetLinkPreview(ctx *gofr.Context, messageId, content string, preview model.LinkPreview) (bool, error)
+}
+
+// messageColumns are the columns of messageSource scanned by scanMessage, in order.
+const messageColumns = "messages.id,messages.content,messages.senderId,messages.recieverId,messages.timestamp,messages.deletedAt,messages.editedAt,messages.editCount," +
+	"messages.replyToId,quoted.content,quoted.senderId,quoted.deletedAt,messages.conversationId,messages.disappearAfter,messages.expiresAt,messages.system,messages.forwardCount,messages.kind,messages.payload,messages.entities,messages.linkPreview"
+
+// messageSource joins every message with the message it replies to, if any, as long as that has not expired.
+const messageSource = "messages LEFT JOIN messages quoted ON quoted.id = messages.replyToId AND (quoted.expiresAt IS NULL OR quoted.expiresAt > now())"
+
+// notExpired filters out disappearing messages past their expiry, which the sweeper may not have deleted yet.
+const notExpired = "(messages.expiresAt IS NULL OR messages.expiresAt > now())"
+
+// hiddenForUser filters out messages the user at the given parameter deleted for themselves.
+const hiddenForUser = "NOT EXISTS (SELECT 1 FROM message_deletions d WHERE d.message_id = messages.id AND d.account_id = $%d)"
+
+// isSent filters out scheduled messages that are still waiting to be sent.
+const isSent = "messages.scheduledAt IS NULL"
+
+// isMember tells whether the user at the given parameter is a member of the conversation of a message.
+const isMember = "EXISTS (SELECT 1 FROM conversation_members cm WHERE cm.conversation_id = messages.conversationId AND cm.account_id = $%d)"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMessage reads a row of messageColumns, followed by any extra columns into dest.
+// Messages deleted for everyone come back as tombstones without content.
+func scanMessage(row scanner, dest ...interface{}) (*model.Message, error) {
+	var message model.Message
+	var deletedAt, editedAt, quotedDeletedAt, expiresAt sql.NullTime
+	var to, replyToId, quotedContent, quotedFrom, conversationId sql.NullString
+	var disappearAfter sql.NullInt64
+	var system, payload, entities, linkPreview []byte
+
+	dest = append([]interface{}{&message.ID, &message.Content, &message.From, &to, &message.Timestamp, &deletedAt, &editedAt, &message.EditCount,
+		&replyToId, &quotedContent, &quotedFrom, &quotedDeletedAt, &conversationId, &disappearAfter, &expiresAt, &system, &message.ForwardCount,
+		&message.Type, &payload, &entities, &linkPreview}, dest...)
+
+	err := row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	message.To, message.ConversationID = to.String, conversationId.String
+	message.DisappearAfter = uint(disappearAfter.Int64)
+	message.Forwarded = message.ForwardCount > 0
+
+	if expiresAt.Valid {
+		message.ExpiresAt = &expiresAt.Time
+	}
+
+	if system != nil {
+		err = json.Unmarshal(system, &message.System)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if payload != nil {
+		err = json.Unmarshal(payload, &message.Payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if entities != nil {
+		err = json.Unmarshal(entities, &message.Entities)
+		if err != nil {
+			return nil, err
+		}
+		message.HTML = markup.HTML(message.Content, message.Entities)
+	}
+
+	if linkPreview != nil {
+		err = json.Unmarshal(linkPreview, &message.LinkPreview)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if replyToId.Valid {
+		// A quoted message that has since been purged is shown the same as one deleted for everyone.
+		quoted := model.Message{ID: replyToId.String, Content: quotedContent.String, From: quotedFrom.String,
+			Deleted: quotedDeletedAt.Valid || !quotedFrom.Valid}
+		preview := quoted.Preview()
+		message.ReplyTo = &preview
+	}
+
+	if editedAt.Valid {
+		message.EditedAt = &editedAt.Time
+	}
+
+	if deletedAt.Valid {
+		message.Content = ""
+		message.Payload = nil
+		message.Entities, message.HTML, message.LinkPreview = nil, "", nil
+		message.Deleted = true
+		message.DeletedAt = &deletedAt.Time
+	}
+
+	return &message, nil
 }
 
 func NewMessageStore(db *datastore.SQLClient) MessageStore {
@@ -30,71 +155,345 @@ func NewMessageStore(db *datastore.SQLClient) MessageStore {
 }
 
 func (m message) init(db *datastore.SQLClient) error {
-	return m.createMessageTable(db)
+	err := m.createMessageTable(db)
+	if err != nil {
+		return err
+	}
+	err = m.createMessageDeletionsTable(db)
+	if err != nil {
+		return err
+	}
+	err = m.createMessageRevisionsTable(db)
+	if err != nil {
+		return err
+	}
+	err = m.createMessageReactionsTable(db)
+	if err != nil {
+		return err
+	}
+	err = m.createMessageStarsTable(db)
+	if err != nil {
+		return err
+	}
+	err = m.createMessagePinsTable(db)
+	if err != nil {
+		return err
+	}
+	return m.createPollVotesTable(db)
 }
 
 func (m message) AddMessage(ctx *gofr.Context, message model.Message) error {
-	_, err := ctx.DB().ExecContext(ctx, "INSERT INTO messages (id,content,senderId,recieverId,timestamp) VALUES ($1,$2,$3,$4,$5)", message.ID, message.Content, message.From, message.To, message.Timestamp)
+	var replyToId sql.NullString
+	if message.ReplyTo != nil {
+		replyToId = sql.NullString{String: message.ReplyTo.ID, Valid: true}
+	}
+	to := sql.NullString{String: message.To, Valid: message.To != ""}
+	disappearAfter := sql.NullInt64{Int64: int64(message.DisappearAfter), Valid: message.DisappearAfter > 0}
+
+	var system interface{}
+	if message.System != nil {
+		event, err := json.Marshal(message.System)
+		if err != nil {
+			return err
+		}
+		system = event
+	}
+
+	kind := message.Type
+	if kind == "" {
+		kind = model.MessageText
+	}
+
+	var payload interface{}
+	if message.Payload != nil {
+		encoded, err := json.Marshal(message.Payload)
+		if err != nil {
+			return err
+		}
+		payload = encoded
+	}
+
+	entities, err := encodeEntities(message.Entities)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO messages (id,content,senderId,recieverId,timestamp,replyToId,conversationId,scheduledAt,disappearAfter,expiresAt,system,forwardCount,kind,payload,entities)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)`
+	args := []interface{}{message.ID, message.Content, message.From, to, message.Timestamp, replyToId, message.ConversationID, message.ScheduledAt,
+		disappearAfter, message.ExpiresAt, system, message.ForwardCount, kind, payload, entities}
+
+	if message.Forwarded && len(message.Attachments) > 0 {
+		return m.addForwardedMessage(ctx, query, args, message.Attachments)
+	}
+
+	// Attachments are linked in the same statement, so a message is never stored without them.
+	if len(message.Attachments) > 0 {
+		for _, attachment := range message.Attachments {
+			args = append(args, attachment.ID)
+		}
+		query = fmt.Sprintf(`WITH inserted AS (%s RETURNING id)
+		UPDATE attachments SET message_id = (SELECT id FROM inserted)
+		WHERE owner_id=$3 AND message_id IS NULL AND id IN (%s)`, query, placeholders(16, len(message.Attachments)))
+	}
+
+	_, err = ctx.DB().ExecContext(ctx, query, args...)
+	return err
+}
+
+// encodeEntities encodes the entities of a message for storage, as NULL for messages without any.
+func encodeEntities(entities []model.Entity) (interface{}, error) {
+	if len(entities) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(entities)
+}
+
+// addForwardedMessage inserts a forwarded message along with copies of the attachments it was forwarded with,
+// which are owned by the sender but share the files of the attachments they copy.
+func (m message) addForwardedMessage(ctx *gofr.Context, query string, args []interface{}, attachments []model.Attachment) error {
+	rows := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		var thumbnails sql.NullString
+		if len(attachment.Thumbnails) > 0 {
+			encoded, err := json.Marshal(attachment.Thumbnails)
+			if err != nil {
+				return err
+			}
+			thumbnails = sql.NullString{String: string(encoded), Valid: true}
+		}
+
+		rows = append(rows, fmt.Sprintf("(%s, $3, $1)", placeholders(len(args)+1, 11)))
+		args = append(args, attachment.ID, attachment.FileName, attachment.ContentType, attachment.Size, attachment.CreatedAt, attachment.Status,
+			sql.NullInt64{Int64: int64(attachment.Width), Valid: attachment.Width > 0}, sql.NullInt64{Int64: int64(attachment.Height), Valid: attachment.Height > 0},
+			sql.NullString{String: attachment.BlurHash, Valid: attachment.BlurHash != ""}, thumbnails, attachment.BlobID)
+	}
+
+	query = fmt.Sprintf(`WITH inserted AS (%s RETURNING id)
+	INSERT INTO attachments (id, file_name, content_type, size, created_at, status, width, height, blurhash, thumbnails, blob_id, owner_id, message_id)
+	VALUES %s`, query, strings.Join(rows, ", "))
+
+	_, err := ctx.DB().ExecContext(ctx, query, args...)
 	return err
 }
 
+// GetMessage returns a message of a conversation the user is a member of. Messages the user deleted for themselves,
+// messages yet to be sent and messages that disappeared do not exist for them.
 func (m message) GetMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, error) {
-	var message model.Message
-	err := ctx.DB().QueryRowContext(ctx, "SELECT id,content,senderId,recieverId,timestamp FROM messages WHERE id=$1", messageId).
-		Scan(&message.ID, &message.Content, &message.From, &message.To, &message.Timestamp)
+	message, err := m.getMessage(ctx, userId, messageId)
+	if err != nil {
+		return nil, err
+	}
 
+	messages := []model.Message{*message}
+	err = m.attachAttachments(ctx, messages)
 	if err != nil {
 		return nil, err
 	}
-	if !(userId == message.From || userId == message.To) {
-		return nil, e.NewError("You are not authorized to see that message")
+	err = m.attachReactions(ctx, userId, messages)
+	if err != nil {
+		return nil, err
 	}
 
-	return &message, nil
+	return &messages[0], nil
 }
 
-func (m message) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string) (*model.Message, error) {
-	var message model.Message
-	err := ctx.DB().QueryRowContext(ctx, "SELECT id,content,senderId,recieverId,timestamp FROM messages WHERE id=$1", messageId).
-		Scan(&message.ID, &message.Content, &message.From, &message.To, &message.Timestamp)
+func (m message) getMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, error) {
+	query := "SELECT " + messageColumns + ", " + fmt.Sprintf(isMember, 2) + " FROM " + messageSource + " WHERE messages.id=$1 AND " + isSent + " AND " + notExpired + " AND " + fmt.Sprintf(hiddenForUser, 2)
+
+	var member bool
+	message, err := scanMessage(ctx.DB().QueryRowContext(ctx, query, messageId, userId), &member)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, e.NewError("You are not authorized to see that message")
+	}
 
+	return message, nil
+}
+
+// UpdateMessage replaces the content of a message the user sent within window of sending, along with its entities.
+// The content being replaced is kept as a revision, so the history of the message can be retrieved later.
+func (m message) UpdateMessage(ctx *gofr.Context, userId, messageId, updatedContent string, entities []model.Entity, window time.Duration) (*model.Message, error) {
+	message, err := scanMessage(ctx.DB().QueryRowContext(ctx, "SELECT "+messageColumns+" FROM "+messageSource+" WHERE messages.id=$1 AND "+isSent+" AND "+notExpired, messageId))
 	if err != nil {
 		return nil, err
 	}
 	if !(userId == message.From) {
 		return nil, e.NewError("You are not authorized to update that message")
 	}
+	if message.Deleted {
+		return nil, sql.ErrNoRows
+	}
+	if message.Type != model.MessageText {
+		return nil, e.NewError("Only text messages can be edited")
+	}
+	if time.Since(message.Timestamp) > window {
+		return nil, e.NewError("That message can no longer be edited")
+	}
+
+	editedAt := time.Now()
 
-	_, err = ctx.DB().ExecContext(ctx, "UPDATE messages SET content=$1 WHERE id=$2", updatedContent, messageId)
+	query := `WITH revision AS (
+		INSERT INTO message_revisions (message_id, version, content, written_at, replaced_at)
+		SELECT id, editCount, content, COALESCE(editedAt, timestamp), $3 FROM messages WHERE id=$2
+	)
+	UPDATE messages SET content=$1, entities=$4, linkPreview=NULL, editedAt=$3, editCount=editCount+1 WHERE id=$2`
+
+	encoded, err := encodeEntities(entities)
 	if err != nil {
 		return nil, err
 	}
 
-	message.Content = updatedContent
-	return &message, nil
+	_, err = ctx.DB().ExecContext(ctx, query, updatedContent, messageId, editedAt, encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	message.Content, message.Entities, message.LinkPreview = updatedContent, entities, nil
+	message.HTML = ""
+	if len(entities) > 0 {
+		message.HTML = markup.HTML(updatedContent, entities)
+	}
+	message.EditedAt = &editedAt
+	message.EditCount++
+	return message, nil
 }
 
-func (m message) DeleteMessage(ctx *gofr.Context, userId, messageId string) error {
-	var senderId string
-	err := ctx.DB().QueryRowContext(ctx, "SELECT senderId FROM messages WHERE id=$1", messageId).
-		Scan(&senderId)
+// GetMessageHistory returns a message the user takes part in along with every version it had before its last edit.
+// Messages deleted for everyone keep no history.
+func (m message) GetMessageHistory(ctx *gofr.Context, userId, messageId string) (*model.MessageHistory, error) {
+	message, err := m.getMessage(ctx, userId, messageId)
+	if err != nil {
+		return nil, err
+	}
 
+	history := model.MessageHistory{Message: *message, Revisions: make([]model.MessageRevision, 0)}
+	if message.Deleted {
+		return &history, nil
+	}
+
+	rows, err := ctx.DB().QueryContext(ctx, `SELECT version, content, written_at, replaced_at FROM message_revisions
+	WHERE message_id=$1 ORDER BY version DESC`, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var revision model.MessageRevision
+		err = rows.Scan(&revision.Version, &revision.Content, &revision.WrittenAt, &revision.ReplacedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		history.Revisions = append(history.Revisions, revision)
+	}
+
+	err = rows.Err()
 	if err != nil {
+		return nil, err
+	}
+
+	return &history, nil
+}
+
+// DeleteMessage deletes a message either for everyone or only for the user.
+// Deleting for everyone is left to the sender within window of sending and leaves a tombstone behind,
+// stripped of everything but its place in the history, while any member of the conversation may hide a message from their own history at any time.
+func (m message) DeleteMessage(ctx *gofr.Context, userId, messageId, mode string, window time.Duration) error {
+	query := "SELECT " + messageColumns + ", " + fmt.Sprintf(isMember, 2) + " FROM " + messageSource + " WHERE messages.id=$1 AND " + isSent + " AND " + notExpired + " AND " + fmt.Sprintf(hiddenForUser, 2)
+
+	var member bool
+	message, err := scanMessage(ctx.DB().QueryRowContext(ctx, query, messageId, userId), &member)
+	if err != nil {
+		return err
+	}
+
+	if mode == model.DeleteForMe {
+		if !member {
+			return e.NewError("You are not authorized to delete that message")
+		}
+		_, err = ctx.DB().ExecContext(ctx, `INSERT INTO message_deletions (message_id, account_id, deleted_at) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`, messageId, userId, time.Now())
 		return err
 	}
-	if !(userId == senderId) {
+
+	if !(userId == message.From) {
 		return e.NewError("You are not authorized to delete that message")
 	}
-	_, err = ctx.DB().ExecContext(ctx, "DELETE FROM messages where id=$1 ", messageId)
+	if message.Deleted {
+		return nil
+	}
+	if time.Since(message.Timestamp) > window {
+		return e.NewError("That message can no longer be deleted for everyone")
+	}
+
+	query = `WITH revisions AS (DELETE FROM message_revisions WHERE message_id=$2),
+		reactions AS (DELETE FROM message_reactions WHERE message_id=$2),
+		votes AS (DELETE FROM poll_votes WHERE message_id=$2),
+		pins AS (DELETE FROM message_pins WHERE message_id=$2),
+		stars AS (DELETE FROM message_stars WHERE message_id=$2)
+	UPDATE messages SET content='', payload=NULL, entities=NULL, linkPreview=NULL, deletedAt=$1 WHERE id=$2`
+	_, err = ctx.DB().ExecContext(ctx, query, time.Now(), messageId)
 	return err
 }
 
-func (m message) GetMessages(ctx *gofr.Context, senderId, recieverId string, page, limit uint) (*[]model.Message, error) {
+// PurgeDeletedMessages removes the tombstones of messages deleted for everyone before deletedBefore, along with their
+// attachments. It returns how many tombstones it removed and the blob IDs of their attachments that no forwarded copy
+// still shares, whose contents are left for the caller to delete.
+func (m message) PurgeDeletedMessages(ctx *gofr.Context, deletedBefore time.Time) (int, []string, error) {
+	query := `WITH purged AS (
+		DELETE FROM messages WHERE deletedAt < $1
+		RETURNING id
+	)
+	` + fmt.Sprintf(unsharedBlobs, "purged")
+
+	rows, err := ctx.DB().QueryContext(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return scanDeletedBlobs(rows)
+}
+
+func (m message) GetMessages(ctx *gofr.Context, userId, senderId, recieverId string, page, limit uint) (*[]model.Message, error) {
 	
-	query:=`SELECT id,content,senderId,recieverId,timestamp FROM messages
-	WHERE senderId=$1 and recieverId=$2 ORDER BY timestamp DESC LIMIT $3 OFFSET $4`
+	query:=`SELECT `+messageColumns+` FROM `+messageSource+`
+	WHERE messages.senderId=$1 and messages.recieverId=$2 AND `+isSent+` AND `+notExpired+` AND `+fmt.Sprintf(hiddenForUser, 3)+` ORDER BY messages.timestamp DESC LIMIT $4 OFFSET $5`
 	
-	rows, err := ctx.DB().QueryContext(ctx, query, senderId, recieverId, limit, (page-1)*limit)
+	rows, err := ctx.DB().QueryContext(ctx, query, senderId, recieverId, userId, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.scanMessages(ctx, userId, rows)
+}
+
+// GetConversationMessages returns a page of the messages of a conversation the user is a member of, newest first.
+// It returns no messages to anyone else.
+func (m message) GetConversationMessages(ctx *gofr.Context, userId, conversationId string, page, limit uint) (*[]model.Message, error) {
+	query := `SELECT ` + messageColumns + ` FROM ` + messageSource + `
+	WHERE messages.conversationId=$1 AND ` + isSent + ` AND ` + notExpired + ` AND ` + fmt.Sprintf(isMember, 2) + ` AND ` + fmt.Sprintf(hiddenForUser, 2) + `
+	ORDER BY messages.timestamp DESC LIMIT $3 OFFSET $4`
+
+	rows, err := ctx.DB().QueryContext(ctx, query, conversationId, userId, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.scanMessages(ctx, userId, rows)
+}
+
+// GetScheduledMessages returns a page of the messages the user scheduled that are yet to be sent, the earliest first.
+func (m message) GetScheduledMessages(ctx *gofr.Context, userId string, page, limit uint) (*[]model.Message, error) {
+	query := `SELECT ` + messageColumns + `, messages.scheduledAt FROM ` + messageSource + `
+	WHERE messages.senderId=$1 AND messages.scheduledAt IS NOT NULL
+	ORDER BY messages.scheduledAt, messages.id LIMIT $2 OFFSET $3`
+
+	rows, err := ctx.DB().QueryContext(ctx, query, userId, limit, (page-1)*limit)
 	if err != nil {
 		return nil, err
 	}
@@ -104,14 +503,14 @@ func (m message) GetMessages(ctx *gofr.Context, senderId, recieverId string, pag
 	messages := make([]model.Message, 0)
 
 	for rows.Next() {
-		var message model.Message
-
-		err = rows.Scan(&message.ID, &message.Content, &message.From, &message.To, &message.Timestamp)
+		var scheduledAt time.Time
+		message, err := scanMessage(rows, &scheduledAt)
 		if err != nil {
 			return nil, err
 		}
 
-		messages = append(messages, message)
+		message.ScheduledAt = &scheduledAt
+		messages = append(messages, *message)
 	}
 
 	err = rows.Err()
@@ -119,9 +518,647 @@ func (m message) GetMessages(ctx *gofr.Context, senderId, recieverId string, pag
 		return nil, err
 	}
 
+	err = m.attachAttachments(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return &messages, nil
+}
+
+// UpdateScheduledMessage replaces the content of a message the user scheduled, the time it is to be sent at, or both.
+// Once the message is sent it can only be edited like any other; until then no revisions are kept. Only text messages
+// have their content, and the entities formatting it, replaced. Messages that disappear some time after being sent
+// have their expiry moved along with the time they are sent at.
+func (m message) UpdateScheduledMessage(ctx *gofr.Context, userId, messageId string, content *string, entities []model.Entity, sendAt *time.Time) (*model.Message, error) {
+	query := `WITH updated AS (
+		UPDATE messages SET content=CASE WHEN kind = 'text' THEN COALESCE($3, content) ELSE content END,
+			entities=CASE WHEN kind = 'text' AND $3 IS NOT NULL THEN $5 ELSE entities END, scheduledAt=COALESCE($4, scheduledAt),
+			expiresAt=CASE WHEN expiresAt IS NOT NULL THEN COALESCE($4, scheduledAt) + disappearAfter * interval '1 second' ELSE NULL END
+		WHERE id=$1 AND senderId=$2 AND scheduledAt IS NOT NULL
+		RETURNING *
+	)
+	SELECT ` + messageColumns + `, messages.scheduledAt FROM updated messages LEFT JOIN messages quoted ON quoted.id = messages.replyToId`
+
+	encoded, err := encodeEntities(entities)
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduledAt time.Time
+	message, err := scanMessage(ctx.DB().QueryRowContext(ctx, query, messageId, userId, content, sendAt, encoded), &scheduledAt)
+	if err != nil {
+		return nil, err
+	}
+	message.ScheduledAt = &scheduledAt
+
+	messages := []model.Message{*message}
+	err = m.attachAttachments(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return &messages[0], nil
+}
+
+// CancelScheduledMessage deletes a message the user scheduled, along with its attachments, before it is sent.
+// It returns the blob IDs of the attachments that no forwarded copy shares, whose contents are left for the caller to delete.
+func (m message) CancelScheduledMessage(ctx *gofr.Context, userId, messageId string) ([]string, error) {
+	query := `WITH cancelled AS (
+		DELETE FROM messages WHERE id=$1 AND senderId=$2 AND scheduledAt IS NOT NULL
+		RETURNING id
+	)
+	` + fmt.Sprintf(unsharedBlobs, "cancelled")
+
+	rows, err := ctx.DB().QueryContext(ctx, query, messageId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, blobIds, err := scanDeletedBlobs(rows)
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return blobIds, nil
+}
+
+// ReleaseScheduledMessages sends up to limit scheduled messages that are due by now, with the time they were scheduled at
+// as their timestamp. The messages are claimed with SKIP LOCKED, so concurrent releases never send a message twice.
+// Messages to recipients who blocked the sender in the meantime are dropped instead, along with their attachments.
+// It returns the messages sent, which are yet to be recorded in the inboxes of their conversations, and the blob IDs
+// of the attachments of the dropped messages that no forwarded copy shares, whose contents are left for the caller to delete.
+func (m message) ReleaseScheduledMessages(ctx *gofr.Context, now time.Time, limit uint) (*[]model.Message, []string, error) {
+	blocked := "EXISTS (SELECT 1 FROM blocks WHERE blocker_id = messages.recieverId AND blocked_id = messages.senderId)"
+
+	// The released messages and the blobs of the dropped ones come back together; the rows of blobs have no message ID.
+	query := `WITH due AS (
+		SELECT id FROM messages WHERE scheduledAt <= $1 ORDER BY scheduledAt LIMIT $2 FOR UPDATE SKIP LOCKED
+	), dropped AS (
+		DELETE FROM messages USING due WHERE messages.id = due.id AND ` + blocked + `
+		RETURNING messages.id
+	), released AS (
+		UPDATE messages SET timestamp = scheduledAt, scheduledAt = NULL FROM due WHERE messages.id = due.id AND NOT ` + blocked + `
+		RETURNING messages.id, messages.content, messages.senderId, messages.recieverId, messages.timestamp, messages.conversationId,
+			messages.kind, messages.entities
+	), dropped_blobs (message_id, blob_id) AS (
+		` + fmt.Sprintf(unsharedBlobs, "dropped") + `
+	)
+	SELECT released.*, NULL FROM released
+	UNION ALL
+	SELECT NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, blob_id FROM dropped_blobs WHERE blob_id IS NOT NULL`
+
+	rows, err := ctx.DB().QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer rows.Close()
+
+	messages := make([]model.Message, 0)
+	blobIds := make([]string, 0)
+
+	for rows.Next() {
+		var id, content, from, to, conversationId, kind, blobId sql.NullString
+		var timestamp sql.NullTime
+		var entities []byte
+		err = rows.Scan(&id, &content, &from, &to, &timestamp, &conversationId, &kind, &entities, &blobId)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !id.Valid {
+			blobIds = append(blobIds, blobId.String)
+			continue
+		}
+
+		message := model.Message{ID: id.String, Content: content.String, From: from.String, To: to.String, Timestamp: timestamp.Time,
+			ConversationID: conversationId.String, Type: kind.String}
+		if entities != nil {
+			err = json.Unmarshal(entities, &message.Entities)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		messages = append(messages, message)
+	}
+
+	return &messages, blobIds, rows.Err()
+}
+
+// DeleteExpiredMessages deletes up to limit disappearing messages that expired by now, along with their revisions,
+// reactions and attachments. It returns how many messages it deleted and the blob IDs of their attachments
+// that no forwarded copy still shares, whose contents are left for the caller to delete.
+func (m message) DeleteExpiredMessages(ctx *gofr.Context, now time.Time, limit uint) (int, []string, error) {
+	query := `WITH expired AS (
+		DELETE FROM messages WHERE id IN (SELECT id FROM messages WHERE expiresAt <= $1 AND scheduledAt IS NULL LIMIT $2 FOR UPDATE SKIP LOCKED)
+		RETURNING id
+	)
+	` + fmt.Sprintf(unsharedBlobs, "expired")
+
+	rows, err := ctx.DB().QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return scanDeletedBlobs(rows)
+}
+
+// unsharedBlobs selects the IDs of the messages deleted by the CTE it is formatted with, each with the blob ID
+// of every attachment of the message that no attachment of a message left behind shares.
+const unsharedBlobs = `SELECT %[1]s.id, COALESCE(a.blob_id, a.id) FROM %[1]s LEFT JOIN attachments a ON a.message_id = %[1]s.id
+		AND NOT EXISTS (SELECT 1 FROM attachments shared WHERE COALESCE(shared.blob_id, shared.id) = COALESCE(a.blob_id, a.id)
+			AND shared.message_id NOT IN (SELECT id FROM %[1]s))`
+
+// scanDeletedBlobs reads rows of unsharedBlobs, returning how many messages were deleted and the blob IDs to delete.
+func scanDeletedBlobs(rows *sql.Rows) (int, []string, error) {
+	defer rows.Close()
+
+	deleted := make(map[string]bool)
+	blobIds := make([]string, 0)
+
+	for rows.Next() {
+		var messageId string
+		var blobId sql.NullString
+		err := rows.Scan(&messageId, &blobId)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		deleted[messageId] = true
+		if blobId.Valid {
+			blobIds = append(blobIds, blobId.String)
+		}
+	}
+
+	return len(deleted), blobIds, rows.Err()
+}
+
+// scanMessages reads rows of messageColumns and fills in their attachments and reactions as seen by the user.
+func (m message) scanMessages(ctx *gofr.Context, userId string, rows *sql.Rows) (*[]model.Message, error) {
+	defer rows.Close()
+
+	messages := make([]model.Message, 0)
+
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, *message)
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.attachAttachments(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	err = m.attachReactions(ctx, userId, messages)
+	if err != nil {
+		return nil, err
+	}
+
 	return &messages, nil
 }
 
+// AddReaction reacts to a message the user takes part in with emoji.
+// It returns the message and whether the user had not reacted with that emoji before.
+func (m message) AddReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error) {
+	message, err := m.getMessage(ctx, userId, messageId)
+	if err != nil {
+		return nil, false, err
+	}
+	if message.Deleted {
+		return nil, false, sql.ErrNoRows
+	}
+
+	result, err := ctx.DB().ExecContext(ctx, `INSERT INTO message_reactions (message_id, account_id, emoji, created_at) VALUES ($1, $2, $3, $4)
+	ON CONFLICT DO NOTHING`, messageId, userId, emoji, time.Now())
+	if err != nil {
+		return nil, false, err
+	}
+
+	added, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+	return message, added > 0, nil
+}
+
+// RemoveReaction takes back the reaction of the user with emoji.
+// It returns the message and whether the user had reacted with that emoji.
+func (m message) RemoveReaction(ctx *gofr.Context, userId, messageId, emoji string) (*model.Message, bool, error) {
+	message, err := m.getMessage(ctx, userId, messageId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	result, err := ctx.DB().ExecContext(ctx, "DELETE FROM message_reactions WHERE message_id=$1 AND account_id=$2 AND emoji=$3", messageId, userId, emoji)
+	if err != nil {
+		return nil, false, err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+	return message, removed > 0, nil
+}
+
+// StarMessage bookmarks a message the user can see for themselves. Starring a message twice changes nothing.
+func (m message) StarMessage(ctx *gofr.Context, userId, messageId string) error {
+	message, err := m.getMessage(ctx, userId, messageId)
+	if err != nil {
+		return err
+	}
+	if message.Deleted {
+		return sql.ErrNoRows
+	}
+
+	_, err = ctx.DB().ExecContext(ctx, `INSERT INTO message_stars (message_id, account_id, starred_at) VALUES ($1, $2, $3)
+	ON CONFLICT DO NOTHING`, messageId, userId, time.Now())
+	return err
+}
+
+// UnstarMessage removes the bookmark of the user from a message, if they starred it.
+func (m message) UnstarMessage(ctx *gofr.Context, userId, messageId string) error {
+	_, err := ctx.DB().ExecContext(ctx, "DELETE FROM message_stars WHERE message_id=$1 AND account_id=$2", messageId, userId)
+	return err
+}
+
+// GetStarredMessages returns a page of the messages the user starred across their conversations, the most recently
+// starred first. Messages that were deleted, expired or that the user can no longer see are left out.
+func (m message) GetStarredMessages(ctx *gofr.Context, userId string, page, limit uint) (*[]model.Message, error) {
+	query := `SELECT ` + messageColumns + ` FROM ` + messageSource + `
+	JOIN message_stars s ON s.message_id = messages.id AND s.account_id = $1
+	WHERE messages.deletedAt IS NULL AND ` + isSent + ` AND ` + notExpired + ` AND ` + fmt.Sprintf(isMember, 1) + ` AND ` + fmt.Sprintf(hiddenForUser, 1) + `
+	ORDER BY s.starred_at DESC LIMIT $2 OFFSET $3`
+
+	rows, err := ctx.DB().QueryContext(ctx, query, userId, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.scanMessages(ctx, userId, rows)
+}
+
+// PinMessage pins a message the user can see to its conversation, for every member to see. Only direct conversations
+// and groups have pins, at most limit of them. It returns the message and whether it was not pinned before.
+func (m message) PinMessage(ctx *gofr.Context, userId, messageId string, limit int) (*model.Message, bool, error) {
+	message, err := m.getMessage(ctx, userId, messageId)
+	if err != nil {
+		return nil, false, err
+	}
+	if message.Deleted {
+		return nil, false, sql.ErrNoRows
+	}
+
+	// Pins of messages that were deleted or expired since do not count towards the limit. The conversation is locked
+	// first, so that concurrent pins are counted one after the other.
+	query := `WITH pinned AS (
+		SELECT 1 FROM message_pins WHERE message_id = $1
+	), allowed AS (
+		SELECT 1 FROM conversations WHERE id = $2 AND kind IN ('direct', 'group')
+	), room AS (
+		SELECT COUNT(*) < $5 AS ok FROM message_pins p JOIN messages ON messages.id = p.message_id
+		WHERE p.conversation_id = $2 AND messages.deletedAt IS NULL AND ` + notExpired + `
+	), inserted AS (
+		INSERT INTO message_pins (message_id, conversation_id, pinned_by, pinned_at)
+		SELECT $1, $2, $3, $4 WHERE NOT EXISTS (SELECT 1 FROM pinned) AND EXISTS (SELECT 1 FROM allowed) AND (SELECT ok FROM room)
+		ON CONFLICT DO NOTHING
+		RETURNING 1
+	)
+	SELECT EXISTS (SELECT 1 FROM pinned), EXISTS (SELECT 1 FROM allowed), EXISTS (SELECT 1 FROM inserted)`
+
+	var pinned, allowed, inserted bool
+	err = inTransaction(ctx, func(tx *sql.Tx) error {
+		err := lockConversation(ctx, tx, message.ConversationID)
+		if err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, query, messageId, message.ConversationID, userId, time.Now(), limit).Scan(&pinned, &allowed, &inserted)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch {
+	case pinned:
+		return message, false, nil
+	case !allowed:
+		return nil, false, e.NewError("Messages of channels cannot be pinned")
+	case !inserted:
+		return nil, false, e.NewError("That conversation has too many pinned messages")
+	}
+	return message, true, nil
+}
+
+// UnpinMessage unpins a message of a conversation the user is a member of.
+// It returns the message and whether it was pinned.
+func (m message) UnpinMessage(ctx *gofr.Context, userId, messageId string) (*model.Message, bool, error) {
+	message, err := m.getMessage(ctx, userId, messageId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	result, err := ctx.DB().ExecContext(ctx, "DELETE FROM message_pins WHERE message_id=$1", messageId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+	return message, removed > 0, nil
+}
+
+// GetPinnedMessages returns the messages pinned to a conversation the user is a member of, the most recently pinned first.
+// Direct conversations may also be referred to by the ID of the peer. Messages that were deleted, expired or that
+// the user deleted for themselves are left out.
+func (m message) GetPinnedMessages(ctx *gofr.Context, userId, conversationId string) (*[]model.PinnedMessage, error) {
+	query := `SELECT ` + messageColumns + `, p.pinned_by, p.pinned_at FROM ` + messageSource + `
+	JOIN message_pins p ON p.message_id = messages.id
+	WHERE p.conversation_id IN ($2, $3) AND messages.deletedAt IS NULL AND ` + notExpired + ` AND ` + fmt.Sprintf(isMember, 1) + ` AND ` + fmt.Sprintf(hiddenForUser, 1) + `
+	ORDER BY p.pinned_at DESC`
+
+	rows, err := ctx.DB().QueryContext(ctx, query, userId, conversationId, DirectConversationID(userId, conversationId))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	pins := make([]model.PinnedMessage, 0)
+	for rows.Next() {
+		var pin model.PinnedMessage
+		message, err := scanMessage(rows, &pin.PinnedBy, &pin.PinnedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		pin.Message = *message
+		pins = append(pins, pin)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]model.Message, len(pins))
+	for i := range pins {
+		messages[i] = pins[i].Message
+	}
+	err = m.attachAttachments(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	err = m.attachReactions(ctx, userId, messages)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pins {
+		pins[i].Message = messages[i]
+	}
+
+	return &pins, nil
+}
+
+// Vote casts the vote of the user in a poll of a conversation the user is a member of, replacing any earlier vote,
+// and returns the results of the poll. A vote is a single row, so concurrent votes of the same user cannot
+// combine into one the poll does not allow.
+func (m message) Vote(ctx *gofr.Context, userId, messageId string, options []int) (*model.PollResults, error) {
+	poll, message, err := m.getPoll(ctx, userId, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(options) > 1 && !poll.MultiSelect {
+		return nil, e.NewError("That poll takes a single option")
+	}
+	for _, option := range options {
+		if option < 0 || option >= len(poll.Options) {
+			return nil, e.NewError("That poll has no such option")
+		}
+	}
+
+	now := time.Now()
+	if poll.Closed(now) {
+		return nil, e.NewError("That poll is closed")
+	}
+
+	sorted := append([]int(nil), options...)
+	sort.Ints(sorted)
+	encoded, err := json.Marshal(sorted)
+	if err != nil {
+		return nil, err
+	}
+
+	// The poll is checked again as the vote is cast, so no vote slips in past the deadline or after the poll is deleted.
+	query := `INSERT INTO poll_votes (message_id, account_id, options, voted_at)
+	SELECT id, $2, $3, $4 FROM messages
+	WHERE id = $1 AND deletedAt IS NULL AND (payload->'poll'->>'closesAt' IS NULL OR (payload->'poll'->>'closesAt')::timestamptz > $4)
+	ON CONFLICT (message_id, account_id) DO UPDATE SET options = EXCLUDED.options, voted_at = EXCLUDED.voted_at`
+
+	result, err := ctx.DB().ExecContext(ctx, query, message.ID, userId, encoded, now)
+	if err != nil {
+		return nil, err
+	}
+
+	cast, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if cast == 0 {
+		return nil, e.NewError("That poll is closed")
+	}
+
+	return m.pollResults(ctx, userId, message)
+}
+
+// GetPollResults returns the results of a poll of a conversation the user is a member of.
+func (m message) GetPollResults(ctx *gofr.Context, userId, messageId string) (*model.PollResults, error) {
+	_, message, err := m.getPoll(ctx, userId, messageId)
+	if err != nil {
+		return nil, err
+	}
+	return m.pollResults(ctx, userId, message)
+}
+
+// getPoll returns a poll of a conversation the user is a member of, along with the message asking it.
+func (m message) getPoll(ctx *gofr.Context, userId, messageId string) (*model.Poll, *model.Message, error) {
+	message, err := m.getMessage(ctx, userId, messageId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if message.Deleted {
+		return nil, nil, sql.ErrNoRows
+	}
+	if message.Type != model.MessagePoll || message.Payload == nil || message.Payload.Poll == nil {
+		return nil, nil, e.NewError("That message is not a poll")
+	}
+	return message.Payload.Poll, message, nil
+}
+
+// pollResults tallies the votes of the poll of a message as seen by the user. Every vote is read at once, so the tallies
+// are consistent even while others vote.
+func (m message) pollResults(ctx *gofr.Context, userId string, message *model.Message) (*model.PollResults, error) {
+	poll := message.Payload.Poll
+	rows, err := ctx.DB().QueryContext(ctx, "SELECT account_id, options FROM poll_votes WHERE message_id=$1 ORDER BY voted_at", message.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results := model.PollResults{MessageID: message.ID, ConversationID: message.ConversationID, Options: make([]model.PollTally, len(poll.Options)), Closed: poll.Closed(time.Now())}
+	for i, text := range poll.Options {
+		results.Options[i] = model.PollTally{Option: i, Text: text}
+	}
+
+	for rows.Next() {
+		var voterId string
+		var encoded []byte
+		err = rows.Scan(&voterId, &encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		var options []int
+		err = json.Unmarshal(encoded, &options)
+		if err != nil {
+			return nil, err
+		}
+
+		results.Voters++
+		for _, option := range options {
+			if option < 0 || option >= len(results.Options) {
+				continue
+			}
+
+			tally := &results.Options[option]
+			tally.Count++
+			if !poll.Anonymous {
+				tally.VoterIDs = append(tally.VoterIDs, voterId)
+			}
+		}
+		if voterId == userId {
+			results.MyVotes = options
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
+
+// SetLinkPreview attaches the preview of a link to a message, as long as the message still has the content the link
+// was found in and was not deleted meanwhile. It reports whether the preview was attached.
+func (m message) SetLinkPreview(ctx *gofr.Context, messageId, content string, preview model.LinkPreview) (bool, error) {
+	encoded, err := json.Marshal(preview)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := ctx.DB().ExecContext(ctx, "UPDATE messages SET linkPreview=$3 WHERE id=$1 AND content=$2 AND deletedAt IS NULL", messageId, content, encoded)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// attachAttachments fills in the attachments of messages. Messages deleted for everyone keep none.
+func (m message) attachAttachments(ctx *gofr.Context, messages []model.Message) error {
+	args := make([]interface{}, 0, len(messages))
+	index := make(map[string]int, len(messages))
+	for i, message := range messages {
+		if !message.Deleted {
+			args = append(args, message.ID)
+			index[message.ID] = i
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(`SELECT `+attachmentColumns+`, a.message_id FROM attachments a
+	WHERE a.message_id IN (%s) ORDER BY a.created_at`, placeholders(1, len(args)))
+
+	rows, err := ctx.DB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageId string
+		attachment, err := scanAttachment(rows, &messageId)
+		if err != nil {
+			return err
+		}
+
+		i := index[messageId]
+		messages[i].Attachments = append(messages[i].Attachments, *attachment)
+	}
+
+	return rows.Err()
+}
+
+// attachReactions fills in the reactions of messages as seen by the user, in the order they were first made.
+func (m message) attachReactions(ctx *gofr.Context, userId string, messages []model.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	args := []interface{}{userId}
+	index := make(map[string]int, len(messages))
+	for i, message := range messages {
+		args = append(args, message.ID)
+		index[message.ID] = i
+	}
+
+	query := fmt.Sprintf(`SELECT message_id, emoji, COUNT(*), BOOL_OR(account_id = $1) FROM message_reactions
+	WHERE message_id IN (%s)
+	GROUP BY message_id, emoji ORDER BY MIN(created_at)`, placeholders(2, len(messages)))
+
+	rows, err := ctx.DB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageId string
+		var reaction model.Reaction
+		err = rows.Scan(&messageId, &reaction.Emoji, &reaction.Count, &reaction.Me)
+		if err != nil {
+			return err
+		}
+
+		i := index[messageId]
+		messages[i].Reactions = append(messages[i].Reactions, reaction)
+	}
+
+	return rows.Err()
+}
+
 func (message) createMessageTable(db *datastore.SQLClient) error {
 	query := `CREATE TABLE IF NOT EXISTS messages (
 		id UUID PRIMARY KEY,
@@ -129,7 +1166,109 @@ func (message) createMessageTable(db *datastore.SQLClient) error {
 		senderId UUID NOT NULL,
 		recieverId UUID NOT NULL,
 		timestamp TIMESTAMP NOT NULL
+	);
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS deletedAt TIMESTAMP;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS editedAt TIMESTAMP;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS editCount INT NOT NULL DEFAULT 0;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS replyToId UUID;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS conversationId UUID;
+	ALTER TABLE messages ALTER COLUMN recieverId DROP NOT NULL;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS scheduledAt TIMESTAMP;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS disappearAfter INT;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS expiresAt TIMESTAMPTZ;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS system JSONB;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS forwardCount INT NOT NULL DEFAULT 0;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'text';
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS payload JSONB;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS entities JSONB;
+	ALTER TABLE messages ADD COLUMN IF NOT EXISTS linkPreview JSONB;
+	CREATE INDEX IF NOT EXISTS messages_conversation_idx ON messages (conversationId, timestamp DESC);
+	CREATE INDEX IF NOT EXISTS messages_scheduled_idx ON messages (scheduledAt) WHERE scheduledAt IS NOT NULL;
+	CREATE INDEX IF NOT EXISTS messages_expiry_idx ON messages (expiresAt) WHERE expiresAt IS NOT NULL;`
+	_, err := db.Exec(query)
+	return err
+}
+
+func (message) createMessageDeletionsTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS message_deletions (
+		message_id UUID NOT NULL,
+		account_id UUID NOT NULL,
+		deleted_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+		FOREIGN KEY (account_id) REFERENCES accounts(id),
+		PRIMARY KEY (message_id, account_id)
+	);`
+	_, err := db.Exec(query)
+	return err
+}
+
+func (message) createMessageRevisionsTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS message_revisions (
+		message_id UUID NOT NULL,
+		version INT NOT NULL,
+		content TEXT NOT NULL,
+		written_at TIMESTAMP NOT NULL,
+		replaced_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+		PRIMARY KEY (message_id, version)
 	);`
 	_, err := db.Exec(query)
 	return err
 }
+
+func (message) createMessageReactionsTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS message_reactions (
+		message_id UUID NOT NULL,
+		account_id UUID NOT NULL,
+		emoji TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+		FOREIGN KEY (account_id) REFERENCES accounts(id),
+		PRIMARY KEY (message_id, account_id, emoji)
+	);`
+	_, err := db.Exec(query)
+	return err
+}
+
+func (message) createMessageStarsTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS message_stars (
+		message_id UUID NOT NULL,
+		account_id UUID NOT NULL,
+		starred_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+		FOREIGN KEY (account_id) REFERENCES accounts(id),
+		PRIMARY KEY (message_id, account_id)
+	);
+	CREATE INDEX IF NOT EXISTS message_stars_account_idx ON message_stars (account_id, starred_at);`
+	_, err := db.Exec(query)
+	return err
+}
+
+// createPollVotesTable creates the votes cast in polls, one per voter holding every option they picked.
+func (message) createPollVotesTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS poll_votes (
+		message_id UUID NOT NULL,
+		account_id UUID NOT NULL,
+		options JSONB NOT NULL,
+		voted_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+		FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+		PRIMARY KEY (message_id, account_id)
+	);`
+	_, err := db.Exec(query)
+	return err
+}
+
+func (message) createMessagePinsTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS message_pins (
+		message_id UUID PRIMARY KEY,
+		conversation_id UUID NOT NULL,
+		pinned_by UUID NOT NULL,
+		pinned_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+		FOREIGN KEY (pinned_by) REFERENCES accounts(id)
+	);
+	CREATE INDEX IF NOT EXISTS message_pins_conversation_idx ON message_pins (conversation_id, pinned_at);`
+	_, err := db.Exec(query)
+	return err
+}
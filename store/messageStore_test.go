@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"testing"
@@ -30,15 +31,16 @@ func TestAddMessage(t *testing.T) {
 	messageStore := message{}
 
 	sampleMessage := model.Message{
-		ID:        "test-message-id",
-		Content:   "Hello, world!",
-		From:      "sender-user-id",
-		To:        "receiver-user-id",
-		Timestamp: time.Now(),
+		ID:             "test-message-id",
+		ConversationID: "test-conversation-id",
+		Content:        "Hello, world!",
+		From:           "sender-user-id",
+		To:             "receiver-user-id",
+		Timestamp:      time.Now(),
 	}
 
 	mock.ExpectExec("INSERT INTO messages").
-		WithArgs(sampleMessage.ID, sampleMessage.Content, sampleMessage.From, sampleMessage.To, sampleMessage.Timestamp).
+		WithArgs(sampleMessage.ID, sampleMessage.Content, sampleMessage.From, sampleMessage.To, sampleMessage.Timestamp, nil, sampleMessage.ConversationID, nil, sql.NullInt64{}, nil, nil, uint(0), model.MessageText, nil, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1)).
 		WillReturnError(nil)
 
@@ -50,7 +52,8 @@ func TestAddMessage(t *testing.T) {
 	}
 
 	mock.ExpectExec("INSERT INTO messages").
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnError(fmt.Errorf(""))
 
 	err = messageStore.AddMessage(ctx, model.Message{})
@@ -59,7 +62,37 @@ func TestAddMessage(t *testing.T) {
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %s", err)
 	}
-	
+
+	sampleMessage.Attachments = []model.Attachment{{ID: "attachment-id-1"}, {ID: "attachment-id-2"}}
+
+	mock.ExpectExec("WITH inserted AS \\(INSERT INTO messages .* RETURNING id\\) UPDATE attachments SET message_id").
+		WithArgs(sampleMessage.ID, sampleMessage.Content, sampleMessage.From, sampleMessage.To, sampleMessage.Timestamp, nil, sampleMessage.ConversationID, nil, sql.NullInt64{}, nil, nil, uint(0), model.MessageText, nil, nil, "attachment-id-1", "attachment-id-2").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err = messageStore.AddMessage(ctx, sampleMessage)
+
+	assert.NoError(t, err, "Unexpected error during insertion of a message with attachments")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	createdAt := time.Now()
+	sampleMessage.Forwarded, sampleMessage.ForwardCount = true, 1
+	sampleMessage.Attachments = []model.Attachment{{ID: "copy-id", FileName: "cat.png", ContentType: "image/png", Size: 1024, CreatedAt: createdAt,
+		Status: model.AttachmentReady, BlobID: "attachment-id-1"}}
+
+	mock.ExpectExec("WITH inserted AS \\(INSERT INTO messages .* RETURNING id\\) INSERT INTO attachments \\(id, file_name, content_type, size, created_at, status, width, height, blurhash, thumbnails, blob_id, owner_id, message_id\\) " +
+		"VALUES \\(\\$16,\\$17,\\$18,\\$19,\\$20,\\$21,\\$22,\\$23,\\$24,\\$25,\\$26, \\$3, \\$1\\)").
+		WithArgs(sampleMessage.ID, sampleMessage.Content, sampleMessage.From, sampleMessage.To, sampleMessage.Timestamp, nil, sampleMessage.ConversationID, nil, sql.NullInt64{}, nil, nil, uint(1), model.MessageText, nil, nil,
+			"copy-id", "cat.png", "image/png", int64(1024), createdAt, model.AttachmentReady, sql.NullInt64{}, sql.NullInt64{}, sql.NullString{}, sql.NullString{}, "attachment-id-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = messageStore.AddMessage(ctx, sampleMessage)
+
+	assert.NoError(t, err, "Unexpected error during insertion of a forwarded message with attachments")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
 }
 
 func TestGetMessage(t *testing.T) {
@@ -88,23 +121,32 @@ func TestGetMessage(t *testing.T) {
 		Timestamp: time.Now(),
 	}
 
-	mock.ExpectQuery("SELECT id,content,senderId,recieverId,timestamp FROM messages WHERE id=").
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "member"}).
+			AddRow(sampleMessage.ID, sampleMessage.Content, sampleMessage.From, sampleMessage.To, sampleMessage.Timestamp, nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.*, a.message_id FROM attachments a").
 		WithArgs(messageID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp"}).
-			AddRow(sampleMessage.ID, sampleMessage.Content, sampleMessage.From, sampleMessage.To, sampleMessage.Timestamp))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "file_name", "content_type", "size", "created_at", "status", "width", "height", "blurhash", "thumbnails", "blob_id", "message_id"}))
+	mock.ExpectQuery("SELECT message_id, emoji, COUNT").
+		WithArgs(userID, messageID).
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "emoji", "count", "me"}).
+			AddRow(messageID, "👍", 2, true).
+			AddRow(messageID, "😂", 1, false))
 
 	retrievedMessage, err := messageStore.GetMessage(ctx, userID, messageID)
 
 	assert.NoError(t, err, "Unexpected error during message retrieval")
 	assert.NotNil(t, retrievedMessage, "Expected a non-nil retrieved message")
 	assert.Equal(t, messageID, retrievedMessage.ID, "Mismatch in retrieved message ID")
+	assert.Equal(t, []model.Reaction{{Emoji: "👍", Count: 2, Me: true}, {Emoji: "😂", Count: 1}}, retrievedMessage.Reactions, "Mismatch in reactions")
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %s", err)
 	}
 
-	mock.ExpectQuery("SELECT id,content,senderId,recieverId,timestamp FROM messages WHERE id=").
-		WithArgs(messageID).
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
 		WillReturnError(fmt.Errorf(""))
 
 	_, err = messageStore.GetMessage(ctx, userID, messageID)
@@ -114,10 +156,10 @@ func TestGetMessage(t *testing.T) {
 		t.Errorf("Unfulfilled expectations: %s", err)
 	}
 
-	mock.ExpectQuery("SELECT id,content,senderId,recieverId,timestamp FROM messages WHERE id=").
-		WithArgs(messageID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp"}).
-			AddRow(sampleMessage.ID, sampleMessage.Content, "different-user-id", sampleMessage.To, sampleMessage.Timestamp))
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "member"}).
+			AddRow(sampleMessage.ID, sampleMessage.Content, "different-user-id", sampleMessage.To, sampleMessage.Timestamp, nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, false))
 
 	_, err = messageStore.GetMessage(ctx, userID, messageID)
 
@@ -127,6 +169,168 @@ func TestGetMessage(t *testing.T) {
 	}
 }
 
+func TestTypedMessages(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	messageStore := message{}
+
+	userID := "test-user-id"
+	messageID := "test-message-id"
+	latitude, longitude := 48.8584, 2.2945
+	payload := &model.MessagePayload{Location: &model.Location{Latitude: &latitude, Longitude: &longitude, Accuracy: 12}}
+	encoded := []byte(`{"location":{"latitude":48.8584,"longitude":2.2945,"accuracy":12}}`)
+
+	sampleMessage := model.Message{
+		ID:             messageID,
+		ConversationID: "conversation-id",
+		Type:           model.MessageLocation,
+		Content:        "Location: 48.858400, 2.294500",
+		Payload:        payload,
+		From:           userID,
+		To:             "receiver-user-id",
+		Timestamp:      time.Now(),
+	}
+
+	mock.ExpectExec("INSERT INTO messages \\(.*,kind,payload,entities\\)").
+		WithArgs(sampleMessage.ID, sampleMessage.Content, sampleMessage.From, sampleMessage.To, sampleMessage.Timestamp, nil, sampleMessage.ConversationID, nil, sql.NullInt64{}, nil, nil, uint(0),
+			model.MessageLocation, encoded, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := messageStore.AddMessage(ctx, sampleMessage)
+
+	assert.NoError(t, err, "Unexpected error while adding a location")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	columns := []string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "member"}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.*,messages.kind,messages.payload,messages.entities,messages.linkPreview, .* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, sampleMessage.Content, userID, "receiver-user-id", sampleMessage.Timestamp, nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "location", encoded, nil, nil, true))
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.*, a.message_id FROM attachments a").
+		WithArgs(messageID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "file_name", "content_type", "size", "created_at", "status", "width", "height", "blurhash", "thumbnails", "blob_id", "message_id"}))
+	mock.ExpectQuery("SELECT message_id, emoji, COUNT").
+		WithArgs(userID, messageID).
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "emoji", "count", "me"}))
+
+	retrievedMessage, err := messageStore.GetMessage(ctx, userID, messageID)
+
+	assert.NoError(t, err, "Unexpected error while retrieving a location")
+	assert.Equal(t, model.MessageLocation, retrievedMessage.Type, "Mismatch in message type")
+	assert.Equal(t, payload, retrievedMessage.Payload, "Mismatch in location")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID).
+		WillReturnRows(sqlmock.NewRows(columns[:len(columns)-1]).
+			AddRow(messageID, sampleMessage.Content, userID, "receiver-user-id", time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "location", encoded, nil, nil))
+
+	_, err = messageStore.UpdateMessage(ctx, userID, messageID, "Somewhere else", nil, time.Hour)
+
+	assert.Equal(t, e.NewError("Only text messages can be edited"), err, "Expected edits of typed messages to be refused")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPollVotes(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	messageStore := message{}
+
+	userID := "test-user-id"
+	messageID := "test-message-id"
+	columns := []string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "member"}
+
+	expectPoll := func(kind string, payload string) {
+		mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+			WithArgs(messageID, userID).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(messageID, "Poll: Lunch?\n- Salad\n- Eggs", "asker-id", nil, time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, kind, []byte(payload), nil, nil, true))
+	}
+	poll := `{"poll":{"question":"Lunch?","options":["Salad","Eggs"],"multiSelect":false,"anonymous":false}}`
+	votes := sqlmock.NewRows([]string{"account_id", "options"}).
+		AddRow("asker-id", []byte("[1]")).
+		AddRow(userID, []byte("[0]"))
+
+	expectPoll(model.MessagePoll, poll)
+	mock.ExpectExec("INSERT INTO poll_votes .* SELECT id, \\$2, \\$3, \\$4 FROM messages WHERE id = \\$1 AND deletedAt IS NULL .* ON CONFLICT \\(message_id, account_id\\) DO UPDATE").
+		WithArgs(messageID, userID, []byte("[0]"), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT account_id, options FROM poll_votes WHERE message_id=\\$1").
+		WithArgs(messageID).
+		WillReturnRows(votes)
+
+	results, err := messageStore.Vote(ctx, userID, messageID, []int{0})
+
+	assert.NoError(t, err, "Unexpected error while voting")
+	assert.Equal(t, &model.PollResults{MessageID: messageID, ConversationID: "conversation-id", Voters: 2, MyVotes: []int{0},
+		Options: []model.PollTally{{Option: 0, Text: "Salad", Count: 1, VoterIDs: []string{userID}}, {Option: 1, Text: "Eggs", Count: 1, VoterIDs: []string{"asker-id"}}}},
+		results, "Mismatch in poll results")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	testCases := []struct {
+		desc    string
+		kind    string
+		payload string
+		options []int
+		err     error
+	}{
+		{"several options in a single-select poll", model.MessagePoll, poll, []int{0, 1}, e.NewError("That poll takes a single option")},
+		{"option out of range", model.MessagePoll, poll, []int{2}, e.NewError("That poll has no such option")},
+		{"closed poll", model.MessagePoll, `{"poll":{"question":"Lunch?","options":["Salad","Eggs"],"closesAt":"2024-05-01T12:00:00Z"}}`, []int{0}, e.NewError("That poll is closed")},
+		{"not a poll", model.MessageText, "null", []int{0}, e.NewError("That message is not a poll")},
+	}
+
+	for _, tc := range testCases {
+		expectPoll(tc.kind, tc.payload)
+
+		_, err := messageStore.Vote(ctx, userID, messageID, tc.options)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("TEST: %s: unfulfilled expectations: %s", tc.desc, err)
+		}
+	}
+
+	// The poll closing between reading it and casting the vote leaves nothing to update.
+	expectPoll(model.MessagePoll, poll)
+	mock.ExpectExec("INSERT INTO poll_votes").
+		WithArgs(messageID, userID, []byte("[0]"), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = messageStore.Vote(ctx, userID, messageID, []int{0})
+
+	assert.Equal(t, e.NewError("That poll is closed"), err, "Expected no vote past the deadline")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	expectPoll(model.MessagePoll, `{"poll":{"question":"Lunch?","options":["Salad","Eggs"],"multiSelect":true,"anonymous":true}}`)
+	mock.ExpectQuery("SELECT account_id, options FROM poll_votes WHERE message_id=\\$1").
+		WithArgs(messageID).
+		WillReturnRows(sqlmock.NewRows([]string{"account_id", "options"}).
+			AddRow("asker-id", []byte("[0,1]")))
+
+	results, err = messageStore.GetPollResults(ctx, userID, messageID)
+
+	assert.NoError(t, err, "Unexpected error while retrieving poll results")
+	assert.Equal(t, []model.PollTally{{Option: 0, Text: "Salad", Count: 1}, {Option: 1, Text: "Eggs", Count: 1}}, results.Options, "Expected anonymous polls to hide their voters")
+	assert.Nil(t, results.MyVotes, "Expected no votes of a user who has not voted")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
 func TestUpdateMessage(t *testing.T) {
 	app := gofr.New()
 	ctx := gofr.NewContext(nil, nil, app)
@@ -145,6 +349,8 @@ func TestUpdateMessage(t *testing.T) {
 	userID := "test-user-id"
 	messageID := "test-message-id"
 	updatedContent := "Updated content"
+	entities := []model.Entity{{Type: model.EntityBold, Offset: 0, Length: 7}}
+	window := 15 * time.Minute
 
 	sampleMessage := model.Message{
 		ID:        messageID,
@@ -154,48 +360,132 @@ func TestUpdateMessage(t *testing.T) {
 		Timestamp: time.Now(),
 	}
 
-	mock.ExpectQuery("SELECT id,content,senderId,recieverId,timestamp FROM messages WHERE id=").
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
 		WithArgs(messageID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp"}).
-			AddRow(sampleMessage.ID, sampleMessage.Content, sampleMessage.From, sampleMessage.To, sampleMessage.Timestamp))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview"}).
+			AddRow(sampleMessage.ID, sampleMessage.Content, sampleMessage.From, sampleMessage.To, sampleMessage.Timestamp, nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, []byte(`{"url":"https://gofr.dev","title":"GoFr"}`)))
 
-	mock.ExpectExec("UPDATE messages SET content=").
-		WithArgs(updatedContent, messageID).
+	mock.ExpectExec("INSERT INTO message_revisions .* UPDATE messages SET content=\\$1, entities=\\$4, linkPreview=NULL,").
+		WithArgs(updatedContent, messageID, sqlmock.AnyArg(), []byte(`[{"type":"bold","offset":0,"length":7}]`)).
 		WillReturnResult(sqlmock.NewResult(0, 1)).
 		WillReturnError(nil)
 
-	updatedMessage, err := messageStore.UpdateMessage(ctx, userID, messageID, updatedContent)
+	updatedMessage, err := messageStore.UpdateMessage(ctx, userID, messageID, updatedContent, entities, window)
 
 	assert.NoError(t, err, "Unexpected error during message update")
 	assert.NotNil(t, updatedMessage, "Expected a non-nil updated message")
 	assert.Equal(t, updatedContent, updatedMessage.Content, "Mismatch in updated message content")
+	assert.Equal(t, uint(1), updatedMessage.EditCount, "Mismatch in edit count")
+	assert.NotNil(t, updatedMessage.EditedAt, "Expected the edit time to be set")
+	assert.Nil(t, updatedMessage.LinkPreview, "Expected the preview of the replaced content to be dropped")
+	assert.Equal(t, "<strong>Updated</strong> content", updatedMessage.HTML, "Mismatch in rendering of the updated message")
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %s", err)
 	}
 
-	mock.ExpectQuery("SELECT id,content,senderId,recieverId,timestamp FROM messages WHERE id=").
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
 		WithArgs(messageID).
 		WillReturnError(fmt.Errorf(""))
 
-	_, err = messageStore.UpdateMessage(ctx, userID, messageID, updatedContent)
+	_, err = messageStore.UpdateMessage(ctx, userID, messageID, updatedContent, entities, window)
 
 	assert.Error(t, err, "Expected an error during failed message update")
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %s", err)
 	}
 
-	mock.ExpectQuery("SELECT id,content,senderId,recieverId,timestamp FROM messages WHERE id=").
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
 		WithArgs(messageID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp"}).
-			AddRow(sampleMessage.ID, sampleMessage.Content, "different-user-id", sampleMessage.To, sampleMessage.Timestamp))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview"}).
+			AddRow(sampleMessage.ID, sampleMessage.Content, "different-user-id", sampleMessage.To, sampleMessage.Timestamp, nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil))
 
-	_, err = messageStore.UpdateMessage(ctx, userID, messageID, updatedContent)
+	_, err = messageStore.UpdateMessage(ctx, userID, messageID, updatedContent, entities, window)
 
 	assert.EqualError(t, err, e.NewError("You are not authorized to update that message").Error(), "Expected an authorization error")
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %s", err)
 	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview"}).
+			AddRow(sampleMessage.ID, sampleMessage.Content, sampleMessage.From, sampleMessage.To, time.Now().Add(-2*window), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil))
+
+	_, err = messageStore.UpdateMessage(ctx, userID, messageID, updatedContent, entities, window)
+
+	assert.EqualError(t, err, e.NewError("That message can no longer be edited").Error(), "Expected an edit window error")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetMessageHistory(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	messageStore := message{}
+
+	userID := "test-user-id"
+	messageID := "test-message-id"
+	columns := []string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "member"}
+	sentAt := time.Now().Add(-time.Hour)
+	editedAt := time.Now().Add(-time.Minute)
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Third", "sender-user-id", userID, sentAt, nil, editedAt, 2, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+	mock.ExpectQuery("SELECT version, content, written_at, replaced_at FROM message_revisions").
+		WithArgs(messageID).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "content", "written_at", "replaced_at"}).
+			AddRow(1, "Second", sentAt.Add(time.Minute), editedAt).
+			AddRow(0, "First", sentAt, sentAt.Add(time.Minute)))
+
+	history, err := messageStore.GetMessageHistory(ctx, userID, messageID)
+
+	assert.NoError(t, err, "Unexpected error while retrieving message history")
+	assert.Equal(t, "Third", history.Message.Content, "Mismatch in current content")
+	assert.Equal(t, uint(2), history.Message.EditCount, "Mismatch in edit count")
+	assert.Len(t, history.Revisions, 2, "Mismatch in number of revisions")
+	assert.Equal(t, "First", history.Revisions[1].Content, "Mismatch in original content")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "", "sender-user-id", userID, sentAt, time.Now(), editedAt, 2, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+
+	history, err = messageStore.GetMessageHistory(ctx, userID, messageID)
+
+	assert.NoError(t, err, "Unexpected error while retrieving message history")
+	assert.Empty(t, history.Revisions, "Expected no revisions for a message deleted for everyone")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Third", "sender-user-id", "receiver-user-id", sentAt, nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, false))
+
+	_, err = messageStore.GetMessageHistory(ctx, userID, messageID)
+
+	assert.EqualError(t, err, e.NewError("You are not authorized to see that message").Error(), "Expected an authorization error")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
 }
 
 func TestDeleteMessage(t *testing.T) {
@@ -215,18 +505,20 @@ func TestDeleteMessage(t *testing.T) {
 
 	userID := "test-user-id"
 	messageID := "test-message-id"
+	window := time.Hour
+	columns := []string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "member"}
 
-	mock.ExpectQuery("SELECT senderId FROM messages WHERE id=").
-		WithArgs(messageID).
-		WillReturnRows(sqlmock.NewRows([]string{"senderId"}).
-			AddRow(userID))
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Hello, world!", userID, "receiver-user-id", time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
 
-	mock.ExpectExec("DELETE FROM messages where id=").
-		WithArgs(messageID).
+	mock.ExpectExec("WITH .*DELETE FROM poll_votes .*DELETE FROM message_pins .*DELETE FROM message_stars .*UPDATE messages SET content='', payload=NULL, entities=NULL, linkPreview=NULL, deletedAt=").
+		WithArgs(sqlmock.AnyArg(), messageID).
 		WillReturnResult(sqlmock.NewResult(0, 1)).
 		WillReturnError(nil)
 
-	err = messageStore.DeleteMessage(ctx, userID, messageID)
+	err = messageStore.DeleteMessage(ctx, userID, messageID, model.DeleteForEveryone, window)
 
 	assert.NoError(t, err, "Unexpected error during message deletion")
 
@@ -234,28 +526,135 @@ func TestDeleteMessage(t *testing.T) {
 		t.Errorf("Unfulfilled expectations: %s", err)
 	}
 
-	mock.ExpectQuery("SELECT senderId FROM messages WHERE id=").
-		WithArgs(messageID).
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
 		WillReturnError(fmt.Errorf(""))
 
-	err = messageStore.DeleteMessage(ctx, userID, messageID)
+	err = messageStore.DeleteMessage(ctx, userID, messageID, model.DeleteForEveryone, window)
 
 	assert.Error(t, err, "Expected an error during failed message deletion")
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %s", err)
 	}
 
-	mock.ExpectQuery("SELECT senderId FROM messages WHERE id=").
-		WithArgs(messageID).
-		WillReturnRows(sqlmock.NewRows([]string{"senderId"}).
-			AddRow("different-user-id"))
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Hello, world!", "different-user-id", userID, time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
 
-	err = messageStore.DeleteMessage(ctx, userID, messageID)
+	err = messageStore.DeleteMessage(ctx, userID, messageID, model.DeleteForEveryone, window)
 
 	assert.EqualError(t, err, e.NewError("You are not authorized to delete that message").Error(), "Expected an authorization error")
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %s", err)
 	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Hello, world!", userID, "receiver-user-id", time.Now().Add(-2*window), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+
+	err = messageStore.DeleteMessage(ctx, userID, messageID, model.DeleteForEveryone, window)
+
+	assert.EqualError(t, err, e.NewError("That message can no longer be deleted for everyone").Error(), "Expected an expired window error")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "", userID, "receiver-user-id", time.Now().Add(-2*window), time.Now(), nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+
+	err = messageStore.DeleteMessage(ctx, userID, messageID, model.DeleteForEveryone, window)
+
+	assert.NoError(t, err, "Expected deleting a tombstone again to succeed")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteMessageForMe(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	messageStore := message{}
+
+	userID := "test-user-id"
+	messageID := "test-message-id"
+	columns := []string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "member"}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Hello, world!", "sender-user-id", userID, time.Now().Add(-24*time.Hour), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+
+	mock.ExpectExec("INSERT INTO message_deletions").
+		WithArgs(messageID, userID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = messageStore.DeleteMessage(ctx, userID, messageID, model.DeleteForMe, time.Hour)
+
+	assert.NoError(t, err, "Expected the recipient to be able to delete a message for themselves")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Hello, world!", "sender-user-id", "receiver-user-id", time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, false))
+
+	err = messageStore.DeleteMessage(ctx, userID, messageID, model.DeleteForMe, time.Hour)
+
+	assert.EqualError(t, err, e.NewError("You are not authorized to delete that message").Error(), "Expected an authorization error")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPurgeDeletedMessages(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	messageStore := message{}
+	deletedBefore := time.Now()
+
+	mock.ExpectQuery("WITH purged AS \\( DELETE FROM messages WHERE deletedAt < \\$1 RETURNING id \\) " +
+		"SELECT purged.id, COALESCE\\(a.blob_id, a.id\\) FROM purged LEFT JOIN attachments a ON a.message_id = purged.id " +
+		"AND NOT EXISTS \\(SELECT 1 FROM attachments shared WHERE COALESCE\\(shared.blob_id, shared.id\\) = COALESCE\\(a.blob_id, a.id\\) AND shared.message_id NOT IN \\(SELECT id FROM purged\\)\\)").
+		WithArgs(deletedBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id"}).
+			AddRow("message-id-1", "attachment-id-1").
+			AddRow("message-id-2", nil).
+			AddRow("message-id-3", nil))
+
+	purged, blobIds, err := messageStore.PurgeDeletedMessages(ctx, deletedBefore)
+
+	assert.NoError(t, err, "Unexpected error while purging tombstones")
+	assert.Equal(t, 3, purged, "Mismatch in number of purged tombstones")
+	assert.Equal(t, []string{"attachment-id-1"}, blobIds, "Mismatch in blobs of purged tombstones")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
 }
 
 func TestGetMessages(t *testing.T) {
@@ -279,30 +678,540 @@ func TestGetMessages(t *testing.T) {
 	page := uint(1)
 	limit := uint(10)
 
-	mock.ExpectQuery("SELECT id,content,senderId,recieverId,timestamp FROM messages").
-		WithArgs(senderID, receiverID, limit, (page-1)*limit).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp"}).
-			AddRow("message-id-1", "Hello", senderID, receiverID, time.Now()).
-			AddRow("message-id-2", "Hi", senderID, receiverID, time.Now()))
-
-	messages, err := messageStore.GetMessages(ctx, senderID, receiverID, page, limit)
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted").
+		WithArgs(senderID, receiverID, senderID, limit, (page-1)*limit).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview"}).
+			AddRow("message-id-1", "Hello", senderID, receiverID, time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil).
+			AddRow("message-id-2", "Hi", senderID, receiverID, time.Now(), time.Now(), nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil).
+			AddRow("message-id-3", "Sure", senderID, receiverID, time.Now(), nil, nil, 0, "message-id-0", "How are you?", receiverID, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil).
+			AddRow("message-id-4", "Yes", senderID, receiverID, time.Now(), nil, nil, 0, "message-id-2", "", senderID, time.Now(), "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil).
+			AddRow("message-id-5", "No", senderID, receiverID, time.Now(), nil, nil, 0, "purged-message-id", nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil))
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.*, a.message_id FROM attachments a").
+		WithArgs("message-id-1", "message-id-3", "message-id-4", "message-id-5").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "file_name", "content_type", "size", "created_at", "status", "width", "height", "blurhash", "thumbnails", "blob_id", "message_id"}).
+			AddRow("attachment-id", senderID, "cat.png", "image/png", 1024, time.Now(), "ready", 640, 480, "LEHV6nWB2yk8pyo0adR*.7kCMdnj", []byte(`[{"size":"small","width":96,"height":72,"contentType":"image/jpeg"}]`), "attachment-id", "message-id-1"))
+	mock.ExpectQuery("SELECT message_id, emoji, COUNT").
+		WithArgs(senderID, "message-id-1", "message-id-2", "message-id-3", "message-id-4", "message-id-5").
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "emoji", "count", "me"}).
+			AddRow("message-id-3", "❤️", 1, false))
+
+	messages, err := messageStore.GetMessages(ctx, senderID, senderID, receiverID, page, limit)
 
 	assert.NoError(t, err, "Unexpected error during message retrieval")
 	assert.NotNil(t, messages, "Expected a non-nil list of messages")
-	assert.Len(t, *messages, 2, "Unexpected number of retrieved messages")
+	assert.Len(t, *messages, 5, "Unexpected number of retrieved messages")
+	assert.False(t, (*messages)[0].Deleted, "Expected a message that was not deleted")
+	assert.Nil(t, (*messages)[0].ReplyTo, "Expected no preview for a message that is not a reply")
+	assert.Empty(t, (*messages)[0].Reactions, "Expected no reactions")
+	assert.Len(t, (*messages)[0].Attachments, 1, "Mismatch in number of attachments")
+	assert.Equal(t, "cat.png", (*messages)[0].Attachments[0].FileName, "Mismatch in attachment")
+	assert.Equal(t, 640, (*messages)[0].Attachments[0].Width, "Mismatch in attachment width")
+	assert.Equal(t, []model.Thumbnail{{Size: "small", Width: 96, Height: 72, ContentType: "image/jpeg"}}, (*messages)[0].Attachments[0].Thumbnails, "Mismatch in thumbnails")
+	assert.Empty(t, (*messages)[2].Attachments, "Expected no attachments")
+	assert.Equal(t, []model.Reaction{{Emoji: "❤️", Count: 1}}, (*messages)[2].Reactions, "Mismatch in reactions")
+	assert.True(t, (*messages)[1].Deleted, "Expected a tombstone for a message deleted for everyone")
+	assert.Empty(t, (*messages)[1].Content, "Expected a tombstone without content")
+	assert.Equal(t, &model.MessagePreview{ID: "message-id-0", From: receiverID, Snippet: "How are you?"}, (*messages)[2].ReplyTo, "Mismatch in reply preview")
+	assert.Equal(t, &model.MessagePreview{ID: "message-id-2", Deleted: true}, (*messages)[3].ReplyTo, "Expected a tombstone preview for a quoted message deleted for everyone")
+	assert.Equal(t, &model.MessagePreview{ID: "purged-message-id", Deleted: true}, (*messages)[4].ReplyTo, "Expected a tombstone preview for a purged quoted message")
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %s", err)
 	}
 
-	mock.ExpectQuery("SELECT id,content,senderId,recieverId,timestamp FROM messages").
-		WithArgs(senderID, receiverID, limit, (page-1)*limit).
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted").
+		WithArgs(senderID, receiverID, senderID, limit, (page-1)*limit).
 		WillReturnError(fmt.Errorf(""))
 
-	_, err = messageStore.GetMessages(ctx, senderID, receiverID, page, limit)
+	_, err = messageStore.GetMessages(ctx, senderID, senderID, receiverID, page, limit)
 
 	assert.Error(t, err, "Expected an error during failed message retrieval")
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %s", err)
 	}
-}
\ No newline at end of file
+}
+func TestReactions(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	messageStore := message{}
+
+	userID := "test-user-id"
+	messageID := "test-message-id"
+	columns := []string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "member"}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Hello, world!", "sender-user-id", userID, time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+	mock.ExpectExec("INSERT INTO message_reactions").
+		WithArgs(messageID, userID, "👍", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	message, added, err := messageStore.AddReaction(ctx, userID, messageID, "👍")
+
+	assert.NoError(t, err, "Unexpected error while adding a reaction")
+	assert.True(t, added, "Expected the reaction to be added")
+	assert.Equal(t, "sender-user-id", message.From, "Mismatch in reacted message")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Hello, world!", "sender-user-id", userID, time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+	mock.ExpectExec("INSERT INTO message_reactions").
+		WithArgs(messageID, userID, "👍", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, added, err = messageStore.AddReaction(ctx, userID, messageID, "👍")
+
+	assert.NoError(t, err, "Unexpected error while adding a reaction twice")
+	assert.False(t, added, "Expected a repeated reaction not to be added again")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "", "sender-user-id", userID, time.Now(), time.Now(), nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+
+	_, _, err = messageStore.AddReaction(ctx, userID, messageID, "👍")
+
+	assert.Equal(t, sql.ErrNoRows, err, "Expected reactions to a message deleted for everyone to be refused")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Hello, world!", "sender-user-id", "receiver-user-id", time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, false))
+
+	_, _, err = messageStore.AddReaction(ctx, userID, messageID, "👍")
+
+	assert.EqualError(t, err, e.NewError("You are not authorized to see that message").Error(), "Expected an authorization error")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Hello, world!", "sender-user-id", userID, time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+	mock.ExpectExec("DELETE FROM message_reactions WHERE message_id=").
+		WithArgs(messageID, userID, "👍").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, removed, err := messageStore.RemoveReaction(ctx, userID, messageID, "👍")
+
+	assert.NoError(t, err, "Unexpected error while removing a reaction")
+	assert.True(t, removed, "Expected the reaction to be removed")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStars(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	messageStore := message{}
+
+	userID := "test-user-id"
+	messageID := "test-message-id"
+	columns := []string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "member"}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Hello, world!", "sender-user-id", userID, time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+	mock.ExpectExec("INSERT INTO message_stars .* ON CONFLICT DO NOTHING").
+		WithArgs(messageID, userID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := messageStore.StarMessage(ctx, userID, messageID)
+
+	assert.NoError(t, err, "Unexpected error while starring a message")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "", "sender-user-id", userID, time.Now(), time.Now(), nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+
+	err = messageStore.StarMessage(ctx, userID, messageID)
+
+	assert.Equal(t, sql.ErrNoRows, err, "Expected stars on a message deleted for everyone to be refused")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectExec("DELETE FROM message_stars WHERE message_id=\\$1 AND account_id=\\$2").
+		WithArgs(messageID, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = messageStore.UnstarMessage(ctx, userID, messageID)
+
+	assert.NoError(t, err, "Unexpected error while unstarring a message")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* JOIN message_stars s .* ORDER BY s.starred_at DESC LIMIT \\$2 OFFSET \\$3").
+		WithArgs(userID, uint(10), uint(10)).
+		WillReturnRows(sqlmock.NewRows(columns[:len(columns)-1]).
+			AddRow(messageID, "Hello, world!", "sender-user-id", userID, time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil))
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.*, a.message_id FROM attachments a").
+		WithArgs(messageID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "file_name", "content_type", "size", "created_at", "status", "width", "height", "blurhash", "thumbnails", "blob_id", "message_id"}))
+	mock.ExpectQuery("SELECT message_id, emoji, COUNT").
+		WithArgs(userID, messageID).
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "emoji", "count", "me"}))
+
+	messages, err := messageStore.GetStarredMessages(ctx, userID, 2, 10)
+
+	assert.NoError(t, err, "Unexpected error while listing starred messages")
+	assert.Len(t, *messages, 1, "Mismatch in number of starred messages")
+	assert.Equal(t, "Hello, world!", (*messages)[0].Content, "Mismatch in starred message")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPins(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	messageStore := message{}
+
+	userID := "test-user-id"
+	messageID := "test-message-id"
+	columns := []string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "member"}
+
+	testCases := []struct {
+		desc                      string
+		pinned, allowed, inserted bool
+		changed                   bool
+		err                       error
+	}{
+		{desc: "pinned", allowed: true, inserted: true, changed: true},
+		{desc: "already pinned", pinned: true, allowed: true},
+		{desc: "channel message", err: e.NewError("Messages of channels cannot be pinned")},
+		{desc: "too many pins", allowed: true, err: e.NewError("That conversation has too many pinned messages")},
+	}
+
+	for _, tc := range testCases {
+		mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+			WithArgs(messageID, userID).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(messageID, "Hello, world!", "sender-user-id", userID, time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT 1 FROM conversations WHERE id=\\$1 FOR UPDATE").
+			WithArgs("conversation-id").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("WITH pinned AS .* INSERT INTO message_pins").
+			WithArgs(messageID, "conversation-id", userID, sqlmock.AnyArg(), 5).
+			WillReturnRows(sqlmock.NewRows([]string{"pinned", "allowed", "inserted"}).AddRow(tc.pinned, tc.allowed, tc.inserted))
+		mock.ExpectCommit()
+
+		_, changed, err := messageStore.PinMessage(ctx, userID, messageID, 5)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		assert.Equal(t, tc.changed, changed, "TEST: %s: mismatch in whether the message was pinned", tc.desc)
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("TEST: %s: unfulfilled expectations: %s", tc.desc, err)
+		}
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Hello, world!", "sender-user-id", userID, time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT 1 FROM conversations WHERE id=\\$1 FOR UPDATE").
+		WithArgs("conversation-id").
+		WillReturnError(fmt.Errorf("lock timeout"))
+	mock.ExpectRollback()
+
+	_, _, err := messageStore.PinMessage(ctx, userID, messageID, 5)
+
+	assert.Error(t, err, "Expected an error when the conversation cannot be locked")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.* FROM messages LEFT JOIN messages quoted .* WHERE messages.id=").
+		WithArgs(messageID, userID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(messageID, "Hello, world!", "sender-user-id", userID, time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, true))
+	mock.ExpectExec("DELETE FROM message_pins WHERE message_id=\\$1").
+		WithArgs(messageID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, removed, err := messageStore.UnpinMessage(ctx, userID, messageID)
+
+	assert.NoError(t, err, "Unexpected error while unpinning a message")
+	assert.True(t, removed, "Expected the message to be unpinned")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	pinnedAt := time.Now()
+	mock.ExpectQuery("SELECT messages.id,messages.content,.*, p.pinned_by, p.pinned_at FROM messages .* JOIN message_pins p .* ORDER BY p.pinned_at DESC").
+		WithArgs(userID, "conversation-id", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(append(columns[:len(columns)-1:len(columns)-1], "pinned_by", "pinned_at")).
+			AddRow(messageID, "Hello, world!", "sender-user-id", userID, time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, "sender-user-id", pinnedAt))
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.*, a.message_id FROM attachments a").
+		WithArgs(messageID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "file_name", "content_type", "size", "created_at", "status", "width", "height", "blurhash", "thumbnails", "blob_id", "message_id"}))
+	mock.ExpectQuery("SELECT message_id, emoji, COUNT").
+		WithArgs(userID, messageID).
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "emoji", "count", "me"}).
+			AddRow(messageID, "📌", 2, true))
+
+	pins, err := messageStore.GetPinnedMessages(ctx, userID, "conversation-id")
+
+	assert.NoError(t, err, "Unexpected error while listing pinned messages")
+	assert.Len(t, *pins, 1, "Mismatch in number of pinned messages")
+	assert.Equal(t, "sender-user-id", (*pins)[0].PinnedBy, "Mismatch in who pinned the message")
+	assert.Equal(t, pinnedAt, (*pins)[0].PinnedAt, "Mismatch in when the message was pinned")
+	assert.Equal(t, []model.Reaction{{Emoji: "📌", Count: 2, Me: true}}, (*pins)[0].Message.Reactions, "Mismatch in reactions of the pinned message")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetScheduledMessages(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	messageStore := message{}
+
+	scheduledAt := time.Now().Add(time.Hour)
+
+	mock.ExpectQuery("SELECT messages.id,.*, messages.scheduledAt FROM messages LEFT JOIN messages quoted .* WHERE messages.senderId=\\$1 AND messages.scheduledAt IS NOT NULL " +
+		"ORDER BY messages.scheduledAt, messages.id LIMIT \\$2 OFFSET \\$3").
+		WithArgs("user-1", uint(5), uint(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "scheduledAt"}).
+			AddRow("message-id-1", "Happy birthday!", "user-1", "user-2", time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, scheduledAt))
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.*, a.message_id FROM attachments a").
+		WithArgs("message-id-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	messages, err := messageStore.GetScheduledMessages(ctx, "user-1", 2, 5)
+
+	assert.NoError(t, err, "Unexpected error listing scheduled messages")
+	assert.Len(t, *messages, 1, "Unexpected number of scheduled messages")
+	assert.Equal(t, scheduledAt, *(*messages)[0].ScheduledAt, "Mismatch in scheduled time")
+
+	mock.ExpectQuery("SELECT messages.id").
+		WillReturnError(fmt.Errorf(""))
+
+	_, err = messageStore.GetScheduledMessages(ctx, "user-1", 1, 5)
+
+	assert.Error(t, err, "Expected an error during failed listing of scheduled messages")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateScheduledMessage(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	messageStore := message{}
+
+	content := "Happy birthday!!"
+	scheduledAt := time.Now().Add(time.Hour)
+
+	mock.ExpectQuery("WITH updated AS \\( UPDATE messages SET content=CASE WHEN kind = 'text' THEN COALESCE\\(\\$3, content\\) ELSE content END, " +
+		"entities=CASE WHEN kind = 'text' AND \\$3 IS NOT NULL THEN \\$5 ELSE entities END, scheduledAt=COALESCE\\(\\$4, scheduledAt\\), " +
+		"expiresAt=CASE WHEN expiresAt IS NOT NULL THEN COALESCE\\(\\$4, scheduledAt\\) \\+ disappearAfter \\* interval '1 second' ELSE NULL END " +
+		"WHERE id=\\$1 AND senderId=\\$2 AND scheduledAt IS NOT NULL RETURNING \\* \\) SELECT messages.id,.*, messages.scheduledAt FROM updated messages").
+		WithArgs("message-id-1", "user-1", &content, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "scheduledAt"}).
+			AddRow("message-id-1", content, "user-1", "user-2", time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, scheduledAt))
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.*, a.message_id FROM attachments a").
+		WithArgs("message-id-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	updated, err := messageStore.UpdateScheduledMessage(ctx, "user-1", "message-id-1", &content, nil, nil)
+
+	assert.NoError(t, err, "Unexpected error updating a scheduled message")
+	assert.Equal(t, content, updated.Content, "Mismatch in content")
+	assert.Equal(t, scheduledAt, *updated.ScheduledAt, "Mismatch in scheduled time")
+
+	later := scheduledAt.Add(time.Hour)
+	expiresAt := later.Add(time.Minute)
+	mock.ExpectQuery("WITH updated AS").
+		WithArgs("message-id-1", "user-1", nil, &later, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "scheduledAt"}).
+			AddRow("message-id-1", content, "user-1", "user-2", time.Now(), nil, nil, 0, nil, nil, nil, nil, "conversation-id", 60, expiresAt, nil, 0, "text", nil, nil, nil, later))
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.*, a.message_id FROM attachments a").
+		WithArgs("message-id-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	updated, err = messageStore.UpdateScheduledMessage(ctx, "user-1", "message-id-1", nil, nil, &later)
+
+	assert.NoError(t, err, "Unexpected error rescheduling a message")
+	assert.Equal(t, later, *updated.ScheduledAt, "Mismatch in scheduled time")
+	assert.Equal(t, expiresAt, *updated.ExpiresAt, "Expected the expiry to move along with the scheduled time")
+
+	mock.ExpectQuery("WITH updated AS").
+		WithArgs("message-id-1", "user-1", nil, &scheduledAt, nil).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = messageStore.UpdateScheduledMessage(ctx, "user-1", "message-id-1", nil, nil, &scheduledAt)
+
+	assert.Equal(t, sql.ErrNoRows, err, "Expected no rows for a message that was already sent")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCancelScheduledMessage(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	messageStore := message{}
+
+	mock.ExpectQuery("WITH cancelled AS \\( DELETE FROM messages WHERE id=\\$1 AND senderId=\\$2 AND scheduledAt IS NOT NULL RETURNING id \\) " +
+		"SELECT cancelled.id, COALESCE\\(a.blob_id, a.id\\) FROM cancelled LEFT JOIN attachments a ON a.message_id = cancelled.id").
+		WithArgs("message-id-1", "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id"}).
+			AddRow("message-id-1", "attachment-id-1"))
+
+	blobIds, err := messageStore.CancelScheduledMessage(ctx, "user-1", "message-id-1")
+
+	assert.NoError(t, err, "Unexpected error cancelling a scheduled message")
+	assert.Equal(t, []string{"attachment-id-1"}, blobIds, "Mismatch in blobs of the cancelled message")
+
+	mock.ExpectQuery("WITH cancelled AS").
+		WithArgs("message-id-1", "user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id"}))
+
+	_, err = messageStore.CancelScheduledMessage(ctx, "user-1", "message-id-1")
+
+	assert.Equal(t, sql.ErrNoRows, err, "Expected no rows for a message that was already sent")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestReleaseScheduledMessages(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	messageStore := message{}
+
+	now := time.Now()
+
+	mock.ExpectQuery("WITH due AS \\( SELECT id FROM messages WHERE scheduledAt <= \\$1 ORDER BY scheduledAt LIMIT \\$2 FOR UPDATE SKIP LOCKED \\), " +
+		"dropped AS \\( DELETE FROM messages USING due WHERE messages.id = due.id AND EXISTS \\(SELECT 1 FROM blocks WHERE blocker_id = messages.recieverId AND blocked_id = messages.senderId\\) RETURNING messages.id \\), " +
+		"released AS \\( UPDATE messages SET timestamp = scheduledAt, scheduledAt = NULL FROM due WHERE messages.id = due.id AND NOT EXISTS .* RETURNING messages.id,.* messages.kind, messages.entities \\), " +
+		"dropped_blobs \\(message_id, blob_id\\) AS \\( SELECT dropped.id, COALESCE\\(a.blob_id, a.id\\) FROM dropped .* " +
+		"SELECT released.\\*, NULL FROM released UNION ALL SELECT NULL, .*, blob_id FROM dropped_blobs WHERE blob_id IS NOT NULL").
+		WithArgs(now, uint(100)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "senderId", "recieverId", "timestamp", "conversationId", "kind", "entities", "blob_id"}).
+			AddRow("message-id-1", "Happy birthday!", "user-1", "user-2", now, "conversation-id", "text", []byte(`[{"type":"bold","offset":0,"length":5}]`), nil).
+			AddRow(nil, nil, nil, nil, nil, nil, nil, nil, "blob-id"))
+
+	messages, blobIds, err := messageStore.ReleaseScheduledMessages(ctx, now, 100)
+
+	assert.NoError(t, err, "Unexpected error releasing scheduled messages")
+	assert.Equal(t, []model.Message{{ID: "message-id-1", Content: "Happy birthday!", From: "user-1", To: "user-2", Timestamp: now, ConversationID: "conversation-id",
+		Type: model.MessageText, Entities: []model.Entity{{Type: model.EntityBold, Offset: 0, Length: 5}}}}, *messages, "Mismatch in released messages")
+	assert.Equal(t, []string{"blob-id"}, blobIds, "Mismatch in the blobs of dropped messages")
+
+	mock.ExpectQuery("WITH due AS").
+		WillReturnError(fmt.Errorf(""))
+
+	_, _, err = messageStore.ReleaseScheduledMessages(ctx, now, 100)
+
+	assert.Error(t, err, "Expected an error during failed release of scheduled messages")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteExpiredMessages(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	messageStore := message{}
+
+	now := time.Now()
+
+	mock.ExpectQuery("WITH expired AS \\( DELETE FROM messages WHERE id IN \\(SELECT id FROM messages WHERE expiresAt <= \\$1 AND scheduledAt IS NULL LIMIT \\$2 FOR UPDATE SKIP LOCKED\\) " +
+		"RETURNING id \\) SELECT expired.id, COALESCE\\(a.blob_id, a.id\\) FROM expired LEFT JOIN attachments a ON a.message_id = expired.id " +
+		"AND NOT EXISTS \\(SELECT 1 FROM attachments shared WHERE COALESCE\\(shared.blob_id, shared.id\\) = COALESCE\\(a.blob_id, a.id\\) AND shared.message_id NOT IN \\(SELECT id FROM expired\\)\\)").
+		WithArgs(now, uint(100)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id"}).
+			AddRow("message-id-1", "attachment-id-1").
+			AddRow("message-id-1", "attachment-id-2").
+			AddRow("message-id-2", nil))
+
+	deleted, attachmentIds, err := messageStore.DeleteExpiredMessages(ctx, now, 100)
+
+	assert.NoError(t, err, "Unexpected error deleting expired messages")
+	assert.Equal(t, 2, deleted, "Mismatch in number of deleted messages")
+	assert.Equal(t, []string{"attachment-id-1", "attachment-id-2"}, attachmentIds, "Mismatch in attachments of deleted messages")
+
+	mock.ExpectQuery("WITH expired AS").
+		WillReturnError(fmt.Errorf(""))
+
+	_, _, err = messageStore.DeleteExpiredMessages(ctx, now, 100)
+
+	assert.Error(t, err, "Expected an error during failed deletion of expired messages")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSetLinkPreview(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	messageStore := message{}
+	preview := model.LinkPreview{URL: "https://gofr.dev", Title: "GoFr"}
+
+	testCases := []struct {
+		desc     string
+		affected int64
+		err      error
+		attached bool
+	}{
+		{desc: "attached", affected: 1, attached: true},
+		{desc: "edited or deleted meanwhile", affected: 0},
+		{desc: "store error", err: fmt.Errorf("db error")},
+	}
+
+	for _, tc := range testCases {
+		expectation := mock.ExpectExec("UPDATE messages SET linkPreview=\\$3 WHERE id=\\$1 AND content=\\$2 AND deletedAt IS NULL").
+			WithArgs("message-id", "see https://gofr.dev", []byte(`{"url":"https://gofr.dev","title":"GoFr"}`))
+		if tc.err != nil {
+			expectation.WillReturnError(tc.err)
+		} else {
+			expectation.WillReturnResult(sqlmock.NewResult(0, tc.affected))
+		}
+
+		attached, err := messageStore.SetLinkPreview(ctx, "message-id", "see https://gofr.dev", preview)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		assert.Equal(t, tc.attached, attached, "TEST: %s: mismatch in whether the preview was attached", tc.desc)
+	}
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+type presence struct {
+}
+
+type PresenceStore interface {
+	UpdateLastSeen(ctx *gofr.Context, userId string, lastSeen time.Time) error
+	GetPresenceRecords(ctx *gofr.Context, userIds []string) (*[]model.PresenceRecord, error)
+}
+
+func NewPresenceStore(db *datastore.SQLClient) PresenceStore {
+	p := presence{}
+	p.init(db)
+	return p
+}
+
+func (p presence) init(db *datastore.SQLClient) {
+	createPresenceTable(db)
+}
+
+func (presence) UpdateLastSeen(ctx *gofr.Context, userId string, lastSeen time.Time) error {
+	_, err := ctx.DB().ExecContext(ctx, `INSERT INTO presence (account_id, last_seen) VALUES ($1, $2)
+	ON CONFLICT (account_id) DO UPDATE SET last_seen = GREATEST(presence.last_seen, EXCLUDED.last_seen)`, userId, lastSeen)
+	return err
+}
+
+// GetPresenceRecords returns the persisted last-seen of the given accounts together with their last-seen privacy setting.
+// Unknown accounts are left out.
+func (presence) GetPresenceRecords(ctx *gofr.Context, userIds []string) (*[]model.PresenceRecord, error) {
+	records := make([]model.PresenceRecord, 0)
+	if len(userIds) == 0 {
+		return &records, nil
+	}
+
+	args := make([]interface{}, len(userIds))
+	for i, userId := range userIds {
+		args[i] = userId
+	}
+
+	query := fmt.Sprintf(`SELECT a.id, p.last_seen, COALESCE(s.last_seen_visibility, '%s')
+	FROM accounts a
+	LEFT JOIN presence p ON p.account_id = a.id
+	LEFT JOIN account_settings s ON s.account_id = a.id
+	WHERE a.id IN (%s)`, model.LastSeenFriends, placeholders(1, len(userIds)))
+
+	rows, err := ctx.DB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var record model.PresenceRecord
+		var lastSeen sql.NullTime
+
+		err = rows.Scan(&record.UserID, &lastSeen, &record.LastSeenVisibility)
+		if err != nil {
+			return nil, err
+		}
+
+		if lastSeen.Valid {
+			record.LastSeen = &lastSeen.Time
+		}
+
+		records = append(records, record)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &records, nil
+}
+
+func createPresenceTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS presence (
+		account_id UUID PRIMARY KEY,
+		last_seen TIMESTAMP NOT NULL,
+		FOREIGN KEY (account_id) REFERENCES accounts(id)
+	);`
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+func TestUpdateLastSeen(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	presenceStore := presence{}
+	lastSeen := time.Now()
+
+	mock.ExpectExec("INSERT INTO presence").
+		WithArgs("test-user-id", lastSeen).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = presenceStore.UpdateLastSeen(ctx, "test-user-id", lastSeen)
+
+	assert.NoError(t, err, "Unexpected error while updating last seen")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetPresenceRecords(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	presenceStore := presence{}
+	lastSeen := time.Now()
+
+	records, err := presenceStore.GetPresenceRecords(ctx, []string{})
+
+	assert.NoError(t, err, "Unexpected error for an empty id list")
+	assert.Len(t, *records, 0, "Expected no records for an empty id list")
+
+	mock.ExpectQuery(`SELECT a.id, p.last_seen, .* WHERE a.id IN \(\$1,\$2\)`).
+		WithArgs("user-1", "user-2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "last_seen", "last_seen_visibility"}).
+			AddRow("user-1", lastSeen, model.LastSeenFriends).
+			AddRow("user-2", nil, model.LastSeenNobody))
+
+	records, err = presenceStore.GetPresenceRecords(ctx, []string{"user-1", "user-2"})
+
+	assert.NoError(t, err, "Unexpected error during presence retrieval")
+	assert.Len(t, *records, 2, "Unexpected number of presence records")
+	assert.Equal(t, lastSeen, *(*records)[0].LastSeen, "Mismatch in last seen")
+	assert.Nil(t, (*records)[1].LastSeen, "Expected no last seen for a user never seen")
+	assert.Equal(t, model.LastSeenNobody, (*records)[1].LastSeenVisibility, "Mismatch in last seen visibility")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectQuery("SELECT a.id, p.last_seen").
+		WillReturnError(fmt.Errorf(""))
+
+	_, err = presenceStore.GetPresenceRecords(ctx, []string{"user-1"})
+
+	assert.Error(t, err, "Expected an error during failed presence retrieval")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
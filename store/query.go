@@ -0,0 +1,40 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gofr.dev/pkg/gofr"
+)
+
+// placeholders renders n consecutive positional parameters starting at $start, e.g. "$2,$3,$4".
+func placeholders(start, n int) string {
+	params := make([]string, n)
+	for i := range params {
+		params[i] = fmt.Sprintf("$%d", start+i)
+	}
+	return strings.Join(params, ",")
+}
+
+// inTransaction runs fn in a transaction, which is committed if fn succeeds and rolled back otherwise.
+func inTransaction(ctx *gofr.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := ctx.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockConversation holds the row of a conversation until the end of the transaction, so that checks against
+// what the conversation holds, such as how many members or pins it has, are not raced by concurrent changes.
+func lockConversation(ctx *gofr.Context, tx *sql.Tx, conversationId string) error {
+	_, err := tx.ExecContext(ctx, "SELECT 1 FROM conversations WHERE id=$1 FOR UPDATE", conversationId)
+	return err
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+type search struct {
+}
+
+// SearchStore finds messages by their content. Other search engines can be plugged in by implementing it.
+type SearchStore interface {
+	SearchMessages(ctx *gofr.Context, search model.MessageSearch) (*[]model.SearchResult, error)
+}
+
+// searchConfig is the Postgres text search configuration messages are indexed and searched with.
+const searchConfig = "english"
+
+// searchHeadline highlights the matches of query within the HTML escaped content of a message.
+const searchHeadline = `ts_headline('` + searchConfig + `', replace(replace(replace(messages.content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), query,
+	'StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=5, MaxFragments=2, FragmentDelimiter=" … "')`
+
+// NewSearchStore searches messages with Postgres full-text search.
+func NewSearchStore(db *datastore.SQLClient) SearchStore {
+	s := search{}
+	err := s.init(db)
+	if err != nil {
+		fmt.Println("errr:", err)
+	}
+	return s
+}
+
+func (s search) init(db *datastore.SQLClient) error {
+	return s.addSearchColumn(db)
+}
+
+// SearchMessages returns the messages of the conversations the user is a member of that match the query, most relevant first.
+// The query takes the syntax of web search engines: "quoted phrases", OR and -excluded words.
+// Messages deleted for everyone, or by the user for themselves, are never found.
+func (s search) SearchMessages(ctx *gofr.Context, search model.MessageSearch) (*[]model.SearchResult, error) {
+	args := []interface{}{search.Query, search.UserID}
+	filters := ""
+
+	if search.PeerID != "" {
+		args = append(args, DirectConversationID(search.UserID, search.PeerID))
+		filters += fmt.Sprintf(" AND messages.conversationId = $%d", len(args))
+	}
+	if search.Since != nil {
+		args = append(args, *search.Since)
+		filters += fmt.Sprintf(" AND messages.timestamp >= $%d", len(args))
+	}
+	if search.Until != nil {
+		args = append(args, *search.Until)
+		filters += fmt.Sprintf(" AND messages.timestamp < $%d", len(args))
+	}
+	if search.After != nil {
+		args = append(args, search.After.Rank, search.After.Timestamp, search.After.ID)
+		filters += fmt.Sprintf(" AND (match.rank, messages.timestamp, messages.id) < ($%d::real, $%d, $%d)", len(args)-2, len(args)-1, len(args))
+	}
+	args = append(args, search.Limit)
+
+	query := `SELECT ` + messageColumns + `, ` + searchHeadline + `, match.rank
+	FROM ` + messageSource + `
+	CROSS JOIN websearch_to_tsquery('` + searchConfig + `', $1) query
+	CROSS JOIN LATERAL (SELECT ts_rank(messages.contentSearch, query) AS rank) match
+	WHERE messages.contentSearch @@ query AND messages.deletedAt IS NULL AND ` + isSent + ` AND ` + notExpired + ` AND ` + fmt.Sprintf(isMember, 2) + ` AND ` + fmt.Sprintf(hiddenForUser, 2) + filters + `
+	ORDER BY match.rank DESC, messages.timestamp DESC, messages.id DESC LIMIT ` + fmt.Sprintf("$%d", len(args))
+
+	rows, err := ctx.DB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results := make([]model.SearchResult, 0)
+	messages := make([]model.Message, 0)
+
+	for rows.Next() {
+		var result model.SearchResult
+		message, err := scanMessage(rows, &result.Snippet, &result.Rank)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+		messages = append(messages, *message)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	err = message{}.attachAttachments(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	err = message{}.attachReactions(ctx, search.UserID, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].Message = messages[i]
+	}
+	return &results, nil
+}
+
+// addSearchColumn keeps the indexed words of every message in messages.contentSearch, which follows edits and deletions.
+func (search) addSearchColumn(db *datastore.SQLClient) error {
+	query := `ALTER TABLE messages ADD COLUMN IF NOT EXISTS contentSearch tsvector
+		GENERATED ALWAYS AS (to_tsvector('` + searchConfig + `', content)) STORED;
+	CREATE INDEX IF NOT EXISTS messages_content_search_idx ON messages USING GIN (contentSearch);`
+	_, err := db.Exec(query)
+	return err
+}
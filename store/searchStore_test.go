@@ -0,0 +1,66 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchMessages(t *testing.T) {
+	ctx, mock, done := newMockDBContext(t)
+	defer done()
+
+	searchStore := search{}
+
+	columns := []string{"id", "content", "senderId", "recieverId", "timestamp", "deletedAt", "editedAt", "editCount", "replyToId", "quotedContent", "quotedFrom", "quotedDeletedAt", "conversationId", "disappearAfter", "expiresAt", "system", "forwardCount", "kind", "payload", "entities", "linkPreview", "snippet", "rank"}
+	sentAt := time.Now()
+
+	mock.ExpectQuery("SELECT messages.id,messages.content,.*, ts_headline\\('english', replace\\(.*\\), match.rank FROM messages LEFT JOIN messages quoted .* "+
+		"CROSS JOIN websearch_to_tsquery\\('english', \\$1\\) query CROSS JOIN LATERAL \\(SELECT ts_rank\\(messages.contentSearch, query\\) AS rank\\) match "+
+		"WHERE messages.contentSearch @@ query AND messages.deletedAt IS NULL AND messages.scheduledAt IS NULL AND \\(messages.expiresAt IS NULL OR messages.expiresAt > now\\(\\)\\) AND EXISTS .* cm.account_id = \\$2\\) AND NOT EXISTS .* d.account_id = \\$2\\) "+
+		"ORDER BY match.rank DESC, messages.timestamp DESC, messages.id DESC LIMIT \\$3").
+		WithArgs(`"fish tacos"`, "user-1", uint(21)).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("message-id-1", "Fish tacos tonight?", "user-2", "user-1", sentAt, nil, nil, 0, nil, nil, nil, nil, "conversation-id", nil, nil, nil, 0, "text", nil, nil, nil, "<mark>Fish</mark> <mark>tacos</mark> tonight?", float32(0.09910322)))
+	mock.ExpectQuery("SELECT a.id,a.owner_id,.*, a.message_id FROM attachments a").
+		WithArgs("message-id-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT message_id, emoji, COUNT").
+		WithArgs("user-1", "message-id-1").
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "emoji", "count", "me"}))
+
+	results, err := searchStore.SearchMessages(ctx, model.MessageSearch{UserID: "user-1", Query: `"fish tacos"`, Limit: 21})
+
+	assert.NoError(t, err, "Unexpected error during message search")
+	assert.Len(t, *results, 1, "Unexpected number of search results")
+	assert.Equal(t, "message-id-1", (*results)[0].Message.ID, "Mismatch in found message")
+	assert.Equal(t, "<mark>Fish</mark> <mark>tacos</mark> tonight?", (*results)[0].Snippet, "Mismatch in snippet")
+	assert.Equal(t, float32(0.09910322), (*results)[0].Rank, "Mismatch in rank")
+
+	since, until := sentAt.Add(-time.Hour), sentAt
+	after := model.SearchCursor{Rank: 0.5, Timestamp: sentAt, ID: "message-id-1"}
+
+	mock.ExpectQuery("WHERE messages.contentSearch @@ query .* AND messages.conversationId = \\$3 AND messages.timestamp >= \\$4 AND messages.timestamp < \\$5 "+
+		"AND \\(match.rank, messages.timestamp, messages.id\\) < \\(\\$6::real, \\$7, \\$8\\) ORDER BY .* LIMIT \\$9").
+		WithArgs("tacos", "user-1", DirectConversationID("user-1", "user-2"), since, until, float32(0.5), sentAt, "message-id-1", uint(21)).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	results, err = searchStore.SearchMessages(ctx, model.MessageSearch{UserID: "user-1", Query: "tacos", PeerID: "user-2", Since: &since, Until: &until, After: &after, Limit: 21})
+
+	assert.NoError(t, err, "Unexpected error during filtered message search")
+	assert.Empty(t, *results, "Expected no search results")
+
+	mock.ExpectQuery("SELECT messages.id,messages.content").
+		WillReturnError(fmt.Errorf(""))
+
+	_, err = searchStore.SearchMessages(ctx, model.MessageSearch{UserID: "user-1", Query: "tacos", Limit: 21})
+
+	assert.Error(t, err, "Expected an error during failed message search")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+type settings struct {
+}
+
+type SettingsStore interface {
+	GetSettings(ctx *gofr.Context, userId string) (*model.Settings, error)
+	UpdateSettings(ctx *gofr.Context, userId string, settings model.Settings) (*model.Settings, error)
+}
+
+func NewSettingsStore(db *datastore.SQLClient) SettingsStore {
+	s := settings{}
+	s.init(db)
+	return s
+}
+
+func (s settings) init(db *datastore.SQLClient) {
+	createAccountSettingsTable(db)
+}
+
+// GetSettings returns the user's settings, falling back to the defaults for users who never changed them.
+func (settings) GetSettings(ctx *gofr.Context, userId string) (*model.Settings, error) {
+	lastSeenVisibility, discoverable := model.LastSeenFriends, true
+	userSettings := model.Settings{LastSeenVisibility: &lastSeenVisibility, Discoverable: &discoverable}
+
+	err := ctx.DB().QueryRowContext(ctx, "SELECT last_seen_visibility, discoverable FROM account_settings WHERE account_id=$1", userId).
+		Scan(userSettings.LastSeenVisibility, userSettings.Discoverable)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return &userSettings, nil
+}
+
+// UpdateSettings changes the settings given and leaves the omitted ones as they were, returning all of them.
+func (settings) UpdateSettings(ctx *gofr.Context, userId string, userSettings model.Settings) (*model.Settings, error) {
+	var lastSeenVisibility string
+	var discoverable bool
+	err := ctx.DB().QueryRowContext(ctx, `INSERT INTO account_settings (account_id, last_seen_visibility, discoverable)
+	VALUES ($1, COALESCE($2, 'friends'), COALESCE($3, true))
+	ON CONFLICT (account_id) DO UPDATE SET last_seen_visibility = COALESCE($2, account_settings.last_seen_visibility),
+		discoverable = COALESCE($3, account_settings.discoverable)
+	RETURNING last_seen_visibility, discoverable`, userId, userSettings.LastSeenVisibility, userSettings.Discoverable).
+		Scan(&lastSeenVisibility, &discoverable)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Settings{LastSeenVisibility: &lastSeenVisibility, Discoverable: &discoverable}, nil
+}
+
+func createAccountSettingsTable(db *datastore.SQLClient) error {
+	query := `CREATE TABLE IF NOT EXISTS account_settings (
+		account_id UUID PRIMARY KEY,
+		last_seen_visibility VARCHAR(10) NOT NULL DEFAULT 'friends',
+		FOREIGN KEY (account_id) REFERENCES accounts(id)
+	);
+	ALTER TABLE account_settings ADD COLUMN IF NOT EXISTS discoverable BOOLEAN NOT NULL DEFAULT true;
+	ALTER TABLE account_settings ALTER COLUMN last_seen_visibility SET DEFAULT 'friends';
+	UPDATE account_settings SET last_seen_visibility = 'friends' WHERE last_seen_visibility = 'everyone';`
+	_, err := db.Exec(query)
+	return err
+}
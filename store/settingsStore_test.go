@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+	"gofr.dev/pkg/datastore"
+	"gofr.dev/pkg/gofr"
+)
+
+func TestGetSettings(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	settingsStore := settings{}
+
+	mock.ExpectQuery("SELECT last_seen_visibility, discoverable FROM account_settings WHERE account_id=").
+		WithArgs("test-user-id").
+		WillReturnRows(sqlmock.NewRows([]string{"last_seen_visibility", "discoverable"}).AddRow(model.LastSeenNobody, false))
+
+	userSettings, err := settingsStore.GetSettings(ctx, "test-user-id")
+
+	assert.NoError(t, err, "Unexpected error during settings retrieval")
+	assert.Equal(t, model.LastSeenNobody, *userSettings.LastSeenVisibility, "Mismatch in last seen visibility")
+	assert.False(t, *userSettings.Discoverable, "Mismatch in discoverability")
+
+	mock.ExpectQuery("SELECT last_seen_visibility, discoverable FROM account_settings WHERE account_id=").
+		WithArgs("test-user-id").
+		WillReturnError(sql.ErrNoRows)
+
+	userSettings, err = settingsStore.GetSettings(ctx, "test-user-id")
+
+	assert.NoError(t, err, "Expected defaults for a user without settings")
+	assert.Equal(t, model.LastSeenFriends, *userSettings.LastSeenVisibility, "Expected the default last seen visibility")
+	assert.True(t, *userSettings.Discoverable, "Expected users to be discoverable by default")
+
+	mock.ExpectQuery("SELECT last_seen_visibility, discoverable FROM account_settings WHERE account_id=").
+		WithArgs("test-user-id").
+		WillReturnError(fmt.Errorf(""))
+
+	_, err = settingsStore.GetSettings(ctx, "test-user-id")
+
+	assert.Error(t, err, "Expected an error during failed settings retrieval")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateSettings(t *testing.T) {
+	app := gofr.New()
+	ctx := gofr.NewContext(nil, nil, app)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx.Context = context.Background()
+	ctx.DataStore = datastore.DataStore{ORM: db}
+
+	settingsStore := settings{}
+
+	lastSeenVisibility, discoverable := model.LastSeenFriends, true
+
+	mock.ExpectQuery("INSERT INTO account_settings .* last_seen_visibility = COALESCE\\(\\$2, account_settings.last_seen_visibility\\), "+
+		"discoverable = COALESCE\\(\\$3, account_settings.discoverable\\)\\s+RETURNING last_seen_visibility, discoverable").
+		WithArgs("test-user-id", &lastSeenVisibility, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"last_seen_visibility", "discoverable"}).AddRow(model.LastSeenFriends, false))
+
+	userSettings, err := settingsStore.UpdateSettings(ctx, "test-user-id", model.Settings{LastSeenVisibility: &lastSeenVisibility})
+
+	assert.NoError(t, err, "Unexpected error during settings update")
+	assert.Equal(t, model.LastSeenFriends, *userSettings.LastSeenVisibility, "Mismatch in last seen visibility")
+	assert.False(t, *userSettings.Discoverable, "Expected discoverability to be left as it was")
+
+	mock.ExpectQuery("INSERT INTO account_settings").
+		WithArgs("test-user-id", nil, &discoverable).
+		WillReturnRows(sqlmock.NewRows([]string{"last_seen_visibility", "discoverable"}).AddRow(model.LastSeenNobody, true))
+
+	userSettings, err = settingsStore.UpdateSettings(ctx, "test-user-id", model.Settings{Discoverable: &discoverable})
+
+	assert.NoError(t, err, "Unexpected error during settings update")
+	assert.Equal(t, model.LastSeenNobody, *userSettings.LastSeenVisibility, "Expected last seen visibility to be left as it was")
+	assert.True(t, *userSettings.Discoverable, "Mismatch in discoverability")
+
+	mock.ExpectQuery("INSERT INTO account_settings").
+		WillReturnError(fmt.Errorf(""))
+
+	_, err = settingsStore.UpdateSettings(ctx, "test-user-id", model.Settings{LastSeenVisibility: &lastSeenVisibility})
+
+	assert.Error(t, err, "Expected an error during failed settings update")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,63 @@
+package unfurl
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+)
+
+// cache remembers the outcome of unfurling links for ttl, keeping at most size of them.
+type cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	preview *model.LinkPreview
+	err     error
+	expires time.Time
+}
+
+func newCache(ttl time.Duration, size int) *cache {
+	return &cache{ttl: ttl, size: size, entries: make(map[string]cacheEntry)}
+}
+
+func (c *cache) get(link string, now time.Time) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[link]
+	if !ok || !now.Before(entry.expires) {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// put remembers the outcome of unfurling link. A full cache first forgets what expired, then what expires soonest.
+func (c *cache) put(link string, preview *model.LinkPreview, err error, now time.Time) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[link]; !ok && len(c.entries) >= c.size {
+		soonest := ""
+		for cached, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, cached)
+			} else if soonest == "" || entry.expires.Before(c.entries[soonest].expires) {
+				soonest = cached
+			}
+		}
+		if len(c.entries) >= c.size {
+			delete(c.entries, soonest)
+		}
+	}
+
+	c.entries[link] = cacheEntry{preview: preview, err: err, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,95 @@
+package unfurl
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// MaxRedirects bounds the redirects followed to reach a page.
+const MaxRedirects = 3
+
+var (
+	ErrPrivateAddress   = errors.New("the address is not public")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// blockedNetworks are the special-purpose networks that net.IP has no method for: "this network", carrier-grade NAT,
+// IETF protocol assignments, benchmarking, reserved and broadcast addresses, and NAT64, which can reach private IPv4.
+var blockedNetworks = parseNetworks("0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4", "64:ff9b::/96", "64:ff9b:1::/48")
+
+// NewClient returns the HTTP client pages are fetched with. Links in messages are chosen by users, so it only
+// connects to public addresses, checked once the host name resolved so DNS cannot point it anywhere else. It
+// ignores proxy settings, follows at most MaxRedirects redirects to http and https URLs, and gives up on a page
+// that takes longer than timeout.
+func NewClient(timeout time.Duration) *http.Client {
+	return newClient(timeout, publicIP)
+}
+
+func newClient(timeout time.Duration, allowed func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !allowed(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			if !webURL(req.URL) {
+				return ErrUnsupportedURL
+			}
+			return nil
+		},
+	}
+}
+
+// publicIP tells whether ip is a public unicast address, which excludes loopback, private, link-local,
+// multicast and other special-purpose addresses.
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,83 @@
+package unfurl
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+)
+
+// MaxLinks bounds the links of a message that are tried, in order, until one has a preview.
+const MaxLinks = 3
+
+var bareLink = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+`)
+
+// Links returns the links of a text message in the order they appear, each once and at most MaxLinks of them:
+// the URLs of its link entities and the URLs written out in its content, except within code.
+func Links(content string, entities []model.Entity) []string {
+	type found struct {
+		offset int
+		link   string
+	}
+	all := make([]found, 0)
+
+	for _, entity := range entities {
+		if entity.Type == model.EntityLink {
+			all = append(all, found{entity.Offset, entity.URL})
+		}
+	}
+
+	for _, match := range bareLink.FindAllStringIndex(content, -1) {
+		offset := utf8.RuneCountInString(content[:match[0]])
+		if !inCode(offset, entities) {
+			all = append(all, found{offset, trimLink(content[match[0]:match[1]])})
+		}
+	}
+
+	// Entities come in order and so do bare links; merging the two keeps the order of the text.
+	for i := 1; i < len(all); i++ {
+		for j := i; j > 0 && all[j].offset < all[j-1].offset; j-- {
+			all[j], all[j-1] = all[j-1], all[j]
+		}
+	}
+
+	links := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, f := range all {
+		parsed, err := url.Parse(f.link)
+		if err != nil || !webURL(parsed) || seen[f.link] {
+			continue
+		}
+		seen[f.link] = true
+		links = append(links, f.link)
+		if len(links) == MaxLinks {
+			break
+		}
+	}
+	return links
+}
+
+// trimLink drops the punctuation that ends the sentence a link is written in, keeping closing parentheses
+// that belong to the link, as in https://en.wikipedia.org/wiki/Wolf_(disambiguation).
+func trimLink(link string) string {
+	for len(link) > 0 {
+		last := link[len(link)-1]
+		if strings.IndexByte(".,:;!?'*_", last) < 0 && !(last == ')' && strings.Count(link, ")") > strings.Count(link, "(")) {
+			break
+		}
+		link = link[:len(link)-1]
+	}
+	return link
+}
+
+// inCode tells whether the character at offset is within a code entity.
+func inCode(offset int, entities []model.Entity) bool {
+	for _, entity := range entities {
+		if entity.Type == model.EntityCode && offset >= entity.Offset && offset < entity.Offset+entity.Length {
+			return true
+		}
+	}
+	return false
+}
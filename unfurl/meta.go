@@ -0,0 +1,217 @@
+package unfurl
+
+import (
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+)
+
+const (
+	titleLength       = 200
+	descriptionLength = 500
+)
+
+// head is the metadata read from the head of a page: the content of its meta tags by property or name,
+// first one winning, and its title.
+type head struct {
+	meta  map[string]string
+	title string
+}
+
+// parseHead reads the meta tags and title of an HTML page. It is no HTML parser, but it skips comments,
+// scripts and styles, honours quoted attributes, and stops at the body, which is enough to find metadata.
+func parseHead(page string) head {
+	parsed := head{meta: make(map[string]string)}
+
+	for i := 0; i < len(page); {
+		start := strings.IndexByte(page[i:], '<')
+		if start < 0 {
+			break
+		}
+		i += start
+
+		if strings.HasPrefix(page[i:], "<!--") {
+			i = skipPast(page, i+4, "-->")
+			continue
+		}
+
+		name, end := tagName(page, i+1)
+		if name == "" {
+			i++
+			continue
+		}
+		attributes, end := parseAttributes(page, end)
+
+		switch name {
+		case "meta":
+			key := strings.ToLower(attributes["property"])
+			if key == "" {
+				key = strings.ToLower(attributes["name"])
+			}
+			if _, seen := parsed.meta[key]; key != "" && !seen {
+				parsed.meta[key] = attributes["content"]
+			}
+		case "title":
+			closing := indexFold(page, end, "</title")
+			if closing < 0 {
+				return parsed
+			}
+			if parsed.title == "" {
+				parsed.title = html.UnescapeString(page[end:closing])
+			}
+			end = closing
+		case "script", "style":
+			end = skipPast(page, end, "</"+name)
+		case "body", "/head":
+			return parsed
+		}
+		i = end
+	}
+	return parsed
+}
+
+// previewOf picks the preview of a page from its metadata, preferring OpenGraph to Twitter cards to plain HTML.
+func previewOf(parsed head, base *url.URL) model.LinkPreview {
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if value := strings.Join(strings.Fields(parsed.meta[key]), " "); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	preview := model.LinkPreview{
+		SiteName:    truncate(first("og:site_name"), titleLength),
+		Title:       truncate(first("og:title", "twitter:title"), titleLength),
+		Description: truncate(first("og:description", "twitter:description", "description"), descriptionLength),
+	}
+	if preview.Title == "" {
+		preview.Title = truncate(strings.Join(strings.Fields(parsed.title), " "), titleLength)
+	}
+
+	if image := first("og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src"); image != "" {
+		resolved, err := base.Parse(image)
+		if err == nil && webURL(resolved) {
+			preview.ImageURL = resolved.String()
+		}
+	}
+	return preview
+}
+
+// tagName reads the lower-cased name of the tag starting at i, including the slash of closing tags, and returns
+// where it ends. Anything that does not start like a tag has no name.
+func tagName(page string, i int) (string, int) {
+	start := i
+	if i < len(page) && page[i] == '/' {
+		i++
+	}
+	if i >= len(page) || !isLetter(page[i]) {
+		return "", start
+	}
+	for i < len(page) && (isLetter(page[i]) || (page[i] >= '0' && page[i] <= '9')) {
+		i++
+	}
+	return strings.ToLower(page[start:i]), i
+}
+
+// parseAttributes reads the attributes of a tag from i up to the end of the tag, and returns where the tag ends.
+// Names are lower-cased and values unescaped.
+func parseAttributes(page string, i int) (map[string]string, int) {
+	attributes := make(map[string]string)
+	for i < len(page) {
+		i = skipSpace(page, i)
+		if i >= len(page) {
+			break
+		}
+		if page[i] == '>' {
+			return attributes, i + 1
+		}
+		if page[i] == '/' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(page) && !isSpace(page[i]) && page[i] != '=' && page[i] != '>' && page[i] != '/' {
+			i++
+		}
+		if i == start {
+			i++
+			continue
+		}
+		name := strings.ToLower(page[start:i])
+
+		value := ""
+		i = skipSpace(page, i)
+		if i < len(page) && page[i] == '=' {
+			i = skipSpace(page, i+1)
+			if i < len(page) && (page[i] == '"' || page[i] == '\'') {
+				quote := page[i]
+				end := strings.IndexByte(page[i+1:], quote)
+				if end < 0 {
+					return attributes, len(page)
+				}
+				value = page[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(page) && !isSpace(page[i]) && page[i] != '>' {
+					i++
+				}
+				value = page[start:i]
+			}
+		}
+
+		if _, seen := attributes[name]; !seen {
+			attributes[name] = html.UnescapeString(value)
+		}
+	}
+	return attributes, len(page)
+}
+
+// skipPast returns the index after the first occurrence of marker from i on, ignoring case, or the end of the page.
+func skipPast(page string, i int, marker string) int {
+	at := indexFold(page, i, marker)
+	if at < 0 {
+		return len(page)
+	}
+	return at + len(marker)
+}
+
+// indexFold returns the index of the first occurrence of the ASCII marker from i on, ignoring case, or -1.
+func indexFold(page string, i int, marker string) int {
+	for ; i+len(marker) <= len(page); i++ {
+		if strings.EqualFold(page[i:i+len(marker)], marker) {
+			return i
+		}
+	}
+	return -1
+}
+
+func skipSpace(page string, i int) int {
+	for i < len(page) && isSpace(page[i]) {
+		i++
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// truncate shortens text to at most length characters, ending it with an ellipsis when it was cut.
+func truncate(text string, length int) string {
+	text = strings.ToValidUTF8(text, "")
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:length-1])) + "…"
+}
@@ -0,0 +1,112 @@
+// Package unfurl previews the pages that messages link to. It finds the links in a message, fetches the pages
+// they point to over a client that only reaches the public internet, and reads their OpenGraph and Twitter card
+// metadata, falling back to the title and description of the page.
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+)
+
+const (
+	// MaxBytes bounds how much of a page is read. Metadata sits in the head of a page, near its start.
+	MaxBytes = 512 << 10
+
+	// UserAgent identifies the fetcher to the sites it visits.
+	UserAgent = "legoshichat-unfurl/1.0 (link preview)"
+)
+
+var (
+	ErrUnsupportedURL = errors.New("only http and https URLs are previewed")
+	ErrNoPreview      = errors.New("the page has no preview")
+)
+
+// HTTPFetcher sends the requests for the pages that are previewed. NewClient returns the one to use in production.
+type HTTPFetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Unfurler fetches the previews of links, remembering them for a while so links shared widely are fetched once.
+type Unfurler struct {
+	fetcher HTTPFetcher
+	cache   *cache
+}
+
+// NewUnfurler returns an Unfurler fetching pages with fetcher, which keeps up to size previews for ttl each.
+func NewUnfurler(fetcher HTTPFetcher, ttl time.Duration, size int) *Unfurler {
+	return &Unfurler{fetcher: fetcher, cache: newCache(ttl, size)}
+}
+
+// Unfurl returns the preview of the page link points to. Pages without any metadata to show yield ErrNoPreview,
+// which is remembered like previews are; other failures are not, so the link is fetched again next time.
+func (u *Unfurler) Unfurl(ctx context.Context, link string) (*model.LinkPreview, error) {
+	if entry, ok := u.cache.get(link, time.Now()); ok {
+		return entry.preview, entry.err
+	}
+
+	preview, err := u.fetch(ctx, link)
+	if err == nil || errors.Is(err, ErrNoPreview) {
+		u.cache.put(link, preview, err, time.Now())
+	}
+	return preview, err
+}
+
+func (u *Unfurler) fetch(ctx context.Context, link string) (*model.LinkPreview, error) {
+	parsed, err := url.Parse(link)
+	if err != nil || !webURL(parsed) {
+		return nil, ErrUnsupportedURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := u.fetcher.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNoPreview
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, MaxBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	// Relative image URLs are relative to where the page was found after redirects.
+	base := parsed
+	if resp.Request != nil && resp.Request.URL != nil {
+		base = resp.Request.URL
+	}
+
+	preview := previewOf(parseHead(string(page)), base)
+	if preview.Title == "" {
+		return nil, ErrNoPreview
+	}
+	preview.URL = link
+	return &preview, nil
+}
+
+// webURL tells whether link is an absolute http or https URL.
+func webURL(link *url.URL) bool {
+	return (link.Scheme == "http" || link.Scheme == "https") && link.Hostname() != ""
+}
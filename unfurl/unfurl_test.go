@@ -0,0 +1,246 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aryanA101a/legoshichat-backend/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const ogPage = `<!DOCTYPE html>
+<html><head>
+<!-- <meta property="og:title" content="Commented out"> -->
+<title>Fallback title</title>
+<meta property="og:site_name" content="Cherryton">
+<meta property="og:title" content="Beastars &amp; friends">
+<meta name="twitter:title" content="Twitter title">
+<meta property="og:description" content="  Carnivores and
+   herbivores  ">
+<meta property='og:image' content='/images/legoshi.png'>
+<script>var html = '<meta property="og:title" content="From a script">';</script>
+</head><body><meta property="og:description" content="In the body"></body></html>`
+
+// newTestServer serves ogPage at /og, along with pages exercising the other outcomes of unfurling, and counts the
+// requests it gets.
+func newTestServer(t *testing.T, requests *int32) *httptest.Server {
+	mux := http.NewServeMux()
+	page := func(contentType, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(requests, 1)
+			w.Header().Set("Content-Type", contentType)
+			fmt.Fprint(w, body)
+		}
+	}
+
+	mux.HandleFunc("/og", page("text/html; charset=utf-8", ogPage))
+	mux.HandleFunc("/twitter", page("text/html", `<meta name="twitter:title" content="Only a card"><meta name="twitter:image:src" content="https://img.example/c.png">`))
+	mux.HandleFunc("/plain", page("text/html", `<html><head><TITLE>Plain  page</TITLE><meta name="description" content="Nothing special"></head></html>`))
+	mux.HandleFunc("/empty", page("text/html", `<html><body>No metadata</body></html>`))
+	mux.HandleFunc("/image", page("image/png", "\x89PNG"))
+	mux.HandleFunc("/huge", page("text/html", "<html><head>"+strings.Repeat(" ", MaxBytes)+`<meta property="og:title" content="Too far"></head>`))
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/og", http.StatusFound)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		http.NotFound(w, r)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestUnfurl(t *testing.T) {
+	var requests int32
+	server := newTestServer(t, &requests)
+	u := NewUnfurler(server.Client(), time.Hour, 10)
+
+	testCases := []struct {
+		desc    string
+		path    string
+		preview *model.LinkPreview
+		err     error
+	}{
+		{desc: "OpenGraph", path: "/og", preview: &model.LinkPreview{SiteName: "Cherryton", Title: "Beastars & friends",
+			Description: "Carnivores and herbivores", ImageURL: server.URL + "/images/legoshi.png"}},
+		{desc: "Twitter card", path: "/twitter", preview: &model.LinkPreview{Title: "Only a card", ImageURL: "https://img.example/c.png"}},
+		{desc: "plain HTML", path: "/plain", preview: &model.LinkPreview{Title: "Plain page", Description: "Nothing special"}},
+		{desc: "redirect", path: "/moved", preview: &model.LinkPreview{SiteName: "Cherryton", Title: "Beastars & friends",
+			Description: "Carnivores and herbivores", ImageURL: server.URL + "/images/legoshi.png"}},
+		{desc: "no metadata", path: "/empty", err: ErrNoPreview},
+		{desc: "not a page", path: "/image", err: ErrNoPreview},
+		{desc: "metadata past the size limit", path: "/huge", err: ErrNoPreview},
+		{desc: "missing page", path: "/missing", err: errors.New("unexpected status 404 Not Found")},
+	}
+
+	for _, tc := range testCases {
+		link := server.URL + tc.path
+		if tc.preview != nil {
+			tc.preview.URL = link
+		}
+
+		preview, err := u.Unfurl(context.Background(), link)
+
+		assert.Equal(t, tc.err, err, "TEST: %s: unexpected error", tc.desc)
+		assert.Equal(t, tc.preview, preview, "TEST: %s: mismatch in preview", tc.desc)
+	}
+
+	_, err := u.Unfurl(context.Background(), "ftp://example.com/file")
+	assert.Equal(t, ErrUnsupportedURL, err, "Expected links other than http and https ones not to be fetched")
+}
+
+func TestUnfurlCaches(t *testing.T) {
+	var requests int32
+	server := newTestServer(t, &requests)
+	u := NewUnfurler(server.Client(), time.Hour, 10)
+
+	for _, path := range []string{"/og", "/og", "/empty", "/empty", "/missing", "/missing"} {
+		u.Unfurl(context.Background(), server.URL+path)
+	}
+
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests), "Expected previews and pages without one to be fetched once, and failures again")
+}
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+	c := newCache(time.Minute, 2)
+
+	c.put("a", &model.LinkPreview{URL: "a"}, nil, now)
+	c.put("b", nil, ErrNoPreview, now.Add(time.Second))
+
+	entry, ok := c.get("b", now)
+	assert.True(t, ok, "Expected b to be cached")
+	assert.Equal(t, ErrNoPreview, entry.err, "Mismatch in cached error")
+
+	_, ok = c.get("a", now.Add(time.Minute))
+	assert.False(t, ok, "Expected a to expire after a minute")
+
+	c.put("c", &model.LinkPreview{URL: "c"}, nil, now.Add(2*time.Second))
+	_, ok = c.get("a", now)
+	assert.False(t, ok, "Expected a full cache to drop what expires soonest")
+	_, ok = c.get("c", now)
+	assert.True(t, ok, "Expected c to be cached")
+}
+
+func TestClientBlocksPrivateAddresses(t *testing.T) {
+	var requests int32
+	server := newTestServer(t, &requests)
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	u := NewUnfurler(NewClient(time.Second), time.Hour, 10)
+	for _, link := range []string{server.URL + "/og", "http://localhost:" + port + "/og", "http://[::ffff:127.0.0.1]:" + port + "/og"} {
+		_, err := u.Unfurl(context.Background(), link)
+
+		assert.ErrorIs(t, err, ErrPrivateAddress, "Expected %s not to be fetched", link)
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests), "Expected no request to reach the server")
+}
+
+func TestClientLimits(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hop/", func(w http.ResponseWriter, r *http.Request) {
+		var hop int
+		fmt.Sscanf(r.URL.Path, "/hop/%d", &hop)
+		if hop == 0 {
+			fmt.Fprint(w, `<meta property="og:title" content="Arrived">`)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", hop-1), http.StatusFound)
+	})
+	mux.HandleFunc("/scheme", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := newClient(100*time.Millisecond, func(net.IP) bool { return true })
+	fetch := func(path string) error {
+		resp, err := client.Get(server.URL + path)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	assert.NoError(t, fetch(fmt.Sprintf("/hop/%d", MaxRedirects)), "Expected up to MaxRedirects redirects to be followed")
+	assert.ErrorIs(t, fetch(fmt.Sprintf("/hop/%d", MaxRedirects+1)), ErrTooManyRedirects, "Expected a redirect too many to fail")
+	assert.ErrorIs(t, fetch("/scheme"), ErrUnsupportedURL, "Expected redirects to other schemes to fail")
+
+	err := fetch("/slow")
+	var urlErr *url.Error
+	assert.True(t, errors.As(err, &urlErr) && urlErr.Timeout(), "Expected a slow page to time out, got %v", err)
+}
+
+func TestPublicIP(t *testing.T) {
+	testCases := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"::ffff:10.0.0.1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"64:ff9b::a00:1", false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.public, publicIP(net.ParseIP(tc.ip)), "TEST: %s: mismatch in whether the address is public", tc.ip)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		content  string
+		entities []model.Entity
+		links    []string
+	}{
+		{desc: "none", content: "no links here"},
+		{desc: "bare links", content: "see https://gofr.dev/docs, and http://example.com/a?b=c.",
+			links: []string{"https://gofr.dev/docs", "http://example.com/a?b=c"}},
+		{desc: "parentheses", content: "(https://en.wikipedia.org/wiki/Wolf_(disambiguation))",
+			links: []string{"https://en.wikipedia.org/wiki/Wolf_(disambiguation)"}},
+		{desc: "link entities in order", content: "docs then https://example.com",
+			entities: []model.Entity{{Type: model.EntityLink, Offset: 0, Length: 4, URL: "https://gofr.dev/docs"}},
+			links:    []string{"https://gofr.dev/docs", "https://example.com"}},
+		{desc: "within code", content: "run curl https://localhost:8080",
+			entities: []model.Entity{{Type: model.EntityCode, Offset: 4, Length: 27}}},
+		{desc: "repeated and capped", content: "https://a.io https://a.io https://b.io https://c.io https://d.io",
+			links: []string{"https://a.io", "https://b.io", "https://c.io"}},
+		{desc: "other schemes", content: "ftp://example.com and javascript:alert(1)"},
+	}
+
+	for _, tc := range testCases {
+		links := Links(tc.content, tc.entities)
+		if tc.links == nil {
+			tc.links = []string{}
+		}
+
+		assert.Equal(t, tc.links, links, "TEST: %s: mismatch in links", tc.desc)
+	}
+}